@@ -1,10 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/osteele/cmd-runner/internal"
@@ -20,14 +21,70 @@ func showHelp() {
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	fmt.Fprintf(os.Stderr, "  --interactive, -i       Launch interactive mode for command selection\n")
+	fmt.Fprintf(os.Stderr, "  --pick                  Pick a command to run from a fuzzy-searchable list\n")
 	fmt.Fprintf(os.Stderr, "  --list, -l              List available commands for current project\n")
 	fmt.Fprintf(os.Stderr, "    --all                 Show commands from all sources (not just primary)\n")
 	fmt.Fprintf(os.Stderr, "    --verbose             Show full command descriptions\n")
+	fmt.Fprintf(os.Stderr, "    --format F            Print for scripts: plain, tsv, json, or yaml\n")
+	fmt.Fprintf(os.Stderr, "    --json                Print commands, what they run, synthesis, and aliases\n")
+	fmt.Fprintf(os.Stderr, "    --aliases             Show what each alias runs in this project\n")
+	fmt.Fprintf(os.Stderr, "  --project, -p NAME      Run in a project registered in the user config\n")
+	fmt.Fprintf(os.Stderr, "  --env, -e KEY=VALUE     Set an environment variable for the command (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --env-file PATH         Load environment variables from a dotenv file (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --retry N               Retry up to N attempts on transient network errors\n")
+	fmt.Fprintf(os.Stderr, "  --jobs, -j N            Run at most N commands at once when running several\n")
+	fmt.Fprintf(os.Stderr, "  --no-source NAME        Ignore a command source, e.g. make (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --analyze-only          Inspect an untrusted project: parse files only, never run anything\n")
+	fmt.Fprintf(os.Stderr, "  --client                Fail unless a cmdr daemon is running to serve source listings\n")
+	fmt.Fprintf(os.Stderr, "  --no-synth              Run only commands the project defines (no synthesized or tool defaults)\n")
+	fmt.Fprintf(os.Stderr, "  --parallel              Run check's steps, or --recursive's projects, at once with labeled output\n")
+	fmt.Fprintf(os.Stderr, "  --fail-fast             Stop synthesized check at the first failing step\n")
+	fmt.Fprintf(os.Stderr, "  --keep-going            Run every step of synthesized check (the default)\n")
+	fmt.Fprintf(os.Stderr, "  --watch, -w CMD...      Re-run one or more commands when files change\n")
+	fmt.Fprintf(os.Stderr, "  --recursive, -r         Run the command in every project under the repo root\n")
+	fmt.Fprintf(os.Stderr, "    --filter GLOB         Only projects whose path or name matches (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "    --exclude GLOB        Skip projects whose path or name matches (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --dashboard             Run a process group under an interactive dashboard\n")
+	fmt.Fprintf(os.Stderr, "  --debug                 Log how the command resolves: files, sources, and list commands\n")
+	fmt.Fprintf(os.Stderr, "  --debug-file PATH       Append that log to a file instead of stderr\n")
+	fmt.Fprintf(os.Stderr, "  --junit PATH            Write a JUnit XML report, one testcase per check step\n")
+	fmt.Fprintf(os.Stderr, "  --sarif PATH            Write a SARIF log of the diagnostics linters print\n")
+	fmt.Fprintf(os.Stderr, "  --log-file PATH         Also write the command's output to a file\n")
+	fmt.Fprintf(os.Stderr, "  --time                  Print how long the command (and each step of check/fix) took\n")
+	fmt.Fprintf(os.Stderr, "  --timings               Print how long each phase took: detecting sources, listing, lookup, running\n")
+	fmt.Fprintf(os.Stderr, "  --quiet, -q             Don't print the Running: banner or synthesized-command progress\n")
+	fmt.Fprintf(os.Stderr, "  --color WHEN            Color output: auto (default), always, or never\n")
+	fmt.Fprintf(os.Stderr, "  --pty, --no-pty         Always (or never) run under a pseudo-terminal to keep colors\n")
+	fmt.Fprintf(os.Stderr, "  --yes, -y               Run update or publish without asking\n")
+	fmt.Fprintf(os.Stderr, "  --sudo                  Run the command through sudo (asks for confirmation)\n")
+	fmt.Fprintf(os.Stderr, "  --as-user USER          Run the command as another user via sudo\n")
+	fmt.Fprintf(os.Stderr, "  --in-container          Run the command in the project's devcontainer or [container] image\n")
 	fmt.Fprintf(os.Stderr, "  --version, -v           Show version information\n")
 	fmt.Fprintf(os.Stderr, "  --help, -h              Show this help message\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Special Commands:\n")
-	fmt.Fprintf(os.Stderr, "  install-alias [--dry-run]  Install 'cr' alias to shell config\n")
+	fmt.Fprintf(os.Stderr, "  install-alias [--name N]   Install 'cr' (or another) alias to shell config\n")
+	fmt.Fprintf(os.Stderr, "  uninstall-alias [--name N] Remove the alias install-alias added\n")
+	fmt.Fprintf(os.Stderr, "  help <command>             Show a command's definition and details\n")
+	fmt.Fprintf(os.Stderr, "  which [--format F] <cmd>   Show what a command resolves to (text, shell, json, argv)\n")
+	fmt.Fprintf(os.Stderr, "  explain <cmd>              Show each resolution step: sources, variants, matches\n")
+	fmt.Fprintf(os.Stderr, "  env [--all]                Show environment variables passed to commands\n")
+	fmt.Fprintf(os.Stderr, "  audit-log tail|verify|path Show or check the log of commands cmdr has run\n")
+	fmt.Fprintf(os.Stderr, "  history [--failed] [<text>] Show recent runs: command, what it ran, exit code, time\n")
+	fmt.Fprintf(os.Stderr, "  last, rerun                Run this project's most recent command again\n")
+	fmt.Fprintf(os.Stderr, "  doctor                     Check that the project's build tools are installed\n")
+	fmt.Fprintf(os.Stderr, "  init [--force] [--dry-run] Write a starter .cmdr.toml for this project\n")
+	fmt.Fprintf(os.Stderr, "  sources                    Show detected sources, their priority, files, and tools\n")
+	fmt.Fprintf(os.Stderr, "  pm [set <manager>]         Show (or pin) the Node/Python package manager and why\n")
+	fmt.Fprintf(os.Stderr, "  projects                   List projects registered for --project\n")
+	fmt.Fprintf(os.Stderr, "  stop [<group>...]          Stop running process groups of this project\n")
+	fmt.Fprintf(os.Stderr, "  daemon [status|stop]       Keep mise/just/make listings warm for faster runs\n")
+	fmt.Fprintf(os.Stderr, "  cache path|clear           Show or clear the on-disk cache of source listings\n")
+	fmt.Fprintf(os.Stderr, "  export procfile|compose [<group>]  Write a process group as a Procfile or Compose file\n")
+	fmt.Fprintf(os.Stderr, "  watch                      Run the commands in .cmdr.toml [watch] rules on file changes\n")
+	fmt.Fprintf(os.Stderr, "  upgrade-tools [--dry-run]  Update the project's toolchains (mise, rustup, corepack, uv)\n")
+	fmt.Fprintf(os.Stderr, "  self-update [--check]      Install the latest release of cmdr (or just check for one)\n")
+	fmt.Fprintf(os.Stderr, "  completion <shell>         Write a shell completion script (bash, zsh, fish, powershell)\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Common Commands:\n")
 	fmt.Fprintf(os.Stderr, "  setup      Install dependencies for local development\n")
@@ -36,10 +93,14 @@ func showHelp() {
 	fmt.Fprintf(os.Stderr, "  build      Build the project\n")
 	fmt.Fprintf(os.Stderr, "  run        Run the project (or dev/serve)\n")
 	fmt.Fprintf(os.Stderr, "  format     Format code (or fmt)\n")
+	fmt.Fprintf(os.Stderr, "  fmt-check  Check formatting without changing files (or format --check)\n")
 	fmt.Fprintf(os.Stderr, "  lint       Run linters\n")
 	fmt.Fprintf(os.Stderr, "  typecheck  Run type checker\n")
 	fmt.Fprintf(os.Stderr, "  check      Run lint, typecheck, and test\n")
-	fmt.Fprintf(os.Stderr, "  clean      Clean build artifacts\n")
+	fmt.Fprintf(os.Stderr, "  clean      Clean build artifacts (--deep, --dry-run)\n")
+	fmt.Fprintf(os.Stderr, "  outdated   List outdated dependencies (or deps)\n")
+	fmt.Fprintf(os.Stderr, "  update     Update dependencies, after asking (or upgrade)\n")
+	fmt.Fprintf(os.Stderr, "  publish    Check, then publish a release, after asking (or release)\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Short Aliases:\n")
 	fmt.Fprintf(os.Stderr, "  f → format    t → test     tc → typecheck\n")
@@ -53,32 +114,411 @@ func showVersion() {
 	fmt.Printf("cmdr version %s\n", version)
 }
 
+// options holds global flags that apply to every runner
+type options struct {
+	envOverrides map[string]string
+	envFiles     []string
+	sudo         bool
+	inContainer  bool
+	asUser       string
+	retry        internal.RetryPolicy
+	dashboard    bool
+	pty          internal.PTYMode
+	quiet        bool
+	time         bool
+	debugFile    *os.File
+	project      string
+	noSynth      bool
+	failFast     *bool
+	parallel     bool
+	filters      []string
+	logFile      string
+	junit        string
+	sarif        string
+	yes          bool
+	excludes     []string
+	jobs         int
+}
+
+// newRunner creates and initializes a runner with the global options applied
+func newRunner(opts *options, command string, args []string) *internal.CommandRunner {
+	runner := internal.New(command, args)
+	if err := runner.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing: %v\n", err)
+		os.Exit(1)
+	}
+	runner.EnvOverrides = opts.envOverrides
+	runner.EnvFiles = opts.envFiles
+	runner.Sudo = opts.sudo
+	runner.Yes = opts.yes
+	runner.AsUser = opts.asUser
+	runner.InContainer = opts.inContainer
+	runner.Retry = opts.retry
+	runner.Dashboard = opts.dashboard
+	runner.NoSynth = opts.noSynth
+	runner.FailFast = opts.failFast
+	runner.Parallel = opts.parallel
+	runner.LogFile = opts.logFile
+	runner.JUnit = opts.junit
+	runner.SARIF = opts.sarif
+	runner.ProjectFilters = opts.filters
+	runner.ProjectExcludes = opts.excludes
+	runner.Jobs = opts.jobs
+	runner.PTY = opts.pty
+	runner.Quiet = opts.quiet
+	runner.Time = opts.time
+	return runner
+}
+
+// addEnvFile records the argument to --env-file, as an absolute path,
+// exiting if it isn't a readable, valid env file
+func (o *options) addEnvFile(path string) {
+	if err := internal.CheckEnvFile(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --env-file %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	o.envFiles = append(o.envFiles, absPath)
+}
+
+// addEnvOverride records a KEY=VALUE argument to -e/--env, exiting on error
+func (o *options) addEnvOverride(arg string) {
+	name, value, err := internal.ParseEnvAssignment(arg)
+	if err != nil {
+		fail(err)
+	}
+	if o.envOverrides == nil {
+		o.envOverrides = make(map[string]string)
+	}
+	o.envOverrides[name] = value
+}
+
+// setRetryAttempts parses the argument to --retry, exiting on error
+func (o *options) setRetryAttempts(arg string) {
+	attempts, err := strconv.Atoi(arg)
+	if err != nil || attempts < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --retry expects a positive number of attempts, got %q\n", arg)
+		os.Exit(1)
+	}
+	o.retry.Attempts = attempts
+}
+
+// setDebugFile starts logging resolution to the end of path, exiting on error
+func (o *options) setDebugFile(path string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --debug-file: %v\n", err)
+		os.Exit(1)
+	}
+	o.debugFile = f
+	internal.SetDebugLog(f)
+}
+
+// setJobs parses the argument to --jobs, exiting on error
+func (o *options) setJobs(arg string) {
+	jobs, err := strconv.Atoi(arg)
+	if err != nil || jobs < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --jobs expects a positive number, got %q\n", arg)
+		os.Exit(1)
+	}
+	o.jobs = jobs
+}
+
+// setColorMode applies --color, exiting on an unknown mode
+func setColorMode(mode string) {
+	if err := internal.SetColorMode(mode); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// addProjectFilter records a --filter or --exclude pattern, exiting on an
+// invalid glob
+func (o *options) addProjectFilter(flag, pattern string) {
+	if err := internal.ValidateProjectFilter(pattern); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s %q: %v\n", flag, pattern, err)
+		os.Exit(1)
+	}
+	if flag == "--filter" {
+		o.filters = append(o.filters, pattern)
+	} else {
+		o.excludes = append(o.excludes, pattern)
+	}
+}
+
+// takeReportOption removes a report option such as --junit PATH from a
+// command's arguments, so that it can follow the command (cmdr check
+// --junit report.xml) as well as precede it
+func takeReportOption(args []string, flag string, value *string) []string {
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return append(rest, args[i:]...)
+		case arg == flag && i+1 < len(args):
+			i++
+			*value = args[i]
+		case strings.HasPrefix(arg, flag+"="):
+			*value = strings.TrimPrefix(arg, flag+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// fail reports an error, with a hint for the kinds of failure that have
+// one, and exits with its exit code class
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	var missing *internal.ToolMissingError
+	switch {
+	case errors.As(err, &missing) && len(missing.Install) > 0:
+		fmt.Fprintf(os.Stderr, "Install it with: %s\n", missing.Install[0])
+		for _, command := range missing.Install[1:] {
+			fmt.Fprintf(os.Stderr, "            or: %s\n", command)
+		}
+	case errors.As(err, &missing):
+		fmt.Fprintf(os.Stderr, "Install %s, or run `cmdr doctor` to check the project's tools\n", missing.Tool)
+	case errors.Is(err, internal.ErrNoSources):
+		fmt.Fprintf(os.Stderr, "Run cmdr in a project directory, or define commands under [commands] in %s\n", internal.ProjectConfigFile)
+	case errors.Is(err, internal.ErrCommandNotFound):
+		fmt.Fprintf(os.Stderr, "Run `cmdr --list` to see this project's commands\n")
+	}
+	internal.WriteTimings(os.Stderr)
+	os.Exit(internal.ExitCode(err))
+}
+
 func main() {
+	opts := &options{}
+
 	// Parse arguments
 	if len(os.Args) < 2 {
-		// No arguments - show command list
-		runner := internal.New("", nil)
-		if err := runner.Init(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error initializing: %v\n", err)
-			os.Exit(1)
+		// No arguments - show command list, or the picker if configured
+		if internal.PickOnBareCommand() {
+			if err := internal.RunPicker(); err != nil {
+				fail(err)
+			}
+			os.Exit(0)
 		}
+		runner := newRunner(opts, "", nil)
 		runner.ListCommands()
 		os.Exit(0)
 	}
 
+	// Default flags from the user config come before the actual arguments
+	argv := append([]string{os.Args[0]}, internal.DefaultFlags()...)
+	argv = append(argv, os.Args[1:]...)
+
 	preCommandFlags := []string{}
 	command := ""
 	commandIndex := -1
+	listFormat := ""
+	listJSON := false
 
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
+	for i := 1; i < len(argv); i++ {
+		arg := argv[i]
 		if arg == "--" {
-			if i+1 < len(os.Args) {
-				command = os.Args[i+1]
+			if i+1 < len(argv) {
+				command = argv[i+1]
 				commandIndex = i + 1
 			}
 			break
 		}
+		// Flags that take a value consume the following argument
+		if arg == "-e" || arg == "--env" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a KEY=VALUE argument\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.addEnvOverride(argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--env="); ok {
+			opts.addEnvOverride(value)
+			continue
+		}
+		if arg == "--env-file" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a file path\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.addEnvFile(argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--env-file="); ok {
+			opts.addEnvFile(value)
+			continue
+		}
+		if arg == "--retry" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a number of attempts\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.setRetryAttempts(argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--retry="); ok {
+			opts.setRetryAttempts(value)
+			continue
+		}
+		if arg == "--color" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires auto, always, or never\n", arg)
+				os.Exit(1)
+			}
+			i++
+			setColorMode(argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--color="); ok {
+			setColorMode(value)
+			continue
+		}
+		if arg == "-j" || arg == "--jobs" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a number of jobs\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.setJobs(argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--jobs="); ok {
+			opts.setJobs(value)
+			continue
+		}
+		if arg == "--debug-file" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a file path\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.setDebugFile(argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--debug-file="); ok {
+			opts.setDebugFile(value)
+			continue
+		}
+		if arg == "--junit" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a file path\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.junit = argv[i]
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--junit="); ok {
+			opts.junit = value
+			continue
+		}
+		if arg == "--sarif" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a file path\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.sarif = argv[i]
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--sarif="); ok {
+			opts.sarif = value
+			continue
+		}
+		if arg == "--log-file" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a file path\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.logFile = argv[i]
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--log-file="); ok {
+			opts.logFile = value
+			continue
+		}
+		if arg == "--filter" || arg == "--exclude" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a glob or project name\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.addProjectFilter(arg, argv[i])
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--filter="); ok {
+			opts.addProjectFilter("--filter", value)
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--exclude="); ok {
+			opts.addProjectFilter("--exclude", value)
+			continue
+		}
+		if arg == "-p" || arg == "--project" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a project name\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.project = argv[i]
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--project="); ok {
+			opts.project = value
+			continue
+		}
+		if arg == "--no-source" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a source name\n", arg)
+				os.Exit(1)
+			}
+			i++
+			internal.DisableSources(strings.Split(argv[i], ",")...)
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--no-source="); ok {
+			internal.DisableSources(strings.Split(value, ",")...)
+			continue
+		}
+		if arg == "--format" && command == "" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a format (%s)\n", arg, strings.Join(internal.ListFormats, ", "))
+				os.Exit(1)
+			}
+			i++
+			listFormat = argv[i]
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--format="); ok && command == "" {
+			listFormat = value
+			continue
+		}
+		if arg == "--json" && command == "" {
+			listJSON = true
+			continue
+		}
+		if arg == "--as-user" {
+			if i+1 >= len(argv) {
+				fmt.Fprintf(os.Stderr, "Option %s requires a user name\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.asUser = argv[i]
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--as-user="); ok {
+			opts.asUser = value
+			continue
+		}
 		if strings.HasPrefix(arg, "-") && command == "" {
 			preCommandFlags = append(preCommandFlags, arg)
 			continue
@@ -90,6 +530,17 @@ func main() {
 		break
 	}
 
+	// Commands for another project run as if cmdr had been started there
+	if opts.project != "" {
+		dir, err := internal.ProjectDir(opts.project)
+		if err != nil {
+			fail(err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			fail(err)
+		}
+	}
+
 	listRequested := false
 	for _, flag := range preCommandFlags {
 		if flag == "--list" || flag == "-l" || flag == "--commands" {
@@ -98,17 +549,21 @@ func main() {
 	}
 
 	listAll := false
+	listAliases := false
 	verbose := false
 	showHelpFlag := false
+	watch := false
+	recursive := false
+	// Modes that take over the terminal start once every flag is applied
+	interactive := false
+	pick := false
 
 	for _, flag := range preCommandFlags {
 		switch flag {
 		case "--interactive", "-i":
-			if err := internal.RunInteractive(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			os.Exit(0)
+			interactive = true
+		case "--pick":
+			pick = true
 		case "--help", "-h":
 			showHelpFlag = true
 		case "--version", "-v":
@@ -116,6 +571,45 @@ func main() {
 			os.Exit(0)
 		case "--list", "-l", "--commands":
 			// processed after loop
+		case "--sudo":
+			opts.sudo = true
+		case "--in-container":
+			opts.inContainer = true
+		case "--yes", "-y":
+			opts.yes = true
+		case "--watch", "-w":
+			watch = true
+		case "--recursive", "-r":
+			recursive = true
+		case "--dashboard":
+			opts.dashboard = true
+		case "--debug":
+			if opts.debugFile == nil {
+				internal.SetDebugLog(os.Stderr)
+			}
+		case "--timings":
+			internal.SetTimings(true)
+		case "--time":
+			opts.time = true
+		case "--quiet", "-q":
+			opts.quiet = true
+		case "--pty":
+			opts.pty = internal.PTYAlways
+		case "--no-pty":
+			opts.pty = internal.PTYNever
+		case "--no-synth":
+			opts.noSynth = true
+		case "--parallel":
+			opts.parallel = true
+		case "--fail-fast", "--keep-going":
+			failFast := flag == "--fail-fast"
+			opts.failFast = &failFast
+		case "--analyze-only":
+			internal.SetAnalyzeOnly(true)
+		case "--client":
+			if err := internal.RequireDaemon(); err != nil {
+				fail(err)
+			}
 		case "--all", "-a", "--list-all":
 			if !listRequested {
 				fmt.Fprintf(os.Stderr, "Unknown option: %s\n", flag)
@@ -130,6 +624,13 @@ func main() {
 				os.Exit(1)
 			}
 			verbose = true
+		case "--aliases":
+			if !listRequested {
+				fmt.Fprintf(os.Stderr, "Unknown option: %s\n", flag)
+				fmt.Fprintf(os.Stderr, "Try 'cmdr --help' for more information.\n")
+				os.Exit(1)
+			}
+			listAliases = true
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown option: %s\n", flag)
 			fmt.Fprintf(os.Stderr, "Try 'cmdr --help' for more information.\n")
@@ -137,6 +638,36 @@ func main() {
 		}
 	}
 
+	if interactive {
+		if err := internal.RunInteractive(); err != nil {
+			fail(err)
+		}
+		os.Exit(0)
+	}
+	if pick {
+		if err := internal.RunPicker(); err != nil {
+			fail(err)
+		}
+		os.Exit(0)
+	}
+
+	if listFormat != "" && !listRequested {
+		fmt.Fprintf(os.Stderr, "The --format option applies to --list.\n")
+		fmt.Fprintf(os.Stderr, "Try 'cmdr --help' for more information.\n")
+		os.Exit(1)
+	}
+	if listJSON && (!listRequested || listFormat != "") {
+		fmt.Fprintf(os.Stderr, "The --json option applies to --list, without --format.\n")
+		fmt.Fprintf(os.Stderr, "Try 'cmdr --help' for more information.\n")
+		os.Exit(1)
+	}
+
+	if (len(opts.filters) > 0 || len(opts.excludes) > 0) && !recursive {
+		fmt.Fprintf(os.Stderr, "The --filter and --exclude options apply to --recursive.\n")
+		fmt.Fprintf(os.Stderr, "Try 'cmdr --help' for more information.\n")
+		os.Exit(1)
+	}
+
 	if showHelpFlag && !listRequested {
 		showHelp()
 		os.Exit(0)
@@ -151,6 +682,9 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Options:\n")
 			fmt.Fprintf(os.Stderr, "  --all, -a      Show commands from all sources (not just primary)\n")
 			fmt.Fprintf(os.Stderr, "  --verbose      Show full command descriptions (no truncation)\n")
+			fmt.Fprintf(os.Stderr, "  --format F     Print for scripts: plain (names), tsv, json, or yaml\n")
+			fmt.Fprintf(os.Stderr, "  --json         Print commands with what they run, synthesized commands, and aliases\n")
+			fmt.Fprintf(os.Stderr, "  --aliases      Show what each alias runs in this project (with --json, as JSON)\n")
 			fmt.Fprintf(os.Stderr, "  --help, -h     Show this help message\n")
 			fmt.Fprintf(os.Stderr, "\n")
 			fmt.Fprintf(os.Stderr, "By default, only commands from the primary source (e.g., mise, just, make)\n")
@@ -158,12 +692,31 @@ func main() {
 			os.Exit(0)
 		}
 
-		runner := internal.New("", nil)
-		if err := runner.Init(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error initializing: %v\n", err)
-			os.Exit(1)
+		runner := newRunner(opts, "", nil)
+		if listAliases && listJSON {
+			if err := runner.WriteAliasesJSON(os.Stdout); err != nil {
+				fail(err)
+			}
+			os.Exit(0)
+		}
+		if listAliases {
+			runner.ListAliases(os.Stdout)
+			os.Exit(0)
+		}
+		if listJSON {
+			if err := runner.WriteCommandListJSON(os.Stdout, listAll); err != nil {
+				fail(err)
+			}
+			os.Exit(0)
+		}
+		if listFormat != "" {
+			if err := runner.WriteCommandList(os.Stdout, listFormat, listAll); err != nil {
+				fail(err)
+			}
+			os.Exit(0)
 		}
 		runner.ListCommandsWithOptions(listAll, verbose)
+		internal.WriteTimings(os.Stderr)
 		os.Exit(0)
 	}
 
@@ -177,145 +730,434 @@ func main() {
 			showHelp()
 			os.Exit(0)
 		}
+		if opts.project != "" {
+			runner := newRunner(opts, "", nil)
+			runner.ListCommands()
+			os.Exit(0)
+		}
 		showHelp()
 		os.Exit(1)
 	}
 
 	// We have a command - pass all args after it unchanged
 	args := []string{}
-	if commandIndex >= 0 && commandIndex+1 < len(os.Args) {
-		args = os.Args[commandIndex+1:]
+	if commandIndex >= 0 && commandIndex+1 < len(argv) {
+		args = argv[commandIndex+1:]
+	}
+
+	if watch {
+		// Every argument names a command to watch
+		commands := append([]string{command}, args...)
+		runner := newRunner(opts, "", nil)
+		if err := runner.Watch(commands); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	if recursive {
+		runner := newRunner(opts, command, args)
+		if err := runner.RunRecursive(); err != nil {
+			fail(err)
+		}
+		return
 	}
 
 	// Handle special commands
-	if command == "install-alias" {
+	if command == "install-alias" || command == "uninstall-alias" {
 		dryRun := false
-		for _, arg := range args {
-			if arg == "--dry-run" || arg == "-n" {
+		name := ""
+		for i := 0; i < len(args); i++ {
+			switch arg := args[i]; {
+			case arg == "--dry-run" || arg == "-n":
 				dryRun = true
-				break
+			case arg == "--name" && i+1 < len(args):
+				i++
+				name = args[i]
+			case strings.HasPrefix(arg, "--name="):
+				name = strings.TrimPrefix(arg, "--name=")
+			default:
+				fmt.Fprintf(os.Stderr, "Usage: cmdr %s [--name NAME] [--dry-run]\n", command)
+				os.Exit(1)
+			}
+		}
+		configFiles, err := internal.ShellConfigFiles()
+		if err != nil {
+			fail(err)
+		}
+		if command == "uninstall-alias" {
+			if err := internal.UninstallAlias(os.Stdout, configFiles, name, dryRun); err != nil {
+				fmt.Fprintf(os.Stderr, "Error removing alias: %v\n", err)
+				os.Exit(1)
 			}
+			return
+		}
+		if name == "" {
+			name = "cr"
 		}
-		if err := installAlias(dryRun); err != nil {
+		if err := internal.InstallAlias(os.Stdout, configFiles, name, dryRun); err != nil {
 			fmt.Fprintf(os.Stderr, "Error installing alias: %v\n", err)
 			os.Exit(1)
 		}
 		return
 	}
 
-	runner := internal.New(command, args)
+	if command == "__complete" {
+		// Called by the completion scripts; the last argument is the word
+		// being completed
+		runner := newRunner(opts, "", nil)
+		runner.Complete(os.Stdout, args)
+		return
+	}
 
-	if err := runner.Init(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing: %v\n", err)
-		os.Exit(1)
+	if command == "completion" {
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "Usage: cmdr completion %s\n", strings.Join(internal.CompletionShells, "|"))
+			os.Exit(1)
+		}
+		if err := internal.CompletionScript(os.Stdout, args[0]); err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	if err := runner.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if command == "help" {
+		if len(args) == 0 {
+			showHelp()
+			return
+		}
+		runner := newRunner(opts, "", nil)
+		if err := runner.ShowCommandHelp(args[0]); err != nil {
+			fail(err)
+		}
+		return
 	}
-}
 
-func installAlias(dryRun bool) error {
-	// Determine which shell config file to use
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+	if command == "which" || command == "explain" {
+		format := "text"
+		explain := command == "explain"
+		whichArgs := []string{}
+		for i := 0; i < len(args); i++ {
+			arg := args[i]
+			if arg == "--" {
+				whichArgs = append(whichArgs, args[i+1:]...)
+				break
+			}
+			if arg == "--format" && i+1 < len(args) {
+				i++
+				format = args[i]
+				continue
+			}
+			if value, ok := strings.CutPrefix(arg, "--format="); ok {
+				format = value
+				continue
+			}
+			if arg == "--explain" {
+				explain = true
+				continue
+			}
+			whichArgs = append(whichArgs, arg)
+		}
+		if len(whichArgs) == 0 {
+			fmt.Fprintf(os.Stderr, "Usage: cmdr which [--format text|shell|json|argv | --explain] <command> [args...]\n")
+			os.Exit(1)
+		}
+		runner := newRunner(opts, whichArgs[0], whichArgs[1:])
+		if explain {
+			if err := runner.Explain(os.Stdout); err != nil {
+				fail(err)
+			}
+			return
+		}
+		if err := runner.ShowWhich(os.Stdout, format); err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	// Check shell and determine config file
-	shell := os.Getenv("SHELL")
-	var configFiles []string
+	if command == "env" {
+		showAll := false
+		for _, arg := range args {
+			if arg == "--all" || arg == "-a" {
+				showAll = true
+			}
+		}
+		runner := newRunner(opts, "", nil)
+		runner.ShowEnv(showAll)
+		return
+	}
 
-	if strings.Contains(shell, "zsh") {
-		configFiles = []string{
-			filepath.Join(homeDir, ".zshrc"),
-			filepath.Join(homeDir, ".zprofile"),
+	if command == "audit-log" {
+		path := internal.AuditLogPath()
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "Error: no audit log; set audit_log in %s or CMDR_AUDIT_LOG\n", internal.UserConfigPath())
+			os.Exit(1)
+		}
+		var err error
+		switch {
+		case len(args) >= 1 && args[0] == "tail":
+			n := 10
+			if len(args) == 3 && args[1] == "-n" {
+				if n, err = strconv.Atoi(args[2]); err == nil && n < 1 {
+					err = fmt.Errorf("-n must be at least 1, got %d", n)
+				}
+			} else if len(args) != 1 {
+				err = fmt.Errorf("usage: cmdr audit-log tail [-n N]")
+			}
+			if err == nil {
+				err = internal.TailAuditLog(os.Stdout, path, n)
+			}
+		case len(args) == 1 && args[0] == "verify":
+			err = internal.VerifyAuditLog(os.Stdout, path)
+		case len(args) == 1 && args[0] == "path":
+			fmt.Println(path)
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: cmdr audit-log tail [-n N] | verify | path\n")
+			os.Exit(1)
 		}
-	} else if strings.Contains(shell, "bash") {
-		configFiles = []string{
-			filepath.Join(homeDir, ".bashrc"),
-			filepath.Join(homeDir, ".bash_profile"),
-			filepath.Join(homeDir, ".profile"),
+		if err != nil {
+			fail(err)
 		}
-	} else {
-		// Default to common shell config files
-		configFiles = []string{
-			filepath.Join(homeDir, ".zshrc"),
-			filepath.Join(homeDir, ".bashrc"),
-			filepath.Join(homeDir, ".profile"),
+		return
+	}
+
+	if command == "history" {
+		path := internal.HistoryPath()
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "Error: history is off; history = false in %s\n", internal.UserConfigPath())
+			os.Exit(1)
+		}
+		filter := internal.HistoryFilter{Limit: 20}
+		usage := func() {
+			fmt.Fprintf(os.Stderr, "Usage: cmdr history [-n N | --all] [--failed] [--here] [<text>] | path\n")
+			os.Exit(1)
 		}
+		var terms []string
+		for i := 0; i < len(args); i++ {
+			switch arg := args[i]; {
+			case arg == "-n" && i+1 < len(args):
+				i++
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 1 {
+					usage()
+				}
+				filter.Limit = n
+			case arg == "--all" || arg == "-a":
+				filter.Limit = 0
+			case arg == "--failed":
+				filter.Failed = true
+			case arg == "--here":
+				filter.Dir = newRunner(opts, "", nil).ProjectRoot
+			case arg == "path" && len(args) == 1:
+				fmt.Println(path)
+				return
+			case strings.HasPrefix(arg, "-"):
+				usage()
+			default:
+				terms = append(terms, arg)
+			}
+		}
+		filter.Match = strings.Join(terms, " ")
+		if err := internal.ShowHistory(os.Stdout, path, filter); err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	aliasLine := "alias cr=cmdr"
+	if command == "last" || command == "rerun" {
+		if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "Usage: cmdr %s\n", command)
+			os.Exit(1)
+		}
+		entry, err := newRunner(opts, "", nil).LastRun()
+		if err != nil {
+			fail(err)
+		}
+		// Run it from the directory it ran in, which may be below the root
+		if err := os.Chdir(entry.Cwd); err != nil {
+			fail(err)
+		}
+		runner := newRunner(opts, entry.Command, entry.Args)
+		if err := runner.Run(); err != nil {
+			fail(err)
+		}
+		return
+	}
 
-	// Find the first existing config file
-	var targetFile string
-	for _, file := range configFiles {
-		if internal.FileExists(file) {
-			targetFile = file
-			break
+	if command == "doctor" {
+		runner := newRunner(opts, "", nil)
+		if err := runner.Doctor(os.Stdout); err != nil {
+			fail(err)
 		}
+		return
 	}
 
-	// If no config file exists, create the most appropriate one
-	if targetFile == "" {
-		if strings.Contains(shell, "zsh") {
-			targetFile = filepath.Join(homeDir, ".zshrc")
-		} else {
-			targetFile = filepath.Join(homeDir, ".bashrc")
+	if command == "self-update" {
+		checkOnly := false
+		for _, arg := range args {
+			if arg != "--check" {
+				fmt.Fprintf(os.Stderr, "Usage: cmdr self-update [--check]\n")
+				os.Exit(1)
+			}
+			checkOnly = true
+		}
+		if err := internal.SelfUpdate(os.Stdout, version, checkOnly); err != nil {
+			fail(err)
 		}
+		return
 	}
 
-	// Check if alias already exists
-	if internal.FileExists(targetFile) {
-		content, err := os.ReadFile(targetFile)
-		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", targetFile, err)
+	if command == "upgrade-tools" {
+		dryRun := false
+		for _, arg := range args {
+			if arg != "--dry-run" && arg != "-n" {
+				fmt.Fprintf(os.Stderr, "Usage: cmdr upgrade-tools [--dry-run]\n")
+				os.Exit(1)
+			}
+			dryRun = true
 		}
+		runner := newRunner(opts, "", nil)
+		if err := runner.UpgradeTools(os.Stdout, dryRun); err != nil {
+			fail(err)
+		}
+		return
+	}
 
-		// Match "alias cr=cmdr" as a complete statement (not substring)
-		// This avoids false positives like "alias cr=cmdr-dev" or commented lines
-		aliasPattern := regexp.MustCompile(`(?m)^\s*alias\s+cr=cmdr\s*$`)
-		if aliasPattern.Match(content) {
-			if dryRun {
-				fmt.Printf("[DRY RUN] Alias 'cr' is already installed in %s\n", targetFile)
-			} else {
-				fmt.Printf("Alias 'cr' is already installed in %s\n", targetFile)
+	if command == "init" {
+		force, dryRun := false, false
+		for _, arg := range args {
+			switch arg {
+			case "--force", "-f":
+				force = true
+			case "--dry-run", "-n":
+				dryRun = true
+			default:
+				fmt.Fprintf(os.Stderr, "Usage: cmdr init [--force] [--dry-run]\n")
+				os.Exit(1)
 			}
-			return nil
 		}
+		runner := newRunner(opts, "", nil)
+		if err := runner.InitConfig(os.Stdout, force, dryRun); err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	if dryRun {
-		fmt.Println("[DRY RUN] Would perform the following actions:")
-		fmt.Printf("  - Add alias to: %s\n", targetFile)
-		fmt.Printf("  - Add line: %s\n", aliasLine)
-		if !internal.FileExists(targetFile) {
-			fmt.Printf("  - Create new file: %s\n", targetFile)
+	if command == "sources" {
+		runner := newRunner(opts, "", nil)
+		if err := runner.ShowSources(os.Stdout); err != nil {
+			fail(err)
 		}
-		return nil
+		return
 	}
 
-	// Append alias to config file
-	file, err := os.OpenFile(targetFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", targetFile, err)
+	if command == "pm" {
+		runner := newRunner(opts, "", nil)
+		var err error
+		switch {
+		case len(args) == 0:
+			err = runner.ShowPackageManagers(os.Stdout)
+		case len(args) == 2 && args[0] == "set":
+			err = runner.SetPackageManager(os.Stdout, args[1])
+		case len(args) == 2 && args[0] == "unset":
+			err = runner.UnsetPackageManager(os.Stdout, args[1])
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: cmdr pm [set <manager> | unset node|python]\n")
+			os.Exit(1)
+		}
+		if err != nil {
+			fail(err)
+		}
+		return
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+
+	if command == "projects" {
+		internal.ShowProjects(os.Stdout)
+		return
+	}
+
+	if command == "stop" {
+		runner := newRunner(opts, "", nil)
+		if err := runner.StopGroups(args); err != nil {
+			fail(err)
 		}
-	}()
+		return
+	}
 
-	// Add a newline before the alias to ensure it's on its own line
-	_, err = fmt.Fprintf(file, "\n# Added by cmdr\n%s\n", aliasLine)
-	if err != nil {
-		return fmt.Errorf("failed to write to %s: %w", targetFile, err)
+	if command == "daemon" {
+		var err error
+		switch {
+		case len(args) == 0:
+			err = internal.RunDaemon(os.Stderr)
+		case len(args) == 1 && args[0] == "status":
+			err = internal.ShowDaemonStatus(os.Stdout)
+		case len(args) == 1 && args[0] == "stop":
+			err = internal.StopDaemon()
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: cmdr daemon [status|stop]\n")
+			os.Exit(1)
+		}
+		if err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	fmt.Printf("Successfully added 'cr' alias to %s\n", targetFile)
-	fmt.Println("To use it immediately, run: source " + targetFile)
-	fmt.Println("Or start a new terminal session.")
-	return nil
+	if command == "cache" {
+		switch {
+		case len(args) == 1 && args[0] == "path":
+			fmt.Println(internal.CacheDir())
+		case len(args) == 1 && args[0] == "clear":
+			if err := internal.ClearCache(); err != nil {
+				fail(err)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: cmdr cache path|clear\n")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "export" {
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintf(os.Stderr, "Usage: cmdr export procfile|compose [<group>]\n")
+			os.Exit(1)
+		}
+		group := ""
+		if len(args) == 2 {
+			group = args[1]
+		}
+		runner := newRunner(opts, "", nil)
+		if err := runner.ExportGroup(os.Stdout, args[0], group); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	if command == "watch" {
+		runner := newRunner(opts, "", nil)
+		rules, root := runner.ProjectWatchRules()
+		if rules == nil {
+			fmt.Fprintf(os.Stderr, "Error: no [watch] rules in %s; use 'cmdr --watch <command>...' to watch specific commands\n", internal.ProjectConfigFile)
+			os.Exit(1)
+		}
+		if err := runner.WatchRules(rules, root); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	switch command {
+	case "check":
+		args = takeReportOption(args, "--junit", &opts.junit)
+		args = takeReportOption(args, "--sarif", &opts.sarif)
+	case "lint":
+		args = takeReportOption(args, "--sarif", &opts.sarif)
+	}
+	runner := newRunner(opts, command, args)
+
+	if err := runner.Run(); err != nil {
+		fail(err)
+	}
+	internal.WriteTimings(os.Stderr)
 }
@@ -0,0 +1,88 @@
+// Package cmdrunner answers "what would cmdr run here?" for other tools,
+// such as task launchers and editor integrations, and runs it for them
+package cmdrunner
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/osteele/cmd-runner/internal"
+)
+
+// Resolution is the plan for a command: the source that provides it, the
+// directory it runs in, its argv, and its environment
+type Resolution = internal.CommandResolution
+
+// CommandResolution is another name for Resolution
+type CommandResolution = internal.CommandResolution
+
+// RunnerOption configures a Runner created with New, or how Resolve
+// resolves a command
+type RunnerOption = internal.RunnerOption
+
+// Runner runs a command as cmdr does; its RunContext method makes the run
+// cancellable
+type Runner = internal.CommandRunner
+
+// Errors that Plan's and a runner's errors match with errors.Is
+var (
+	ErrCommandNotFound = internal.ErrCommandNotFound
+	ErrNoSources       = internal.ErrNoSources
+	ErrToolMissing     = internal.ErrToolMissing
+)
+
+// ToolMissingError reports a program that a command needs and that isn't
+// installed; errors.As finds it in errors that match ErrToolMissing
+type ToolMissingError = internal.ToolMissingError
+
+// Plan returns what `cmdr command args...` would run in dir. It reads
+// project files but never starts a process, and its result doesn't depend
+// on the working directory unless dir is relative. Commands that cmdr
+// synthesizes from several others, and process groups, return an error.
+func Plan(dir, command string, args ...string) (*Resolution, error) {
+	return internal.Plan(dir, command, args...)
+}
+
+// Resolve returns what `cmdr command args...` would run, without running it.
+// Unlike Plan, it resolves from the working directory unless WithDir says
+// otherwise, and sources may run their tools to list their tasks.
+func Resolve(command string, args []string, opts ...RunnerOption) (*CommandResolution, error) {
+	return internal.Resolve(command, args, opts...)
+}
+
+// New returns a runner for command with args, in the working directory
+// unless WithDir says otherwise
+func New(command string, args []string, opts ...RunnerOption) (*Runner, error) {
+	r := internal.New(command, args, opts...)
+	if r.CurrentDir == "" {
+		if err := r.Init(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// WithContext makes ctx cancel the runner's runs and watches: when it's
+// done, the processes they started are killed
+func WithContext(ctx context.Context) RunnerOption {
+	return internal.WithContext(ctx)
+}
+
+// WithCommandFactory makes the runner, and the sources it resolves, create
+// processes with factory instead of exec.Command, so that every process it
+// starts can be recorded or stubbed
+func WithCommandFactory(factory func(name string, arg ...string) *exec.Cmd) RunnerOption {
+	return internal.WithCommandFactory(factory)
+}
+
+// WithLookPath makes the runner, and the sources it resolves, find programs
+// with lookPath instead of exec.LookPath
+func WithLookPath(lookPath func(file string) (string, error)) RunnerOption {
+	return internal.WithLookPath(lookPath)
+}
+
+// WithDir makes Resolve resolve commands in dir, and the project root above
+// it, instead of the working directory
+func WithDir(dir string) RunnerOption {
+	return internal.WithDir(dir)
+}
@@ -2,6 +2,27 @@ module github.com/osteele/cmd-runner
 
 go 1.21
 
-require golang.org/x/term v0.27.0
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/charmbracelet/bubbletea v0.26.6
+	github.com/creack/pty v1.1.24
+	github.com/fsnotify/fsnotify v1.7.0
+	golang.org/x/sys v0.28.0
+	golang.org/x/term v0.27.0
+)
 
-require golang.org/x/sys v0.28.0 // indirect
+require (
+	github.com/charmbracelet/x/ansi v0.1.2 // indirect
+	github.com/charmbracelet/x/input v0.1.0 // indirect
+	github.com/charmbracelet/x/term v0.1.1 // indirect
+	github.com/charmbracelet/x/windows v0.1.0 // indirect
+	github.com/erikgeiser/coninput v0.0.0-20211004153227-1c3628e74d0f // indirect
+	github.com/mattn/go-localereader v0.0.1 // indirect
+	github.com/mattn/go-runewidth v0.0.15 // indirect
+	github.com/muesli/ansi v0.0.0-20230316100256-276c6243b2f6 // indirect
+	github.com/muesli/cancelreader v0.2.2 // indirect
+	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/text v0.3.8 // indirect
+)
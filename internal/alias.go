@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// aliasMarker is the comment install-alias writes before the alias line,
+// by which uninstall-alias finds it
+const aliasMarker = "# Added by cmdr"
+
+// aliasNamePattern matches alias names a shell accepts without quoting
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z0-9_,@%+.:-]+$`)
+
+// aliasLinePattern matches an alias of cmdr, as a complete statement
+var aliasLinePattern = regexp.MustCompile(`^\s*alias\s+(\S+)=cmdr\s*$`)
+
+// ShellConfigFiles returns the shell config files an alias may be in, for
+// the user's shell, most likely first
+func ShellConfigFiles() ([]string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	shell := os.Getenv("SHELL")
+	if strings.Contains(shell, "zsh") {
+		return []string{
+			filepath.Join(homeDir, ".zshrc"),
+			filepath.Join(homeDir, ".zprofile"),
+		}, nil
+	} else if strings.Contains(shell, "bash") {
+		return []string{
+			filepath.Join(homeDir, ".bashrc"),
+			filepath.Join(homeDir, ".bash_profile"),
+			filepath.Join(homeDir, ".profile"),
+		}, nil
+	}
+	// Default to common shell config files
+	return []string{
+		filepath.Join(homeDir, ".zshrc"),
+		filepath.Join(homeDir, ".bashrc"),
+		filepath.Join(homeDir, ".profile"),
+	}, nil
+}
+
+// addAlias returns content with an alias of name for cmdr appended, after
+// the marker uninstall-alias looks for, or content unchanged and false if
+// it already has that alias. Lines like "alias cr=cmdr-dev" and commented
+// out aliases don't count.
+func addAlias(content, name string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if m := aliasLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil && m[1] == name {
+			return content, false
+		}
+	}
+	// The blank line keeps the alias apart from whatever came before
+	return content + "\n" + aliasMarker + "\nalias " + name + "=cmdr\n", true
+}
+
+// removeAliases returns content without the aliases addAlias added: the
+// one named name, or with name "" all of them, and the names it removed.
+// Aliases without the marker above them are the user's own, and stay.
+func removeAliases(content, name string) (string, []string) {
+	lines := strings.SplitAfter(content, "\n")
+	kept := make([]string, 0, len(lines))
+	var names []string
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == aliasMarker && i+1 < len(lines) {
+			if m := aliasLinePattern.FindStringSubmatch(strings.TrimRight(lines[i+1], "\r\n")); m != nil && (name == "" || m[1] == name) {
+				names = append(names, m[1])
+				// Drop the blank line install-alias wrote before the marker
+				if n := len(kept); n > 0 && strings.TrimSpace(kept[n-1]) == "" {
+					kept = kept[:n-1]
+				}
+				i++
+				continue
+			}
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.Join(kept, ""), names
+}
+
+// InstallAlias adds an alias of name for cmdr to the first of configFiles
+// that exists, or creates the shell's usual one next to the first, and
+// reports what it did, or with dryRun what it would do, to w
+func InstallAlias(w io.Writer, configFiles []string, name string, dryRun bool) error {
+	if !aliasNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+
+	targetFile := firstExisting("", configFiles...)
+	if targetFile == "" {
+		if strings.Contains(os.Getenv("SHELL"), "zsh") {
+			targetFile = filepath.Join(filepath.Dir(configFiles[0]), ".zshrc")
+		} else {
+			targetFile = filepath.Join(filepath.Dir(configFiles[0]), ".bashrc")
+		}
+	}
+
+	content, err := os.ReadFile(targetFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", targetFile, err)
+	}
+	updated, added := addAlias(string(content), name)
+	if !added {
+		if dryRun {
+			fmt.Fprintf(w, "[DRY RUN] Alias '%s' is already installed in %s\n", name, targetFile)
+		} else {
+			fmt.Fprintf(w, "Alias '%s' is already installed in %s\n", name, targetFile)
+		}
+		return nil
+	}
+
+	if dryRun {
+		fmt.Fprintln(w, "[DRY RUN] Would perform the following actions:")
+		fmt.Fprintf(w, "  - Add alias to: %s\n", targetFile)
+		fmt.Fprintf(w, "  - Add line: alias %s=cmdr\n", name)
+		if !FileExists(targetFile) {
+			fmt.Fprintf(w, "  - Create new file: %s\n", targetFile)
+		}
+		return nil
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(targetFile); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.WriteFile(targetFile, []byte(updated), perm); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", targetFile, err)
+	}
+
+	fmt.Fprintf(w, "Successfully added '%s' alias to %s\n", name, targetFile)
+	fmt.Fprintln(w, "To use it immediately, run: source "+targetFile)
+	fmt.Fprintln(w, "Or start a new terminal session.")
+	return nil
+}
+
+// UninstallAlias removes the aliases InstallAlias added to configFiles: the
+// one named name, or with name "" all of them. It reports what it did, or
+// with dryRun what it would do, to w.
+func UninstallAlias(w io.Writer, configFiles []string, name string, dryRun bool) error {
+	removed := 0
+	for _, path := range configFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		kept, names := removeAliases(string(content), name)
+		if len(names) == 0 {
+			continue
+		}
+		removed += len(names)
+		for _, alias := range names {
+			if dryRun {
+				fmt.Fprintf(w, "[DRY RUN] Would remove alias '%s' from %s\n", alias, path)
+			} else {
+				fmt.Fprintf(w, "Removed alias '%s' from %s\n", alias, path)
+			}
+		}
+		if dryRun {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(kept), info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	if removed == 0 {
+		what := "no alias"
+		if name != "" {
+			what = fmt.Sprintf("no alias '%s'", name)
+		}
+		return fmt.Errorf("%s added by cmdr install-alias in %s", what, strings.Join(configFiles, ", "))
+	}
+	if !dryRun {
+		fmt.Fprintln(w, "Start a new terminal session for the change to take effect.")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAddAlias(t *testing.T) {
+	got, added := addAlias("export EDITOR=vim\n", "cr")
+	if want := "export EDITOR=vim\n\n# Added by cmdr\nalias cr=cmdr\n"; !added || got != want {
+		t.Errorf("addAlias() = %q, %v; want %q", got, added, want)
+	}
+
+	// An alias that's already there, marked or not, isn't added again
+	for _, content := range []string{got, "alias cr=cmdr\n", "  alias cr=cmdr  \r\n"} {
+		if got, added := addAlias(content, "cr"); added || got != content {
+			t.Errorf("addAlias(%q) = %q, %v; want it unchanged", content, got, added)
+		}
+	}
+	// But similar lines don't count
+	for _, content := range []string{"alias cr=cmdr-dev\n", "# alias cr=cmdr\n", "alias c=cmdr\n"} {
+		if _, added := addAlias(content, "cr"); !added {
+			t.Errorf("addAlias(%q) didn't add the alias", content)
+		}
+	}
+}
+
+func TestRemoveAliases(t *testing.T) {
+	content := "export EDITOR=vim\n\n# Added by cmdr\nalias cr=cmdr\n\n# Added by cmdr\nalias c=cmdr\n\nalias x=cmdr\n"
+
+	// --name removes only that alias, with the blank line before it, and
+	// keeps the blank line after it
+	got, names := removeAliases(content, "cr")
+	if want := "export EDITOR=vim\n\n# Added by cmdr\nalias c=cmdr\n\nalias x=cmdr\n"; got != want || !slicesEqual(names, []string{"cr"}) {
+		t.Errorf("removeAliases(cr) = %q, %q; want %q", got, names, want)
+	}
+
+	// Without a name, all marked aliases go; x has no marker, so it's the
+	// user's own
+	got, names = removeAliases(content, "")
+	if want := "export EDITOR=vim\n\nalias x=cmdr\n"; got != want || !slicesEqual(names, []string{"cr", "c"}) {
+		t.Errorf("removeAliases() = %q, %q; want %q", got, names, want)
+	}
+	if got, names := removeAliases("alias cr=cmdr\n", "cr"); got != "alias cr=cmdr\n" || len(names) != 0 {
+		t.Errorf("removeAliases() without the marker = %q, %q; want it unchanged", got, names)
+	}
+
+	// What addAlias adds, removeAliases takes away
+	original := "export EDITOR=vim\n"
+	added, _ := addAlias(original, "cr")
+	if got, _ := removeAliases(added, "cr"); got != original {
+		t.Errorf("removeAliases(addAlias()) = %q, want %q", got, original)
+	}
+}
+
+func TestInstallAlias(t *testing.T) {
+	home := t.TempDir()
+	rc := filepath.Join(home, ".bashrc")
+	configFiles := []string{rc, filepath.Join(home, ".profile")}
+	original := "export EDITOR=vim\n"
+	if err := os.WriteFile(rc, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		data, err := os.ReadFile(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// A dry run leaves the file alone
+	var out strings.Builder
+	if err := InstallAlias(&out, configFiles, "cr", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != original || !strings.Contains(out.String(), "[DRY RUN]") {
+		t.Errorf("after a dry run, %s = %q and output %q", rc, got, out.String())
+	}
+
+	if err := InstallAlias(io.Discard, configFiles, "cr", false); err != nil {
+		t.Fatal(err)
+	}
+	installed := read()
+	if want := original + "\n# Added by cmdr\nalias cr=cmdr\n"; installed != want {
+		t.Errorf("after install, %s = %q, want %q", rc, installed, want)
+	}
+	if info, err := os.Stat(rc); err != nil || runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("install changed the mode of %s", rc)
+	}
+	out.Reset()
+	if err := InstallAlias(&out, configFiles, "cr", false); err != nil || read() != installed || !strings.Contains(out.String(), "already installed") {
+		t.Errorf("installing again: error = %v, output %q, file %q", err, out.String(), read())
+	}
+	if err := InstallAlias(io.Discard, configFiles, "c r", false); err == nil {
+		t.Error("InstallAlias() accepted an invalid name")
+	}
+
+	// Uninstalling, too, can be tried first
+	if err := UninstallAlias(io.Discard, configFiles, "cr", true); err != nil || read() != installed {
+		t.Errorf("after a dry-run uninstall: error = %v, file %q", err, read())
+	}
+	if err := UninstallAlias(io.Discard, configFiles, "other", false); err == nil || !strings.Contains(err.Error(), "no alias 'other'") {
+		t.Errorf("UninstallAlias(other) error = %v", err)
+	}
+	if err := UninstallAlias(io.Discard, configFiles, "", false); err != nil || read() != original {
+		t.Errorf("after uninstall: error = %v, file %q; want %q", err, read(), original)
+	}
+}
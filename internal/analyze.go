@@ -0,0 +1,21 @@
+package internal
+
+import "fmt"
+
+// analyzeOnly keeps cmdr from running anything, so that an untrusted
+// repository can be inspected safely. Sources that normally ask their tool
+// for its tasks (mise, just, deno) only parse their files (see taskfiles.go).
+var analyzeOnly bool
+
+// SetAnalyzeOnly turns analyze-only mode on or off
+func SetAnalyzeOnly(on bool) {
+	analyzeOnly = on
+}
+
+// refuseToRun returns an error in analyze-only mode
+func refuseToRun(what string) error {
+	if analyzeOnly {
+		return fmt.Errorf("not running %s: --analyze-only never executes commands", what)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseJustRecipes(t *testing.T) {
+	dir := t.TempDir()
+	justfile := `set shell := ["bash", "-c"]
+version := "1.0"
+
+alias t := test
+
+# Run the tests
+test *args:
+    # not a description
+    go test {{args}}
+
+[private]
+helper:
+    echo helper
+
+_hidden:
+    echo hidden
+
+@build target="all":
+    make {{target}}
+`
+	if err := os.WriteFile(filepath.Join(dir, "justfile"), []byte(justfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commands := parseJustRecipes(dir)
+	if names := sortCommands(commands); !slicesEqual(names, []string{"build", "test"}) {
+		t.Errorf("parseJustRecipes() names = %v", names)
+	}
+	if commands["test"].Description != "Run the tests" || commands["build"].Description != "" {
+		t.Errorf("parseJustRecipes() = %+v", commands)
+	}
+}
+
+func TestParseMiseTasks(t *testing.T) {
+	dir := t.TempDir()
+	config := `[tasks.build]
+description = "Build it"
+run = "go build"
+
+[tasks.secret]
+run = "echo"
+hide = true
+
+[tasks]
+lint = "golangci-lint run"
+`
+	if err := os.WriteFile(filepath.Join(dir, ".mise.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".mise", "tasks", "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".mise", "tasks", "db", "reset"), []byte("#!/bin/sh\n#MISE description=\"Reset the database\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := parseMiseTasks(dir)
+	if names := sortCommands(commands); !slicesEqual(names, []string{"build", "db:reset", "lint"}) {
+		t.Errorf("parseMiseTasks() names = %v", names)
+	}
+	if commands["build"].Description != "Build it" || commands["db:reset"].Description != "Reset the database" {
+		t.Errorf("parseMiseTasks() = %+v", commands)
+	}
+}
+
+func TestAnalyzeOnlyRefusesToRun(t *testing.T) {
+	t.Cleanup(func() { analyzeOnly = false })
+	SetAnalyzeOnly(true)
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	writeConfig(t, dir, "[commands]\ntouch = \"touch "+marker+"\"\n")
+	runner := &CommandRunner{Command: "touch", CurrentDir: dir, ProjectRoot: dir}
+	if cmd, err := runner.Resolve(); err != nil || cmd == nil {
+		t.Errorf("Resolve() = %v, %v; resolution should still work", cmd, err)
+	}
+	if err := runner.Run(); err == nil {
+		t.Error("Run() error = nil in analyze-only mode")
+	}
+	if FileExists(marker) {
+		t.Error("the command ran in analyze-only mode")
+	}
+}
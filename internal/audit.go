@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a line of the audit log. Each entry holds the hash of the
+// line before it, so that edits and deletions break the chain.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Cwd      string    `json:"cwd"`
+	Argv     []string  `json:"argv"`
+	Kind     string    `json:"kind"` // command, pre hook, post hook, process, stop, or ready check
+	ExitCode int       `json:"exit_code"`
+	Duration float64   `json:"duration_seconds"`
+	Config   string    `json:"config_sha256,omitempty"`
+	Prev     string    `json:"prev_sha256"`
+}
+
+// auditMu serializes writes from this process; a file lock serializes them
+// with other cmdr processes
+var auditMu sync.Mutex
+
+// AuditLogPath returns the audit log file from CMDR_AUDIT_LOG or the user
+// config's audit_log, or "" if auditing is off
+func AuditLogPath() string {
+	if path := os.Getenv("CMDR_AUDIT_LOG"); path != "" {
+		return expandProjectDir(".", path)
+	}
+	if config := LoadUserConfig(); config != nil && config.AuditLog != "" {
+		return expandProjectDir(config.Path, config.AuditLog)
+	}
+	return ""
+}
+
+// audit records a finished command in the audit log, if there is one.
+// Failing to write the log is reported but doesn't fail the command.
+func (r *CommandRunner) audit(kind string, cmd *exec.Cmd, start time.Time, err error) {
+	path := AuditLogPath()
+	if path == "" {
+		return
+	}
+	cwd := cmd.Dir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	entry := AuditEntry{
+		Time:     start.UTC(),
+		User:     currentUserName(),
+		Cwd:      cwd,
+		Argv:     cmd.Args,
+		Kind:     kind,
+		ExitCode: ExitCode(err),
+		Duration: time.Since(start).Seconds(),
+		Config:   r.configHash(),
+	}
+	if err := appendAuditEntry(path, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: can't write audit log %s: %v\n", path, err)
+	}
+}
+
+func currentUserName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// configHash returns a hash of the config files that affect resolution: the
+// user config and the current directory and project root .cmdr.toml
+func (r *CommandRunner) configHash() string {
+	paths := []string{UserConfigPath()}
+	for _, dir := range r.searchDirs() {
+		paths = append(paths, filepath.Join(dir, ProjectConfigFile))
+	}
+	h := sha256.New()
+	found := false
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		found = true
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(data))
+		h.Write(data)
+	}
+	if !found {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// appendAuditEntry chains entry to the last line of the log and appends it
+func appendAuditEntry(path string, entry AuditEntry) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	last, err := lastLine(f)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		entry.Prev = lineHash(last)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// lastLine returns the last newline-terminated line of f, without the
+// newline, or nil if f is empty
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return nil, err
+	}
+	size := info.Size()
+	for chunk := int64(4096); ; chunk *= 2 {
+		start := max(size-chunk, 0)
+		buf := make([]byte, size-start)
+		if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = bytes.TrimSuffix(buf, []byte("\n"))
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			return buf[i+1:], nil
+		}
+		if start == 0 {
+			return buf, nil
+		}
+	}
+}
+
+func lineHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// readJSONLines returns the lines of a JSONL file such as the audit log
+func readJSONLines(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, bytes.Clone(scanner.Bytes()))
+	}
+	return lines, scanner.Err()
+}
+
+// VerifyAuditLog checks that every entry parses and names the hash of the
+// entry before it. It can't detect entries removed from the end of the log.
+func VerifyAuditLog(w io.Writer, path string) error {
+	lines, err := readJSONLines(path)
+	if err != nil {
+		return err
+	}
+	prev := ""
+	for i, line := range lines {
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("%s:%d: invalid entry: %v", path, i+1, err)
+		}
+		if entry.Prev != prev {
+			return fmt.Errorf("%s:%d: chain is broken; an entry before this one was changed, removed, or inserted", path, i+1)
+		}
+		prev = lineHash(line)
+	}
+	fmt.Fprintf(w, "%s: %d entries, chain intact\n", path, len(lines))
+	return nil
+}
+
+// TailAuditLog writes the last n entries of the audit log, one per line
+func TailAuditLog(w io.Writer, path string, n int) error {
+	if n < 1 {
+		return fmt.Errorf("the number of entries must be at least 1, got %d", n)
+	}
+	lines, err := readJSONLines(path)
+	if err != nil {
+		return err
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			fmt.Fprintf(w, "(invalid entry) %s\n", line)
+			continue
+		}
+		fmt.Fprintf(w, "%s  %-8s %3d  %7s  %s  %s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.User,
+			entry.ExitCode,
+			(time.Duration(entry.Duration * float64(time.Second))).Round(time.Millisecond),
+			entry.Cwd,
+			strings.Join(entry.Argv, " "))
+	}
+	return nil
+}
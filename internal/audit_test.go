@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	t.Setenv("CMDR_AUDIT_LOG", path)
+
+	dir := t.TempDir()
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	for _, arg := range []string{"one", "two", "three"} {
+		cmd := exec.Command("echo", arg)
+		cmd.Dir = dir
+		runner.audit("command", cmd, time.Now(), nil)
+	}
+
+	lines, err := readJSONLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("audit log has %d lines, want 3", len(lines))
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(lines[1], &entry); err != nil {
+		t.Fatal(err)
+	}
+	if !slicesEqual(entry.Argv, []string{"echo", "two"}) || entry.Cwd != dir || entry.Prev != lineHash(lines[0]) {
+		t.Errorf("second entry = %+v", entry)
+	}
+
+	var out strings.Builder
+	if err := VerifyAuditLog(&out, path); err != nil {
+		t.Errorf("VerifyAuditLog() = %v", err)
+	}
+	out.Reset()
+	if err := TailAuditLog(&out, path, 2); err != nil || strings.Count(out.String(), "\n") != 2 || !strings.Contains(out.String(), "echo three") {
+		t.Errorf("TailAuditLog() = %v:\n%s", err, out.String())
+	}
+	for _, n := range []int{0, -3} {
+		if err := TailAuditLog(io.Discard, path, n); err == nil {
+			t.Errorf("TailAuditLog(%d) = nil, want an error", n)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, bytes.Replace(data, []byte(`"two"`), []byte(`"2"`), 1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAuditLog(&out, path); err == nil || !strings.Contains(err.Error(), ":3:") {
+		t.Errorf("VerifyAuditLog() after an edit = %v, want a broken chain at line 3", err)
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// defaultBanner is the line cmdr writes before running a command
+const defaultBanner = "Running: {command}"
+
+// announce writes the banner before cmd runs
+func (r *CommandRunner) announce(cmd *exec.Cmd) {
+	if banner := r.banner(cmd); banner != "" {
+		fmt.Fprintf(r.statusWriter(), "%s%s\n", r.badge(), banner)
+	}
+}
+
+// banner returns the user config's banner for cmd, with {command} replaced
+// by the command line and {dir} by its directory, or "" for a quiet run or
+// an empty banner
+func (r *CommandRunner) banner(cmd *exec.Cmd) string {
+	if r.Quiet {
+		return ""
+	}
+	banner := defaultBanner
+	if config := LoadUserConfig(); config != nil && config.Banner != nil {
+		banner = *config.Banner
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		dir = r.CurrentDir
+	}
+	return strings.NewReplacer("{command}", strings.Join(cmd.Args, " "), "{dir}", dir).Replace(banner)
+}
+
+// progressf writes what a synthesized command is doing, unless quiet
+func (r *CommandRunner) progressf(format string, args ...any) {
+	if !r.Quiet {
+		fmt.Fprintf(r.statusWriter(), format, args...)
+	}
+}
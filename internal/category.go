@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+)
+
+// listCategories are the categories --list groups commands into, in the
+// order it shows them. Categories set in config that aren't among them come
+// after deploy, and misc is always last.
+var listCategories = []string{"build", "test", "quality", "run", "deploy", "misc"}
+
+// categoryTitles are the headings of the categories
+var categoryTitles = map[string]string{
+	"build":   "Build",
+	"test":    "Test",
+	"quality": "Quality",
+	"run":     "Run & serve",
+	"deploy":  "Deploy",
+	"misc":    "Other",
+}
+
+// categoryWords are the words of a command name that place it in a category.
+// A word of the name matches when it starts with one of these.
+var categoryWords = []struct {
+	category string
+	words    []string
+}{
+	{"test", []string{"test", "spec", "e2e", "bench", "coverage", "cov"}},
+	{"quality", []string{"lint", "format", "fmt", "check", "typecheck", "tc", "fix", "vet", "audit", "prettier", "eslint"}},
+	{"deploy", []string{"deploy", "release", "publish", "ship", "upload"}},
+	{"run", []string{"run", "serve", "server", "start", "dev", "watch", "preview"}},
+	{"build", []string{"build", "compile", "bundle", "dist", "install", "setup", "clean", "gen", "docs"}},
+}
+
+// commandCategory guesses a command's category from the words of its name,
+// the first word deciding before the others: build-docs is build, and
+// test:lint is test
+func commandCategory(name string) string {
+	for _, word := range nameWords(name) {
+		for _, c := range categoryWords {
+			for _, prefix := range c.words {
+				if strings.HasPrefix(word, prefix) {
+					return c.category
+				}
+			}
+		}
+	}
+	return "misc"
+}
+
+// nameWords splits a command name into lower-case words, at separators and
+// lower-to-upper case changes
+func nameWords(name string) []string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !wordStart(runes, i) {
+			continue
+		}
+		word := strings.Trim(string(runes[start:i]), "-_:./ ")
+		if word != "" {
+			words = append(words, strings.ToLower(word))
+		}
+		start = i
+	}
+	return words
+}
+
+// categoryOf returns the category --list shows a command under: the
+// category in its config, or else one guessed from its name
+func (r *CommandRunner) categoryOf(name string) string {
+	if cc := r.commandConfig(name); cc != nil && cc.Category != "" {
+		return strings.ToLower(cc.Category)
+	}
+	return commandCategory(name)
+}
+
+// categorize groups commands by category, in the order --list shows the
+// categories, each category's commands in their order in commands
+func (r *CommandRunner) categorize(commands []string) (categories []string, byCategory map[string][]string) {
+	byCategory = make(map[string][]string)
+	for _, name := range commands {
+		category := r.categoryOf(name)
+		byCategory[category] = append(byCategory[category], name)
+	}
+	for _, category := range listCategories {
+		if category != "misc" && len(byCategory[category]) > 0 {
+			categories = append(categories, category)
+		}
+	}
+	var custom []string
+	for category := range byCategory {
+		if _, known := categoryTitles[category]; !known {
+			custom = append(custom, category)
+		}
+	}
+	sort.Strings(custom)
+	categories = append(categories, custom...)
+	if len(byCategory["misc"]) > 0 {
+		categories = append(categories, "misc")
+	}
+	return categories, byCategory
+}
+
+// categoryTitle is the heading of a category
+func categoryTitle(category string) string {
+	if title, ok := categoryTitles[category]; ok {
+		return title
+	}
+	return strings.ToUpper(category[:1]) + category[1:]
+}
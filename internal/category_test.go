@@ -0,0 +1,44 @@
+package internal
+
+import "testing"
+
+func TestCommandCategory(t *testing.T) {
+	tests := map[string]string{
+		"build":        "build",
+		"build-docs":   "build",
+		"test:unit":    "test",
+		"testLint":     "test",
+		"e2e":          "test",
+		"lint-fix":     "quality",
+		"fmt":          "quality",
+		"dev":          "run",
+		"serve:prod":   "run",
+		"release":      "deploy",
+		"publishDocs":  "deploy",
+		"outdated":     "misc",
+		"db:migrate":   "misc",
+		"docker-build": "build",
+	}
+	for name, want := range tests {
+		if got := commandCategory(name); got != want {
+			t.Errorf("commandCategory(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands.migrate]\ncategory = \"Database\"\n\n[commands.ship-it]\ncategory = \"misc\"\n")
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+
+	categories, byCategory := runner.categorize([]string{"deploy", "migrate", "outdated", "ship-it", "test", "zz-lint"})
+	if want := []string{"test", "quality", "deploy", "database", "misc"}; !slicesEqual(categories, want) {
+		t.Errorf("categories = %v, want %v", categories, want)
+	}
+	if want := []string{"outdated", "ship-it"}; !slicesEqual(byCategory["misc"], want) {
+		t.Errorf("misc = %v, want %v", byCategory["misc"], want)
+	}
+	if got := categoryTitle("database"); got != "Database" {
+		t.Errorf("categoryTitle(database) = %q", got)
+	}
+}
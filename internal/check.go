@@ -27,12 +27,56 @@ func HandleCheckCommand(r *CommandRunner) error {
 	return r.synthesizeCheckCommand()
 }
 
-// synthesizeCheckCommand runs lint, typecheck, and test as separate commands
-func (r *CommandRunner) synthesizeCheckCommand() error {
+// failFast reports whether synthesized check stops at the first failing
+// step: --fail-fast or --keep-going, else the nearest config that sets
+// fail_fast, else keep going
+func (r *CommandRunner) failFast() bool {
+	if r.FailFast != nil {
+		return *r.FailFast
+	}
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.FailFast != nil {
+			return *config.FailFast
+		}
+	}
+	return false
+}
+
+// checkCandidates returns the commands synthesized check may run: lint,
+// typecheck, and test, after fmt-check if the project defines one
+func (r *CommandRunner) checkCandidates() []string {
 	commands := []string{"lint", "typecheck", "test"}
+	if cmd, source := r.lookupCommand("fmt-check"); cmd != nil && definesCommand(source, "fmt-check") {
+		commands = append([]string{"fmt-check"}, commands...)
+	}
+	return commands
+}
+
+// checkSteps returns the commands of candidates that synthesized check runs:
+// those the project has, leaving out typecheck where the project type has
+// none
+func (r *CommandRunner) checkSteps(candidates []string) []string {
+	var available []string
+	for _, cmdName := range candidates {
+		// Skip typecheck if it doesn't exist for this project type
+		if cmdName == "typecheck" && !r.hasTypecheckCapability() {
+			continue
+		}
+		if r.hasCommand(cmdName) {
+			available = append(available, cmdName)
+		}
+	}
+	return available
+}
+
+// synthesizeCheckCommand runs lint, typecheck, and test as separate commands,
+// after fmt-check if the project defines one
+func (r *CommandRunner) synthesizeCheckCommand() error {
+	commands := r.checkCandidates()
 	var foundAny bool
 	var failedCommands []string
 	var hasErrors bool
+	var steps []stepTime
 
 	// First check which commands are available
 	for _, cmdName := range commands {
@@ -42,37 +86,68 @@ func (r *CommandRunner) synthesizeCheckCommand() error {
 	}
 
 	if !foundAny {
-		return fmt.Errorf("no check, lint, typecheck, or test commands found")
+		return missingCommandError(fmt.Errorf("no check, lint, typecheck, or test commands found"))
 	}
 
-	fmt.Fprintf(os.Stderr, "Running check (synthesizing from available commands)...\n")
+	r.progressf("%sRunning check (synthesizing from available commands)...\n", r.badge())
 
-	for _, cmdName := range commands {
-		// Skip typecheck if it doesn't exist for this project type
-		if cmdName == "typecheck" && !r.hasTypecheckCapability() {
-			continue
-		}
+	available := r.checkSteps(commands)
 
-		if !r.hasCommand(cmdName) {
-			continue
+	var skipped []string
+	if r.Parallel && len(available) > 1 {
+		r.progressf("\n→ Running %s in parallel...\n", strings.Join(available, ", "))
+		runs := make([]labeledRun, len(available))
+		for i, cmdName := range available {
+			subRunner := r.subRunner(cmdName, r.Args)
+			subRunner.inner = true
+			subRunner.capture = r.stepCapture()
+			runs[i] = labeledRun{cmdName, subRunner}
 		}
-
-		fmt.Fprintf(os.Stderr, "\n→ Running %s...\n", cmdName)
-		subRunner := &CommandRunner{
-			Command:     cmdName,
-			Args:        r.Args,
-			CurrentDir:  r.CurrentDir,
-			ProjectRoot: r.ProjectRoot,
+		for i, result := range r.runParallel(runs, r.failFast()) {
+			cmdName := available[i]
+			if result.skipped {
+				skipped = append(skipped, cmdName)
+				continue
+			}
+			if result.err != nil {
+				runs[i].runner.annotate()
+			}
+			r.report.addStep(cmdName, result.duration, result.err, runs[i].runner.capture)
+			steps = append(steps, stepTime{cmdName, result.duration, result.err != nil})
+			if result.err != nil {
+				hasErrors = true
+				failedCommands = append(failedCommands, cmdName)
+				fmt.Fprintf(r.statusWriter(), "  %s %s failed: %v\n", passMark(r.statusWriter(), false), cmdName, result.err)
+			}
 		}
+	} else {
+		for _, cmdName := range available {
+			if hasErrors && r.failFast() {
+				skipped = append(skipped, cmdName)
+				continue
+			}
+
+			r.progressf("\n→ Running %s...\n", cmdName)
+			subRunner := r.subRunner(cmdName, r.Args)
+			subRunner.inner = true
 
-		if err := subRunner.Run(); err != nil {
-			hasErrors = true
-			failedCommands = append(failedCommands, cmdName)
-			fmt.Fprintf(os.Stderr, "  ✗ %s failed: %v\n", cmdName, err)
+			elapsed, err := r.runStep(cmdName, subRunner)
+			steps = append(steps, stepTime{cmdName, elapsed, err != nil})
+			if err != nil {
+				hasErrors = true
+				failedCommands = append(failedCommands, cmdName)
+				fmt.Fprintf(r.statusWriter(), "  %s %s failed: %v\n", passMark(r.statusWriter(), false), cmdName, err)
+			}
 		}
 	}
 
+	r.reportSteps(steps)
+	r.report.addSkipped("not run after an earlier step failed", skipped...)
+
 	if hasErrors {
+		if len(skipped) > 0 {
+			return fmt.Errorf("check failed: %s (skipped %s)", strings.Join(failedCommands, ", "), strings.Join(skipped, ", "))
+		}
 		return fmt.Errorf("check failed: %s", strings.Join(failedCommands, ", "))
 	}
 
@@ -82,11 +157,11 @@ func (r *CommandRunner) synthesizeCheckCommand() error {
 func (r *CommandRunner) findNativeCheckCommand(dir string) *exec.Cmd {
 	// Check for mise
 	if FileExists(filepath.Join(dir, ".mise.toml")) {
-		project := ResolveProject(dir)
+		project := r.resolveProject(dir)
 		if miseSource := findSourceByName(project.CommandSources, "mise"); miseSource != nil {
 			commands := miseSource.ListCommands()
 			if _, exists := commands["check"]; exists {
-				cmd := exec.Command("mise", append([]string{"run", "check"}, r.Args...)...)
+				cmd := r.executor.command("mise", append([]string{"run", "check"}, r.Args...)...)
 				cmd.Dir = dir
 				return cmd
 			}
@@ -95,11 +170,11 @@ func (r *CommandRunner) findNativeCheckCommand(dir string) *exec.Cmd {
 
 	// Check for just
 	if FileExists(filepath.Join(dir, "justfile")) || FileExists(filepath.Join(dir, "Justfile")) {
-		project := ResolveProject(dir)
+		project := r.resolveProject(dir)
 		if justSource := findSourceByName(project.CommandSources, "just"); justSource != nil {
 			commands := justSource.ListCommands()
 			if _, exists := commands["check"]; exists {
-				cmd := exec.Command("just", append([]string{"check"}, r.Args...)...)
+				cmd := r.executor.command("just", append([]string{"check"}, r.Args...)...)
 				cmd.Dir = dir
 				return cmd
 			}
@@ -108,11 +183,11 @@ func (r *CommandRunner) findNativeCheckCommand(dir string) *exec.Cmd {
 
 	// Check for make
 	if FileExists(filepath.Join(dir, "Makefile")) || FileExists(filepath.Join(dir, "makefile")) {
-		project := ResolveProject(dir)
+		project := r.resolveProject(dir)
 		if makeSource := findSourceByName(project.CommandSources, "make"); makeSource != nil {
 			commands := makeSource.ListCommands()
 			if _, exists := commands["check"]; exists {
-				cmd := exec.Command("make", append([]string{"check"}, r.Args...)...)
+				cmd := r.executor.command("make", append([]string{"check"}, r.Args...)...)
 				cmd.Dir = dir
 				return cmd
 			}
@@ -130,7 +205,7 @@ func (r *CommandRunner) findNativeCheckCommand(dir string) *exec.Cmd {
 				if _, ok := pkg.Scripts["check"]; ok {
 					packageManager := detectPackageManager(dir)
 					if packageManager != "" {
-						cmd := exec.Command(packageManager, append([]string{"run", "check"}, r.Args...)...)
+						cmd := r.executor.command(packageManager, append([]string{"run", "check"}, r.Args...)...)
 						cmd.Dir = dir
 						return cmd
 					}
@@ -144,15 +219,7 @@ func (r *CommandRunner) findNativeCheckCommand(dir string) *exec.Cmd {
 
 // hasCommand checks if a command exists in any runner
 func (r *CommandRunner) hasCommand(command string) bool {
-	// Create a temporary runner to check for the specific command
-	// Build projects and check if command exists
-	projects := []*Project{}
-	projects = append(projects, ResolveProject(r.CurrentDir))
-	if r.ProjectRoot != r.CurrentDir && r.ProjectRoot != "" {
-		projects = append(projects, ResolveProject(r.ProjectRoot))
-	}
-
-	for _, project := range projects {
+	for _, project := range r.projects() {
 		for _, source := range project.CommandSources {
 			if cmd := source.FindCommand(command, []string{}); cmd != nil {
 				return true
@@ -166,12 +233,7 @@ func (r *CommandRunner) hasCommand(command string) bool {
 // provided command names. This ignores synthesized fallbacks that don't appear
 // in the source listings.
 func (r *CommandRunner) hasListedCommand(names ...string) bool {
-	projects := []*Project{ResolveProject(r.CurrentDir)}
-	if r.ProjectRoot != r.CurrentDir && r.ProjectRoot != "" {
-		projects = append(projects, ResolveProject(r.ProjectRoot))
-	}
-
-	for _, project := range projects {
+	for _, project := range r.projects() {
 		for _, source := range project.CommandSources {
 			commands := source.ListCommands()
 			for _, name := range names {
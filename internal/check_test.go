@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckFailFast(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		config   string
+		failFast *bool
+		log      string
+		wantErr  string
+	}{
+		{"keep going by default", "", nil, "lint\ntest\n", "check failed: lint"},
+		{"config", "fail_fast = true\n", nil, "lint\n", "check failed: lint (skipped test)"},
+		{"--fail-fast", "", &yes, "lint\n", "(skipped test)"},
+		{"--keep-going over config", "fail_fast = true\n", &no, "lint\ntest\n", "check failed: lint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, tt.config+"[commands]\nlint = \"echo lint >> log; exit 1\"\ntest = \"echo test >> log\"\n")
+			runner := &CommandRunner{Command: "check", CurrentDir: dir, ProjectRoot: dir, Quiet: true, FailFast: tt.failFast}
+			err := runner.Run()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			log, _ := os.ReadFile(filepath.Join(dir, "log"))
+			if string(log) != tt.log {
+				t.Errorf("steps run = %q, want %q", log, tt.log)
+			}
+		})
+	}
+}
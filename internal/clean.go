@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// cleanEcosystem lists the artifacts a synthesized clean removes from a
+// project with one of markers. Paths are globs relative to the project;
+// deep ones, such as installed dependencies, are removed only with --deep.
+type cleanEcosystem struct {
+	name    string
+	markers []string
+	paths   []string
+	deep    []string
+}
+
+var cleanEcosystems = []cleanEcosystem{
+	{
+		name:    "Node",
+		markers: []string{"package.json", "deno.json", "deno.jsonc"},
+		paths:   []string{"dist", "build", ".next", ".nuxt", ".svelte-kit", ".turbo", ".parcel-cache", "coverage", "*.tsbuildinfo"},
+		deep:    []string{"node_modules"},
+	},
+	{
+		name:    "Python",
+		markers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"},
+		paths:   []string{"dist", "build", "*.egg-info", ".pytest_cache", ".mypy_cache", ".ruff_cache", ".coverage", "htmlcov", "**/__pycache__"},
+		deep:    []string{".venv", ".tox", ".nox"},
+	},
+	{name: "Rust", markers: []string{"Cargo.toml"}, paths: []string{"target"}},
+	{name: "Gradle", markers: []string{"build.gradle", "build.gradle.kts"}, paths: []string{"build"}, deep: []string{".gradle"}},
+	{name: "Maven", markers: []string{"pom.xml"}, paths: []string{"target"}},
+	{name: "Go", markers: []string{"go.mod"}},
+}
+
+// cleanSkipDirs are not searched for nested artifacts such as __pycache__
+var cleanSkipDirs = map[string]bool{".git": true, "node_modules": true, ".venv": true, "venv": true, "target": true}
+
+// HandleCleanCommand handles clean when the project doesn't define it: it
+// runs the tool's clean, if there is one, then removes the well-known
+// artifacts of the project's ecosystems that remain
+func HandleCleanCommand(r *CommandRunner) error {
+	deep, dryRun := false, false
+	for _, arg := range r.Args {
+		switch arg {
+		case "--deep":
+			deep = true
+		case "--dry-run", "-n":
+			dryRun = true
+		default:
+			return fmt.Errorf("unknown clean option %s: the synthesized clean takes --deep and --dry-run", arg)
+		}
+	}
+
+	dir, ecosystems := r.cleanProject()
+	tool, _ := r.subRunner(r.Command, nil).lookupCommand(r.Command)
+	if len(ecosystems) == 0 && tool == nil {
+		return r.commandNotFound(r.Command)
+	}
+	targets, err := r.cleanTargets(dir, ecosystems, deep)
+	if err != nil {
+		return err
+	}
+	rel := func(path string) string {
+		if p, err := filepath.Rel(dir, path); err == nil {
+			path = p
+		}
+		return filepath.ToSlash(path)
+	}
+
+	if dryRun {
+		if tool != nil {
+			fmt.Printf("Would run: %s\n", strings.Join(tool.Args, " "))
+		}
+		for _, path := range targets {
+			fmt.Printf("Would remove %s (%s)\n", rel(path), formatBytes(diskUsage(path)))
+		}
+		if tool == nil && len(targets) == 0 {
+			fmt.Println("Nothing to clean")
+		}
+		return nil
+	}
+
+	if err := refuseToRun("clean"); err != nil {
+		return err
+	}
+	if tool != nil {
+		if err := r.ExecuteCommand(tool); err != nil {
+			return err
+		}
+	}
+	var failed []string
+	for _, path := range targets {
+		// The tool's clean may already have removed it
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		size := diskUsage(path)
+		if err := os.RemoveAll(path); err != nil {
+			fmt.Fprintf(r.statusWriter(), "  %s can't remove %s: %v\n", passMark(r.statusWriter(), false), rel(path), err)
+			failed = append(failed, rel(path))
+			continue
+		}
+		r.progressf("Removed %s (%s)\n", rel(path), formatBytes(size))
+	}
+	if tool == nil && len(targets) == 0 {
+		r.progressf("Nothing to clean\n")
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("clean couldn't remove %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// cleanProject returns the nearest directory, of the current directory and
+// project root, with an ecosystem that clean knows, and its ecosystems
+func (r *CommandRunner) cleanProject() (string, []cleanEcosystem) {
+	for _, dir := range r.searchDirs() {
+		var found []cleanEcosystem
+		for _, ecosystem := range cleanEcosystems {
+			for _, marker := range ecosystem.markers {
+				if FileExists(filepath.Join(dir, marker)) {
+					found = append(found, ecosystem)
+					break
+				}
+			}
+		}
+		if len(found) > 0 {
+			return dir, found
+		}
+	}
+	return r.CurrentDir, nil
+}
+
+// cleanTargets returns the artifacts of ecosystems that exist in dir.
+// Paths that have files tracked by git are source, not artifacts, and are
+// left alone. In a repository where git can't say which paths it tracks,
+// nothing is cleaned.
+func (r *CommandRunner) cleanTargets(dir string, ecosystems []cleanEcosystem, deep bool) ([]string, error) {
+	seen := make(map[string]bool)
+	var targets []string
+	var trackErr error
+	add := func(path string) {
+		if seen[path] || trackErr != nil {
+			return
+		}
+		seen[path] = true
+		tracked, err := r.gitTracked(dir, path)
+		if err != nil {
+			trackErr = err
+			return
+		}
+		if tracked {
+			debugf("clean: %s has files tracked by git, so it isn't removed", path)
+			return
+		}
+		targets = append(targets, path)
+	}
+	for _, ecosystem := range ecosystems {
+		patterns := ecosystem.paths
+		if deep {
+			patterns = append(append([]string{}, patterns...), ecosystem.deep...)
+		}
+		for _, pattern := range patterns {
+			if name, ok := strings.CutPrefix(pattern, "**/"); ok {
+				for _, path := range findNamedDirs(dir, name) {
+					add(path)
+				}
+				continue
+			}
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, path := range matches {
+				add(path)
+			}
+		}
+	}
+	if trackErr != nil {
+		return nil, trackErr
+	}
+	return targets, nil
+}
+
+// findNamedDirs returns the directories named name under dir
+func findNamedDirs(dir, name string) []string {
+	var found []string
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || path == dir {
+			return nil
+		}
+		if entry.Name() == name {
+			found = append(found, path)
+			return filepath.SkipDir
+		}
+		if cleanSkipDirs[entry.Name()] {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return found
+}
+
+// gitTracked reports whether path has files tracked by the git repository
+// dir is in. Outside a repository, nothing is tracked; inside one, failing
+// to run git is an error, since tracked source would otherwise look like an
+// artifact.
+func (r *CommandRunner) gitTracked(dir, path string) (bool, error) {
+	if !inGitRepository(dir) {
+		return false, nil
+	}
+	git := r.executor.command("git", "ls-files", "--", path)
+	git.Dir = dir
+	output, err := git.Output()
+	if err != nil {
+		var stderr string
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		if stderr != "" {
+			err = fmt.Errorf("%w: %s", err, stderr)
+		}
+		return false, fmt.Errorf("not cleaning: can't tell whether git tracks %s: %v", path, err)
+	}
+	return len(output) > 0, nil
+}
+
+// inGitRepository reports whether dir or a parent has a .git
+func inGitRepository(dir string) bool {
+	for {
+		if FileExists(filepath.Join(dir, ".git")) {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// diskUsage returns the size of the files under path, without following
+// symlinks
+func diskUsage(path string) int64 {
+	var total int64
+	_ = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
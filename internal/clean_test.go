@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanTargets(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"package.json", "pyproject.toml",
+		"dist/app.js", "coverage/lcov.info", "app.tsbuildinfo",
+		"pkg/__pycache__/mod.pyc", "node_modules/x/index.js", "node_modules/y/__pycache__/z.pyc",
+		"src/main.py", "build/tracked.sh",
+	} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A tracked build directory is source, not an artifact
+	if _, err := exec.LookPath("git"); err == nil {
+		for _, args := range [][]string{{"init", "-q"}, {"add", "build"}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, output)
+			}
+		}
+	} else {
+		os.RemoveAll(filepath.Join(dir, "build"))
+	}
+
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	cleanDir, ecosystems := runner.cleanProject()
+	if cleanDir != dir || len(ecosystems) != 2 {
+		t.Fatalf("cleanProject() = %s, %d ecosystems", cleanDir, len(ecosystems))
+	}
+	relative := func(paths []string) []string {
+		var rel []string
+		for _, path := range paths {
+			p, _ := filepath.Rel(dir, path)
+			rel = append(rel, filepath.ToSlash(p))
+		}
+		return rel
+	}
+	targets, err := runner.cleanTargets(dir, ecosystems, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := relative(targets)
+	want := []string{"dist", "coverage", "app.tsbuildinfo", "pkg/__pycache__"}
+	if !slicesEqual(got, want) {
+		t.Errorf("cleanTargets() = %v, want %v", got, want)
+	}
+	if targets, err = runner.cleanTargets(dir, ecosystems, true); err != nil {
+		t.Fatal(err)
+	}
+	got = relative(targets)
+	if want := []string{"dist", "coverage", "app.tsbuildinfo", "node_modules", "pkg/__pycache__"}; !slicesEqual(got, want) {
+		t.Errorf("cleanTargets(deep) = %v, want %v", got, want)
+	}
+}
+
+func TestSynthesizedClean(t *testing.T) {
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	for _, file := range []string{"package.json", "dist/app.js", "node_modules/x/index.js"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) {
+		t.Helper()
+		runner := &CommandRunner{Command: "clean", Args: args, CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+		if err := runner.Run(); err != nil {
+			t.Fatalf("clean %v: %v", args, err)
+		}
+	}
+
+	run("--dry-run")
+	if !FileExists(filepath.Join(dir, "dist")) {
+		t.Error("clean --dry-run removed dist")
+	}
+	run()
+	if FileExists(filepath.Join(dir, "dist")) || !FileExists(filepath.Join(dir, "node_modules")) {
+		t.Error("clean should remove dist and keep node_modules")
+	}
+	run("--deep")
+	if FileExists(filepath.Join(dir, "node_modules")) {
+		t.Error("clean --deep should remove node_modules")
+	}
+
+	runner := &CommandRunner{Command: "clean", Args: []string{"--force"}, CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+	if err := runner.Run(); err == nil {
+		t.Error("clean should reject unknown options")
+	}
+}
+
+func TestCleanWithoutGit(t *testing.T) {
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	for _, file := range []string{".git/HEAD", "package.json", "dist/app.js"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// In a repository, git failing mustn't make tracked files look like
+	// artifacts
+	factory := func(name string, arg ...string) *exec.Cmd {
+		if name == "git" {
+			return exec.Command("cmdr-test-missing-git", arg...)
+		}
+		return exec.Command(name, arg...)
+	}
+	for _, args := range [][]string{{"--dry-run"}, nil} {
+		runner := New("clean", args, WithCommandFactory(factory))
+		runner.CurrentDir, runner.ProjectRoot, runner.Quiet = dir, dir, true
+		if err := runner.Run(); err == nil || !strings.Contains(err.Error(), "not cleaning") {
+			t.Errorf("clean %v with git failing: error = %v", args, err)
+		}
+	}
+	if !FileExists(filepath.Join(dir, "dist")) {
+		t.Error("clean removed dist while git was failing")
+	}
+
+	// Outside a repository there is nothing to ask git
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		t.Fatal(err)
+	}
+	runner := New("clean", nil, WithCommandFactory(factory))
+	runner.CurrentDir, runner.ProjectRoot, runner.Quiet = dir, dir, true
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(filepath.Join(dir, "dist")) {
+		t.Error("clean outside a repository left dist")
+	}
+}
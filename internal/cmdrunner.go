@@ -1,12 +1,15 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/term"
 )
@@ -16,13 +19,137 @@ type CommandRunner struct {
 	Args        []string
 	CurrentDir  string
 	ProjectRoot string
+
+	// EnvOverrides are variables set with -e/--env; they take precedence
+	// over every other environment source
+	EnvOverrides map[string]string
+
+	// EnvFiles are dotenv files given with --env-file, loaded after those
+	// from config
+	EnvFiles []string
+
+	// Sudo runs commands through sudo; AsUser runs them as another user
+	Sudo   bool
+	AsUser string
+
+	// InContainer runs commands in the project's devcontainer, or the
+	// image its config names
+	InContainer bool
+
+	// Retry retries commands that fail with transient (network) errors
+	Retry RetryPolicy
+
+	// Yes runs a tool's default update or publish without asking first
+	Yes bool
+
+	// Dashboard runs process groups under a full-screen dashboard
+	Dashboard bool
+
+	// NoSynth runs only commands the project defines, as synthesize = false
+	// in config does
+	NoSynth bool
+
+	// ProjectFilters and ProjectExcludes select the projects of a
+	// recursive run, by path glob or manifest name
+	ProjectFilters  []string
+	ProjectExcludes []string
+
+	// Parallel runs the steps of synthesized check, and the projects of a
+	// recursive run, at once, labeling each line of their output
+	Parallel bool
+
+	// FailFast stops synthesized check at the first failing step, when
+	// true, or runs every step, when false; nil leaves it to fail_fast in
+	// config
+	FailFast *bool
+
+	// Jobs is the most commands cmdr runs at once when it runs several, or
+	// 0 for the user config's jobs (by default, no limit)
+	Jobs int
+
+	// PTY says when commands run under a pseudo-terminal, so that tools
+	// keep colors and progress bars when cmdr passes their output on
+	PTY PTYMode
+
+	// Time prints how long the run took, and each step of check and fix
+	Time bool
+
+	// Quiet leaves out the banner before a command and the progress of
+	// synthesized commands
+	Quiet bool
+
+	// executor creates the processes the runner and its sources start
+	executor *Executor
+
+	// resolved holds the projects the runner has resolved, shared with
+	// its sub-runners; nil resolves them on every use
+	resolved *projectCache
+
+	// ctx cancels the run, killing the processes it started; nil for a
+	// run that can't be cancelled. Set with WithContext or RunContext.
+	ctx context.Context
+
+	// inner is set on runs inside another run, such as the steps of check,
+	// whose time the outer run reports
+	inner bool
+
+	// LogFile is a file to copy the run's output to, as log_dir in the
+	// user config does
+	LogFile string
+
+	// outputLog is the open log of the top-level run, shared by the runs
+	// inside it
+	outputLog *outputLog
+
+	// JUnit is a file to write a JUnit XML report of the run to, with a
+	// testcase for each step of synthesized check
+	JUnit string
+
+	// report collects the steps of the top-level run for JUnit, and
+	// capture keeps the output of the current run or step
+	report  *junitReport
+	capture *tailBuffer
+
+	// SARIF is a file to write a SARIF log of the diagnostics in the run's
+	// output to, and diagnostics keeps that output
+	SARIF       string
+	diagnostics *tailBuffer
+
+	// output is where a run made alongside others writes, its lines
+	// labeled; nil for the terminal
+	output *prefixWriter
+
+	// prompter asks the user to confirm; nil asks on the terminal
+	prompter prompter
+
+	// trace collects the command lines the top-level run executes, for the
+	// history file
+	trace *runTrace
+
+	// aliasExpanded is set once a user alias has been expanded, so that
+	// aliases don't chain or recurse
+	aliasExpanded bool
 }
 
-func New(command string, args []string) *CommandRunner {
-	return &CommandRunner{
-		Command: command, // Keep raw command; normalization happens in Run()
-		Args:    args,
+func New(command string, args []string, opts ...RunnerOption) *CommandRunner {
+	r := &CommandRunner{
+		Command:  command, // Keep raw command; normalization happens in Run()
+		Args:     args,
+		resolved: &projectCache{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
+}
+
+// subRunner returns a runner for another command in the same context,
+// carrying over the options of r
+func (r *CommandRunner) subRunner(command string, args []string) *CommandRunner {
+	sub := *r
+	sub.Command = command
+	sub.Args = args
+	return &sub
 }
 
 func (r *CommandRunner) Init() error {
@@ -51,60 +178,400 @@ func (r *CommandRunner) FindProjectRoot(dir string) string {
 	return dir
 }
 
-func (r *CommandRunner) Run() error {
-	// Build projects for current dir and project root
-	projects := []*Project{}
+// resolveProject resolves the project in dir with the runner's executor
+func (r *CommandRunner) resolveProject(dir string) *Project {
+	if r.resolved == nil {
+		return resolveProject(dir, r.executor)
+	}
+	return r.resolved.get(dir, r.executor)
+}
+
+// projectCache holds resolved projects, so that the steps of a run, such as
+// those of synthesized check, share their sources and listings
+type projectCache struct {
+	sync.Mutex
+	projects map[projectKey]*Project
 
-	// Add current directory project
-	projects = append(projects, ResolveProject(r.CurrentDir))
+	// generation is the count of invalidations the projects are current
+	// with; a changed file in a long session drops them
+	generation int64
+}
+
+// projectKey identifies a resolved project: sources resolved through
+// another executor, such as one bound to a context, start processes
+// differently
+type projectKey struct {
+	dir      string
+	executor *Executor
+}
 
-	// Add project root if different
+func (c *projectCache) get(dir string, x *Executor) *Project {
+	c.Lock()
+	defer c.Unlock()
+	if generation := invalidations.Load(); c.projects == nil || c.generation != generation {
+		c.projects = make(map[projectKey]*Project)
+		c.generation = generation
+	}
+	key := projectKey{dir, x}
+	if project, ok := c.projects[key]; ok {
+		return project
+	}
+	project := resolveProject(dir, x)
+	c.projects[key] = project
+	return project
+}
+
+// projects returns the projects for the current directory and project root
+func (r *CommandRunner) projects() []*Project {
+	projects := []*Project{r.resolveProject(r.CurrentDir)}
 	if r.ProjectRoot != r.CurrentDir && r.ProjectRoot != "" {
-		projects = append(projects, ResolveProject(r.ProjectRoot))
+		projects = append(projects, r.resolveProject(r.ProjectRoot))
+	}
+	return projects
+}
+
+// RunContext runs the command as Run does. If ctx is done first, the
+// processes the run started are killed and it returns ctx.Err().
+func (r *CommandRunner) RunContext(ctx context.Context) error {
+	sub := *r
+	WithContext(ctx)(&sub)
+	return sub.Run()
+}
+
+// context returns the context that cancels the run
+func (r *CommandRunner) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// Run runs the command, along with its pre and post hooks from config
+func (r *CommandRunner) Run() error {
+	r.applyCommandConfig()
+	r.setTitle(r.Command)
+	if group, _ := r.groupConfig(r.Command); r.Dashboard && group == nil {
+		return fmt.Errorf("--dashboard only applies to process groups, and %s is not one", r.Command)
+	}
+	if !r.inner {
+		r.trace = &runTrace{}
+	}
+	start := time.Now()
+	ownsLog := false
+	if r.outputLog == nil {
+		log, err := r.openOutputLog(start)
+		if err != nil {
+			return fmt.Errorf("can't open log file: %w", err)
+		}
+		r.outputLog, ownsLog = log, log != nil
+	}
+	ownsReport := r.JUnit != "" && r.report == nil
+	if ownsReport {
+		r.report = &junitReport{start: start}
+	}
+	ownsSARIF := r.SARIF != "" && r.diagnostics == nil
+	if ownsSARIF {
+		r.diagnostics = &tailBuffer{max: sarifOutputMax}
+	}
+	ownsCapture := r.capture == nil && (ownsReport || githubActions())
+	if ownsCapture {
+		r.capture = r.stepCapture()
+	}
+	err := r.runWithHooks(r.run)
+	if ownsCapture && err != nil {
+		r.annotate()
+	}
+	if ownsReport {
+		err = r.finishJUnit(time.Since(start), err, r.capture)
+		r.report = nil
+	}
+	if ownsSARIF {
+		err = r.finishSARIF(err, parseDiagnostics(r.diagnostics.Bytes(), r.CurrentDir, r.ProjectRoot))
+		r.diagnostics = nil
+	}
+	if ownsCapture {
+		r.capture = nil
+	}
+	if r.timed() {
+		r.reportTime(time.Since(start), err)
+	}
+	if !r.inner {
+		r.recordHistory(start, err)
+	}
+	if ownsLog {
+		r.closeOutputLog(time.Since(start), err)
+	}
+	return err
+}
+
+func (r *CommandRunner) run() error {
+	if group, dir := r.groupConfig(r.Command); group != nil {
+		debugf("%s is a process group in %s", r.Command, dir)
+		return r.RunGroup(r.Command, group, dir)
+	}
+
+	// A command pinned to a source in config bypasses normal resolution
+	pinned, err := r.pinnedCommand(r.Command)
+	if err != nil {
+		return err
+	}
+	if pinned != nil {
+		debugf("%s is pinned to a source in config: %s", r.Command, strings.Join(pinned.Args, " "))
+		return r.ExecuteCommand(pinned)
+	}
+
+	if sub := r.formatCheck(); sub != nil {
+		debugf("%s --check runs fmt-check", r.Command)
+		sub.inner = true
+		return sub.Run()
 	}
 
 	// First, try to find the exact command (no normalization)
-	for _, project := range projects {
-		for _, source := range project.CommandSources {
-			if cmd := source.FindCommand(r.Command, r.Args); cmd != nil {
-				return r.ExecuteCommand(cmd)
-			}
+	if cmd, source := r.lookupCommand(r.Command); cmd != nil {
+		// A tool's clean is one step of the synthesized clean
+		if r.Command == "clean" && !definesCommand(source, r.Command) {
+			debugf("clean is a %s default, so cmd-runner synthesizes it", source.Name())
+			return HandleCleanCommand(r)
+		}
+		// Likewise, a tool's setup follows provisioning the toolchains
+		if r.Command == "setup" && !definesCommand(source, r.Command) && r.provisions() {
+			debugf("setup is a %s default and the project pins toolchains, so cmd-runner provisions them first", source.Name())
+			return HandleSetupCommand(r)
+		}
+		if err := r.requireTool(cmd, source); err != nil {
+			return err
+		}
+		if err := r.guardDefault(cmd, source); err != nil {
+			return err
 		}
+		return r.ExecuteCommand(cmd)
+	}
+
+	// User-defined aliases apply when no source has a command by that name
+	if command, args, ok := expandUserAlias(r.Command, r.Args); ok && !r.aliasExpanded {
+		debugf("%s is a user alias for %s", r.Command, strings.Join(append([]string{command}, args...), " "))
+		sub := r.subRunner(command, args)
+		sub.aliasExpanded = true
+		sub.inner = true
+		return sub.Run()
 	}
 
 	// Special handling for synthesized commands (only if no exact match found)
 	switch r.Command {
+	case "check", "fix", "typecheck", "clean":
+		if r.strict() {
+			return r.commandNotFound(r.Command)
+		}
+		debugf("%s has no match, so cmd-runner synthesizes it", r.Command)
+	}
+	switch r.Command {
 	case "check":
 		return HandleCheckCommand(r)
 	case "fix":
 		return HandleFixCommand(r)
 	case "typecheck":
 		return HandleTypecheckCommand(r)
+	case "clean":
+		return HandleCleanCommand(r)
+	case "setup":
+		if !r.strict() && r.provisions() {
+			debugf("setup has no match, so cmd-runner provisions the pinned toolchains")
+			return HandleSetupCommand(r)
+		}
 	}
 
 	// If no direct match found and the command might be an alias,
 	// try with the normalized version
 	normalizedCommand := NormalizeCommand(r.Command)
 	if normalizedCommand != r.Command {
-		for _, project := range projects {
-			for _, source := range project.CommandSources {
-				if cmd := source.FindCommand(normalizedCommand, r.Args); cmd != nil {
-					return r.ExecuteCommand(cmd)
-				}
+		debugf("%s normalizes to %s", r.Command, normalizedCommand)
+		if cmd, source := r.lookupCommand(normalizedCommand); cmd != nil {
+			if err := r.requireTool(cmd, source); err != nil {
+				return err
+			}
+			if err := r.guardDefault(cmd, source); err != nil {
+				return err
 			}
+			return r.ExecuteCommand(cmd)
 		}
 	}
 
-	return fmt.Errorf("no command '%s' found in current directory or project root", r.Command)
+	// With prefix_match on, a unique prefix stands for the whole name
+	if handled, err := r.runPrefixMatch(); handled {
+		return err
+	}
+
+	return r.commandNotFound(r.Command)
+}
+
+// guardDefault runs the safety checks for a tool default that changes
+// things beyond the build: update asks first, and publish also requires a
+// clean tree and a passing check. Commands the project defines run as
+// they are.
+func (r *CommandRunner) guardDefault(cmd *exec.Cmd, source CommandSource) error {
+	if source == nil || definesCommand(source, r.Command) || analyzeOnly {
+		return nil
+	}
+	switch NormalizeCommand(r.Command) {
+	case "update":
+		return r.confirmUpdate(cmd, source)
+	case "publish":
+		return r.preparePublish(cmd)
+	}
+	return nil
+}
+
+// Resolve returns the command that Run would execute, without running it.
+// Synthesized commands that run several commands can't be resolved to a
+// single invocation and return an error.
+func (r *CommandRunner) Resolve() (*exec.Cmd, error) {
+	cmd, _, _, err := r.resolve()
+	return cmd, err
+}
+
+// resolve is Resolve, also returning the source that provides the command
+// and the name it was found under
+func (r *CommandRunner) resolve() (*exec.Cmd, CommandSource, string, error) {
+	if group, _ := r.groupConfig(r.Command); group != nil {
+		return nil, nil, "", fmt.Errorf("'%s' is a process group and does not resolve to a single command", r.Command)
+	}
+	if cmd, source, err := r.pinnedLookup(r.Command); cmd != nil || err != nil {
+		return cmd, source, r.Command, err
+	}
+	if sub := r.formatCheck(); sub != nil {
+		return sub.resolve()
+	}
+
+	if cmd, source := r.lookupCommand(r.Command); cmd != nil {
+		if r.Command == "clean" && !definesCommand(source, r.Command) {
+			return nil, nil, "", fmt.Errorf("'clean' is synthesized by cmd-runner, running %s and removing build artifacts, and does not resolve to a single command", strings.Join(cmd.Args, " "))
+		}
+		if r.Command == "setup" && !definesCommand(source, r.Command) && r.provisions() {
+			return nil, nil, "", fmt.Errorf("'setup' is synthesized by cmd-runner, provisioning toolchains and then running %s, and does not resolve to a single command", strings.Join(cmd.Args, " "))
+		}
+		return cmd, source, r.Command, nil
+	}
+
+	if command, args, ok := expandUserAlias(r.Command, r.Args); ok && !r.aliasExpanded {
+		sub := r.subRunner(command, args)
+		sub.aliasExpanded = true
+		return sub.resolve()
+	}
+
+	switch r.Command {
+	case "check", "fix", "typecheck", "clean":
+		if r.strict() {
+			return nil, nil, "", r.commandNotFound(r.Command)
+		}
+		return nil, nil, "", fmt.Errorf("'%s' is synthesized by cmd-runner and does not resolve to a single command", r.Command)
+	case "setup":
+		if !r.strict() && r.provisions() {
+			return nil, nil, "", fmt.Errorf("'setup' is synthesized by cmd-runner, provisioning toolchains, and does not resolve to a single command")
+		}
+	}
+
+	normalizedCommand := NormalizeCommand(r.Command)
+	if normalizedCommand != r.Command {
+		if cmd, source := r.lookupCommand(normalizedCommand); cmd != nil {
+			return cmd, source, normalizedCommand, nil
+		}
+	}
+
+	return nil, nil, "", r.commandNotFound(r.Command)
+}
+
+// findCommand searches the sources of the current directory, then the
+// project root, for command
+func (r *CommandRunner) findCommand(command string) *exec.Cmd {
+	cmd, _ := r.lookupCommand(command)
+	return cmd
+}
+
+// lookupCommand is findCommand, also returning the source that matched
+func (r *CommandRunner) lookupCommand(command string) (*exec.Cmd, CommandSource) {
+	defer recordPhase(time.Now(), "look up %s", command)
+	strict := r.strict()
+	// The project root's sources are only detected if the current
+	// directory has no match
+	for _, dir := range r.searchDirs() {
+		project := r.resolveProject(dir)
+		for _, source := range project.CommandSources {
+			if strict && !definesCommand(source, command) {
+				debugf("check %s in %s: %s doesn't define it, and synthesized commands are off", command, project.Dir, source.Name())
+				continue
+			}
+			if cmd := source.FindCommand(command, r.Args); cmd != nil {
+				debugf("check %s in %s: %s matches: %s", command, project.Dir, source.Name(), strings.Join(cmd.Args, " "))
+				return cmd, source
+			}
+			debugf("check %s in %s: %s has no match", command, project.Dir, source.Name())
+		}
+	}
+	return nil, nil
 }
 
 func (r *CommandRunner) ExecuteCommand(cmd *exec.Cmd) error {
+	if err := refuseToRun(strings.Join(cmd.Args, " ")); err != nil {
+		return err
+	}
+	if err := r.context().Err(); err != nil {
+		return err
+	}
+	if err := r.requireTool(cmd, nil); err != nil {
+		return err
+	}
+	cmd = r.checkToolVersion(cmd)
+	if cmd.Env == nil {
+		cmd.Env = r.commandEnv()
+	}
+	if r.InContainer {
+		containerized, cleanup, err := r.containerCommand(cmd)
+		if err != nil {
+			return err
+		}
+		if cleanup != nil {
+			defer cleanup()
+		}
+		cmd = containerized
+	}
+	if r.needsEscalation() {
+		escalated, err := r.escalateCommand(cmd)
+		if err != nil {
+			return err
+		}
+		cmd = escalated
+	}
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = r.tee(os.Stdout)
+	cmd.Stderr = r.tee(os.Stderr)
+	if r.output != nil {
+		// Runs alongside others share the terminal, so none reads it
+		output := r.captured(r.output)
+		cmd.Stdin = nil
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
 
-	fmt.Fprintf(os.Stderr, "Running: %s\n", strings.Join(cmd.Args, " "))
-	return cmd.Run()
+	r.announce(cmd)
+	r.trace.add(cmd.Args)
+	r.outputLog.printf("\n$ %s\n", strings.Join(cmd.Args, " "))
+	start := time.Now()
+	var err error
+	switch {
+	case r.Retry.Attempts > 1:
+		err = r.runWithRetry(cmd)
+	case r.PTY == PTYAlways && r.usePTY() && !term.IsTerminal(int(os.Stdout.Fd())):
+		// On a terminal, the command already writes to it directly
+		err = runInPTY(r.context(), cmd, cmd.Stdout)
+	case (r.output != nil || r.outputLog != nil || r.capture != nil || r.diagnostics != nil) && r.usePTY():
+		// Copying or labeling output would otherwise cost its colors
+		err = runInPTY(r.context(), cmd, cmd.Stdout)
+	default:
+		err = runForeground(r.context(), cmd)
+	}
+	r.audit("command", cmd, start, err)
+	recordPhase(start, "run %s", strings.Join(cmd.Args, " "))
+	return r.toolMissing(cmd, err)
 }
 
 // ListCommands is the original method for backward compatibility
@@ -114,9 +581,80 @@ func (r *CommandRunner) ListCommands() {
 
 // ListCommandsWithOptions shows available commands with configurable options
 func (r *CommandRunner) ListCommandsWithOptions(showAll bool, verbose bool) {
-	fmt.Println("Available commands for this project:")
+	fmt.Println(style(os.Stdout, styleHeader, "Available commands for this project:"))
 	fmt.Println()
 
+	for _, section := range r.listSections(showAll) {
+		switch {
+		case section.rootHeader != "":
+			fmt.Printf("\n%s\n", style(os.Stdout, styleHeader, fmt.Sprintf("From project root (%s):", section.rootHeader)))
+		case section.source == synthesizedSource:
+			fmt.Printf("\n%s\n", style(os.Stdout, styleHeader, "Synthesized commands (provided by cmd-runner):"))
+		}
+		if section.source != synthesizedSource {
+			fmt.Printf("\n%s commands:\n", style(os.Stdout, styleSource, section.source))
+		}
+		// A source with commands of several kinds lists them by category
+		categories, byCategory := r.categorize(sortCommands(section.commands))
+		for i, category := range categories {
+			if len(categories) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("  %s\n", style(os.Stdout, styleDim, categoryTitle(category)+":"))
+			}
+			for _, cmd := range byCategory[category] {
+				r.printCommand(cmd, section.commands[cmd], verbose, section.alsoIn[cmd])
+			}
+		}
+		for _, cmd := range sortCommands(section.shadowed) {
+			fmt.Printf("  %s\n", style(os.Stdout, styleDim, fmt.Sprintf("%-12s → shadowed by %s", cmd, section.shadowed[cmd])))
+		}
+	}
+
+	fmt.Printf("\n%s\n", style(os.Stdout, styleHeader, "Command aliases:"))
+	fmt.Println("  f  → format     t  → test       tc → typecheck")
+	fmt.Println("  r  → run        s  → serve      b  → build")
+	fmt.Println("  l  → lint")
+	fmt.Println(style(os.Stdout, styleDim, "  (cmdr --list --aliases shows what they run here)"))
+}
+
+// synthesizedSource names the section of commands cmd-runner provides itself
+const synthesizedSource = "cmd-runner"
+
+// listSection is the commands one source contributes to a listing, with
+// core commands before the rest
+type listSection struct {
+	dir              string
+	source           string
+	rootHeader       string // relative path of the project root, before its first section
+	core, additional []string
+	commands         map[string]CommandInfo
+
+	// With --all, shadowed is the commands an earlier source also defines,
+	// which wins, mapped to that source's label; alsoIn is the labels of
+	// the sources whose commands this section's shadow
+	shadowed map[string]string
+	alsoIn   map[string][]string
+}
+
+// label names the section's source for conflicts, with the directory when
+// it isn't the current one
+func (s listSection) label(currentDir string) string {
+	if s.dir == currentDir {
+		return s.source
+	}
+	relPath, err := filepath.Rel(currentDir, s.dir)
+	if err != nil {
+		relPath = s.dir
+	}
+	return s.source + " in " + relPath
+}
+
+// listSections returns the commands a listing shows, by source: those of the
+// primary source, or of every source in both directories with showAll, then
+// the synthesized commands the project doesn't already provide
+func (r *CommandRunner) listSections(showAll bool) []listSection {
 	// Core commands we always want to show if they exist
 	coreCommands := map[string]bool{
 		"build": true, "check": true, "clean": true, "dev": true,
@@ -125,40 +663,35 @@ func (r *CommandRunner) ListCommandsWithOptions(showAll bool, verbose bool) {
 		"test": true, "typecheck": true,
 	}
 
-	// Track what we've already shown to avoid duplicates
+	// Track what we've already shown to avoid duplicates, and in which
+	// section, to note the sources it shadows
 	shown := make(map[string]bool)
+	shownIn := make(map[string]int)
+	sections := []listSection{}
 
-	// Build projects for current dir and project root
-	projects := []*Project{}
-	projects = append(projects, ResolveProject(r.CurrentDir))
-
-	if r.ProjectRoot != r.CurrentDir && r.ProjectRoot != "" {
-		projects = append(projects, ResolveProject(r.ProjectRoot))
-	}
-
-	sourcesShown := 0
+	strict := r.strict()
 	hasExplicitTypecheck := r.hasListedCommand("typecheck", "tc")
 
 	// Show commands from each project
-	for i, project := range projects {
+	for i, project := range r.projects() {
+		rootHeader := ""
 		if i > 0 {
 			relPath, _ := filepath.Rel(r.CurrentDir, project.Dir)
 			if relPath == "." {
 				continue
 			}
 			// If showing all sources, or if current dir had no commands, show project root
-			if showAll || sourcesShown == 0 {
-				fmt.Printf("\nFrom project root (%s):\n", relPath)
-			} else {
+			if !showAll && len(sections) > 0 {
 				// Skip project root if we already showed commands from current dir
 				continue
 			}
+			rootHeader = relPath
 		}
 
 		// Show commands from each source
-		for sourceIdx, source := range project.CommandSources {
+		for _, source := range project.CommandSources {
 			// If not showing all, only show the first source with commands
-			if !showAll && sourcesShown > 0 {
+			if !showAll && len(sections) > 0 {
 				break
 			}
 
@@ -168,58 +701,39 @@ func (r *CommandRunner) ListCommandsWithOptions(showAll bool, verbose bool) {
 			}
 
 			// Separate core and additional commands
-			core := make(map[string]CommandInfo)
-			additional := make(map[string]CommandInfo)
-
+			section := listSection{dir: project.Dir, source: source.Name(), commands: make(map[string]CommandInfo), shadowed: make(map[string]string)}
 			for cmd, info := range commands {
-				if !shown[cmd] && !isPrivateCommand(cmd) {
-					if coreCommands[cmd] {
-						core[cmd] = info
-					} else {
-						additional[cmd] = info
-					}
+				if (strict && !definesCommand(source, cmd)) || isPrivateCommand(cmd) {
+					continue
 				}
-			}
-
-			// Only show source if it has commands
-			if len(core) > 0 || len(additional) > 0 {
-				fmt.Printf("\n%s commands:\n", source.Name())
-				sourcesShown++
-
-				// Show core commands first
-				if len(core) > 0 {
-					for _, cmd := range sortCommands(core) {
-						if !shown[cmd] {
-							r.printCommand(cmd, core[cmd], verbose)
-							shown[cmd] = true
-						}
+				if !shown[cmd] {
+					section.commands[cmd] = info
+				} else if showAll {
+					winner := &sections[shownIn[cmd]]
+					section.shadowed[cmd] = winner.label(r.CurrentDir)
+					if winner.alsoIn == nil {
+						winner.alsoIn = make(map[string][]string)
 					}
+					winner.alsoIn[cmd] = append(winner.alsoIn[cmd], section.label(r.CurrentDir))
 				}
-
-				// Show additional commands
-				if len(additional) > 0 {
-					if len(core) > 0 {
-						fmt.Println() // Add spacing between core and additional
-					}
-					for _, cmd := range sortCommands(additional) {
-						if !shown[cmd] {
-							r.printCommand(cmd, additional[cmd], verbose)
-							shown[cmd] = true
-						}
-					}
+			}
+			for _, cmd := range sortCommands(section.commands) {
+				shown[cmd] = true
+				shownIn[cmd] = len(sections)
+				if coreCommands[cmd] {
+					section.core = append(section.core, cmd)
+				} else {
+					section.additional = append(section.additional, cmd)
 				}
 			}
 
-			// If this is the primary source and we're not showing all, stop here
-			if !showAll && sourceIdx == 0 && sourcesShown > 0 {
-				break
+			// Only show source if it has commands
+			if len(section.commands) > 0 || len(section.shadowed) > 0 {
+				section.rootHeader = rootHeader
+				rootHeader = ""
+				sections = append(sections, section)
 			}
 		}
-
-		// If not showing all and we've shown a source, stop
-		if !showAll && sourcesShown > 0 {
-			break
-		}
 	}
 
 	// Show synthesized commands if they're not already provided
@@ -228,10 +742,16 @@ func (r *CommandRunner) ListCommandsWithOptions(showAll bool, verbose bool) {
 		"fix":       {Description: "Runs format and lint fix", Execution: "synthesized"},
 		"typecheck": {Description: "Runs type checking", Execution: "synthesized"},
 	}
+	if _, ecosystems := r.cleanProject(); len(ecosystems) > 0 {
+		synth["clean"] = CommandInfo{Description: "Removes build artifacts", Execution: "synthesized"}
+	}
+	if r.provisions() {
+		synth["setup"] = CommandInfo{Description: "Provisions toolchains and installs dependencies", Execution: "synthesized"}
+	}
 
-	synthToShow := make(map[string]CommandInfo)
+	synthToShow := listSection{dir: r.CurrentDir, source: synthesizedSource, commands: make(map[string]CommandInfo)}
 	for cmd, info := range synth {
-		if shown[cmd] {
+		if shown[cmd] || strict {
 			continue
 		}
 		// Show synthesized typecheck only when there's no explicit one AND project supports it
@@ -240,20 +760,13 @@ func (r *CommandRunner) ListCommandsWithOptions(showAll bool, verbose bool) {
 				continue
 			}
 		}
-		synthToShow[cmd] = info
+		synthToShow.commands[cmd] = info
 	}
-
-	if len(synthToShow) > 0 {
-		fmt.Println("\nSynthesized commands (provided by cmd-runner):")
-		for _, cmd := range sortCommands(synthToShow) {
-			r.printCommand(cmd, synthToShow[cmd], verbose)
-		}
+	synthToShow.additional = sortCommands(synthToShow.commands)
+	if len(synthToShow.commands) > 0 {
+		sections = append(sections, synthToShow)
 	}
-
-	fmt.Println("\nCommand aliases:")
-	fmt.Println("  f  → format     t  → test       tc → typecheck")
-	fmt.Println("  r  → run        s  → serve      b  → build")
-	fmt.Println("  l  → lint")
+	return sections
 }
 
 // getTerminalWidth returns the terminal width, defaulting to 80 if it can't be determined
@@ -265,17 +778,22 @@ func getTerminalWidth() int {
 	return width
 }
 
-// printCommand prints a command with optional verbose description
-func (r *CommandRunner) printCommand(cmd string, info CommandInfo, verbose bool) {
+// printCommand prints a command with optional verbose description, noting the
+// sources alsoIn whose command of the same name it shadows
+func (r *CommandRunner) printCommand(cmd string, info CommandInfo, verbose bool, alsoIn []string) {
+	note := ""
+	if len(alsoIn) > 0 {
+		note = "  " + style(os.Stdout, styleWarning, "(also in "+strings.Join(alsoIn, ", ")+")")
+	}
 	if verbose {
 		// Show both description and execution command
-		fmt.Printf("  %-12s → %s\n", cmd, info.Description)
-		fmt.Printf("  %-12s   (runs: %s)\n", "", info.Execution)
+		fmt.Printf("  %s → %s%s\n", style(os.Stdout, styleCommand, fmt.Sprintf("%-12s", cmd)), info.Description, note)
+		fmt.Printf("  %-12s   %s\n", "", style(os.Stdout, styleDim, "(runs: "+info.Execution+")"))
 	} else {
 		// Calculate available space for description
 		termWidth := getTerminalWidth()
 		// Account for: "  " (2) + command (12) + " → " (3) = 17 chars of overhead
-		availableWidth := termWidth - 17
+		availableWidth := termWidth - 17 - visibleWidth(note)
 		if availableWidth < 20 {
 			availableWidth = 20 // Minimum reasonable width
 		}
@@ -284,7 +802,7 @@ func (r *CommandRunner) printCommand(cmd string, info CommandInfo, verbose bool)
 		if len(desc) > availableWidth {
 			desc = desc[:availableWidth-3] + "..."
 		}
-		fmt.Printf("  %-12s → %s\n", cmd, desc)
+		fmt.Printf("  %s → %s%s\n", style(os.Stdout, styleCommand, fmt.Sprintf("%-12s", cmd)), desc, note)
 	}
 }
 
@@ -303,6 +821,9 @@ func sortCommands[T any](commands map[string]T) []string {
 
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
+	if debugEnabled() {
+		debugf("sniff %s: %s", path, map[bool]string{true: "found", false: "absent"}[err == nil])
+	}
 	return err == nil
 }
 
@@ -334,7 +855,15 @@ func GetCommandVariants(command string) []string {
 		"check":     {"check"},
 		"typecheck": {"typecheck", "type-check", "types", "tc"},
 		"tc":        {"tc", "typecheck", "type-check", "types"},
+		"outdated":  {"outdated", "deps"},
+		"deps":      {"deps", "outdated"},
+		"publish":   {"publish", "release"},
+		"release":   {"release", "publish"},
+		"update":    {"update", "upgrade"},
+		"upgrade":   {"upgrade", "update"},
+		"fmt-check": {"fmt-check", "format-check", "format:check", "fmt:check", "check-format", "check:format"},
 	}
+	variants["format-check"] = variants["fmt-check"]
 
 	if v, ok := variants[command]; ok {
 		return v
@@ -344,28 +873,35 @@ func GetCommandVariants(command string) []string {
 
 func NormalizeCommand(cmd string) string {
 	aliases := map[string][]string{
-		"format":    {"format", "fmt"},
-		"fmt":       {"format", "fmt"},
-		"f":         {"format"}, // Short alias for format
-		"run":       {"run", "dev", "serve", "start"},
-		"r":         {"run"}, // Short alias for run
-		"dev":       {"dev", "run", "serve", "start"},
-		"serve":     {"serve", "dev", "run", "start"},
-		"s":         {"serve"}, // Short alias for serve/server
-		"start":     {"start", "run", "dev", "serve"},
-		"build":     {"build"},
-		"b":         {"build"}, // Short alias for build
-		"lint":      {"lint"},
-		"l":         {"lint"}, // Short alias for lint
-		"test":      {"test"},
-		"t":         {"test"}, // Short alias for test
-		"fix":       {"fix"},
-		"clean":     {"clean"},
-		"install":   {"install"},
-		"setup":     {"setup"},
-		"check":     {"check"},
-		"typecheck": {"typecheck"},
-		"tc":        {"typecheck"}, // Short alias for typecheck
+		"format":       {"format", "fmt"},
+		"fmt":          {"format", "fmt"},
+		"f":            {"format"}, // Short alias for format
+		"run":          {"run", "dev", "serve", "start"},
+		"r":            {"run"}, // Short alias for run
+		"dev":          {"dev", "run", "serve", "start"},
+		"serve":        {"serve", "dev", "run", "start"},
+		"s":            {"serve"}, // Short alias for serve/server
+		"start":        {"start", "run", "dev", "serve"},
+		"build":        {"build"},
+		"b":            {"build"}, // Short alias for build
+		"lint":         {"lint"},
+		"l":            {"lint"}, // Short alias for lint
+		"test":         {"test"},
+		"t":            {"test"}, // Short alias for test
+		"fix":          {"fix"},
+		"clean":        {"clean"},
+		"install":      {"install"},
+		"setup":        {"setup"},
+		"check":        {"check"},
+		"typecheck":    {"typecheck"},
+		"tc":           {"typecheck"}, // Short alias for typecheck
+		"outdated":     {"outdated"},
+		"deps":         {"outdated"},
+		"publish":      {"publish"},
+		"release":      {"publish"},
+		"update":       {"update"},
+		"upgrade":      {"update"},
+		"format-check": {"fmt-check"},
 	}
 
 	if alternatives, ok := aliases[cmd]; ok {
@@ -214,3 +214,52 @@ func slicesEqual(a, b []string) bool {
 	}
 	return true
 }
+
+func TestProjectsResolvedOnce(t *testing.T) {
+	dir := t.TempDir()
+	r := New("test", nil, WithDir(dir))
+	project := r.resolveProject(dir)
+	if r.subRunner("lint", nil).resolveProject(dir) != project {
+		t.Error("a sub-runner resolved the project again")
+	}
+	invalidateDir(dir)
+	if r.resolveProject(dir) == project {
+		t.Error("the project wasn't resolved again after its files changed")
+	}
+}
+
+func TestListSectionsConflicts(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "Makefile"), []byte("test:\n\techo make\nlint:\n\techo lint\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, sub, "[commands]\ntest = \"echo cfg\"\n")
+	runner := &CommandRunner{CurrentDir: sub, ProjectRoot: root, NoSynth: true}
+
+	sections := runner.listSections(true)
+	if len(sections) != 2 {
+		t.Fatalf("listSections() = %+v, want .cmdr.toml and make", sections)
+	}
+	config, makefile := sections[0], sections[1]
+	if want := []string{"make in .."}; !slicesEqual(config.alsoIn["test"], want) {
+		t.Errorf("alsoIn[test] = %q, want %q", config.alsoIn["test"], want)
+	}
+	if makefile.shadowed["test"] != ".cmdr.toml" || len(makefile.shadowed) != 1 {
+		t.Errorf("shadowed = %v, want test shadowed by .cmdr.toml", makefile.shadowed)
+	}
+	if _, ok := makefile.commands["test"]; ok {
+		t.Error("a shadowed command shouldn't be listed as the source's own")
+	}
+
+	// Without --all only the winning source is listed, with no conflicts
+	if sections := runner.listSections(false); len(sections) != 1 || len(sections[0].alsoIn) != 0 {
+		t.Errorf("listSections(false) = %+v", sections)
+	}
+}
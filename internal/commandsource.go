@@ -2,11 +2,14 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 // CommandInfo holds information about a command
@@ -19,30 +22,57 @@ type CommandInfo struct {
 // Key format: "sourceName:directory"
 var commandListCache = struct {
 	sync.RWMutex
-	data map[string]map[string]CommandInfo
-}{data: make(map[string]map[string]CommandInfo)}
+	data  map[string]map[string]CommandInfo
+	byDir map[string]map[string]bool // the keys listed from each directory
+}{data: make(map[string]map[string]CommandInfo), byDir: make(map[string]map[string]bool)}
 
 // getCachedCommands retrieves cached commands or executes the list function
-func getCachedCommands(cacheKey string, listFunc func() map[string]CommandInfo) map[string]CommandInfo {
+// for a source in dir
+func getCachedCommands(cacheKey, dir string, listFunc func() map[string]CommandInfo) map[string]CommandInfo {
 	// Try to read from cache first
 	commandListCache.RLock()
 	if cached, exists := commandListCache.data[cacheKey]; exists {
 		commandListCache.RUnlock()
+		debugf("list %s: %d commands, cached", cacheKey, len(cached))
 		return cached
 	}
 	commandListCache.RUnlock()
 
 	// Cache miss - execute the list function
+	start := time.Now()
 	commands := listFunc()
+	debugf("list %s: %d commands, %s", cacheKey, len(commands), time.Since(start).Round(time.Microsecond))
 
 	// Store in cache
 	commandListCache.Lock()
 	commandListCache.data[cacheKey] = commands
+	if commandListCache.byDir[dir] == nil {
+		commandListCache.byDir[dir] = make(map[string]bool)
+	}
+	commandListCache.byDir[dir][cacheKey] = true
 	commandListCache.Unlock()
 
 	return commands
 }
 
+// forgetCachedCommands drops a cached listing, so that the next one lists
+// the source again
+func forgetCachedCommands(cacheKey string) {
+	commandListCache.Lock()
+	delete(commandListCache.data, cacheKey)
+	commandListCache.Unlock()
+}
+
+// forgetDirCommands drops the cached listings of every source in dir
+func forgetDirCommands(dir string) {
+	commandListCache.Lock()
+	for key := range commandListCache.byDir[dir] {
+		delete(commandListCache.data, key)
+	}
+	delete(commandListCache.byDir, dir)
+	commandListCache.Unlock()
+}
+
 // CommandSource represents a source of commands (mise, just, make, package.json, etc.)
 type CommandSource interface {
 	// Name returns the display name for this source (e.g., "mise", "npm", "Poetry")
@@ -61,16 +91,52 @@ type CommandSource interface {
 	Priority() int
 }
 
+// defaultingSource is implemented by sources that also provide commands the
+// project doesn't define, such as `go test ./...` for any Go module. Sources
+// that don't implement it only run the project's own scripts and tasks.
+type defaultingSource interface {
+	// DefinesCommand reports whether the project defines command (or a
+	// variant) as a script, task, or entry point
+	DefinesCommand(command string) bool
+}
+
+// definesCommand reports whether source finds command among the project's
+// own definitions rather than the tool's defaults
+func definesCommand(source CommandSource, command string) bool {
+	if defaulting, ok := source.(defaultingSource); ok {
+		return defaulting.DefinesCommand(command)
+	}
+	return true
+}
+
 // Project represents a directory with multiple command sources
 type Project struct {
 	Dir            string
 	CommandSources []CommandSource
 }
 
+// executorSource is implemented by sources that start processes, so that
+// a runner's executor reaches them
+type executorSource interface {
+	setExecutor(x *Executor)
+}
+
 // ResolveProject analyzes a directory and returns a Project with all applicable CommandSources
 func ResolveProject(dir string) *Project {
+	return resolveProject(dir, nil)
+}
+
+// resolveProject resolves the project in dir with sources that start
+// processes through x
+func resolveProject(dir string, x *Executor) *Project {
+	defer recordPhase(time.Now(), "detect sources in %s", dir)
 	sources := []CommandSource{}
 
+	// Custom commands from the project config take precedence over everything
+	if source := NewConfigSource(dir); source != nil {
+		sources = append(sources, source)
+	}
+
 	// Check for command runners (highest priority)
 	if FileExists(filepath.Join(dir, ".mise.toml")) {
 		if source := NewMiseSource(dir); source != nil {
@@ -101,12 +167,21 @@ func ResolveProject(dir string) *Project {
 		if source := detectPythonProject(dir); source != nil {
 			sources = append(sources, source)
 		}
+		if source := NewPoeSource(dir); source != nil {
+			sources = append(sources, source)
+		}
 	}
 
 	if FileExists(filepath.Join(dir, "Cargo.toml")) {
 		if source := NewCargoSource(dir); source != nil {
 			sources = append(sources, source)
 		}
+		if source := NewCargoMakeSource(dir); source != nil {
+			sources = append(sources, source)
+		}
+		if source := NewXtaskSource(dir); source != nil {
+			sources = append(sources, source)
+		}
 	}
 
 	if FileExists(filepath.Join(dir, "go.mod")) {
@@ -128,8 +203,28 @@ func ResolveProject(dir string) *Project {
 		}
 	}
 
-	// Sort sources by priority (lower number = higher priority)
+	// Commands from the user config are available everywhere
+	if source := NewUserCommandSource(dir); source != nil {
+		sources = append(sources, source)
+	}
+
+	// Sort sources by priority (lower number = higher priority), then apply
+	// the user's preferred source order
 	sortSourcesByPriority(sources)
+	applySourceOrder(sources)
+	sources = removeDisabledSources(dir, sources)
+	for _, source := range sources {
+		if s, ok := source.(executorSource); ok {
+			s.setExecutor(x)
+		}
+	}
+	if debugEnabled() {
+		names := make([]string, len(sources))
+		for i, source := range sources {
+			names[i] = source.Name()
+		}
+		debugf("project %s: sources %s", dir, strings.Join(names, ", "))
+	}
 
 	return &Project{
 		Dir:            dir,
@@ -137,6 +232,36 @@ func ResolveProject(dir string) *Project {
 	}
 }
 
+// disabledSources are the source names excluded with --no-source
+var disabledSources []string
+
+// DisableSources excludes sources by name (as shown in --list --all) from
+// resolution and listing, in addition to those disabled in config
+func DisableSources(names ...string) {
+	disabledSources = append(disabledSources, names...)
+}
+
+// removeDisabledSources drops the sources disabled on the command line or by
+// the config in dir
+func removeDisabledSources(dir string, sources []CommandSource) []CommandSource {
+	disabled := disabledSources
+	if config := loadProjectConfig(dir); config != nil {
+		disabled = append(slices.Clip(disabled), config.DisabledSources...)
+	}
+	if len(disabled) == 0 {
+		return sources
+	}
+	return slices.DeleteFunc(sources, func(source CommandSource) bool {
+		if slices.ContainsFunc(disabled, func(name string) bool {
+			return strings.EqualFold(name, source.Name())
+		}) {
+			debugf("project %s: source %s disabled", dir, source.Name())
+			return true
+		}
+		return false
+	})
+}
+
 // sortSourcesByPriority sorts CommandSources in-place by their Priority() value
 func sortSourcesByPriority(sources []CommandSource) {
 	// Simple insertion sort (list is small, typically < 10 elements)
@@ -158,8 +283,25 @@ func detectNodeProject(dir string) CommandSource {
 		return NewDenoSource(dir)
 	}
 
+	// Prefer a pin in .cmdr.toml, then the package manager pinned in
+	// package.json, over lockfile heuristics
+	name := pinnedManager(dir, "node")
+	if name == "" {
+		name, _ = pinnedPackageManager(dir)
+	}
+	switch name {
+	case "bun":
+		return NewBunSource(dir)
+	case "pnpm":
+		return NewPnpmSource(dir)
+	case "yarn":
+		return NewYarnSource(dir)
+	case "npm":
+		return NewNpmSource(dir)
+	}
+
 	// Check lockfiles to determine package manager
-	if FileExists(filepath.Join(dir, "bun.lockb")) {
+	if FileExists(filepath.Join(dir, "bun.lockb")) || FileExists(filepath.Join(dir, "bun.lock")) {
 		return NewBunSource(dir)
 	}
 
@@ -188,6 +330,13 @@ func detectNodeProject(dir string) CommandSource {
 func detectPythonProject(dir string) CommandSource {
 	pyprojectPath := filepath.Join(dir, "pyproject.toml")
 
+	switch pinnedManager(dir, "python") {
+	case "poetry":
+		return NewPoetrySource(dir)
+	case "uv":
+		return NewUvSource(dir)
+	}
+
 	// Check for Poetry
 	if FileExists(filepath.Join(dir, "poetry.lock")) {
 		return NewPoetrySource(dir)
@@ -220,6 +369,7 @@ type baseSource struct {
 	dir      string
 	name     string
 	priority int
+	executor *Executor
 }
 
 func (b *baseSource) Name() string {
@@ -232,9 +382,83 @@ func (b *baseSource) Priority() int {
 
 // cacheKey returns the cache key for this source
 func (b *baseSource) cacheKey() string {
+	if b.executor != nil {
+		// Listings made through a custom executor aren't shared
+		return fmt.Sprintf("%s:%s:%p", b.name, b.dir, b.executor)
+	}
 	return b.name + ":" + b.dir
 }
 
+// cachedCommands returns the source's listing from the cache, from a
+// running daemon, from the disk cache, or by calling listFunc
+func (b *baseSource) cachedCommands(listFunc func() map[string]CommandInfo) map[string]CommandInfo {
+	return getCachedCommands(b.cacheKey(), b.dir, func() map[string]CommandInfo {
+		start := time.Now()
+		// A source with its own executor lists through it, and a static
+		// listing differs from the one the tool gives
+		if b.executor != nil || b.static() {
+			defer recordPhase(start, "list %s in %s", b.name, b.dir)
+			return listFunc()
+		}
+		if commands, ok := daemonCommands(b.name, b.dir); ok {
+			recordPhase(start, "list %s in %s, from the daemon", b.name, b.dir)
+			return commands
+		}
+		key := b.cacheKey()
+		fingerprint := listingFingerprint(b.dir)
+		if commands, ok := readDiskCache(key, fingerprint); ok {
+			debugf("list %s: %d commands, from the disk cache", key, len(commands))
+			recordPhase(start, "list %s in %s, from the disk cache", b.name, b.dir)
+			return commands
+		}
+		defer recordPhase(start, "list %s in %s", b.name, b.dir)
+		commands := listFunc()
+		// An empty listing may mean the tool is missing or failed, which
+		// installing or fixing it changes without changing the files
+		if len(commands) > 0 {
+			writeDiskCache(key, fingerprint, commands)
+		}
+		return commands
+	})
+}
+
+// static reports whether the source must read its files instead of asking
+// its tool for its tasks, as in analyze-only mode
+func (b *baseSource) static() bool {
+	return analyzeOnly || (b.executor != nil && b.executor.static)
+}
+
+// sourceDir returns the directory the source was found in
+func (b *baseSource) sourceDir() string {
+	return b.dir
+}
+
+func (b *baseSource) setExecutor(x *Executor) {
+	b.executor = x
+}
+
+// hasTool reports whether a program is on PATH, as the source's executor
+// finds it
+func (b *baseSource) hasTool(name string) bool {
+	_, err := b.executor.lookPath(name)
+	return err == nil
+}
+
+// command creates a process through the source's executor
+func (b *baseSource) command(name string, arg ...string) *exec.Cmd {
+	return b.executor.command(name, arg...)
+}
+
+// findVariant returns the first variant of command that commands has
+func findVariant(command string, commands map[string]CommandInfo) (string, bool) {
+	for _, variant := range GetCommandVariants(command) {
+		if _, ok := commands[variant]; ok {
+			return variant, true
+		}
+	}
+	return "", false
+}
+
 // Helper functions to find specific CommandSource types from a list
 func findSourceByName(sources []CommandSource, name string) CommandSource {
 	for _, source := range sources {
@@ -245,21 +469,67 @@ func findSourceByName(sources []CommandSource, name string) CommandSource {
 	return nil
 }
 
-// Helper function to parse package.json scripts
-func parsePackageJsonScripts(dir string) (map[string]string, error) {
-	packageJSON := filepath.Join(dir, "package.json")
-	data, err := os.ReadFile(packageJSON)
+// packageJSON holds the parts of package.json that cmd-runner cares about
+type packageJSON struct {
+	Scripts        map[string]string `json:"scripts"`
+	PackageManager string            `json:"packageManager"`
+	Private        bool              `json:"private"`
+
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+	Prettier        json.RawMessage   `json:"prettier"`
+
+	// Script description conventions: npm-scripts-info, ntl, and
+	// scripts-description
+	ScriptsInfo map[string]string `json:"scripts-info"`
+	Ntl         struct {
+		Descriptions map[string]string `json:"descriptions"`
+	} `json:"ntl"`
+	ScriptsDescription map[string]string `json:"scripts-description"`
+}
+
+// readPackageJSON reads and decodes package.json in dir
+func readPackageJSON(dir string) (*packageJSON, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
 	if err != nil {
 		return nil, err
 	}
 
-	var pkg struct {
-		Scripts map[string]string `json:"scripts"`
-	}
-
+	var pkg packageJSON
 	if err := json.Unmarshal(data, &pkg); err != nil {
 		return nil, err
 	}
+	return &pkg, nil
+}
 
+// scriptDescriptions returns human-written script descriptions, from
+// whichever description conventions the package uses
+func (p *packageJSON) scriptDescriptions() map[string]string {
+	descriptions := make(map[string]string)
+	for _, source := range []map[string]string{p.ScriptsDescription, p.Ntl.Descriptions, p.ScriptsInfo} {
+		for name, description := range source {
+			if description != "" {
+				descriptions[name] = description
+			}
+		}
+	}
+	return descriptions
+}
+
+// Helper function to parse package.json scripts
+func parsePackageJsonScripts(dir string) (map[string]string, error) {
+	pkg, err := readPackageJSON(dir)
+	if err != nil {
+		return nil, err
+	}
 	return pkg.Scripts, nil
 }
+
+// scriptDescription returns the description for a package.json script,
+// falling back to the script body
+func scriptDescription(pkg *packageJSON, script string) string {
+	if description, ok := pkg.scriptDescriptions()[script]; ok {
+		return description
+	}
+	return pkg.Scripts[script]
+}
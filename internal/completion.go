@@ -0,0 +1,425 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CompletionShells are the shells CompletionScript supports
+var CompletionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// completionCommands are cmdr's own subcommands
+var completionCommands = []struct{ name, description string }{
+	{"help", "Show a command's definition and details"},
+	{"which", "Show what a command resolves to"},
+	{"explain", "Show each resolution step"},
+	{"env", "Show environment variables passed to commands"},
+	{"audit-log", "Show or check the audit log"},
+	{"history", "Show recent runs"},
+	{"last", "Run this project's most recent command again"},
+	{"rerun", "Run this project's most recent command again"},
+	{"doctor", "Check that the project's build tools are installed"},
+	{"upgrade-tools", "Update the project's toolchains"},
+	{"self-update", "Install the latest release of cmdr"},
+	{"init", "Write a starter .cmdr.toml"},
+	{"sources", "Show the detected command sources and why"},
+	{"pm", "Show or pin the package manager"},
+	{"projects", "List projects registered for --project"},
+	{"stop", "Stop running process groups"},
+	{"daemon", "Keep source listings warm for faster runs"},
+	{"cache", "Show or clear the cache of source listings"},
+	{"export", "Write a process group as a Procfile or Compose file"},
+	{"watch", "Run the commands in [watch] rules on file changes"},
+	{"completion", "Write a shell completion script"},
+	{"install-alias", "Install 'cr' alias to shell config"},
+	{"uninstall-alias", "Remove the alias install-alias added"},
+}
+
+// completionFlags are the options that come before the command
+var completionFlags = []struct{ name, description string }{
+	{"--interactive", "Choose a command interactively"},
+	{"--pick", "Pick a command from a fuzzy-searchable list"},
+	{"--list", "List available commands"},
+	{"--all", "With --list, show commands from all sources"},
+	{"--verbose", "With --list, show full descriptions"},
+	{"--format", "With --list, print for scripts"},
+	{"--json", "With --list, print commands, synthesis, and aliases as JSON"},
+	{"--aliases", "With --list, show what each alias runs in this project"},
+	{"--project", "Run in a registered project"},
+	{"--env", "Set an environment variable"},
+	{"--env-file", "Load variables from a dotenv file"},
+	{"--retry", "Retry on transient network errors"},
+	{"--jobs", "Run at most N commands at once"},
+	{"--no-source", "Ignore a command source"},
+	{"--analyze-only", "Parse files only, never run anything"},
+	{"--client", "Require a running daemon for source listings"},
+	{"--recursive", "Run in every project under the repo root"},
+	{"--filter", "With --recursive, only projects matching a glob or name"},
+	{"--exclude", "With --recursive, skip projects matching a glob or name"},
+	{"--no-synth", "Run only commands the project defines"},
+	{"--parallel", "Run check steps or recursive projects at once"},
+	{"--fail-fast", "Stop check at the first failing step"},
+	{"--keep-going", "Run every step of check"},
+	{"--watch", "Re-run commands when files change"},
+	{"--dashboard", "Run a process group under a dashboard"},
+	{"--debug", "Log how the command resolves"},
+	{"--debug-file", "Append the debug log to a file"},
+	{"--time", "Print how long the command took"},
+	{"--timings", "Print how long each phase took"},
+	{"--log-file", "Also write the command's output to a file"},
+	{"--junit", "Write a JUnit XML report of the run"},
+	{"--sarif", "Write a SARIF log of the diagnostics in the output"},
+	{"--quiet", "Don't print the Running: banner"},
+	{"--color", "Color output: auto, always, or never"},
+	{"--pty", "Always run under a pseudo-terminal"},
+	{"--no-pty", "Never run under a pseudo-terminal"},
+	{"--yes", "Run update or publish without asking"},
+	{"--sudo", "Run the command through sudo"},
+	{"--as-user", "Run the command as another user"},
+	{"--in-container", "Run the command in the project's container"},
+	{"--version", "Show version information"},
+	{"--help", "Show help"},
+}
+
+// valueFlags are the options that take the following word as their value
+var valueFlags = map[string]bool{
+	"-e": true, "--env": true, "--env-file": true, "--retry": true,
+	"-p": true, "--project": true, "--no-source": true, "--as-user": true,
+	"-j": true, "--jobs": true, "--format": true, "--debug-file": true,
+	"--filter": true, "--exclude": true, "--log-file": true, "--junit": true, "--sarif": true,
+	"--color": true,
+}
+
+// completion is a candidate for the word being completed
+type completion struct{ name, description string }
+
+// argumentCompleter is implemented by sources that know what the arguments
+// of their commands can be
+type argumentCompleter interface {
+	// completeArguments returns the candidates for the next argument of
+	// command, after args
+	completeArguments(command string, args []string) []completion
+}
+
+// Complete writes the candidates for the last of words, the arguments after
+// `cmdr` on a command line being completed, one per line with a tab before
+// the description. It writes nothing when the shell should complete file
+// names instead, as for a project command's arguments.
+func (r *CommandRunner) Complete(w io.Writer, words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	partial := words[len(words)-1]
+	before := words[:len(words)-1]
+
+	// Skip the options, noting the ones that change what to complete
+	command, args := "", []string{}
+	watch := false
+	for i := 0; i < len(before); i++ {
+		word := before[i]
+		switch {
+		case command != "":
+			args = append(args, word)
+		case word == "-w" || word == "--watch":
+			watch = true
+		case word == "-p" || word == "--project":
+			if i+1 < len(before) {
+				i++
+				r.useProject(before[i])
+			}
+		case strings.HasPrefix(word, "--project="):
+			r.useProject(strings.TrimPrefix(word, "--project="))
+		case valueFlags[word]:
+			i++
+		case strings.HasPrefix(word, "-"):
+		default:
+			command = word
+		}
+	}
+
+	var candidates []completion
+	previous := ""
+	if len(before) > 0 {
+		previous = before[len(before)-1]
+	}
+	switch {
+	case command == "" && valueFlags[previous]:
+		candidates = r.flagValueCompletions(previous)
+	case command == "" && strings.HasPrefix(partial, "-"):
+		for _, flag := range completionFlags {
+			candidates = append(candidates, completion{flag.name, flag.description})
+		}
+	case command == "" || watch:
+		candidates = r.commandCompletions(!watch)
+	default:
+		candidates = r.argumentCompletions(command, args)
+	}
+
+	for _, c := range candidates {
+		if !strings.HasPrefix(c.name, partial) {
+			continue
+		}
+		if c.description == "" {
+			fmt.Fprintln(w, c.name)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", c.name, strings.ReplaceAll(c.description, "\n", " "))
+		}
+	}
+}
+
+// useProject completes in a registered project, as --project runs in one
+func (r *CommandRunner) useProject(name string) {
+	if dir, err := ProjectDir(name); err == nil {
+		r.CurrentDir = dir
+		r.ProjectRoot = r.FindProjectRoot(dir)
+	}
+}
+
+// commandCompletions returns the project's commands and user aliases, and
+// with subcommands, cmdr's own
+func (r *CommandRunner) commandCompletions(subcommands bool) []completion {
+	candidates := []completion{}
+	seen := make(map[string]bool)
+	add := func(name, description string) {
+		if !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, completion{name, description})
+		}
+	}
+	for _, c := range r.listedCommands(true) {
+		add(c.Name, c.Description)
+	}
+	if config := LoadUserConfig(); config != nil {
+		for _, name := range sortCommands(config.Aliases) {
+			add(name, "alias for "+config.Aliases[name])
+		}
+	}
+	if subcommands {
+		for _, c := range completionCommands {
+			add(c.name, c.description)
+		}
+	}
+	return candidates
+}
+
+// argumentCompletions returns the candidates for an argument of one of
+// cmdr's subcommands, or of a project command whose source knows its
+// arguments; other commands get none, so their arguments complete as files
+func (r *CommandRunner) argumentCompletions(command string, args []string) []completion {
+	names := func(names ...string) []completion {
+		candidates := make([]completion, len(names))
+		for i, name := range names {
+			candidates[i] = completion{name: name}
+		}
+		return candidates
+	}
+	switch command {
+	case "help", "which", "explain":
+		// The command comes after which's options
+		operands := 0
+		for i := 0; i < len(args); i++ {
+			switch {
+			case args[i] == "--format" && command != "help":
+				if i == len(args)-1 {
+					return names("text", "shell", "json", "argv")
+				}
+				i++
+			case args[i] == "--explain" && command != "help":
+			default:
+				operands++
+			}
+		}
+		if operands == 0 {
+			return r.commandCompletions(false)
+		}
+	case "completion":
+		if len(args) == 0 {
+			return names(CompletionShells...)
+		}
+	case "audit-log":
+		if len(args) == 0 {
+			return names("tail", "verify", "path")
+		}
+	case "export":
+		if len(args) == 0 {
+			return names("procfile", "compose")
+		}
+		if len(args) == 1 {
+			return names(r.groupNames()...)
+		}
+	case "stop":
+		return names(r.groupNames()...)
+	case "daemon":
+		if len(args) == 0 {
+			return names("status", "stop")
+		}
+	case "cache":
+		if len(args) == 0 {
+			return names("path", "clear")
+		}
+	case "env":
+		return names("--all")
+	case "init":
+		return names("--force", "--dry-run")
+	case "self-update":
+		return names("--check")
+	case "upgrade-tools":
+		return names("--dry-run")
+	case "install-alias", "uninstall-alias":
+		return names("--name", "--dry-run")
+	case "history":
+		return names("--failed", "--here", "--all", "-n")
+	case "pm":
+		switch {
+		case len(args) == 0:
+			return names("set", "unset")
+		case len(args) == 1 && args[0] == "set":
+			return names(append(slices.Clone(packageManagers["node"]), packageManagers["python"]...)...)
+		case len(args) == 1 && args[0] == "unset":
+			return names("node", "python")
+		}
+	default:
+		if _, source := r.lookupCommand(command); source != nil {
+			if completer, ok := source.(argumentCompleter); ok {
+				return completer.completeArguments(command, args)
+			}
+		}
+	}
+	return nil
+}
+
+// flagValueCompletions returns the candidates for the value of an option
+func (r *CommandRunner) flagValueCompletions(flag string) []completion {
+	candidates := []completion{}
+	switch flag {
+	case "-p", "--project":
+		projects := RegisteredProjects()
+		for _, name := range sortCommands(projects) {
+			candidates = append(candidates, completion{name, projects[name]})
+		}
+	case "--no-source":
+		seen := make(map[string]bool)
+		for _, project := range r.projects() {
+			for _, source := range project.CommandSources {
+				if !seen[source.Name()] {
+					seen[source.Name()] = true
+					candidates = append(candidates, completion{name: source.Name()})
+				}
+			}
+		}
+	case "--format":
+		for _, format := range ListFormats {
+			candidates = append(candidates, completion{name: format})
+		}
+	case "--color":
+		for _, mode := range []string{"auto", "always", "never"} {
+			candidates = append(candidates, completion{name: mode})
+		}
+	case "--filter", "--exclude":
+		dirs, _ := FindProjects(r.ProjectRoot)
+		for _, dir := range dirs {
+			if rel := r.relativeProject(dir); rel != "." {
+				candidates = append(candidates, completion{rel, projectName(dir)})
+			}
+		}
+	}
+	return candidates
+}
+
+// CompletionScript writes the completion script for shell. The scripts ask
+// `cmdr __complete` for candidates, so completions follow the project in the
+// current directory.
+func CompletionScript(w io.Writer, shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unknown shell %q (expected %s)", shell, strings.Join(CompletionShells, ", "))
+	}
+	_, err := io.WriteString(w, strings.TrimLeft(script, "\n"))
+	return err
+}
+
+var completionScripts = map[string]string{
+	"bash": `
+# bash completion for cmdr; load with: source <(cmdr completion bash)
+_cmdr_complete() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n : cur words cword
+    else
+        cur=${COMP_WORDS[COMP_CWORD]} words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(cmdr __complete "${words[@]:1:cword}" 2>/dev/null | cut -f1)" -- "$cur"))
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -o default -F _cmdr_complete cmdr cr
+`,
+	"zsh": `
+#compdef cmdr cr
+# zsh completion for cmdr; load with: source <(cmdr completion zsh)
+_cmdr() {
+    local -a lines candidates
+    local line name desc
+    lines=("${(@f)$(cmdr __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for line in $lines; do
+        [[ -n $line ]] || continue
+        name=${line%%$'\t'*}
+        desc=
+        [[ $line == *$'\t'* ]] && desc=${line#*$'\t'}
+        candidates+=("${name//:/\\:}${desc:+:$desc}")
+    done
+    if (( ${#candidates} )); then
+        _describe 'cmdr' candidates
+    else
+        _files
+    fi
+}
+if [[ $funcstack[1] == _cmdr ]]; then
+    _cmdr "$@"
+else
+    compdef _cmdr cmdr cr
+fi
+`,
+	"fish": `
+# fish completion for cmdr; load with: cmdr completion fish | source
+function __cmdr_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    set -l candidates (cmdr __complete $tokens "$current" 2>/dev/null)
+    if test (count $candidates) -gt 0
+        printf '%s\n' $candidates
+    else
+        __fish_complete_path "$current"
+    end
+end
+complete -c cmdr -f -a '(__cmdr_complete)'
+complete -c cr -f -a '(__cmdr_complete)'
+`,
+	"powershell": `
+# PowerShell completion for cmdr; load with:
+#   cmdr completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName cmdr, cr -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+        Where-Object { $_.Extent.EndOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    # Before 7.3, and in Legacy mode, PowerShell drops empty arguments to
+    # native commands, so the empty word is passed quoted
+    if ($wordToComplete -eq '' -and ($PSVersionTable.PSVersion -lt [version]'7.3' -or $PSNativeCommandArgumentPassing -eq 'Legacy')) {
+        $words += '""'
+    } else {
+        $words += $wordToComplete
+    }
+    cmdr __complete @words 2>$null | ForEach-Object {
+        $name, $description = $_ -split "` + "`" + `t", 2
+        if (-not $description) { $description = $name }
+        [System.Management.Automation.CompletionResult]::new($name, $name, 'ParameterValue', $description)
+    }
+}
+`,
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("# Run the tests\ntest:\n\techo\ntest-e2e:\n\techo\nlint:\n\techo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, "[commands]\nhi = \"echo hi\"\n\n[groups.dev.processes.web]\nrun = \"npm start\"\n")
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"te"}, []string{"test", "test-e2e"}},
+		{[]string{"--no-synth", "li"}, []string{"lint"}},
+		{[]string{"--wat"}, []string{"--watch"}},
+		{[]string{"--list", "--format", "ts"}, []string{"tsv"}},
+		{[]string{"--watch", "lint", "te"}, []string{"test", "test-e2e"}},
+		{[]string{"which", "--format", "json", "li"}, []string{"lint"}},
+		{[]string{"completion", "z"}, []string{"zsh"}},
+		{[]string{"completion", "p"}, []string{"powershell"}},
+		{[]string{"stop", ""}, []string{"dev"}},
+		{[]string{"exp"}, []string{"explain", "export"}},
+		{[]string{"test", ""}, []string{"lint", "test-e2e"}}, // make runs each target
+		{[]string{"test", "lint", ""}, []string{"test-e2e"}},
+		{[]string{"hi", ""}, nil}, // the shell completes file names
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+		runner.Complete(&out, tt.words)
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if name, _, _ := strings.Cut(line, "\t"); name != "" {
+				got = append(got, name)
+			}
+		}
+		if !slicesEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	for _, shell := range CompletionShells {
+		var out strings.Builder
+		if err := CompletionScript(&out, shell); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "cmdr __complete") {
+			t.Errorf("%s script doesn't call cmdr __complete", shell)
+		}
+	}
+	if err := CompletionScript(&strings.Builder{}, "tcsh"); err == nil {
+		t.Error("CompletionScript(tcsh) should fail")
+	}
+}
+
+func TestCompleteCargoArguments(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	manifest := "[package]\nname = \"app\"\n\n[[bin]]\nname = \"server\"\npath = \"src/server.rs\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"run", "--"}, []string{"--bin", "--release"}},
+		{[]string{"b", "--r"}, []string{"--release"}},
+		{[]string{"run", "--bin", "s"}, []string{"server"}},
+		{[]string{"run", "--", ""}, nil},
+		{[]string{"clean", ""}, nil},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+		runner.Complete(&out, tt.words)
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if name, _, _ := strings.Cut(line, "\t"); name != "" {
+				got = append(got, name)
+			}
+		}
+		if !slicesEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
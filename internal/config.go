@@ -0,0 +1,435 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectConfigFile is the name of the per-project configuration file
+const ProjectConfigFile = ".cmdr.toml"
+
+// ProjectConfig is the contents of a .cmdr.toml file
+type ProjectConfig struct {
+	Path     string
+	Commands map[string]CommandConfig
+	// Watch maps file globs to the commands `cmdr watch` runs when a
+	// matching file changes
+	Watch map[string][]string
+	// Groups are named sets of processes run together by `cmdr <group>`
+	Groups map[string]GroupConfig
+	// Env is set for every command. Values may reference the inherited
+	// environment as $VAR or ${VAR}.
+	Env map[string]string
+	// EnvFiles are dotenv files, relative to the config file, loaded before Env
+	EnvFiles []string
+	// Dotenv overrides the dotenv files loaded automatically if present;
+	// nil means the defaults and an empty list disables loading
+	Dotenv *[]string
+	// Pre and Post map command names to shell commands run before and
+	// after them
+	Pre  map[string]string
+	Post map[string]string
+	// DisabledSources are source names, as shown in --list --all, that are
+	// ignored in this directory
+	DisabledSources []string
+	// Synthesize is false to run only commands the project defines
+	Synthesize *bool
+	// Theme is the badge that marks this project's output
+	Theme *ProjectTheme
+	// SourceJobs limits how many commands from a source run at once
+	SourceJobs map[string]int
+	// AugmentPath is false to leave the project's tool directories off PATH
+	AugmentPath *bool
+	// Direnv is false to skip loading the environment of an allowed .envrc
+	Direnv *bool
+	// PackageManager pins the Node and Python package managers
+	PackageManager *PackageManagerPins
+	// FailFast is true for synthesized check to stop at the first failing
+	// step
+	FailFast *bool
+	// Publish adjusts the checks before a tool's publish command
+	Publish *PublishConfig
+	// Container is the image --in-container runs commands in
+	Container *ContainerConfig
+	// ToolVersions is what to do when a pinned tool version differs from
+	// the one on PATH: "warn", "mise", or "off"
+	ToolVersions string
+}
+
+// CommandConfig configures a single command. An entry with Run defines a
+// custom command; an entry without it only adjusts how the command that
+// resolution finds is executed.
+type CommandConfig struct {
+	Run         string       `toml:"run"`         // Shell command line
+	Source      string       `toml:"source"`      // Resolve from this source only (e.g. "npm", "just")
+	Script      string       `toml:"script"`      // Name to look up in Source, if different
+	Description string       `toml:"description"` // Shown in --list
+	Category    string       `toml:"category"`    // Heading --list shows the command under
+	Dir         string       `toml:"dir"`         // Working directory, relative to the config file
+	Sudo        bool         `toml:"sudo"`        // Run through sudo, after confirmation
+	Retry       *RetryConfig `toml:"retry"`       // Retry transient failures
+}
+
+// RetryConfig is the config file form of RetryPolicy
+type RetryConfig struct {
+	Attempts int      `toml:"attempts"`
+	Backoff  string   `toml:"backoff"`
+	Delay    duration `toml:"delay"`
+}
+
+// duration is a time.Duration that decodes from strings such as "2s"
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// policy converts the config to a RetryPolicy
+func (c *RetryConfig) policy() RetryPolicy {
+	return RetryPolicy{Attempts: c.Attempts, Backoff: c.Backoff, Delay: time.Duration(c.Delay)}
+}
+
+// parseProjectConfig reads a config file. Commands may be written as a
+// table, or as a string that is shorthand for { run = "..." }.
+func parseProjectConfig(path string) (*ProjectConfig, error) {
+	var raw struct {
+		Commands        map[string]toml.Primitive `toml:"commands"`
+		Watch           map[string][]string       `toml:"watch"`
+		Groups          map[string]GroupConfig    `toml:"groups"`
+		Env             map[string]string         `toml:"env"`
+		EnvFiles        []string                  `toml:"env_files"`
+		Dotenv          *[]string                 `toml:"dotenv"`
+		Pre             map[string]string         `toml:"pre"`
+		Post            map[string]string         `toml:"post"`
+		DisabledSources []string                  `toml:"disabled_sources"`
+		Synthesize      *bool                     `toml:"synthesize"`
+		Theme           *ProjectTheme             `toml:"theme"`
+		SourceJobs      map[string]int            `toml:"source_jobs"`
+		AugmentPath     *bool                     `toml:"augment_path"`
+		Direnv          *bool                     `toml:"direnv"`
+		PackageManager  *PackageManagerPins       `toml:"package_manager"`
+		FailFast        *bool                     `toml:"fail_fast"`
+		Publish         *PublishConfig            `toml:"publish"`
+		ToolVersions    string                    `toml:"tool_versions"`
+		Container       *ContainerConfig          `toml:"container"`
+	}
+	md, err := toml.DecodeFile(path, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := decodeCommands(md, raw.Commands)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUndecoded(md); err != nil {
+		return nil, err
+	}
+	for pattern := range raw.Watch {
+		if err := validateGlob(pattern); err != nil {
+			return nil, fmt.Errorf("watch.%q: %w", pattern, err)
+		}
+	}
+	if raw.Theme != nil {
+		if err := raw.Theme.validate(); err != nil {
+			return nil, fmt.Errorf("theme: %w", err)
+		}
+	}
+	if err := validateSourceJobs(raw.SourceJobs); err != nil {
+		return nil, err
+	}
+	if raw.PackageManager != nil {
+		if err := raw.PackageManager.validate(); err != nil {
+			return nil, fmt.Errorf("package_manager: %w", err)
+		}
+	}
+	if raw.ToolVersions != "" && !slices.Contains(toolVersionModes, raw.ToolVersions) {
+		return nil, fmt.Errorf("tool_versions must be one of %s, got %q", strings.Join(toolVersionModes, ", "), raw.ToolVersions)
+	}
+	if raw.Container != nil {
+		if err := raw.Container.validate(); err != nil {
+			return nil, fmt.Errorf("container: %w", err)
+		}
+	}
+	for _, name := range sortCommands(raw.Groups) {
+		group := raw.Groups[name]
+		if _, ok := commands[name]; ok {
+			return nil, fmt.Errorf("groups.%s: a command has the same name", name)
+		}
+		if err := group.validate(); err != nil {
+			return nil, fmt.Errorf("groups.%s: %w", name, err)
+		}
+	}
+	return &ProjectConfig{
+		Path:            path,
+		Commands:        commands,
+		Watch:           raw.Watch,
+		Groups:          raw.Groups,
+		Env:             raw.Env,
+		EnvFiles:        raw.EnvFiles,
+		Dotenv:          raw.Dotenv,
+		Pre:             raw.Pre,
+		Post:            raw.Post,
+		DisabledSources: raw.DisabledSources,
+		Synthesize:      raw.Synthesize,
+		Theme:           raw.Theme,
+		SourceJobs:      raw.SourceJobs,
+		AugmentPath:     raw.AugmentPath,
+		Direnv:          raw.Direnv,
+		PackageManager:  raw.PackageManager,
+		FailFast:        raw.FailFast,
+		Publish:         raw.Publish,
+		ToolVersions:    raw.ToolVersions,
+		Container:       raw.Container,
+	}, nil
+}
+
+// decodeCommands decodes a [commands] table, accepting either a table or a
+// string shorthand for each command
+func decodeCommands(md toml.MetaData, raw map[string]toml.Primitive) (map[string]CommandConfig, error) {
+	commands := make(map[string]CommandConfig)
+	for name, primitive := range raw {
+		var run string
+		if err := md.PrimitiveDecode(primitive, &run); err == nil {
+			commands[name] = CommandConfig{Run: run}
+			continue
+		}
+
+		var command CommandConfig
+		if err := md.PrimitiveDecode(primitive, &command); err != nil {
+			return nil, fmt.Errorf("commands.%s: %w", name, err)
+		}
+		if command.Run != "" && command.Source != "" {
+			return nil, fmt.Errorf("commands.%s: run and source are mutually exclusive", name)
+		}
+		if command.Script != "" && command.Source == "" {
+			return nil, fmt.Errorf("commands.%s: script requires source", name)
+		}
+		if command.Retry != nil {
+			if err := ValidateBackoff(command.Retry.Backoff); err != nil {
+				return nil, fmt.Errorf("commands.%s.retry: %w", name, err)
+			}
+		}
+		commands[name] = command
+	}
+	return commands, nil
+}
+
+// checkUndecoded reports keys in a config file that cmd-runner doesn't know
+func checkUndecoded(md toml.MetaData) error {
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("unknown key %q", undecoded[0].String())
+	}
+	return nil
+}
+
+// projectConfigCache holds parsed config files by path. Parse errors are
+// reported once and cached as a nil config.
+var projectConfigCache = struct {
+	sync.Mutex
+	data map[string]*ProjectConfig
+}{data: make(map[string]*ProjectConfig)}
+
+// loadProjectConfig returns the .cmdr.toml in dir, or nil if there is none
+// or it can't be parsed
+func loadProjectConfig(dir string) *ProjectConfig {
+	path := filepath.Join(dir, ProjectConfigFile)
+
+	projectConfigCache.Lock()
+	defer projectConfigCache.Unlock()
+	if config, ok := projectConfigCache.data[path]; ok {
+		return config
+	}
+
+	var config *ProjectConfig
+	if FileExists(path) {
+		var err error
+		config, err = parseProjectConfig(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", path, err)
+		}
+	}
+	projectConfigCache.data[path] = config
+	return config
+}
+
+// commandConfig returns the configuration for command from the current
+// directory or project root, checking command variants as resolution does
+func (r *CommandRunner) commandConfig(command string) *CommandConfig {
+	for _, dir := range r.searchDirs() {
+		config := loadProjectConfig(dir)
+		if config == nil {
+			continue
+		}
+		for _, variant := range GetCommandVariants(command) {
+			if cc, ok := config.Commands[variant]; ok {
+				return &cc
+			}
+		}
+	}
+	return nil
+}
+
+// applyCommandConfig applies per-command execution settings from config.
+// Options given on the command line take precedence.
+func (r *CommandRunner) applyCommandConfig() {
+	cc := r.commandConfig(r.Command)
+	if cc == nil {
+		return
+	}
+	if cc.Sudo {
+		r.Sudo = true
+	}
+	if cc.Retry != nil && r.Retry.Attempts == 0 {
+		r.Retry = cc.Retry.policy()
+	}
+}
+
+// pinnedCommand resolves a command whose config names the source to run it
+// from. It returns nil, nil when the command isn't pinned.
+func (r *CommandRunner) pinnedCommand(command string) (*exec.Cmd, error) {
+	cmd, _, err := r.pinnedLookup(command)
+	return cmd, err
+}
+
+// pinnedLookup is pinnedCommand, also returning the source
+func (r *CommandRunner) pinnedLookup(command string) (*exec.Cmd, CommandSource, error) {
+	cc := r.commandConfig(command)
+	if cc == nil || cc.Source == "" {
+		return nil, nil, nil
+	}
+	target := cc.Script
+	if target == "" {
+		target = command
+	}
+	for _, project := range r.projects() {
+		for _, source := range project.CommandSources {
+			if !strings.EqualFold(source.Name(), cc.Source) {
+				continue
+			}
+			if cmd := source.FindCommand(target, r.Args); cmd != nil {
+				return cmd, source, nil
+			}
+		}
+	}
+	return nil, nil, configError(fmt.Errorf("'%s' is pinned to '%s' from %s, which was not found", command, target, cc.Source))
+}
+
+// searchDirs returns the current directory and, if different, the project root
+func (r *CommandRunner) searchDirs() []string {
+	dirs := []string{r.CurrentDir}
+	if r.ProjectRoot != r.CurrentDir && r.ProjectRoot != "" {
+		dirs = append(dirs, r.ProjectRoot)
+	}
+	return dirs
+}
+
+// ConfigSource represents custom commands defined in a config file: the
+// project's .cmdr.toml, or the user config for commands available everywhere
+type ConfigSource struct {
+	baseSource
+	path     string
+	commands map[string]CommandConfig
+	groups   map[string]GroupConfig
+}
+
+func NewConfigSource(dir string) CommandSource {
+	config := loadProjectConfig(dir)
+	if config == nil {
+		return nil
+	}
+
+	return &ConfigSource{
+		baseSource: baseSource{
+			dir:      dir,
+			name:     ProjectConfigFile,
+			priority: 0,
+		},
+		path:     config.Path,
+		commands: config.Commands,
+		groups:   config.Groups,
+	}
+}
+
+// NewUserCommandSource returns the commands from the user config, run in
+// dir. They have the lowest priority, so project commands win.
+func NewUserCommandSource(dir string) CommandSource {
+	config := LoadUserConfig()
+	if config == nil || len(config.Commands) == 0 {
+		return nil
+	}
+
+	return &ConfigSource{
+		baseSource: baseSource{
+			dir:      dir,
+			name:     "user config",
+			priority: 100,
+		},
+		path:     config.Path,
+		commands: config.Commands,
+	}
+}
+
+func (c *ConfigSource) ListCommands() map[string]CommandInfo {
+	commands := make(map[string]CommandInfo)
+	for name, cc := range c.commands {
+		if cc.Run == "" {
+			continue
+		}
+		description := cc.Description
+		if description == "" {
+			description = cc.Run
+		}
+		commands[name] = CommandInfo{Description: description, Execution: cc.Run}
+	}
+	for name, group := range c.groups {
+		order, _ := group.startOrder()
+		description := group.Description
+		if description == "" {
+			description = "Run " + strings.Join(order, ", ")
+		}
+		commands[name] = CommandInfo{Description: description, Execution: "group: " + joinArrow(order)}
+	}
+	return commands
+}
+
+func (c *ConfigSource) FindCommand(command string, args []string) *exec.Cmd {
+	for _, variant := range GetCommandVariants(command) {
+		cc, ok := c.commands[variant]
+		if !ok || cc.Run == "" {
+			continue
+		}
+		cmd := c.executor.shell(cc.Run, args)
+		cmd.Dir = c.dir
+		if cc.Dir != "" {
+			cmd.Dir = filepath.Join(c.dir, cc.Dir)
+		}
+		return cmd
+	}
+	return nil
+}
+
+func (c *ConfigSource) DescribeCommand(command string) *CommandDoc {
+	path := c.path
+	if doc := tomlTableBlock(path, "[commands."+command+"]"); doc != nil {
+		return doc
+	}
+	if doc := tomlTableBlock(path, "[groups."+command+"]"); doc != nil {
+		return doc
+	}
+	return keyLine(path, "[commands]", regexp.MustCompile(`^\s*"?`+regexp.QuoteMeta(command)+`"?\s*=`))
+}
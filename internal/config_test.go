@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, dir string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseProjectConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	writeConfig(t, tempDir, `[commands]
+todo = "rg TODO"
+
+[commands.deploy]
+run = "./deploy.sh"
+description = "Deploy to staging"
+dir = "infra"
+sudo = true
+
+[commands.setup]
+retry = { attempts = 3, backoff = "linear", delay = "2s" }
+
+[watch]
+"**/*.go" = ["test", "lint"]
+
+[groups.dev]
+description = "Run the app"
+processes.db = { run = "postgres", ready = { tcp = "localhost:5432", timeout = "30s" } }
+processes.web = { command = "dev", depends_on = { db = "healthy" } }
+`)
+
+	config, err := parseProjectConfig(filepath.Join(tempDir, ProjectConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Commands["todo"].Run != "rg TODO" {
+		t.Errorf("todo = %+v, want shorthand run", config.Commands["todo"])
+	}
+	deploy := config.Commands["deploy"]
+	if deploy.Run != "./deploy.sh" || deploy.Dir != "infra" || !deploy.Sudo || deploy.Description != "Deploy to staging" {
+		t.Errorf("deploy = %+v", deploy)
+	}
+	setup := config.Commands["setup"]
+	if setup.Run != "" || setup.Retry == nil {
+		t.Fatalf("setup = %+v", setup)
+	}
+	policy := setup.Retry.policy()
+	if policy.Attempts != 3 || policy.Backoff != "linear" || policy.Delay != 2*time.Second {
+		t.Errorf("setup retry policy = %+v", policy)
+	}
+	if got := config.Watch["**/*.go"]; !slicesEqual(got, []string{"test", "lint"}) {
+		t.Errorf("watch[**/*.go] = %v", got)
+	}
+	dev := config.Groups["dev"]
+	if dev.Processes["db"].Ready == nil || time.Duration(dev.Processes["db"].Ready.Timeout) != 30*time.Second {
+		t.Errorf("groups.dev.processes.db = %+v", dev.Processes["db"])
+	}
+	if dev.Processes["web"].Command != "dev" || dev.Processes["web"].DependsOn["db"] != "healthy" {
+		t.Errorf("groups.dev.processes.web = %+v", dev.Processes["web"])
+	}
+}
+
+func TestParseProjectConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"unknown key", "[commands.test]\nrun = \"x\"\ntimeout = 3\n", "commands.test.timeout"},
+		{"bad backoff", "[commands.test]\nretry = { attempts = 2, backoff = \"random\" }\n", "unknown backoff"},
+		{"syntax", "[commands\n", ""},
+		{"run and source", "[commands.test]\nrun = \"x\"\nsource = \"npm\"\n", "mutually exclusive"},
+		{"script without source", "[commands.test]\nscript = \"x\"\n", "requires source"},
+		{"bad group", "[groups.dev.processes.web]\ndepends_on = { db = \"started\" }\nrun = \"x\"\n", "groups.dev"},
+		{"bad glob", "[watch]\n\"[*.go\" = [\"test\"]\n", "watch."},
+		{"bad theme color", "[theme]\ncolor = \"mauve\"\n", "theme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeConfig(t, tempDir, tt.content)
+			_, err := parseProjectConfig(filepath.Join(tempDir, ProjectConfigFile))
+			if err == nil {
+				t.Fatal("parseProjectConfig() error = nil")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("parseProjectConfig() error = %v, want it to mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestConfigSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	tempDir := t.TempDir()
+	writeConfig(t, tempDir, `[commands.greet]
+run = "echo hello"
+description = "Say hello"
+dir = "sub"
+`)
+
+	project := ResolveProject(tempDir)
+	if len(project.CommandSources) == 0 || project.CommandSources[0].Name() != ProjectConfigFile {
+		t.Fatalf("ResolveProject() sources = %v, want config source first", project.CommandSources)
+	}
+	source := project.CommandSources[0]
+
+	if info := source.ListCommands()["greet"]; info.Description != "Say hello" || info.Execution != "echo hello" {
+		t.Errorf("ListCommands()[greet] = %+v", info)
+	}
+
+	cmd := source.FindCommand("greet", []string{"world"})
+	if cmd == nil {
+		t.Fatal("FindCommand(greet) = nil")
+	}
+	expected := []string{"sh", "-c", `echo hello "$@"`, "sh", "world"}
+	if !slicesEqual(cmd.Args, expected) {
+		t.Errorf("FindCommand(greet).Args = %q, want %q", cmd.Args, expected)
+	}
+	if cmd.Dir != filepath.Join(tempDir, "sub") {
+		t.Errorf("FindCommand(greet).Dir = %q", cmd.Dir)
+	}
+}
+
+func TestPinnedCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte("lint:\n\techo make lint\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	packageJSON := `{"scripts": {"lint": "eslint .", "lint:ci": "eslint --max-warnings 0 ."}}`
+	if err := os.WriteFile(filepath.Join(tempDir, "package.json"), []byte(packageJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, tempDir, `[commands]
+lint = { source = "npm", script = "lint:ci" }
+build = { source = "make" }
+`)
+
+	runner := &CommandRunner{Command: "lint", CurrentDir: tempDir, ProjectRoot: tempDir}
+	cmd, err := runner.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"npm", "run", "lint:ci"}; !slicesEqual(cmd.Args, expected) {
+		t.Errorf("Resolve(lint).Args = %q, want %q", cmd.Args, expected)
+	}
+
+	runner = &CommandRunner{Command: "build", CurrentDir: tempDir, ProjectRoot: tempDir}
+	if _, err := runner.Resolve(); err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("Resolve(build) error = %v, want pinned source not found", err)
+	}
+}
+
+func TestDisabledSources(t *testing.T) {
+	sourceNames := func(dir string) []string {
+		names := []string{}
+		for _, source := range ResolveProject(dir).CommandSources {
+			names = append(names, source.Name())
+		}
+		return names
+	}
+	writeProject := func(t *testing.T, config string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("lint:\n\techo make lint\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"lint": "eslint ."}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if config != "" {
+			writeConfig(t, dir, config)
+		}
+		return dir
+	}
+
+	dir := writeProject(t, `disabled_sources = ["Make"]`)
+	if names := sourceNames(dir); !slicesEqual(names, []string{ProjectConfigFile, "npm"}) {
+		t.Errorf("sources with make disabled in config = %q", names)
+	}
+	runner := &CommandRunner{Command: "lint", CurrentDir: dir, ProjectRoot: dir}
+	if cmd, err := runner.Resolve(); err != nil || cmd.Args[0] != "npm" {
+		t.Errorf("Resolve(lint) = %v, %v; want npm", cmd, err)
+	}
+
+	t.Cleanup(func() { disabledSources = nil })
+	DisableSources("npm")
+	dir = writeProject(t, "")
+	if names := sourceNames(dir); !slicesEqual(names, []string{"make"}) {
+		t.Errorf("sources with --no-source npm = %q", names)
+	}
+}
+
+func TestProjectThemeBadge(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	dir := t.TempDir()
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	if badge := runner.badge(); badge != "" {
+		t.Errorf("badge() without a theme = %q", badge)
+	}
+
+	dir = t.TempDir()
+	runner = &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	writeConfig(t, dir, "[theme]\nemoji = \"🚀\"\ncolor = \"#ff8800\"\n")
+	if badge := runner.badge(); badge != "[🚀 "+filepath.Base(dir)+"] " {
+		t.Errorf("badge() = %q", badge)
+	}
+	if code, err := colorCode("#ff8800"); err != nil || code != "38;2;255;136;0" {
+		t.Errorf("colorCode(#ff8800) = %q, %v", code, err)
+	}
+	if code, err := colorCode("Bright-Cyan"); err != nil || code != "96" {
+		t.Errorf("colorCode(Bright-Cyan) = %q, %v", code, err)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	setUserConfig(t, "[colors]\nsuccess = \"bold #ff0000\"\ndim = \"none\"\n")
+	if got := style(os.Stdout, styleSuccess, "✓"); got != "✓" {
+		t.Errorf("style() under NO_COLOR = %q", got)
+	}
+
+	// --color=always overrides NO_COLOR
+	if err := SetColorMode("always"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { colorMode = "" })
+	for _, tt := range []struct{ role, want string }{
+		{styleSuccess, "\033[1;38;2;255;0;0m✓\033[0m"},
+		{styleFailure, "\033[31m✓\033[0m"},
+		{styleDim, "✓"},
+	} {
+		if got := style(os.Stdout, tt.role, "✓"); got != tt.want {
+			t.Errorf("style(%s) = %q, want %q", tt.role, got, tt.want)
+		}
+	}
+	var b strings.Builder
+	if got := style(&b, styleFailure, "✗"); got != "✗" {
+		t.Errorf("style() for a non-file writer = %q", got)
+	}
+
+	if err := SetColorMode("sometimes"); err == nil {
+		t.Error("SetColorMode() should reject an unknown mode")
+	}
+	path := filepath.Join(t.TempDir(), "config.toml")
+	for _, content := range []string{"[colors]\nheadline = \"red\"\n", "[colors]\nsource = \"puce\"\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseUserConfig(path); err == nil {
+			t.Errorf("parseUserConfig(%q) should fail", content)
+		}
+	}
+}
+
+func TestSynthesizeFalse(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"lint": "eslint ."}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, "synthesize = false\n")
+
+	runner := &CommandRunner{Command: "lint", CurrentDir: dir, ProjectRoot: dir}
+	if cmd, err := runner.Resolve(); err != nil || cmd.Args[0] != "npm" {
+		t.Errorf("Resolve(lint) = %v, %v; want the npm script", cmd, err)
+	}
+	for _, command := range []string{"test", "setup", "check"} {
+		runner := &CommandRunner{Command: command, CurrentDir: dir, ProjectRoot: dir}
+		_, err := runner.Resolve()
+		if ExitCode(err) != ExitNotFound || !strings.Contains(err.Error(), "synthesized commands are disabled") {
+			t.Errorf("Resolve(%s) error = %v, want a strict-mode error", command, err)
+		}
+	}
+
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner = &CommandRunner{Command: "test", CurrentDir: dir, ProjectRoot: dir}
+	if cmd, err := runner.Resolve(); err != nil || cmd.Args[0] != "go" {
+		t.Errorf("Resolve(test) without strict mode = %v, %v", cmd, err)
+	}
+	runner.NoSynth = true
+	if _, err := runner.Resolve(); ExitCode(err) != ExitNotFound {
+		t.Errorf("Resolve(test) with NoSynth error = %v", err)
+	}
+}
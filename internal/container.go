@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// defaultContainerWorkdir is where a configured image gets the project
+// mounted
+const defaultContainerWorkdir = "/workspace"
+
+// ContainerConfig is the [container] table of .cmdr.toml: an image that
+// --in-container runs commands in, instead of the devcontainer
+type ContainerConfig struct {
+	Image   string `toml:"image"`
+	Workdir string `toml:"workdir"` // Where the project is mounted
+}
+
+func (c *ContainerConfig) validate() error {
+	if c.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+	if c.Workdir != "" && !strings.HasPrefix(c.Workdir, "/") {
+		return fmt.Errorf("workdir must be an absolute path, got %q", c.Workdir)
+	}
+	return nil
+}
+
+// containerTarget is the container --in-container runs commands in
+type containerTarget struct {
+	root         string // host directory mounted in the container
+	devcontainer string // devcontainer.json, if the target comes from one
+	image        string
+	workdir      string // where root is mounted
+}
+
+// devcontainerFiles are where the devcontainer spec looks for its config,
+// relative to the project
+var devcontainerFiles = []string{filepath.Join(".devcontainer", "devcontainer.json"), ".devcontainer.json"}
+
+// containerTarget returns the container for the nearest of the current
+// directory and project root with an image in .cmdr.toml or a
+// devcontainer.json, the image taking precedence
+func (r *CommandRunner) containerTarget() (*containerTarget, error) {
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.Container != nil {
+			workdir := config.Container.Workdir
+			if workdir == "" {
+				workdir = defaultContainerWorkdir
+			}
+			return &containerTarget{root: dir, image: config.Container.Image, workdir: workdir}, nil
+		}
+		if name := firstExisting(dir, devcontainerFiles...); name != "" {
+			return &containerTarget{root: dir, devcontainer: filepath.Join(dir, name)}, nil
+		}
+	}
+	return nil, notFoundError(fmt.Errorf("--in-container needs .devcontainer/devcontainer.json, or [container] image in %s, in current directory or project root", ProjectConfigFile))
+}
+
+// devcontainersUp holds the workspaces whose devcontainer is known to be
+// running, so that devcontainer up runs once per run
+var devcontainersUp = struct {
+	sync.Mutex
+	roots map[string]bool
+}{roots: make(map[string]bool)}
+
+// containerCommand returns cmd wrapped to run inside the project's
+// container, in the directory that mirrors cmd's. With the devcontainer CLI
+// installed, devcontainer.json is used as its tools would: the container is
+// started with devcontainer up and the command run with devcontainer exec.
+// Otherwise, and for a configured image, the command runs in a new
+// container with docker (or podman) run, the project mounted. cleanup, if
+// not nil, is called once the command has finished.
+func (r *CommandRunner) containerCommand(cmd *exec.Cmd) (wrapped *exec.Cmd, cleanup func(), err error) {
+	target, err := r.containerTarget()
+	if err != nil {
+		return nil, nil, err
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		dir = r.CurrentDir
+	}
+	rel, err := filepath.Rel(target.root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, nil, fmt.Errorf("%s is outside %s, which is what the container mounts", dir, target.root)
+	}
+
+	// The container has its own PATH; the other variables cmdr adds are
+	// passed in
+	var env []EnvVar
+	for _, v := range r.ResolveEnv(false) {
+		if v.Name != "PATH" {
+			env = append(env, v)
+		}
+	}
+
+	if target.devcontainer != "" {
+		if _, err := r.executor.lookPath("devcontainer"); err == nil {
+			return r.devcontainerExec(cmd, target, filepath.ToSlash(rel), env)
+		}
+		image, workdir, err := devcontainerImage(target.devcontainer)
+		if err != nil {
+			return nil, nil, err
+		}
+		if image == "" {
+			return nil, nil, fmt.Errorf("%s builds its container, which needs the devcontainer CLI (npm install -g @devcontainers/cli)", r.displayPath(target.devcontainer))
+		}
+		target.image = image
+		target.workdir = workdir
+		if target.workdir == "" {
+			target.workdir = "/workspaces/" + filepath.Base(target.root)
+		}
+	}
+
+	engine := ""
+	for _, program := range []string{"docker", "podman"} {
+		if _, err := r.executor.lookPath(program); err == nil {
+			engine = program
+			break
+		}
+	}
+	if engine == "" {
+		return nil, nil, &ToolMissingError{Tool: "docker", Command: r.Command, Err: exec.ErrNotFound, Install: r.executor.installSuggestions("docker")}
+	}
+	args := []string{"run", "--rm", "-i"}
+	if r.output == nil && term.IsTerminal(int(os.Stdin.Fd())) {
+		args = append(args, "-t")
+	}
+	args = append(args, "-v", target.root+":"+target.workdir, "-w", path.Join(target.workdir, filepath.ToSlash(rel)))
+	for _, v := range env {
+		// Without a value, the engine takes it from its own environment,
+		// which keeps secrets off the command line
+		args = append(args, "-e", v.Name)
+	}
+	args = append(args, target.image)
+	wrapped = r.executor.command(engine, append(args, cmd.Args...)...)
+	wrapped.Env = cmd.Env
+	return wrapped, nil, nil
+}
+
+// devcontainerExec starts the workspace's devcontainer, unless it already
+// has this run, and returns cmd wrapped in devcontainer exec, changing to
+// rel within the workspace folder.
+//
+// devcontainer exec only takes variables as --remote-env NAME=VALUE, which
+// would put secrets in the announced command, the audit log, history and
+// ps. Instead they are written to a file only the user can read, in the
+// workspace folder, which a shell in the container loads before running
+// the command. cleanup removes it.
+func (r *CommandRunner) devcontainerExec(cmd *exec.Cmd, target *containerTarget, rel string, env []EnvVar) (wrapped *exec.Cmd, cleanup func(), err error) {
+	devcontainersUp.Lock()
+	defer devcontainersUp.Unlock()
+	if !devcontainersUp.roots[target.root] {
+		up := r.executor.command("devcontainer", "up", "--workspace-folder", target.root)
+		// up reports progress on stderr and its result as JSON on stdout
+		up.Stderr = r.statusWriter()
+		if err := up.Run(); err != nil {
+			return nil, nil, fmt.Errorf("devcontainer up: %w", err)
+		}
+		devcontainersUp.roots[target.root] = true
+	}
+
+	args := []string{"exec", "--workspace-folder", target.root}
+	if len(env) > 0 {
+		file, err := writeContainerEnv(target.root, env)
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.Remove(file) }
+		args = append(args, "sh", "-c", `set -a && . "./$1" && set +a && cd "$2" && shift 2 && exec "$@"`, "sh", filepath.Base(file), rel)
+	} else if rel != "." {
+		args = append(args, "sh", "-c", `cd "$0" && exec "$@"`, rel)
+	}
+	wrapped = r.executor.command("devcontainer", append(args, cmd.Args...)...)
+	wrapped.Env = cmd.Env
+	return wrapped, cleanup, nil
+}
+
+// writeContainerEnv writes env as shell assignments to a new file in dir,
+// which only the user can read, and returns its path
+func writeContainerEnv(dir string, env []EnvVar) (string, error) {
+	file, err := os.CreateTemp(dir, ".cmdr-env-*")
+	if err != nil {
+		return "", fmt.Errorf("writing the container's environment: %w", err)
+	}
+	var b strings.Builder
+	for _, v := range env {
+		fmt.Fprintf(&b, "%s=%s\n", v.Name, shellQuote(v.Value))
+	}
+	_, err = file.WriteString(b.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("writing the container's environment: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// devcontainerImage returns the image a devcontainer.json runs and its
+// workspaceFolder, or "" for a config that builds from a Dockerfile or
+// Compose file
+func devcontainerImage(file string) (image, workdir string, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", err
+	}
+	var config struct {
+		Image           string `json:"image"`
+		WorkspaceFolder string `json:"workspaceFolder"`
+	}
+	if err := json.Unmarshal(stripJSONComments(data), &config); err != nil {
+		return "", "", fmt.Errorf("%s: %w", file, err)
+	}
+	return config.Image, config.WorkspaceFolder, nil
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestContainerCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix paths in the container")
+	}
+	var ran []string
+	factory := func(name string, arg ...string) *exec.Cmd {
+		ran = append(ran, strings.Join(append([]string{name}, arg...), " "))
+		if name == "devcontainer" && arg[0] == "up" {
+			return exec.Command("true")
+		}
+		return exec.Command(name, arg...)
+	}
+	newRunner := func(dir string, installed ...string) *CommandRunner {
+		lookPath := func(file string) (string, error) {
+			for _, program := range installed {
+				if file == program {
+					return "/usr/bin/" + file, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+		runner := New("test", nil, WithCommandFactory(factory), WithLookPath(lookPath))
+		runner.CurrentDir, runner.ProjectRoot = dir, dir
+		runner.InContainer = true
+		return runner
+	}
+	wrap := func(runner *CommandRunner, dir string) (string, error) {
+		cmd := exec.Command("npm", "test")
+		cmd.Dir = dir
+		cmd.Env = []string{"PATH=/usr/bin"}
+		wrapped, cleanup, err := runner.containerCommand(cmd)
+		if err != nil {
+			return "", err
+		}
+		if cleanup != nil {
+			t.Cleanup(cleanup)
+		}
+		// -t depends on whether the test's stdin is a terminal
+		return strings.Replace(strings.Join(wrapped.Args, " "), " -i -t ", " -i ", 1), nil
+	}
+
+	// A configured image runs with the project mounted, in the command's
+	// directory, and gets the variables cmdr adds
+	dir := t.TempDir()
+	writeConfig(t, dir, "[container]\nimage = \"node:20\"\n\n[env]\nAPI_TOKEN = \"secret\"\n")
+	if err := os.MkdirAll(filepath.Join(dir, "web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := wrap(newRunner(dir, "podman"), filepath.Join(dir, "web"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "podman run --rm -i -v " + dir + ":/workspace -w /workspace/web -e API_TOKEN node:20 npm test"; got != want {
+		t.Errorf("containerCommand() = %q, want %q", got, want)
+	}
+	if _, err := wrap(newRunner(dir, "podman"), t.TempDir()); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("containerCommand() outside the project: error = %v", err)
+	}
+	if _, err := wrap(newRunner(dir), dir); !errors.Is(err, ErrToolMissing) {
+		t.Errorf("containerCommand() without docker: error = %v, want ErrToolMissing", err)
+	}
+
+	// Without the devcontainer CLI, devcontainer.json's image runs in its
+	// workspace folder
+	dir = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".devcontainer"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	devcontainer := "{\n  // Go toolchain\n  \"image\": \"mcr.microsoft.com/devcontainers/go:1\",\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, ".devcontainer", "devcontainer.json"), []byte(devcontainer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = wrap(newRunner(dir, "docker"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "docker run --rm -i -v " + dir + ":/workspaces/" + filepath.Base(dir) + " -w /workspaces/" + filepath.Base(dir) + " mcr.microsoft.com/devcontainers/go:1 npm test"; got != want {
+		t.Errorf("containerCommand() = %q, want %q", got, want)
+	}
+
+	// With it, the container is started once and the command run with exec
+	ran = nil
+	runner := newRunner(dir, "devcontainer", "docker")
+	for i := 0; i < 2; i++ {
+		if got, err = wrap(runner, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "devcontainer exec --workspace-folder " + dir + " npm test"; got != want {
+		t.Errorf("containerCommand() = %q, want %q", got, want)
+	}
+	if want := []string{"devcontainer up --workspace-folder " + dir, "devcontainer exec --workspace-folder " + dir + " npm test", "devcontainer exec --workspace-folder " + dir + " npm test"}; !slicesEqual(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+
+	// Variables reach devcontainer exec through a file the container's shell
+	// loads, not the command line
+	dir = t.TempDir()
+	for _, sub := range []string{".devcontainer", "web"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".devcontainer", "devcontainer.json"), []byte(devcontainer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, "[env]\nAPI_TOKEN = \"it's secret\"\n")
+	runner = newRunner(dir, "devcontainer")
+	cmd := exec.Command("sh", "-c", `echo "$API_TOKEN in $(basename "$PWD")"`)
+	cmd.Dir = filepath.Join(dir, "web")
+	wrapped, cleanup, err := runner.containerCommand(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range wrapped.Args {
+		if strings.Contains(arg, "secret") {
+			t.Errorf("containerCommand() argument %q contains a variable's value", arg)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, ".cmdr-env-*"))
+	if len(files) != 1 {
+		t.Fatalf("environment files = %q, want one", files)
+	}
+	if info, err := os.Stat(files[0]); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("environment file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	// The workspace folder stands in for the container: run what follows
+	// devcontainer exec's options there
+	inner := exec.Command(wrapped.Args[4], wrapped.Args[5:]...)
+	inner.Dir = dir
+	if output, err := inner.Output(); err != nil || string(output) != "it's secret in web\n" {
+		t.Errorf("wrapped command output = %q, %v; want %q", output, err, "it's secret in web\n")
+	}
+	cleanup()
+	if FileExists(files[0]) {
+		t.Errorf("cleanup left %s", files[0])
+	}
+
+	if _, err := wrap(newRunner(t.TempDir(), "docker"), ""); err == nil || !strings.Contains(err.Error(), "--in-container needs") {
+		t.Errorf("containerCommand() without a container: error = %v", err)
+	}
+}
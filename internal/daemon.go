@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	// daemonDialTimeout is how long a client waits to connect before
+	// listing commands itself
+	daemonDialTimeout = 100 * time.Millisecond
+
+	// daemonRequestTimeout bounds a request, which may run a slow tool
+	daemonRequestTimeout = 30 * time.Second
+)
+
+// daemonSocket is where the daemon listens: CMDR_DAEMON_SOCKET, or a
+// per-user socket in the temporary directory
+func daemonSocket() string {
+	if path := os.Getenv("CMDR_DAEMON_SOCKET"); path != "" {
+		return path
+	}
+	name := "cmdr"
+	if uid := os.Getuid(); uid >= 0 {
+		name += "-" + strconv.Itoa(uid)
+	}
+	return filepath.Join(os.TempDir(), name, "daemon.sock")
+}
+
+// daemonRequest is a line a client sends the daemon
+type daemonRequest struct {
+	Op     string `json:"op"` // "list", "status", or "stop"
+	Source string `json:"source,omitempty"`
+	Dir    string `json:"dir,omitempty"`
+}
+
+// daemonResponse is the daemon's answer to a request
+type daemonResponse struct {
+	Commands map[string]CommandInfo `json:"commands,omitempty"`
+	Pid      int                    `json:"pid,omitempty"`
+	Listings int                    `json:"listings,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// daemonUnavailable is set once a client fails to reach the daemon, so
+// that the rest of the run doesn't try again
+var daemonUnavailable atomic.Bool
+
+// daemonCommands asks a running daemon for the listing of a source, as
+// its ListCommands would compute it. It reports false, without waiting
+// long, if no daemon answers.
+func daemonCommands(source, dir string) (map[string]CommandInfo, bool) {
+	if analyzeOnly || daemonUnavailable.Load() {
+		return nil, false
+	}
+	response, err := callDaemon(daemonRequest{Op: "list", Source: source, Dir: dir})
+	if err != nil {
+		debugf("daemon: %v", err)
+		daemonUnavailable.Store(true)
+		return nil, false
+	}
+	debugf("list %s:%s: %d commands, from the daemon", source, dir, len(response.Commands))
+	return response.Commands, true
+}
+
+// errNoDaemon reports that no daemon is listening
+var errNoDaemon = errors.New("no cmdr daemon is running; start one with `cmdr daemon`")
+
+// callDaemon sends a request to the daemon and returns its response
+func callDaemon(request daemonRequest) (*daemonResponse, error) {
+	path := daemonSocket()
+	// Another user who controls the socket's directory could answer with
+	// listings that run their commands
+	if err := checkDaemonDir(filepath.Dir(path)); os.IsNotExist(err) {
+		return nil, errNoDaemon
+	} else if err != nil {
+		return nil, fmt.Errorf("not using the daemon socket: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errNoDaemon
+	}
+	conn, err := net.DialTimeout("unix", path, daemonDialTimeout)
+	if err != nil {
+		return nil, errNoDaemon
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(daemonRequestTimeout))
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+	var response daemonResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, fmt.Errorf("bad response from the daemon: %w", err)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// daemonRunning reports whether a daemon answers on the socket
+func daemonRunning() bool {
+	_, err := callDaemon(daemonRequest{Op: "status"})
+	return err == nil
+}
+
+// RequireDaemon returns an error unless a daemon answers on the socket, for
+// --client
+func RequireDaemon() error {
+	_, err := callDaemon(daemonRequest{Op: "status"})
+	return err
+}
+
+// daemon serves source listings from memory, listing a source again when
+// the files in its directory change
+type daemon struct {
+	listener net.Listener
+	stopping atomic.Bool
+
+	// executor is the daemon's own, which keeps its sources from asking
+	// the daemon for their listings and gives them cache keys of their own
+	executor *Executor
+
+	// listings invalidates listings as their files change. Without it, the
+	// daemon compares fingerprints of the files on each request.
+	listings *listingWatcher
+
+	mu           sync.Mutex
+	fingerprints map[string]string // of the listings served, by cache key
+}
+
+// RunDaemon serves source listings on the daemon socket until it's
+// interrupted or asked to stop
+func RunDaemon(out io.Writer) error {
+	if err := refuseToRun("the daemon"); err != nil {
+		return err
+	}
+	path := daemonSocket()
+	if daemonRunning() {
+		return fmt.Errorf("a cmdr daemon is already running on %s", path)
+	}
+	// MkdirAll accepts a directory someone else made first
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := checkDaemonDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("not listening on %s: %w", path, err)
+	}
+	// A socket left by a daemon that didn't exit cleanly
+	_ = os.Remove(path)
+	listener, err := listenPrivate(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	d := &daemon{listener: listener, executor: &Executor{}, fingerprints: make(map[string]string)}
+	if d.listings, err = newListingWatcher(); err == nil {
+		defer d.listings.Close()
+	} else {
+		fmt.Fprintf(out, "Warning: %v; checking files for changes on each request instead\n", err)
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			d.stop()
+		}
+	}()
+
+	fmt.Fprintf(out, "cmdr daemon listening on %s (pid %d)\n", path, os.Getpid())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if d.stopping.Load() {
+				return nil
+			}
+			return err
+		}
+		go d.serve(conn)
+	}
+}
+
+// StopDaemon asks the running daemon to exit
+func StopDaemon() error {
+	_, err := callDaemon(daemonRequest{Op: "stop"})
+	return err
+}
+
+// ShowDaemonStatus writes whether a daemon is running and how many
+// listings it holds
+func ShowDaemonStatus(w io.Writer) error {
+	response, err := callDaemon(daemonRequest{Op: "status"})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "cmdr daemon running on %s (pid %d), %d listings cached\n", daemonSocket(), response.Pid, response.Listings)
+	return nil
+}
+
+func (d *daemon) stop() {
+	d.stopping.Store(true)
+	d.listener.Close()
+}
+
+// serve answers one request
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(daemonRequestTimeout))
+	var request daemonRequest
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		return
+	}
+	response := daemonResponse{Pid: os.Getpid()}
+	switch request.Op {
+	case "list":
+		commands, err := d.list(request.Source, request.Dir)
+		if err != nil {
+			response.Error = err.Error()
+		}
+		response.Commands = commands
+	case "status":
+		d.mu.Lock()
+		response.Listings = len(d.fingerprints)
+		d.mu.Unlock()
+	case "stop":
+		defer d.stop()
+	default:
+		response.Error = fmt.Sprintf("unknown daemon request %q", request.Op)
+	}
+	_ = json.NewEncoder(conn).Encode(response)
+}
+
+// list returns the listing of the named source in dir, from memory unless
+// the directory's files changed since it was listed
+func (d *daemon) list(name, dir string) (map[string]CommandInfo, error) {
+	source := findSourceByName(resolveProject(dir, d.executor).CommandSources, name)
+	if source == nil {
+		return nil, fmt.Errorf("no %s source in %s", name, dir)
+	}
+	keyed, ok := source.(interface{ cacheKey() string })
+	if !ok {
+		return source.ListCommands(), nil
+	}
+	key := keyed.cacheKey()
+	d.mu.Lock()
+	if d.listings != nil {
+		d.listings.watch(dir)
+		d.fingerprints[key] = ""
+	} else if fingerprint := listingFingerprint(dir); d.fingerprints[key] != fingerprint {
+		forgetCachedCommands(key)
+		d.fingerprints[key] = fingerprint
+	}
+	d.mu.Unlock()
+	return source.ListCommands(), nil
+}
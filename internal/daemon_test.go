@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemon(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a unix socket")
+	}
+	// Socket paths are limited to about a hundred bytes
+	socketDir, err := os.MkdirTemp("", "cmdr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(socketDir)
+	t.Setenv("CMDR_DAEMON_SOCKET", filepath.Join(socketDir, "d.sock"))
+	daemonUnavailable.Store(false)
+	defer daemonUnavailable.Store(false)
+
+	if err := RequireDaemon(); err == nil {
+		t.Fatal("RequireDaemon() = nil with no daemon running")
+	}
+	daemonUnavailable.Store(false)
+
+	done := make(chan error, 1)
+	go func() { done <- RunDaemon(io.Discard) }()
+	for start := time.Now(); !daemonRunning(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("daemon didn't start")
+		}
+	}
+	if info, err := os.Stat(daemonSocket()); err != nil || info.Mode().Perm()&0077 != 0 {
+		t.Errorf("socket mode = %v, %v; want it private", info.Mode().Perm(), err)
+	}
+
+	dir := t.TempDir()
+	makefile := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(makefile, []byte("build:\n\tgo build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commands, ok := daemonCommands("make", dir)
+	if !ok || commands["build"].Execution != "make build" {
+		t.Fatalf("daemonCommands() = %v, %v; want build", commands, ok)
+	}
+
+	// Editing the Makefile invalidates the daemon's listing
+	if err := os.WriteFile(makefile, []byte("build:\n\tgo build\ntest:\n\tgo test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(makefile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	// The daemon may learn of the change a moment later
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		commands, _ := daemonCommands("make", dir)
+		if len(commands) == 2 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("after an edit, daemonCommands() = %v, want build and test", commands)
+		}
+	}
+
+	if err := StopDaemon(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunDaemon() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon didn't stop")
+	}
+	if _, err := os.Stat(daemonSocket()); !os.IsNotExist(err) {
+		t.Errorf("socket left behind: %v", err)
+	}
+}
+
+func TestCheckDaemonDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix permissions")
+	}
+	parent := t.TempDir()
+	private := filepath.Join(parent, "private")
+	shared := filepath.Join(parent, "shared")
+	for dir, perm := range map[string]os.FileMode{private: 0700, shared: 0755} {
+		if err := os.Mkdir(dir, perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(dir, perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(parent, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(parent, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkDaemonDir(private); err != nil {
+		t.Errorf("checkDaemonDir(private) = %v", err)
+	}
+	for _, dir := range []string{shared, link, file} {
+		if err := checkDaemonDir(dir); err == nil {
+			t.Errorf("checkDaemonDir(%s) = nil", filepath.Base(dir))
+		}
+	}
+
+	// Neither the daemon nor its clients use a socket in a directory
+	// others can get at
+	t.Setenv("CMDR_DAEMON_SOCKET", filepath.Join(shared, "d.sock"))
+	daemonUnavailable.Store(false)
+	defer daemonUnavailable.Store(false)
+	if err := RunDaemon(io.Discard); err == nil || !strings.Contains(err.Error(), "0755") {
+		t.Errorf("RunDaemon() in a shared directory: error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(shared, "d.sock")); !os.IsNotExist(err) {
+		t.Errorf("RunDaemon() created a socket in a shared directory: %v", err)
+	}
+	if err := RequireDaemon(); err == nil || !strings.Contains(err.Error(), "not using the daemon socket") {
+		t.Errorf("RequireDaemon() in a shared directory: error = %v", err)
+	}
+}
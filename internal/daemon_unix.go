@@ -0,0 +1,40 @@
+//go:build !windows
+
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkDaemonDir returns an error unless dir is a directory, not a symlink
+// to one, that belongs to the user and that only they can use, so that no
+// one else can replace the daemon's socket or connect to it
+func checkDaemonDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s isn't a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s belongs to another user", dir)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("%s has mode %#o, not 0700", dir, perm)
+	}
+	return nil
+}
+
+// listenPrivate listens on a unix socket at path that only the user can
+// connect to, from the moment it exists
+func listenPrivate(path string) (net.Listener, error) {
+	old := unix.Umask(0077)
+	defer unix.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// checkDaemonDir returns an error unless dir is a directory. Windows
+// controls access with the ACLs the directory inherits.
+func checkDaemonDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s isn't a directory", dir)
+	}
+	return nil
+}
+
+// listenPrivate listens on a unix socket at path
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,353 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// dashboardRefresh is how often the dashboard redraws and samples usage
+const dashboardRefresh = time.Second
+
+// groupDashboard is a full-screen view of a running group: each process's
+// status and resource use, and the output of the selected process
+type groupDashboard struct {
+	name     string
+	run      *groupRun
+	terminal *TerminalManager
+	sampler  *usageSampler
+	usage    map[int]resourceUsage
+
+	selected int
+	viewLogs bool   // show the selected process's output full-screen
+	message  string // status line shown under the table
+	stopped  bool   // the group has shut down
+}
+
+// runGroupDashboard runs the group under the dashboard. The group stops when
+// the user quits or when a process exits; after a failure the dashboard stays
+// open so that the failed process's output can be read.
+func (r *CommandRunner) runGroupDashboard(name string, group *GroupConfig, dir string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("--dashboard requires a terminal")
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	run, ctx, err := r.startGroup(group, dir, io.Discard)
+	if err != nil {
+		return err
+	}
+	d := &groupDashboard{
+		name:     name,
+		run:      run,
+		terminal: NewTerminalManager(),
+		sampler:  newUsageSampler(r.executor),
+	}
+	if err := d.terminal.SetRawMode(); err != nil {
+		_ = run.stop()
+		return err
+	}
+	fmt.Print("\033[?1049h\033[?25l") // alternate screen, hide cursor
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		_ = d.terminal.RestoreMode()
+	}()
+
+	keys := readKeys(os.Stdin)
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+	stopped := make(chan error, 1)
+	var stopErr error
+	stopping := false
+	stop := func() {
+		if !stopping {
+			stopping = true
+			d.message = "stopping…"
+			go func() { stopped <- run.stop() }()
+		}
+	}
+
+	d.render()
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			if d.handleKey(key, stop) {
+				return stopErr
+			}
+		case <-interrupt:
+			stop()
+		case <-ctx.Done():
+			stop()
+		case stopErr = <-stopped:
+			d.stopped = true
+			if stopErr == nil {
+				return nil
+			}
+			d.message = fmt.Sprintf("stopped: %v — press q to exit", stopErr)
+		case <-ticker.C:
+		}
+		d.render()
+	}
+}
+
+// handleKey applies a key press, returning true when the dashboard should
+// close
+func (d *groupDashboard) handleKey(key string, stop func()) bool {
+	p := d.run.processes[d.run.order[d.selected]]
+	switch key {
+	case "q", "ctrl-c":
+		if d.stopped {
+			return true
+		}
+		if d.viewLogs && key == "q" {
+			d.viewLogs = false
+			return false
+		}
+		stop()
+	case "esc":
+		d.viewLogs = false
+	case "up", "k":
+		d.selected = (d.selected + len(d.run.order) - 1) % len(d.run.order)
+	case "down", "j":
+		d.selected = (d.selected + 1) % len(d.run.order)
+	case "l", "enter":
+		d.viewLogs = !d.viewLogs
+	case "r":
+		if d.stopped {
+			return false
+		}
+		go func() {
+			if err := d.run.restart(p); err != nil {
+				d.run.output.logf(p.name, "can't restart: %v", err)
+			}
+		}()
+	}
+	return false
+}
+
+// readKeys reports key presses, naming arrow keys and control characters
+func readKeys(in io.Reader) <-chan string {
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- keyName(buf[:n])
+		}
+	}()
+	return keys
+}
+
+func keyName(input []byte) string {
+	switch string(input) {
+	case "\x1b[A", "\x1bOA", "\x10":
+		return "up"
+	case "\x1b[B", "\x1bOB", "\x0e":
+		return "down"
+	case "\x7f", "\b":
+		return "backspace"
+	case "\x15":
+		return "ctrl-u"
+	case "\x1b":
+		return "esc"
+	case "\r", "\n":
+		return "enter"
+	case "\x03":
+		return "ctrl-c"
+	}
+	return string(input)
+}
+
+// render redraws the whole screen
+func (d *groupDashboard) render() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	var screen []string
+	if d.viewLogs {
+		screen = d.renderLogs(height)
+	} else {
+		screen = d.renderTable(height)
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, line := range screen {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(truncateLine(line, width))
+		b.WriteString("\033[K")
+	}
+	b.WriteString("\033[J")
+	fmt.Print(b.String())
+}
+
+// renderTable lists the processes, followed by the selected process's
+// latest output
+func (d *groupDashboard) renderTable(height int) []string {
+	pids := d.run.pids()
+	pgids := []int{}
+	for _, name := range sortCommands(pids) {
+		pgids = append(pgids, pids[name])
+	}
+	d.usage = d.sampler.sample(pgids)
+
+	title := fmt.Sprintf("%s\033[1mcmdr %s\033[0m", d.run.runner.badge(), d.name)
+	width := max(d.run.output.width, len("PROCESS"))
+	lines := []string{title, "", fmt.Sprintf("  %-*s  %-11s %7s %7s %8s  %s", width, "PROCESS", "STATUS", "PID", "CPU", "MEM", "URL")}
+	for i, name := range d.run.order {
+		p := d.run.processes[name]
+		pid, cpu, mem := "-", "-", "-"
+		if n, ok := pids[name]; ok {
+			pid = fmt.Sprint(n)
+			if usage, ok := d.usage[n]; ok {
+				cpu = fmt.Sprintf("%.1f%%", usage.cpu)
+				mem = formatBytes(usage.memory)
+			}
+		}
+		status := d.run.status(p)
+		url := ""
+		if isClosed(p.urlFound) {
+			url = p.url
+		}
+		row := fmt.Sprintf("%-*s  %-11s %7s %7s %8s  %s", width, name, status, pid, cpu, mem, url)
+		if i == d.selected {
+			row = "\033[7m▸ " + row + "\033[0m"
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+
+	lines = append(lines, "", d.message)
+	selected := d.run.order[d.selected]
+	lines = append(lines, fmt.Sprintf("── %s ──", selected))
+	footer := "↑/↓ select  r restart  l logs  q stop group"
+	if d.stopped {
+		footer = "↑/↓ select  l logs  q exit"
+	}
+	available := height - len(lines) - 2
+	if available > 0 {
+		lines = append(lines, d.run.output.recent(selected, available)...)
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	return append(lines, footer)
+}
+
+// renderLogs shows as much of the selected process's output as fits
+func (d *groupDashboard) renderLogs(height int) []string {
+	selected := d.run.order[d.selected]
+	lines := []string{fmt.Sprintf("\033[1m%s\033[0m  %s", selected, d.run.status(d.run.processes[selected]))}
+	lines = append(lines, d.run.output.recent(selected, max(height-2, 0))...)
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	return append(lines, "esc/l back  r restart  ↑/↓ other process")
+}
+
+// truncateLine shortens a line to the terminal width, ignoring escape
+// sequences when measuring it
+func truncateLine(line string, width int) string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			if loc := ansiEscapePattern.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+				b.WriteString(line[i : i+loc[1]])
+				i += loc[1]
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if visible >= width {
+			break
+		}
+		if r >= ' ' {
+			b.WriteRune(r)
+			visible++
+		}
+		i += size
+	}
+	return b.String() + "\033[0m"
+}
+
+// status describes the state of a process for display, with the number of
+// times it has been restarted
+func (g *groupRun) status(p *groupProcess) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status := g.statusLocked(p)
+	if p.restarts > 0 {
+		status += fmt.Sprintf(" ↻%d", p.restarts)
+	}
+	return status
+}
+
+func (g *groupRun) statusLocked(p *groupProcess) string {
+	switch {
+	case isClosed(p.exited):
+		switch {
+		case p.cmd == nil:
+			return "not started"
+		case p.err == nil && g.oneShot[p.name]:
+			return "completed"
+		case g.stopping:
+			return "stopped"
+		case p.err == nil:
+			return "exited"
+		default:
+			return "failed"
+		}
+	case p.restarting:
+		return "restarting"
+	case p.cmd == nil:
+		return "waiting"
+	case isClosed(p.running):
+		return "exited"
+	case g.stopping:
+		return "stopping"
+	case p.config.Ready == nil:
+		return "running"
+	case isClosed(p.healthy):
+		return "ready"
+	default:
+		return "starting"
+	}
+}
+
+// pids returns the process ids of the running processes, which are also
+// their process group ids
+func (g *groupRun) pids() map[string]int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pids := make(map[string]int)
+	for name, p := range g.processes {
+		if p.cmd != nil && !isClosed(p.running) {
+			pids[name] = p.cmd.Process.Pid
+		}
+	}
+	return pids
+}
@@ -0,0 +1,38 @@
+package internal
+
+import "testing"
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		width    int
+		expected string
+	}{
+		{"hello", 10, "hello\033[0m"},
+		{"hello world", 5, "hello\033[0m"},
+		{"\033[1mbold\033[0m text", 6, "\033[1mbold\033[0m t\033[0m"},
+		{"▸ café", 4, "▸ ca\033[0m"},
+		{"a\tb", 10, "a    b\033[0m"},
+	}
+	for _, tt := range tests {
+		if got := truncateLine(tt.line, tt.width); got != tt.expected {
+			t.Errorf("truncateLine(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.expected)
+		}
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	tests := map[string]string{
+		"\x1b[A": "up",
+		"\x1b[B": "down",
+		"\x1b":   "esc",
+		"\r":     "enter",
+		"\x03":   "ctrl-c",
+		"r":      "r",
+	}
+	for input, expected := range tests {
+		if got := keyName([]byte(input)); got != expected {
+			t.Errorf("keyName(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
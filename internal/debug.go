@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugLog receives resolution logging (--debug), or is nil
+var debugLog struct {
+	sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+// SetDebugLog starts logging how commands resolve to w: each file sniffed,
+// each source checked and why it didn't match, and each command run to list
+// a source's commands, with its timing
+func SetDebugLog(w io.Writer) {
+	debugLog.Lock()
+	defer debugLog.Unlock()
+	debugLog.w = w
+	debugLog.start = time.Now()
+}
+
+// debugEnabled reports whether --debug is logging
+func debugEnabled() bool {
+	debugLog.Lock()
+	defer debugLog.Unlock()
+	return debugLog.w != nil
+}
+
+// debugf writes a line to the debug log, prefixed with the time since
+// logging started
+func debugf(format string, args ...any) {
+	debugLog.Lock()
+	defer debugLog.Unlock()
+	if debugLog.w == nil {
+		return
+	}
+	elapsed := float64(time.Since(debugLog.start).Microseconds()) / 1000
+	fmt.Fprintf(debugLog.w, "debug %8.1fms %s\n", elapsed, fmt.Sprintf(format, args...))
+}
+
+// listOutput runs a command that lists a source's commands and returns its
+// output, logging the command, its result, and how long it took
+func listOutput(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.Output()
+	if debugEnabled() {
+		result := fmt.Sprintf("%d bytes", len(output))
+		if err != nil {
+			result = err.Error()
+		}
+		debugf("exec %s (in %s): %s, %s", strings.Join(cmd.Args, " "), cmd.Dir, result, time.Since(start).Round(time.Microsecond))
+	}
+	return output, err
+}
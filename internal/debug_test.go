@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("build:\n\techo build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	SetDebugLog(&log)
+	t.Cleanup(func() { SetDebugLog(nil) })
+
+	runner := &CommandRunner{Command: "build", CurrentDir: dir, ProjectRoot: dir}
+	if _, err := runner.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"sniff " + filepath.Join(dir, "Makefile") + ": found",
+		"sniff " + filepath.Join(dir, "go.mod") + ": absent",
+		"project " + dir + ": sources make",
+		"check build in " + dir + ": make matches: make build",
+	} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("debug log is missing %q:\n%s", want, log.String())
+		}
+	}
+}
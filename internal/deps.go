@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// confirmUpdate asks before a package manager's default update command
+// runs, since it rewrites lockfiles and manifests
+func (r *CommandRunner) confirmUpdate(cmd *exec.Cmd, source CommandSource) error {
+	if r.Yes {
+		return nil
+	}
+	command := strings.Join(cmd.Args, " ")
+	if r.sharesTerminal() {
+		return fmt.Errorf("'%s' changes dependency files; run it from the shell to confirm, or use --yes", command)
+	}
+	if !r.prompt().isTerminal() {
+		return fmt.Errorf("'%s' changes dependency files; use --yes to run it without a terminal", command)
+	}
+	if !r.prompt().confirm(fmt.Sprintf("Run '%s' to update %s dependencies?", command, source.Name())) {
+		return fmt.Errorf("cancelled")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDependencyCommands(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"go.mod":       "module x\n",
+		"Cargo.toml":   "[package]\nname = \"x\"\n",
+		"package.json": `{"scripts": {}}`,
+		".yarnrc.yml":  "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		source  CommandSource
+		command string
+		want    []string
+	}{
+		{NewGoSource(dir), "outdated", []string{"go", "list", "-u", "-m", "all"}},
+		{NewGoSource(dir), "update", []string{"go", "get", "-u", "./..."}},
+		{NewCargoSource(dir), "deps", []string{"cargo", "update", "--dry-run"}},
+		{NewCargoSource(dir), "upgrade", []string{"cargo", "update"}},
+		{NewNpmSource(dir), "outdated", []string{"npm", "outdated"}},
+		{NewNpmSource(dir), "update", []string{"npm", "update"}},
+		{NewYarnSource(dir), "update", []string{"yarn", "up", "*"}},
+		{NewYarnSource(dir), "outdated", nil},
+	} {
+		cmd := tc.source.FindCommand(tc.command, nil)
+		var got []string
+		if cmd != nil {
+			got = cmd.Args
+		}
+		if !slicesEqual(got, tc.want) {
+			t.Errorf("%s FindCommand(%s) = %v, want %v", tc.source.Name(), tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestConfirmUpdate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := NewNpmSource(dir)
+	cmd := source.FindCommand("update", nil)
+
+	// Tests run without a terminal, so a default update is refused
+	runner := &CommandRunner{Command: "update"}
+	if err := runner.guardDefault(cmd, source); err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("confirmUpdate() = %v, want a hint to use --yes", err)
+	}
+	// Nor is one run from the menu, which can't prompt while it has the
+	// terminal
+	runner.output = newPrefixedOutput(io.Discard, []string{"update"}).writer("update")
+	if err := runner.guardDefault(cmd, source); err == nil || !strings.Contains(err.Error(), "from the shell") {
+		t.Errorf("confirmUpdate() from the menu = %v, want a hint to run it from the shell", err)
+	}
+	runner.Yes = true
+	if err := runner.guardDefault(cmd, source); err != nil {
+		t.Errorf("confirmUpdate() with Yes = %v", err)
+	}
+
+	// Other commands don't ask
+	runner = &CommandRunner{Command: "outdated"}
+	if err := runner.guardDefault(cmd, source); err != nil {
+		t.Errorf("confirmUpdate() for outdated = %v", err)
+	}
+
+	// Nor does the project's own update task, here an upgrade script
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"upgrade": "ncu -u"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner = &CommandRunner{Command: "update"}
+	if err := runner.guardDefault(source.FindCommand("update", nil), source); err != nil {
+		t.Errorf("confirmUpdate() for a script = %v", err)
+	}
+}
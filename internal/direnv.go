@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// direnvCache holds the variables direnv exports for each .envrc, so that
+// it is evaluated once per run. An .envrc that can't be loaded is reported
+// once and cached as nil.
+var direnvCache = struct {
+	sync.Mutex
+	data map[string]map[string]string
+}{data: make(map[string]map[string]string)}
+
+// useDirenv reports whether commands get the environment of an allowed
+// .envrc; the nearest config that sets direnv decides
+func (r *CommandRunner) useDirenv() bool {
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.Direnv != nil {
+			return *config.Direnv
+		}
+	}
+	return true
+}
+
+// direnvLayer returns the variables direnv exports for the .envrc in the
+// nearest of the current directory and project root, or nil if there is
+// none, direnv isn't installed, or the .envrc isn't allowed. A runner that
+// mustn't start processes, as for Plan, goes without.
+func (r *CommandRunner) direnvLayer() *envLayer {
+	if analyzeOnly || (r.executor != nil && r.executor.static) || !r.useDirenv() {
+		return nil
+	}
+	for _, dir := range r.searchDirs() {
+		path := filepath.Join(dir, ".envrc")
+		if !FileExists(path) {
+			continue
+		}
+		if vars := r.direnvExport(path); len(vars) > 0 {
+			return &envLayer{source: r.displayPath(path) + " (direnv)", vars: vars}
+		}
+		return nil
+	}
+	return nil
+}
+
+// direnvExport runs direnv export json next to the .envrc at path. direnv
+// refuses an .envrc that hasn't been allowed, and exports nothing when the
+// environment already has it loaded, as in a shell with direnv's hook.
+func (r *CommandRunner) direnvExport(path string) map[string]string {
+	direnvCache.Lock()
+	defer direnvCache.Unlock()
+	if vars, ok := direnvCache.data[path]; ok {
+		return vars
+	}
+	vars, err := r.runDirenvExport(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", r.displayPath(path), err)
+	}
+	direnvCache.data[path] = vars
+	return vars
+}
+
+// runDirenvExport is direnvExport without the cache
+func (r *CommandRunner) runDirenvExport(path string) (map[string]string, error) {
+	if _, err := r.executor.lookPath("direnv"); err != nil {
+		debugf("direnv isn't installed; not loading %s", path)
+		return nil, nil
+	}
+	cmd := r.executor.command("direnv", "export", "json")
+	cmd.Dir = filepath.Dir(path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := outputWithin(cmd, time.Minute)
+	if err != nil {
+		// direnv explains a blocked .envrc in the last line it writes
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if message := strings.TrimPrefix(lines[len(lines)-1], "direnv: "); message != "" {
+			return nil, fmt.Errorf("direnv: %s", message)
+		}
+		return nil, fmt.Errorf("direnv: %w", err)
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, nil
+	}
+	var exported map[string]*string
+	if err := json.Unmarshal(output, &exported); err != nil {
+		return nil, fmt.Errorf("direnv export json: %w", err)
+	}
+	vars := make(map[string]string, len(exported))
+	for name, value := range exported {
+		// A layer can't unset a variable, and direnv's own bookkeeping
+		// would only make a nested direnv think the .envrc is loaded
+		if value == nil || strings.HasPrefix(name, "DIRENV_") {
+			continue
+		}
+		vars[name] = *value
+	}
+	return vars, nil
+}
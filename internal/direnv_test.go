@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDirenvLayer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("PATH", "/usr/bin")
+	newRunner := func(dir, script string) *CommandRunner {
+		factory := func(name string, arg ...string) *exec.Cmd {
+			if name == "direnv" {
+				return exec.Command("sh", "-c", script)
+			}
+			return exec.Command("false")
+		}
+		lookPath := func(file string) (string, error) {
+			if file == "direnv" {
+				return "/usr/bin/direnv", nil
+			}
+			return "", errors.New("not found")
+		}
+		runner := New("", nil, WithCommandFactory(factory), WithLookPath(lookPath))
+		runner.CurrentDir, runner.ProjectRoot = dir, dir
+		return runner
+	}
+	writeEnvrc := func(dir string) {
+		if err := os.WriteFile(filepath.Join(dir, ".envrc"), []byte("export FOO=bar\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	writeEnvrc(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "node_modules", ".bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runner := newRunner(dir, `echo '{"FOO":"bar","PATH":"/envrc/bin:/usr/bin","OLD":null,"DIRENV_DIFF":"eJy"}'`)
+	got := make(map[string]EnvVar)
+	for _, v := range runner.ResolveEnv(false) {
+		got[v.Name] = v
+	}
+	if v := got["FOO"]; v.Value != "bar" || v.Source != ".envrc (direnv)" {
+		t.Errorf("FOO = %+v, want bar from .envrc (direnv)", v)
+	}
+	// The project's tool directories go in front of the PATH direnv sets
+	if want := filepath.Join(dir, "node_modules", ".bin") + ":/envrc/bin:/usr/bin"; got["PATH"].Value != want {
+		t.Errorf("PATH = %q, want %q", got["PATH"].Value, want)
+	}
+	for _, name := range []string{"OLD", "DIRENV_DIFF"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("ResolveEnv() includes %s", name)
+		}
+	}
+
+	// A blocked .envrc, or direnv = false, adds nothing
+	dir = t.TempDir()
+	writeEnvrc(dir)
+	runner = newRunner(dir, `echo 'direnv: error .envrc is blocked. Run `+"`direnv allow`"+` to approve its content' >&2; exit 1`)
+	if layer := runner.direnvLayer(); layer != nil {
+		t.Errorf("direnvLayer() for a blocked .envrc = %+v", layer)
+	}
+	if _, err := runner.runDirenvExport(filepath.Join(dir, ".envrc")); err == nil || !strings.Contains(err.Error(), "is blocked") {
+		t.Errorf("runDirenvExport() error = %v, want direnv's message", err)
+	}
+
+	dir = t.TempDir()
+	writeEnvrc(dir)
+	writeConfig(t, dir, "direnv = false\n")
+	runner = newRunner(dir, `echo '{"FOO":"bar"}'`)
+	if layer := runner.direnvLayer(); layer != nil {
+		t.Errorf("direnvLayer() with direnv = false = %+v", layer)
+	}
+}
@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// diskCacheVersion changes when listings are computed differently, so that
+// listings cached by an older cmdr are ignored
+const diskCacheVersion = 1
+
+// miseTaskDirs are the directories below a project that hold mise file tasks
+var miseTaskDirs = []string{".mise/tasks", "mise-tasks", ".mise-tasks", ".config/mise/tasks"}
+
+// CacheDir returns the directory listings are cached in, in
+// $XDG_CACHE_HOME/cmdr (by default ~/.cache/cmdr), or "" if cache = false
+// in the user config
+func CacheDir() string {
+	if config := LoadUserConfig(); config != nil && config.Cache != nil && !*config.Cache {
+		return ""
+	}
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "cmdr")
+}
+
+// diskCacheEntry is a cached listing, valid while the files it was listed
+// from are unchanged
+type diskCacheEntry struct {
+	Version     int                    `json:"version"`
+	Key         string                 `json:"key"`
+	Fingerprint string                 `json:"fingerprint"`
+	Commands    map[string]CommandInfo `json:"commands"`
+}
+
+// diskCachePath returns the file a listing is cached in
+func diskCachePath(key string) string {
+	dir := CacheDir()
+	if dir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, "commands", hex.EncodeToString(sum[:12])+".json")
+}
+
+// readDiskCache returns a cached listing if its files haven't changed
+func readDiskCache(key, fingerprint string) (map[string]CommandInfo, bool) {
+	path := diskCachePath(key)
+	if path == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry diskCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if entry.Version != diskCacheVersion || entry.Key != key || entry.Fingerprint != fingerprint {
+		debugf("cache %s: stale", key)
+		return nil, false
+	}
+	return entry.Commands, true
+}
+
+// writeDiskCache caches a listing. The cache is only an optimization, so
+// failing to write it is logged but otherwise ignored.
+func writeDiskCache(key, fingerprint string, commands map[string]CommandInfo) {
+	path := diskCachePath(key)
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(diskCacheEntry{Version: diskCacheVersion, Key: key, Fingerprint: fingerprint, Commands: commands})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err == nil {
+		// Write then rename, so that a concurrent cmdr never reads half a file
+		temp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+		if err = os.WriteFile(temp, data, 0600); err == nil {
+			err = os.Rename(temp, path)
+		}
+		if err != nil {
+			os.Remove(temp)
+		}
+	}
+	if err != nil {
+		debugf("cache %s: %v", key, err)
+	}
+}
+
+// ClearCache removes the cached listings
+func ClearCache() error {
+	dir := CacheDir()
+	if dir == "" {
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(dir, "commands"))
+}
+
+// listingFingerprint summarizes the names, sizes, and modification times of
+// the files a directory's listings come from: the files in the directory, in
+// its mise task directories, and those its task files include. Other
+// subdirectories, such as .git and node_modules, change too often to count.
+func listingFingerprint(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var lines []string
+	add := func(name string, info fs.FileInfo) {
+		lines = append(lines, fmt.Sprintf("%s %d %d", name, info.Size(), info.ModTime().UnixNano()))
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			add(entry.Name(), info)
+		}
+	}
+	roots := make([]string, 0, len(miseTaskDirs))
+	for _, tasksDir := range miseTaskDirs {
+		roots = append(roots, filepath.Join(dir, tasksDir))
+	}
+	for _, root := range append(roots, listingIncludes(dir)...) {
+		_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				rel, _ := filepath.Rel(dir, path)
+				add(filepath.ToSlash(rel), info)
+			}
+			return nil
+		})
+	}
+	sort.Strings(lines)
+	data, _ := json.Marshal(lines)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	justImportPattern = regexp.MustCompile(`^import\??\s+['"]([^'"]+)['"]`)
+	justModPattern    = regexp.MustCompile(`^mod\??\s+([\w-]+)(?:\s+['"]([^'"]+)['"])?`)
+)
+
+// listingIncludes returns the files and directories, other than those at
+// the top of dir, that its task files pull in: a justfile's imports and
+// modules, a Makefile's includes, followed through the files they name, and
+// mise's task_config includes
+func listingIncludes(dir string) []string {
+	var includes []string
+	seen := make(map[string]bool)
+	var follow func(path string, parse func(string) []string)
+	follow = func(path string, parse func(string) []string) {
+		path = filepath.Clean(path)
+		if seen[path] || !FileExists(path) {
+			return
+		}
+		seen[path] = true
+		if filepath.Dir(path) != filepath.Clean(dir) {
+			includes = append(includes, path)
+		}
+		for _, included := range parse(path) {
+			follow(included, parse)
+		}
+	}
+	for _, name := range []string{"justfile", "Justfile", ".justfile"} {
+		follow(filepath.Join(dir, name), justIncludes)
+	}
+	for _, name := range []string{"Makefile", "makefile", "GNUmakefile"} {
+		follow(filepath.Join(dir, name), func(path string) []string { return makeIncludes(dir, path) })
+	}
+	for _, name := range []string{".mise.toml", "mise.toml"} {
+		var config struct {
+			TaskConfig struct {
+				Includes []string `toml:"includes"`
+			} `toml:"task_config"`
+		}
+		if _, err := toml.DecodeFile(filepath.Join(dir, name), &config); err != nil {
+			continue
+		}
+		for _, include := range config.TaskConfig.Includes {
+			if path := filepath.Join(dir, filepath.FromSlash(include)); !seen[path] && FileExists(path) {
+				seen[path] = true
+				includes = append(includes, path)
+			}
+		}
+	}
+	return includes
+}
+
+// justIncludes returns the files a justfile imports, and those of its
+// modules, relative to its directory
+func justIncludes(path string) []string {
+	base := filepath.Dir(path)
+	var files []string
+	for _, line := range readLines(path) {
+		line = strings.TrimSpace(line)
+		if match := justImportPattern.FindStringSubmatch(line); match != nil {
+			files = append(files, filepath.Join(base, filepath.FromSlash(match[1])))
+		} else if match := justModPattern.FindStringSubmatch(line); match != nil {
+			if match[2] != "" {
+				modPath := filepath.Join(base, filepath.FromSlash(match[2]))
+				if info, err := os.Stat(modPath); err == nil && info.IsDir() {
+					files = append(files, justModuleFiles(modPath)...)
+				} else {
+					files = append(files, modPath)
+				}
+				continue
+			}
+			files = append(files, filepath.Join(base, match[1]+".just"))
+			files = append(files, justModuleFiles(filepath.Join(base, match[1]))...)
+		}
+	}
+	return files
+}
+
+// justModuleFiles are the files just looks for a module in, in its
+// directory
+func justModuleFiles(dir string) []string {
+	var files []string
+	for _, name := range []string{"mod.just", "justfile", "Justfile", ".justfile"} {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// makeIncludes returns the files a makefile includes. make reads them
+// relative to the directory it runs in, dir; includes that use variables
+// are skipped.
+func makeIncludes(dir, path string) []string {
+	var files []string
+	for _, line := range readLines(path) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || (fields[0] != "include" && fields[0] != "-include" && fields[0] != "sinclude") {
+			continue
+		}
+		for _, pattern := range fields[1:] {
+			if strings.Contains(pattern, "$") {
+				continue
+			}
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(dir, pattern)
+			}
+			matches, _ := filepath.Glob(pattern)
+			files = append(files, matches...)
+		}
+	}
+	return files
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	makefile := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(makefile, []byte("build:\n\tgo build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := NewMakeSource(dir).(*MakeSource)
+	if commands := source.ListCommands(); len(commands) != 1 {
+		t.Fatalf("ListCommands() = %v, want build", commands)
+	}
+
+	// A later run reads the listing from disk
+	key := source.cacheKey()
+	cached, ok := readDiskCache(key, listingFingerprint(dir))
+	if !ok || cached["build"].Execution != "make build" {
+		t.Fatalf("readDiskCache() = %v, %v; want build", cached, ok)
+	}
+
+	// Editing the Makefile invalidates it
+	if err := os.WriteFile(makefile, []byte("build:\n\tgo build\ntest:\n\tgo test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(makefile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readDiskCache(key, listingFingerprint(dir)); ok {
+		t.Error("readDiskCache() hit after the Makefile changed")
+	}
+	forgetCachedCommands(key)
+	if commands := source.ListCommands(); len(commands) != 2 {
+		t.Errorf("after an edit, ListCommands() = %v, want build and test", commands)
+	}
+
+	// cache = false turns the cache off
+	setUserConfig(t, "cache = false\n")
+	if dir := CacheDir(); dir != "" {
+		t.Errorf("CacheDir() = %q with cache = false", dir)
+	}
+}
+
+func TestListingFingerprintIncludes(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	files := map[string]string{
+		"justfile":           "import 'ci/x.just'\nmod deploy\n\nbuild:\n\tgo build\n",
+		"ci/x.just":          "import 'more.just'\n\nlint:\n\tgolangci-lint run\n",
+		"ci/more.just":       "fmt:\n\tgofmt -w .\n",
+		"deploy/mod.just":    "prod:\n\t./deploy.sh\n",
+		"Makefile":           "include mk/*.mk\n-include $(CONFIG)\n",
+		"mk/test.mk":         "test:\n\tgo test\n",
+		".mise.toml":         "[task_config]\nincludes = [\"tasks.toml\", \"scripts/tasks\"]\n",
+		"tasks.toml":         "[hello]\nrun = \"echo hello\"\n",
+		"scripts/tasks/bye":  "#!/bin/sh\necho bye\n",
+		"unrelated/notes.md": "not a task file\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, path := range listingIncludes(dir) {
+		rel, _ := filepath.Rel(dir, path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"ci/x.just", "ci/more.just", "deploy/mod.just", "mk/test.mk", "tasks.toml", "scripts/tasks"}
+	if !slicesEqual(got, want) {
+		t.Errorf("listingIncludes() = %q, want %q", got, want)
+	}
+
+	// Editing a file the justfile imports invalidates the cached listing
+	key := NewJustSource(dir).(*JustSource).cacheKey()
+	writeDiskCache(key, listingFingerprint(dir), map[string]CommandInfo{"build": {Execution: "just build"}})
+	if _, ok := readDiskCache(key, listingFingerprint(dir)); !ok {
+		t.Fatal("readDiskCache() missed before any edit")
+	}
+	imported := filepath.Join(dir, "ci", "x.just")
+	if err := os.WriteFile(imported, []byte("lint:\n\tgolangci-lint run\n\ncheck:\n\tgo vet\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(imported, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readDiskCache(key, listingFingerprint(dir)); ok {
+		t.Error("readDiskCache() hit after an imported justfile changed")
+	}
+}
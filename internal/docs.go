@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CommandDoc describes where and how a command is defined
+type CommandDoc struct {
+	File    string   // Definition file, relative to the project directory
+	Line    int      // 1-based line of the definition
+	Snippet string   // Definition text (recipe, rule, or script line)
+	Params  []string // Declared parameters, if the source supports them
+}
+
+// documentedSource is implemented by sources that can show a command's definition
+type documentedSource interface {
+	DescribeCommand(command string) *CommandDoc
+}
+
+// findListedCommand returns the source that lists command (or one of its
+// variants) and the name it is listed under
+func (r *CommandRunner) findListedCommand(command string) (*Project, CommandSource, string) {
+	for _, project := range r.projects() {
+		for _, source := range project.CommandSources {
+			commands := source.ListCommands()
+			for _, variant := range GetCommandVariants(command) {
+				if _, ok := commands[variant]; ok {
+					return project, source, variant
+				}
+			}
+		}
+	}
+	return nil, nil, ""
+}
+
+// ShowCommandHelp prints detailed documentation for a single command
+func (r *CommandRunner) ShowCommandHelp(command string) error {
+	project, source, name := r.findListedCommand(command)
+	if source == nil {
+		return r.commandNotFound(command)
+	}
+	info := source.ListCommands()[name]
+
+	fmt.Printf("%s (%s)\n", name, source.Name())
+	if info.Description != "" && info.Description != name {
+		fmt.Printf("  %s\n", info.Description)
+	}
+	fmt.Println()
+	fmt.Printf("Runs: %s\n", info.Execution)
+	if rel, err := filepath.Rel(r.CurrentDir, project.Dir); err == nil && rel != "." {
+		fmt.Printf("Directory: %s\n", rel)
+	}
+	if stats := r.runStats(name); stats != "" {
+		fmt.Println(stats)
+	}
+
+	if documented, ok := source.(documentedSource); ok {
+		if doc := documented.DescribeCommand(name); doc != nil {
+			if len(doc.Params) > 0 {
+				fmt.Printf("Parameters: %s\n", strings.Join(doc.Params, " "))
+			}
+			fmt.Printf("\nDefined in %s:%d\n", doc.File, doc.Line)
+			for _, line := range strings.Split(strings.TrimRight(doc.Snippet, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// readLines returns the lines of a file, or nil if it can't be read
+func readLines(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
+}
+
+// indentedBlock returns the header line at start plus the indented lines
+// that follow it
+func indentedBlock(lines []string, start int) string {
+	block := []string{lines[start]}
+	for _, line := range lines[start+1:] {
+		if line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			break
+		}
+		block = append(block, line)
+	}
+	return strings.TrimRight(strings.Join(block, "\n"), "\n ")
+}
+
+// tomlTableBlock finds a [header] table and returns it with its contents,
+// up to the next table header
+func tomlTableBlock(path string, header string) *CommandDoc {
+	lines := readLines(path)
+	for i, line := range lines {
+		if strings.TrimSpace(line) != header {
+			continue
+		}
+		block := []string{line}
+		for _, next := range lines[i+1:] {
+			if strings.HasPrefix(strings.TrimSpace(next), "[") {
+				break
+			}
+			block = append(block, next)
+		}
+		return &CommandDoc{
+			File:    filepath.Base(path),
+			Line:    i + 1,
+			Snippet: strings.TrimRight(strings.Join(block, "\n"), "\n "),
+		}
+	}
+	return nil
+}
+
+// keyLine finds the first line defining key (as `key =` or `"key":`) after
+// the line containing section, for one-line definitions in TOML or JSON
+func keyLine(path string, section string, pattern *regexp.Regexp) *CommandDoc {
+	lines := readLines(path)
+	inSection := section == ""
+	for i, line := range lines {
+		if !inSection {
+			inSection = strings.Contains(line, section)
+			continue
+		}
+		if pattern.MatchString(line) {
+			return &CommandDoc{
+				File:    filepath.Base(path),
+				Line:    i + 1,
+				Snippet: strings.TrimSpace(line),
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MakeSource) DescribeCommand(command string) *CommandDoc {
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(command) + `\s*:([^=]|$)`)
+	for _, mf := range []string{"Makefile", "makefile"} {
+		lines := readLines(filepath.Join(m.dir, mf))
+		for i, line := range lines {
+			if pattern.MatchString(line) {
+				return &CommandDoc{File: mf, Line: i + 1, Snippet: indentedBlock(lines, i)}
+			}
+		}
+	}
+	return nil
+}
+
+// justRecipePattern matches a justfile recipe header, capturing the name and
+// its parameters
+var justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][\w-]*)((?:\s+[^:]*)?):(?:[^=]|$)`)
+
+func (j *JustSource) DescribeCommand(command string) *CommandDoc {
+	for _, jf := range []string{"justfile", "Justfile", ".justfile"} {
+		lines := readLines(filepath.Join(j.dir, jf))
+		for i, line := range lines {
+			match := justRecipePattern.FindStringSubmatch(line)
+			if match == nil || match[1] != command {
+				continue
+			}
+			return &CommandDoc{
+				File:    jf,
+				Line:    i + 1,
+				Snippet: indentedBlock(lines, i),
+				Params:  strings.Fields(match[2]),
+			}
+		}
+	}
+	return nil
+}
+
+func (n *nodeBaseSource) DescribeCommand(command string) *CommandDoc {
+	pattern := regexp.MustCompile(`^\s*"` + regexp.QuoteMeta(command) + `"\s*:`)
+	return keyLine(filepath.Join(n.dir, "package.json"), `"scripts"`, pattern)
+}
+
+func (m *CargoMakeSource) DescribeCommand(command string) *CommandDoc {
+	return tomlTableBlock(filepath.Join(m.dir, "Makefile.toml"), "[tasks."+command+"]")
+}
+
+func (p *PoeSource) DescribeCommand(command string) *CommandDoc {
+	path := filepath.Join(p.dir, "pyproject.toml")
+	if doc := tomlTableBlock(path, "[tool.poe.tasks."+command+"]"); doc != nil {
+		return doc
+	}
+	pattern := regexp.MustCompile(`^\s*"?` + regexp.QuoteMeta(command) + `"?\s*=`)
+	return keyLine(path, "[tool.poe.tasks]", pattern)
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJustDescribeCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	justfile := `version := "1.0"
+
+build:
+    cargo build
+
+# Run tests matching a filter
+test filter="" *args: build
+    cargo test {{filter}} {{args}}
+
+lint:
+    cargo clippy
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "justfile"), []byte(justfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &JustSource{baseSource: baseSource{dir: tempDir, name: "just"}}
+	doc := source.DescribeCommand("test")
+	if doc == nil {
+		t.Fatal("DescribeCommand(test) = nil")
+	}
+	if doc.File != "justfile" || doc.Line != 7 {
+		t.Errorf("DescribeCommand(test) location = %s:%d, want justfile:7", doc.File, doc.Line)
+	}
+	if !slicesEqual(doc.Params, []string{`filter=""`, "*args"}) {
+		t.Errorf("DescribeCommand(test).Params = %v", doc.Params)
+	}
+	expected := "test filter=\"\" *args: build\n    cargo test {{filter}} {{args}}"
+	if doc.Snippet != expected {
+		t.Errorf("DescribeCommand(test).Snippet = %q, want %q", doc.Snippet, expected)
+	}
+
+	if doc := source.DescribeCommand("version"); doc != nil {
+		t.Errorf("DescribeCommand(version) = %+v, want nil for an assignment", doc)
+	}
+}
+
+func TestMakeDescribeCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	makefile := "CC := gcc\n\nlint: deps\n\tgolint ./...\n\techo done\n\nother:\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte(makefile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &MakeSource{baseSource: baseSource{dir: tempDir, name: "make"}}
+	doc := source.DescribeCommand("lint")
+	if doc == nil {
+		t.Fatal("DescribeCommand(lint) = nil")
+	}
+	if doc.Line != 3 || doc.Snippet != "lint: deps\n\tgolint ./...\n\techo done" {
+		t.Errorf("DescribeCommand(lint) = %+v", doc)
+	}
+	if doc := source.DescribeCommand("CC"); doc != nil {
+		t.Errorf("DescribeCommand(CC) = %+v, want nil for a variable", doc)
+	}
+}
@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sourcePrograms names the program each source runs, for sources that can't
+// say (a missing mise or just can't list its tasks)
+var sourcePrograms = map[string]string{
+	"mise": "mise", "just": "just", "make": "make",
+	"npm": "npm", "pnpm": "pnpm", "yarn": "yarn", "bun": "bun", "Deno": "deno",
+	"Poetry": "poetry", "uv": "uv", "poe": "poe",
+	"Cargo": "cargo", "cargo-make": "cargo", "xtask": "cargo",
+	"Go": "go", "Gradle": "gradle", "Maven": "mvn",
+}
+
+// sourceRequires lists programs a source needs besides the one it runs
+var sourceRequires = map[string][]string{
+	"cargo-make": {"cargo-make"},
+}
+
+// nodeLockfiles maps lockfiles to the package manager that writes them
+var nodeLockfiles = []struct{ file, manager string }{
+	{"bun.lockb", "bun"},
+	{"bun.lock", "bun"},
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"package-lock.json", "npm"},
+}
+
+// doctorReport collects the results of the checks
+type doctorReport struct {
+	w        io.Writer
+	executor *Executor
+	problems int
+}
+
+func (d *doctorReport) ok(format string, args ...any) {
+	fmt.Fprintf(d.w, "  %s %s\n", passMark(d.w, true), fmt.Sprintf(format, args...))
+}
+
+func (d *doctorReport) warn(format string, args ...any) {
+	fmt.Fprintf(d.w, "  %s %s\n", style(d.w, styleWarning, "!"), fmt.Sprintf(format, args...))
+}
+
+func (d *doctorReport) problem(format string, args ...any) {
+	d.problems++
+	fmt.Fprintf(d.w, "  %s %s\n", passMark(d.w, false), fmt.Sprintf(format, args...))
+}
+
+// Doctor checks that the tools behind the detected command sources are
+// installed, reports their versions, and looks for configuration that is
+// likely to be a mistake. It returns an error if it found problems.
+func (r *CommandRunner) Doctor(w io.Writer) error {
+	d := &doctorReport{w: w, executor: r.executor}
+
+	configs := []string{}
+	if path := UserConfigPath(); path != "" && FileExists(path) {
+		configs = append(configs, path)
+	}
+	for _, dir := range r.searchDirs() {
+		if path := filepath.Join(dir, ProjectConfigFile); FileExists(path) {
+			configs = append(configs, path)
+		}
+	}
+	if len(configs) > 0 {
+		fmt.Fprintln(w, "Configuration")
+		for _, path := range configs {
+			var err error
+			if path == UserConfigPath() {
+				_, err = parseUserConfig(path)
+			} else if _, err = parseProjectConfig(path); err != nil {
+				err = fmt.Errorf("%w (cmdr ignores this file)", err)
+			}
+			if err != nil {
+				d.problem("%s: %v", path, err)
+			} else {
+				d.ok("%s", path)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	for i, project := range r.projects() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", project.Dir)
+		checked := 0
+		for _, source := range project.CommandSources {
+			if _, ok := sourcePrograms[source.Name()]; !ok {
+				continue
+			}
+			checked++
+			d.checkSource(project.Dir, source)
+		}
+		if checked == 0 {
+			fmt.Fprintln(w, "  (no build tools detected)")
+		}
+		for _, message := range lockfileConflicts(project.Dir) {
+			d.warn("%s", message)
+		}
+	}
+
+	if d.problems > 0 {
+		return fmt.Errorf("found %d problem(s)", d.problems)
+	}
+	fmt.Fprintln(w, "\nNo problems found")
+	return nil
+}
+
+// checkSource reports whether the programs a source runs are available
+func (d *doctorReport) checkSource(dir string, source CommandSource) {
+	name := source.Name()
+	programs := []string{sourceProgram(source)}
+	programs = append(programs, sourceRequires[name]...)
+	for _, program := range programs {
+		path := program
+		if strings.HasPrefix(program, "./") {
+			path = filepath.Join(dir, program)
+			if !FileExists(path) {
+				d.problem("%-10s %s is missing", name, program)
+				continue
+			}
+		} else if found, err := d.executor.lookPath(program); err == nil {
+			path = found
+		} else {
+			d.problem("%-10s %s is not installed or not on PATH", name, program)
+			for i, command := range d.executor.installSuggestions(program) {
+				label := "install with:"
+				if i > 0 {
+					label = "          or:"
+				}
+				fmt.Fprintf(d.w, "    %s %s\n", label, command)
+			}
+			continue
+		}
+		version := d.toolVersion(dir, path)
+		if version == "" {
+			version = path
+		}
+		d.ok("%-10s %s", name, version)
+	}
+
+	// A pinned package manager should match the installed one
+	if pinned, want := pinnedPackageManager(dir); pinned == name && want != "" && !strings.HasPrefix(sourceProgram(source), "corepack") {
+		if have := d.toolVersion(dir, pinned); have != "" && have != want {
+			d.warn("%-10s package.json pins %s@%s, but %s is installed; enable Corepack to use the pinned version", name, pinned, want, have)
+		}
+	}
+}
+
+// sourceProgram returns the program that runs a source's commands, such as
+// a Gradle wrapper or Corepack, or the tool the source is named for
+func sourceProgram(source CommandSource) string {
+	for _, command := range sortCommands(source.ListCommands()) {
+		if cmd := source.FindCommand(command, nil); cmd != nil {
+			return cmd.Args[0]
+		}
+	}
+	return sourcePrograms[source.Name()]
+}
+
+// toolVersion returns the first line of a program's version output, or ""
+func (d *doctorReport) toolVersion(dir, program string) string {
+	args := []string{"--version"}
+	if filepath.Base(program) == "go" {
+		args = []string{"version"}
+	}
+	// Run in the project, even a tool on PATH may read its config and run
+	// project code (a go.mod toolchain line, a mise config)
+	if analyzeOnly {
+		return ""
+	}
+	cmd := d.executor.command(program, args...)
+	cmd.Dir = dir
+	output, err := outputWithin(cmd, 10*time.Second)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// outputWithin runs cmd and returns its output, killing it after timeout
+func outputWithin(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	timer := time.AfterFunc(timeout, func() { _ = cmd.Process.Kill() })
+	defer timer.Stop()
+	err := cmd.Wait()
+	return output.Bytes(), err
+}
+
+// lockfileConflicts describes lockfiles in dir that disagree with each
+// other or with the package.json packageManager field
+func lockfileConflicts(dir string) []string {
+	var messages []string
+	managers := make(map[string]bool)
+	lockfiles := []string{}
+	for _, lock := range nodeLockfiles {
+		if FileExists(filepath.Join(dir, lock.file)) {
+			lockfiles = append(lockfiles, lock.file)
+			managers[lock.manager] = true
+		}
+	}
+	if len(managers) > 1 {
+		messages = append(messages, fmt.Sprintf("several package manager lockfiles (%s); cmdr uses %s", strings.Join(lockfiles, ", "), detectPackageManager(dir)))
+	}
+	if pinned, _ := pinnedPackageManager(dir); pinned != "" && len(managers) > 0 && !managers[pinned] {
+		messages = append(messages, fmt.Sprintf("package.json pins %s, but the lockfile is %s", pinned, strings.Join(lockfiles, ", ")))
+	}
+
+	if FileExists(filepath.Join(dir, "poetry.lock")) && FileExists(filepath.Join(dir, "uv.lock")) {
+		messages = append(messages, "both poetry.lock and uv.lock exist; cmdr uses Poetry")
+	}
+	if FileExists(filepath.Join(dir, "Makefile")) && FileExists(filepath.Join(dir, "makefile")) && !sameFile(filepath.Join(dir, "Makefile"), filepath.Join(dir, "makefile")) {
+		messages = append(messages, "both Makefile and makefile exist; make reads makefile")
+	}
+	return messages
+}
+
+// sameFile reports whether two paths name the same file, as they do on
+// case-insensitive file systems
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
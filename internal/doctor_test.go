@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockfileConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+	}{
+		{"none", map[string]string{"package.json": `{}`, "yarn.lock": ""}, nil},
+		{"two managers", map[string]string{"package.json": `{}`, "yarn.lock": "", "package-lock.json": "{}"}, []string{"several package manager lockfiles (yarn.lock, package-lock.json); cmdr uses yarn"}},
+		{"pin disagrees", map[string]string{"package.json": `{"packageManager": "pnpm@8.15.0"}`, "package-lock.json": "{}"}, []string{"package.json pins pnpm, but the lockfile is package-lock.json"}},
+		{"python", map[string]string{"poetry.lock": "", "uv.lock": ""}, []string{"both poetry.lock and uv.lock exist; cmdr uses Poetry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if messages := lockfileConflicts(dir); !slicesEqual(messages, tt.expected) {
+				t.Errorf("lockfileConflicts() = %q, want %q", messages, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDoctorReportsMissingTools(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "justfile"), []byte("test:\n\techo test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, "[commands\n")
+	t.Setenv("PATH", t.TempDir())
+
+	var out strings.Builder
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	err := runner.Doctor(&out)
+	if err == nil || !strings.Contains(err.Error(), "2 problem") {
+		t.Errorf("Doctor() error = %v, want 2 problems", err)
+	}
+	for _, want := range []string{"just is not installed or not on PATH", "cmdr ignores this file"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Doctor() output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// parseEnvFile reads a dotenv-style file of KEY=VALUE lines. Blank lines,
+// # comments, and an `export ` prefix are allowed. Single-quoted values are
+// literal; double-quoted values may span lines and support \n, \t, \", and
+// \\ escapes; unquoted values end at a " #" comment.
+func parseEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnv(string(data))
+}
+
+func parseEnv(content string) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", lineNumber)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// Keep reading lines until the closing quote
+			text := value[1:]
+			for {
+				if unquoted, ok := unescapeDoubleQuoted(text); ok {
+					value = unquoted
+					break
+				}
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated double quote", lineNumber)
+				}
+				lineNumber++
+				text += "\n" + scanner.Text()
+			}
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars[name] = value
+	}
+	return vars, scanner.Err()
+}
+
+// unescapeDoubleQuoted returns the contents of a double-quoted value up to
+// its closing quote, or false if the quote isn't closed
+func unescapeDoubleQuoted(text string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"':
+			return b.String(), true
+		case c == '\\' && i+1 < len(text):
+			i++
+			switch text[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(text[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(text[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// CheckEnvFile reports whether path is a readable, valid env file
+func CheckEnvFile(path string) error {
+	_, err := parseEnvFile(path)
+	return err
+}
+
+// envFileCache holds parsed env files by path. Unreadable or invalid files
+// are reported once and cached as nil.
+var envFileCache = struct {
+	sync.Mutex
+	data map[string]map[string]string
+}{data: make(map[string]map[string]string)}
+
+// loadEnvFile returns the variables in an env file, warning once if it
+// can't be read
+func loadEnvFile(path string) map[string]string {
+	envFileCache.Lock()
+	defer envFileCache.Unlock()
+	if vars, ok := envFileCache.data[path]; ok {
+		return vars
+	}
+	vars, err := parseEnvFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring env file %s: %v\n", path, err)
+	}
+	envFileCache.data[path] = vars
+	return vars
+}
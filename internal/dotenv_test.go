@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	content := `# comment
+NODE_ENV=development
+export RUST_LOG=debug
+EMPTY=
+SPACED = padded  # trailing comment
+URL=http://example.com/#anchor
+SINGLE='literal $HOME \n'
+DOUBLE="tab\there \"quoted\""
+MULTI="line one
+line two"
+`
+	vars, err := parseEnv(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"NODE_ENV": "development",
+		"RUST_LOG": "debug",
+		"EMPTY":    "",
+		"SPACED":   "padded",
+		"URL":      "http://example.com/#anchor",
+		"SINGLE":   `literal $HOME \n`,
+		"DOUBLE":   "tab\there \"quoted\"",
+		"MULTI":    "line one\nline two",
+	}
+	if len(vars) != len(expected) {
+		t.Errorf("parseEnv() = %q, want %d variables", vars, len(expected))
+	}
+	for name, value := range expected {
+		if vars[name] != value {
+			t.Errorf("parseEnv()[%s] = %q, want %q", name, vars[name], value)
+		}
+	}
+}
+
+func TestParseEnvErrors(t *testing.T) {
+	for _, content := range []string{"NO_EQUALS\n", "A='open\n", "B=\"open\nstill open\n", "BAD NAME=1\n"} {
+		if _, err := parseEnv(content); err == nil {
+			t.Errorf("parseEnv(%q) error = nil", content)
+		}
+	}
+}
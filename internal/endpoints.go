@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// ansiEscapePattern matches terminal color and cursor sequences, which dev
+// servers often wrap around the URLs they print
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// validateEndpoints checks a process's port, URL pattern, and exports
+func (p *ProcessConfig) validateEndpoints() error {
+	if p.Port != nil && (*p.Port < 0 || *p.Port > 65535) {
+		return fmt.Errorf("port %d is out of range", *p.Port)
+	}
+	if p.URLPattern != "" {
+		pattern, err := regexp.Compile(p.URLPattern)
+		if err != nil {
+			return fmt.Errorf("url_pattern: %w", err)
+		}
+		if pattern.NumSubexp() < 1 {
+			return fmt.Errorf("url_pattern must have a group that captures the URL")
+		}
+	}
+	for _, name := range sortCommands(p.Exports) {
+		var missing string
+		os.Expand(p.Exports[name], func(ref string) string {
+			if ref == "PORT" && p.Port == nil || ref == "URL" && p.URLPattern == "" {
+				missing = ref
+			}
+			return ""
+		})
+		if missing != "" {
+			return fmt.Errorf("exports.%s uses ${%s}, but the process has no %s", name, missing, map[string]string{"PORT": "port", "URL": "url_pattern"}[missing])
+		}
+	}
+	return nil
+}
+
+// allocatePort returns the configured port, or a free one if it is 0
+func allocatePort(port int) (int, error) {
+	if port != 0 {
+		return port, nil
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// expand substitutes the process's ${PORT} and ${URL}, falling back to the
+// inherited environment
+func (p *groupProcess) expand(s string) string {
+	return os.Expand(s, func(name string) string {
+		switch name {
+		case "PORT":
+			if p.port != 0 {
+				return strconv.Itoa(p.port)
+			}
+		case "URL":
+			if p.url != "" {
+				return p.url
+			}
+		}
+		return os.Getenv(name)
+	})
+}
+
+// captureURL returns a line handler that records the first URL matching the
+// process's url_pattern
+func (g *groupRun) captureURL(p *groupProcess) func(line []byte) {
+	pattern := regexp.MustCompile(p.config.URLPattern)
+	return func(line []byte) {
+		if p.url != "" {
+			return
+		}
+		match := pattern.FindSubmatch(ansiEscapePattern.ReplaceAll(line, nil))
+		if match == nil {
+			return
+		}
+		p.url = string(match[1])
+		g.output.logf(p.name, "url %s", p.url)
+		close(p.urlFound)
+	}
+}
+
+// processEnv returns the variables the group adds for a process: its own
+// PORT, and the exports of the processes it depends on
+func (g *groupRun) processEnv(p *groupProcess) map[string]string {
+	vars := make(map[string]string)
+	for _, depName := range sortCommands(p.config.DependsOn) {
+		dep := g.processes[depName]
+		for _, name := range sortCommands(dep.config.Exports) {
+			vars[name] = dep.expand(dep.config.Exports[name])
+		}
+	}
+	if p.port != 0 {
+		vars["PORT"] = strconv.Itoa(p.port)
+	}
+	return vars
+}
+
+// withEnv returns env, or the inherited environment if env is nil, with vars
+// appended so that they take precedence
+func withEnv(env []string, vars map[string]string) []string {
+	if len(vars) == 0 {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	for _, name := range sortCommands(vars) {
+		env = append(env, name+"="+vars[name])
+	}
+	return env
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvVar is an environment variable passed to child commands, along with
+// where its value came from
+type EnvVar struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// envLayer is a named set of variables. Later layers override earlier ones.
+type envLayer struct {
+	source string
+	vars   map[string]string
+}
+
+// envLayers returns the variables cmd-runner adds to the inherited
+// environment, ordered from lowest to highest precedence
+func (r *CommandRunner) envLayers() []envLayer {
+	layers := []envLayer{}
+	path := os.Getenv("PATH")
+	if layer := r.direnvLayer(); layer != nil {
+		layers = append(layers, *layer)
+		if direnvPath, ok := layer.vars["PATH"]; ok {
+			path = direnvPath
+		}
+	}
+	if layer := r.toolPathLayer(path); layer != nil {
+		layers = append(layers, *layer)
+	}
+	layers = append(layers, r.configEnvLayers()...)
+	if len(r.EnvOverrides) > 0 {
+		layers = append(layers, envLayer{source: "command line (-e)", vars: r.EnvOverrides})
+	}
+	return layers
+}
+
+// defaultDotenvFiles are loaded automatically when present, unless config
+// says otherwise
+var defaultDotenvFiles = []string{".env", ".env.local"}
+
+// configEnvLayers returns the environment from files and config in each of
+// the project root and current directory, the root first so that the nearer
+// one wins. Within a directory, the order is: dotenv files found
+// automatically, env_files, then the [env] table.
+func (r *CommandRunner) configEnvLayers() []envLayer {
+	layers := []envLayer{}
+	dirs := r.searchDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		config := loadProjectConfig(dirs[i])
+
+		dotenv := defaultDotenvFiles
+		if config != nil && config.Dotenv != nil {
+			dotenv = *config.Dotenv
+		}
+		for _, file := range dotenv {
+			path := filepath.Join(dirs[i], file)
+			if !FileExists(path) {
+				continue
+			}
+			if vars := loadEnvFile(path); len(vars) > 0 {
+				layers = append(layers, envLayer{source: r.displayPath(path), vars: vars})
+			}
+		}
+
+		if config == nil {
+			continue
+		}
+		for _, file := range config.EnvFiles {
+			path := filepath.Join(dirs[i], file)
+			if vars := loadEnvFile(path); len(vars) > 0 {
+				layers = append(layers, envLayer{source: r.displayPath(path), vars: vars})
+			}
+		}
+		if len(config.Env) > 0 {
+			vars := make(map[string]string, len(config.Env))
+			for name, value := range config.Env {
+				vars[name] = os.ExpandEnv(value)
+			}
+			layers = append(layers, envLayer{source: r.displayPath(config.Path) + " [env]", vars: vars})
+		}
+	}
+
+	for _, path := range r.EnvFiles {
+		if vars := loadEnvFile(path); len(vars) > 0 {
+			layers = append(layers, envLayer{source: r.displayPath(path) + " (--env-file)", vars: vars})
+		}
+	}
+	return layers
+}
+
+// displayPath returns path relative to the current directory when that is
+// shorter to read
+func (r *CommandRunner) displayPath(path string) string {
+	if rel, err := filepath.Rel(r.CurrentDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// ParseEnvAssignment parses a KEY=VALUE argument to -e/--env
+func ParseEnvAssignment(arg string) (string, string, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid environment assignment %q (expected KEY=VALUE)", arg)
+	}
+	if strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid environment variable name %q", name)
+	}
+	return name, value, nil
+}
+
+// ResolveEnv returns the variables cmd-runner sets for child commands, with
+// the source of the value that wins. Inherited variables are included only
+// when includeInherited is set.
+func (r *CommandRunner) ResolveEnv(includeInherited bool) []EnvVar {
+	resolved := make(map[string]EnvVar)
+
+	if includeInherited {
+		for _, entry := range os.Environ() {
+			name, value, ok := strings.Cut(entry, "=")
+			if !ok || name == "" {
+				continue
+			}
+			resolved[name] = EnvVar{Name: name, Value: value, Source: "inherited"}
+		}
+	}
+
+	for _, layer := range r.envLayers() {
+		for name, value := range layer.vars {
+			resolved[name] = EnvVar{Name: name, Value: value, Source: layer.source}
+		}
+	}
+
+	vars := make([]EnvVar, 0, len(resolved))
+	for _, name := range sortCommands(resolved) {
+		vars = append(vars, resolved[name])
+	}
+	return vars
+}
+
+// commandEnv returns the environment for a child process, or nil to inherit
+// the current environment unchanged
+func (r *CommandRunner) commandEnv() []string {
+	layers := r.envLayers()
+	if len(layers) == 0 {
+		return nil
+	}
+
+	env := os.Environ()
+	for _, layer := range layers {
+		for _, name := range sortCommands(layer.vars) {
+			env = append(env, name+"="+layer.vars[name])
+		}
+	}
+	return env
+}
+
+// secretNameMarkers are substrings of variable names whose values are masked
+var secretNameMarkers = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "API_KEY", "PRIVATE_KEY", "CREDENTIAL", "AUTH"}
+
+// isSecretEnvName reports whether a variable name looks like it holds a secret
+func isSecretEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range secretNameMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskEnvValue hides secret values, keeping only an indication of length
+func maskEnvValue(name, value string) string {
+	if !isSecretEnvName(name) || value == "" {
+		return value
+	}
+	return strings.Repeat("*", min(len(value), 8))
+}
+
+// ShowEnv prints the environment variables that commands will receive
+func (r *CommandRunner) ShowEnv(includeInherited bool) {
+	vars := r.ResolveEnv(includeInherited)
+	if len(vars) == 0 {
+		fmt.Println("cmd-runner adds no environment variables; commands inherit the shell environment.")
+		fmt.Println("Use 'cmdr env --all' to show inherited variables.")
+		return
+	}
+
+	nameWidth := 0
+	for _, v := range vars {
+		nameWidth = max(nameWidth, len(v.Name))
+	}
+	for _, v := range vars {
+		fmt.Printf("%-*s = %s  (%s)\n", nameWidth, v.Name, maskEnvValue(v.Name, v.Value), v.Source)
+	}
+}
@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMaskEnvValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"PATH", "/usr/bin", "/usr/bin"},
+		{"GITHUB_TOKEN", "ghp_abcdefghijkl", "********"},
+		{"DB_PASSWORD", "pw", "**"},
+		{"OPENAI_API_KEY", "sk-123456", "********"},
+		{"AWS_SECRET_ACCESS_KEY", "", ""},
+		{"NODE_ENV", "production", "production"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := maskEnvValue(tt.name, tt.value)
+			if result != tt.expected {
+				t.Errorf("maskEnvValue(%q, %q) = %q, want %q", tt.name, tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveEnvInherited(t *testing.T) {
+	t.Setenv("CMDR_TEST_VAR", "value")
+	runner := &CommandRunner{}
+
+	found := false
+	for _, v := range runner.ResolveEnv(true) {
+		if v.Name == "CMDR_TEST_VAR" {
+			found = true
+			if v.Value != "value" || v.Source != "inherited" {
+				t.Errorf("ResolveEnv() CMDR_TEST_VAR = %+v", v)
+			}
+		}
+	}
+	if !found {
+		t.Error("ResolveEnv(true) missing CMDR_TEST_VAR")
+	}
+
+	for _, v := range runner.ResolveEnv(false) {
+		if v.Name == "CMDR_TEST_VAR" {
+			t.Error("ResolveEnv(false) should not include inherited variables")
+		}
+	}
+}
+
+func TestParseEnvAssignment(t *testing.T) {
+	tests := []struct {
+		arg       string
+		name      string
+		value     string
+		expectErr bool
+	}{
+		{"FOO=bar", "FOO", "bar", false},
+		{"FOO=", "FOO", "", false},
+		{"URL=http://x?a=b", "URL", "http://x?a=b", false},
+		{"FOO", "", "", true},
+		{"=bar", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			name, value, err := ParseEnvAssignment(tt.arg)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ParseEnvAssignment(%q) error = %v, expectErr %v", tt.arg, err, tt.expectErr)
+			}
+			if name != tt.name || value != tt.value {
+				t.Errorf("ParseEnvAssignment(%q) = %q, %q, want %q, %q", tt.arg, name, value, tt.name, tt.value)
+			}
+		})
+	}
+}
+
+func TestCommandEnvOverrides(t *testing.T) {
+	t.Setenv("CMDR_TEST_VAR", "shell")
+	runner := &CommandRunner{EnvOverrides: map[string]string{"CMDR_TEST_VAR": "flag"}}
+
+	env := runner.commandEnv()
+	last := ""
+	for _, entry := range env {
+		if value, ok := strings.CutPrefix(entry, "CMDR_TEST_VAR="); ok {
+			last = value
+		}
+	}
+	// exec.Cmd uses the last value for duplicate keys
+	if last != "flag" {
+		t.Errorf("commandEnv() CMDR_TEST_VAR = %q, want %q", last, "flag")
+	}
+
+	if (&CommandRunner{}).commandEnv() != nil {
+		t.Error("commandEnv() should be nil when there are no overrides")
+	}
+}
+
+func TestConfigEnvLayers(t *testing.T) {
+	t.Setenv("CMDR_TEST_HOME", "/home/test")
+	root := t.TempDir()
+	sub := filepath.Join(root, "web")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".env.shared"), []byte("RUST_LOG=info\nSHARED=file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, root, `env_files = [".env.shared"]
+
+[env]
+SHARED = "config"
+CACHE = "${CMDR_TEST_HOME}/.cache"
+`)
+	writeConfig(t, sub, `[env]
+RUST_LOG = "debug"
+`)
+
+	runner := &CommandRunner{CurrentDir: sub, ProjectRoot: root, EnvOverrides: map[string]string{"CACHE": "/tmp"}}
+	got := make(map[string]EnvVar)
+	for _, v := range runner.ResolveEnv(false) {
+		got[v.Name] = v
+	}
+
+	expected := map[string]EnvVar{
+		"RUST_LOG": {Name: "RUST_LOG", Value: "debug", Source: ".cmdr.toml [env]"},
+		"SHARED":   {Name: "SHARED", Value: "config", Source: filepath.Join(root, ".cmdr.toml") + " [env]"},
+		"CACHE":    {Name: "CACHE", Value: "/tmp", Source: "command line (-e)"},
+	}
+	for name, want := range expected {
+		if got[name] != want {
+			t.Errorf("ResolveEnv()[%s] = %+v, want %+v", name, got[name], want)
+		}
+	}
+
+	runner.EnvOverrides = nil
+	for _, v := range runner.ResolveEnv(false) {
+		if v.Name == "CACHE" && v.Value != "/home/test/.cache" {
+			t.Errorf("CACHE = %q, want inherited variable expanded", v.Value)
+		}
+	}
+}
+
+func TestDotenvLayers(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".env":         "PORT=3000\nDEBUG=0\n",
+		".env.local":   "DEBUG=1\n",
+		".env.test":    "PORT=4000\n",
+		"override.env": "PORT=5000\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolve := func(runner *CommandRunner) map[string]EnvVar {
+		vars := make(map[string]EnvVar)
+		for _, v := range runner.ResolveEnv(false) {
+			vars[v.Name] = v
+		}
+		return vars
+	}
+
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	vars := resolve(runner)
+	if vars["PORT"].Value != "3000" || vars["DEBUG"].Value != "1" || vars["DEBUG"].Source != ".env.local" {
+		t.Errorf("default dotenv: PORT = %+v, DEBUG = %+v", vars["PORT"], vars["DEBUG"])
+	}
+
+	runner.EnvFiles = []string{filepath.Join(dir, "override.env")}
+	if v := resolve(runner)["PORT"]; v.Value != "5000" || v.Source != "override.env (--env-file)" {
+		t.Errorf("--env-file: PORT = %+v", v)
+	}
+
+	// A separate directory, since config files are cached by path
+	configured := t.TempDir()
+	for _, name := range []string{".env", ".env.local", ".env.test"} {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		if err := os.WriteFile(filepath.Join(configured, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(t, configured, `dotenv = [".env.test"]`)
+	runner = &CommandRunner{CurrentDir: configured, ProjectRoot: configured}
+	vars = resolve(runner)
+	if vars["PORT"].Value != "4000" || vars["DEBUG"].Value != "" {
+		t.Errorf("configured dotenv: PORT = %+v, DEBUG = %+v", vars["PORT"], vars["DEBUG"])
+	}
+}
+
+func TestToolPathLayer(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	makeDirs := func(dirs ...string) {
+		for _, dir := range dirs {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	root := t.TempDir()
+	sub := filepath.Join(root, "web")
+	makeDirs(filepath.Join(sub, "node_modules", ".bin"), filepath.Join(root, "vendor", "bin"))
+	runner := &CommandRunner{CurrentDir: sub, ProjectRoot: root}
+
+	want := strings.Join([]string{
+		filepath.Join(sub, "node_modules", ".bin"),
+		filepath.Join(root, "vendor", "bin"),
+		"/usr/bin",
+	}, string(os.PathListSeparator))
+	if layer := runner.toolPathLayer(os.Getenv("PATH")); layer == nil || layer.vars["PATH"] != want {
+		t.Errorf("toolPathLayer() = %+v, want PATH=%s", layer, want)
+	}
+
+	// A PATH from config replaces the augmented one
+	dir := t.TempDir()
+	makeDirs(filepath.Join(dir, "node_modules", ".bin"))
+	writeConfig(t, dir, "[env]\nPATH = \"/opt/bin\"\n")
+	runner = &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	for _, v := range runner.ResolveEnv(false) {
+		if v.Name == "PATH" && v.Value != "/opt/bin" {
+			t.Errorf("ResolveEnv() PATH = %q, want the config's", v.Value)
+		}
+	}
+
+	dir = t.TempDir()
+	makeDirs(filepath.Join(dir, "node_modules", ".bin"))
+	writeConfig(t, dir, "augment_path = false\n")
+	runner = &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	if layer := runner.toolPathLayer(os.Getenv("PATH")); layer != nil {
+		t.Errorf("toolPathLayer() with augment_path = false = %+v", layer)
+	}
+}
+
+func TestProjectToolsRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the tool")
+	}
+	setUserConfig(t, "")
+	t.Setenv("PATH", "/usr/bin:/bin")
+	dir := t.TempDir()
+	bin := filepath.Join(dir, ".venv", "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(dir, "ran")
+	script := "#!/bin/sh\necho \"$@\" > " + marker + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "cmdr-test-mypy"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{Command: "typecheck", CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+
+	// exec.Command doesn't find the tool on cmdr's PATH, but the command's
+	// PATH has the project's tool directories first
+	cmd := exec.Command("cmdr-test-mypy", "src")
+	cmd.Dir = dir
+	if err := runner.requireTool(cmd, nil); err != nil {
+		t.Fatalf("requireTool() = %v", err)
+	}
+	if want := filepath.Join(bin, "cmdr-test-mypy"); cmd.Path != want {
+		t.Errorf("cmd.Path = %q, want %q", cmd.Path, want)
+	}
+	cmd = exec.Command("cmdr-test-mypy", "src")
+	cmd.Dir = dir
+	if err := runner.ExecuteCommand(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(marker); err != nil || string(data) != "src\n" {
+		t.Errorf("the tool ran with %q, %v", data, err)
+	}
+
+	// A tool in neither place is still missing
+	cmd = exec.Command("cmdr-test-missing-tool")
+	if err := runner.requireTool(cmd, nil); !errors.Is(err, ErrToolMissing) {
+		t.Errorf("requireTool() for a missing tool = %v, want ErrToolMissing", err)
+	}
+}
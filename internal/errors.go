@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Sentinels for the ways resolving or running a command fails, for
+// errors.Is. The errors cmdr returns carry a message for the user and
+// match one of these.
+var (
+	// ErrCommandNotFound: no source in the current directory or project
+	// root has the command, and cmdr can't synthesize it
+	ErrCommandNotFound = errors.New("command not found")
+
+	// ErrNoSources: there are no command sources to look in at all. It
+	// matches ErrCommandNotFound too.
+	ErrNoSources = fmt.Errorf("%w: no command sources", ErrCommandNotFound)
+
+	// ErrToolMissing: the command resolved, but its program isn't
+	// installed. Use errors.As with *ToolMissingError for the tool.
+	ErrToolMissing = errors.New("tool not installed")
+)
+
+// ToolMissingError reports that a command's program isn't on PATH
+type ToolMissingError struct {
+	Tool    string // the program, such as "pnpm"
+	Command string // the cmdr command that needed it
+	Source  string // the source that resolved the command, or ""
+	Err     error  // the error from looking for it
+
+	// Install has commands that would install the tool with the package
+	// managers on this machine, best first
+	Install []string
+}
+
+func (e *ToolMissingError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("tool '%s' not found on PATH: the %s source runs '%s' with it", e.Tool, e.Source, e.Command)
+	}
+	return fmt.Sprintf("tool '%s' not found on PATH: '%s' runs it", e.Tool, e.Command)
+}
+
+func (e *ToolMissingError) Unwrap() error { return e.Err }
+
+func (e *ToolMissingError) Is(target error) bool { return target == ErrToolMissing }
+
+// toolMissing returns err as a *ToolMissingError if it's from starting
+// cmd, whose program isn't installed, and otherwise err itself
+func (r *CommandRunner) toolMissing(cmd *exec.Cmd, err error) error {
+	if !errors.Is(err, exec.ErrNotFound) {
+		return err
+	}
+	return &ToolMissingError{Tool: cmd.Args[0], Command: r.Command, Err: err}
+}
+
+// requireTool returns a *ToolMissingError if cmd's program can't be found,
+// so that cmdr reports it before running anything rather than failing to
+// start it. source is the source that resolved cmd, or nil. In a container,
+// the program is the container's to find.
+func (r *CommandRunner) requireTool(cmd *exec.Cmd, source CommandSource) error {
+	if r.InContainer {
+		return nil
+	}
+	r.lookUpProgram(cmd)
+	err := cmd.Err
+	if err == nil && !filepath.IsAbs(cmd.Path) && strings.ContainsAny(cmd.Path, `/\`) {
+		// A relative path, such as ./gradlew, is relative to the command's
+		// directory
+		if _, statErr := os.Stat(filepath.Join(cmd.Dir, cmd.Path)); statErr != nil {
+			err = &exec.Error{Name: cmd.Path, Err: exec.ErrNotFound}
+		}
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		return nil
+	}
+	missing := &ToolMissingError{Tool: cmd.Args[0], Command: r.Command, Err: err, Install: r.executor.installSuggestions(cmd.Args[0])}
+	if source != nil {
+		missing.Source = source.Name()
+	}
+	debugf("%s: %v", missing.Tool, err)
+	return missing
+}
+
+// hasSources reports whether the current directory or project root has
+// any command source
+func (r *CommandRunner) hasSources() bool {
+	for _, project := range r.projects() {
+		if len(project.CommandSources) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// Executor creates every process cmdr starts and finds every program it
+// looks up on PATH. Embedders and tests supply their own, with
+// WithCommandFactory and WithLookPath, to record or replace process spawns.
+// A nil *Executor uses exec.Command and exec.LookPath.
+type Executor struct {
+	// Command returns a command for a program and its arguments, as
+	// exec.Command does
+	Command func(name string, arg ...string) *exec.Cmd
+
+	// LookPath finds a program on PATH, as exec.LookPath does
+	LookPath func(file string) (string, error)
+
+	// static keeps sources from running their tools to list tasks, as
+	// analyze-only mode does, for resolving without starting processes
+	static bool
+
+	// ctx, if set, kills the processes the executor creates with
+	// exec.Command when it's done
+	ctx context.Context
+}
+
+// RunnerOption configures a CommandRunner created with New
+type RunnerOption func(*CommandRunner)
+
+// WithCommandFactory makes the runner, and the sources it resolves, create
+// processes with factory instead of exec.Command
+func WithCommandFactory(factory func(name string, arg ...string) *exec.Cmd) RunnerOption {
+	return func(r *CommandRunner) {
+		x := r.executor.clone()
+		x.Command = factory
+		r.executor = x
+	}
+}
+
+// WithLookPath makes the runner, and the sources it resolves, find programs
+// with lookPath instead of exec.LookPath
+func WithLookPath(lookPath func(file string) (string, error)) RunnerOption {
+	return func(r *CommandRunner) {
+		x := r.executor.clone()
+		x.LookPath = lookPath
+		r.executor = x
+	}
+}
+
+// WithContext makes ctx cancel the runner's runs and watches: when it's
+// done, the processes they started are killed
+func WithContext(ctx context.Context) RunnerOption {
+	return func(r *CommandRunner) {
+		r.ctx = ctx
+		r.executor = r.executor.withContext(ctx)
+	}
+}
+
+// clone returns a copy of x that options can change without affecting
+// sources already resolved with x
+func (x *Executor) clone() *Executor {
+	if x == nil {
+		return &Executor{}
+	}
+	c := *x
+	return &c
+}
+
+// withContext returns a copy of x whose processes ctx cancels
+func (x *Executor) withContext(ctx context.Context) *Executor {
+	c := x.clone()
+	c.ctx = ctx
+	return c
+}
+
+func (x *Executor) command(name string, arg ...string) *exec.Cmd {
+	switch {
+	case x != nil && x.Command != nil:
+		return x.Command(name, arg...)
+	case x != nil && x.ctx != nil:
+		cmd := exec.CommandContext(x.ctx, name, arg...)
+		// Kill what the command spawned too, and don't wait long for
+		// output from children that outlive it
+		cmd.Cancel = func() error {
+			if err := signalProcessGroup(cmd, syscall.SIGKILL); err != nil {
+				return cmd.Process.Kill()
+			}
+			return nil
+		}
+		cmd.WaitDelay = time.Second
+		return cmd
+	}
+	return exec.Command(name, arg...)
+}
+
+func (x *Executor) lookPath(file string) (string, error) {
+	if x == nil || x.LookPath == nil {
+		return exec.LookPath(file)
+	}
+	return x.LookPath(file)
+}
+
+// lookPathIn finds a program in the directories of path, a PATH value, as
+// lookPath does in PATH. An executor with its own LookPath uses it instead.
+func (x *Executor) lookPathIn(file, path string) (string, error) {
+	if x != nil && x.LookPath != nil {
+		return x.LookPath(file)
+	}
+	for _, dir := range filepath.SplitList(path) {
+		// Relative entries would depend on the command's directory
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		// With a separator in the name, LookPath checks just that file,
+		// adding PATHEXT's extensions on Windows
+		if program, err := exec.LookPath(filepath.Join(dir, file)); err == nil {
+			return program, nil
+		}
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// shell returns a command that runs script in the platform shell.
+// Extra arguments are passed to the script as positional parameters.
+func (x *Executor) shell(script string, args []string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		cmdArgs := append([]string{"/C", script}, args...)
+		return x.command("cmd", cmdArgs...)
+	}
+	if len(args) == 0 {
+		return x.command("sh", "-c", script)
+	}
+	cmdArgs := append([]string{"-c", script + ` "$@"`, "sh"}, args...)
+	return x.command("sh", cmdArgs...)
+}
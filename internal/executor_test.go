@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunnerOptionsInterceptProcesses(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every spawn is recorded and replaced by this test binary listing no tests
+	var spawned []string
+	factory := func(name string, arg ...string) *exec.Cmd {
+		spawned = append(spawned, strings.Join(append([]string{name}, arg...), " "))
+		return exec.Command(os.Args[0], "-test.list=^$")
+	}
+	runner := New("test", nil, WithCommandFactory(factory))
+	runner.CurrentDir, runner.ProjectRoot = dir, dir
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(spawned) != 1 || spawned[0] != "go test ./..." {
+		t.Errorf("spawned %q, want [go test ./...]", spawned)
+	}
+}
+
+func TestWithLookPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"packageManager": "pnpm@9.0.0", "scripts": {"build": "tsc"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		corepack bool
+		want     string
+	}{
+		{true, "corepack pnpm run build"},
+		{false, "pnpm run build"},
+	} {
+		lookPath := func(file string) (string, error) {
+			if file == "corepack" && tt.corepack {
+				return "/usr/bin/corepack", nil
+			}
+			return "", errors.New("not found")
+		}
+		runner := New("build", nil, WithLookPath(lookPath))
+		runner.CurrentDir, runner.ProjectRoot = dir, dir
+		cmd, err := runner.Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(cmd.Args, " "); got != tt.want {
+			t.Errorf("with corepack=%v, Resolve() = %q, want %q", tt.corepack, got, tt.want)
+		}
+	}
+}
+
+func TestRunContextCancels(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nslow = \"sleep 10; echo done > log\"\n")
+	runner := New("slow", nil)
+	runner.CurrentDir, runner.ProjectRoot, runner.Quiet = dir, dir, true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := runner.RunContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunContext() took %s after its deadline", elapsed)
+	}
+	if FileExists(filepath.Join(dir, "log")) {
+		t.Error("the command kept running after cancellation")
+	}
+
+	// A context that is already done runs nothing
+	if err := runner.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() with a done context = %v", err)
+	}
+}
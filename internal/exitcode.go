@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// Exit codes for cmdr's own failures. When the command it runs fails, cmdr
+// exits with the command's code instead.
+const (
+	ExitFailure  = 1   // any other error, including invalid options
+	ExitNotFound = 2   // the command doesn't resolve in this project
+	ExitConfig   = 3   // a config file is invalid or inconsistent
+	ExitTimeout  = 124 // cmdr stopped waiting, as timeout(1) does
+	ExitNoTool   = 127 // a command's program isn't installed, as in a shell
+)
+
+// exitError classifies an error by the code cmdr exits with and, for
+// errors.Is, by kind: one of the exported sentinels, or nil
+type exitError struct {
+	code int
+	err  error
+	kind error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func (e *exitError) Is(target error) bool {
+	return e.kind != nil && errors.Is(e.kind, target)
+}
+
+func notFoundError(err error) error { return &exitError{ExitNotFound, err, nil} }
+func configError(err error) error   { return &exitError{ExitConfig, err, nil} }
+func timeoutError(err error) error  { return &exitError{ExitTimeout, err, nil} }
+
+// missingCommandError classifies err as a command that doesn't resolve
+func missingCommandError(err error) error {
+	return &exitError{ExitNotFound, err, ErrCommandNotFound}
+}
+
+// isNotFound reports whether err is cmdr's own not-found error, as opposed
+// to a command that happened to exit with the same code
+func isNotFound(err error) bool {
+	var classified *exitError
+	return errors.As(err, &classified) && classified.code == ExitNotFound
+}
+
+// ExitCode returns the status cmdr exits with after err: 0 for nil, the
+// class of one of cmdr's own errors, or the code of a failed command. A
+// command killed by a signal gives 128 plus the signal number, as in a shell.
+func ExitCode(err error) int {
+	var classified *exitError
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &classified):
+		return classified.code
+	case errors.Is(err, ErrToolMissing):
+		return ExitNoTool
+	case errors.As(err, &exitErr):
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		if code := exitErr.ExitCode(); code > 0 {
+			return code
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout
+	}
+	return ExitFailure
+}
+
+// commandNotFound reports that command doesn't resolve. If a config file
+// that might have defined it is invalid, that is reported instead.
+func (r *CommandRunner) commandNotFound(command string) error {
+	for _, dir := range r.searchDirs() {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if FileExists(path) && loadProjectConfig(dir) == nil {
+			return configError(fmt.Errorf("no command '%s' found, and %s is invalid", command, path))
+		}
+	}
+	if r.strict() {
+		if err := r.strictNotFound(command); err != nil {
+			return err
+		}
+	}
+	if !r.hasSources() {
+		return &exitError{ExitNotFound, fmt.Errorf("no command '%s' found: no project files (package.json, Makefile, %s, ...) in current directory or project root", command, ProjectConfigFile), ErrNoSources}
+	}
+	return missingCommandError(fmt.Errorf("no command '%s' found in current directory or project root", command))
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("ExitCode(nil) = %d", code)
+	}
+	if code := ExitCode(fmt.Errorf("other")); code != ExitFailure {
+		t.Errorf("ExitCode(other) = %d", code)
+	}
+	if code := ExitCode(fmt.Errorf("wrapped: %w", timeoutError(fmt.Errorf("slow")))); code != ExitTimeout {
+		t.Errorf("ExitCode(timeout) = %d", code)
+	}
+	if code := ExitCode(context.DeadlineExceeded); code != ExitTimeout {
+		t.Errorf("ExitCode(DeadlineExceeded) = %d", code)
+	}
+
+	if runtime.GOOS != "windows" {
+		err := exec.Command("sh", "-c", "exit 5").Run()
+		if code := ExitCode(fmt.Errorf("test exited: %w", err)); code != 5 {
+			t.Errorf("ExitCode(exit 5) = %d", code)
+		}
+		err = exec.Command("sh", "-c", "kill -TERM $$").Run()
+		if code := ExitCode(err); code != 128+15 {
+			t.Errorf("ExitCode(SIGTERM) = %d", code)
+		}
+	}
+}
+
+func TestCommandNotFoundExitCode(t *testing.T) {
+	dir := t.TempDir()
+	runner := &CommandRunner{Command: "deploy", CurrentDir: dir, ProjectRoot: dir}
+	if code := ExitCode(runner.Run()); code != ExitNotFound {
+		t.Errorf("exit code for a missing command = %d, want %d", code, ExitNotFound)
+	}
+
+	dir = t.TempDir()
+	writeConfig(t, dir, "[commands\n")
+	runner = &CommandRunner{Command: "deploy", CurrentDir: dir, ProjectRoot: dir}
+	if code := ExitCode(runner.Run()); code != ExitConfig {
+		t.Errorf("exit code with an invalid config = %d, want %d", code, ExitConfig)
+	}
+
+	dir = t.TempDir()
+	writeConfig(t, dir, "[commands.deploy]\nsource = \"npm\"\n")
+	runner = &CommandRunner{Command: "deploy", CurrentDir: dir, ProjectRoot: dir}
+	if code := ExitCode(runner.Run()); code != ExitConfig {
+		t.Errorf("exit code for a pin to a missing source = %d, want %d", code, ExitConfig)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	dir := t.TempDir()
+	runner := &CommandRunner{Command: "deploy", CurrentDir: dir, ProjectRoot: dir}
+	err := runner.Run()
+	if !errors.Is(err, ErrNoSources) || !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("Run() without sources = %v, want ErrNoSources and ErrCommandNotFound", err)
+	}
+
+	dir = t.TempDir()
+	writeConfig(t, dir, "[commands]\nbuild = \"true\"\n")
+	runner = &CommandRunner{Command: "deploy", CurrentDir: dir, ProjectRoot: dir}
+	err = runner.Run()
+	if !errors.Is(err, ErrCommandNotFound) || errors.Is(err, ErrNoSources) {
+		t.Errorf("Run() for a missing command = %v, want ErrCommandNotFound only", err)
+	}
+
+	dir = t.TempDir()
+	writeConfig(t, dir, "[commands]\nhi = \"echo hi\"\n")
+	runner = New("hi", nil, WithCommandFactory(func(name string, arg ...string) *exec.Cmd {
+		return exec.Command("cmdr-test-no-such-program")
+	}))
+	runner.CurrentDir, runner.ProjectRoot, runner.Quiet = dir, dir, true
+	err = runner.Run()
+	var missing *ToolMissingError
+	if !errors.As(err, &missing) || missing.Tool != "cmdr-test-no-such-program" || missing.Command != "hi" {
+		t.Fatalf("Run() with a missing program = %v, want a ToolMissingError", err)
+	}
+	if !errors.Is(err, ErrToolMissing) || ExitCode(err) != ExitNoTool {
+		t.Errorf("Run() with a missing program: Is(ErrToolMissing) = %v, ExitCode = %d", errors.Is(err, ErrToolMissing), ExitCode(err))
+	}
+}
+
+func TestRequireTool(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "justfile"), []byte("build:\n\tgo build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{Command: "build", CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+	err := runner.Run()
+	var missing *ToolMissingError
+	if !errors.As(err, &missing) || missing.Tool != "just" || missing.Source != "just" {
+		t.Fatalf("Run() without just = %v, want a ToolMissingError from the just source", err)
+	}
+	if want := "tool 'just' not found on PATH: the just source runs 'build' with it"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// A wrapper script is looked for in the command's directory
+	cmd := exec.Command("./gradlew", "build")
+	cmd.Dir = dir
+	if err := runner.requireTool(cmd, nil); !errors.Is(err, ErrToolMissing) {
+		t.Errorf("requireTool(./gradlew) = %v, want ErrToolMissing", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gradlew"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.requireTool(cmd, nil); err != nil {
+		t.Errorf("requireTool(./gradlew) = %v with gradlew present", err)
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Explain writes each step of resolving the runner's command, in the order
+// Run takes them: the sources consulted in each directory, the variants of
+// the name they were asked for, which matched, and the command that runs
+func (r *CommandRunner) Explain(w io.Writer) error {
+	fmt.Fprintf(w, "Resolving '%s'\n", r.Command)
+
+	if group, dir := r.groupConfig(r.Command); group != nil {
+		fmt.Fprintf(w, "  %s process group in %s\n", passMark(w, true), filepath.Join(dir, ProjectConfigFile))
+		order, err := group.startOrder()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nStarts: %s\n", joinArrow(order))
+		return nil
+	}
+
+	if cc := r.commandConfig(r.Command); cc != nil && cc.Source != "" {
+		fmt.Fprintf(w, "  pinned to %s in %s\n", cc.Source, ProjectConfigFile)
+		cmd, err := r.pinnedCommand(r.Command)
+		if err != nil {
+			return err
+		}
+		return r.explainResult(w, cmd)
+	}
+
+	if cmd := r.explainLookup(w, r.Command); cmd != nil {
+		if _, source := r.lookupCommand(r.Command); r.Command == "setup" && !definesCommand(source, r.Command) && r.provisions() {
+			return r.explainProvisioning(w, cmd)
+		}
+		return r.explainResult(w, cmd)
+	}
+
+	if command, args, ok := expandUserAlias(r.Command, r.Args); ok && !r.aliasExpanded {
+		fmt.Fprintf(w, "  user alias: %s → %s\n\n", r.Command, strings.Join(append([]string{command}, args...), " "))
+		sub := r.subRunner(command, args)
+		sub.aliasExpanded = true
+		return sub.Explain(w)
+	}
+
+	switch r.Command {
+	case "check", "fix", "typecheck", "clean":
+		if r.strict() {
+			fmt.Fprintf(w, "  synthesized commands are disabled\n")
+			return r.commandNotFound(r.Command)
+		}
+		fmt.Fprintf(w, "  %s synthesized by cmd-runner from the project's other commands\n", passMark(w, true))
+		return nil
+	case "setup":
+		if !r.strict() && r.provisions() {
+			return r.explainProvisioning(w, nil)
+		}
+	}
+
+	if normalized := NormalizeCommand(r.Command); normalized != r.Command {
+		fmt.Fprintf(w, "  normalized to '%s'\n", normalized)
+		if cmd := r.explainLookup(w, normalized); cmd != nil {
+			return r.explainResult(w, cmd)
+		}
+	}
+
+	if prefixMatching() && r.Command != "" {
+		switch matches := r.prefixMatches(r.Command); len(matches) {
+		case 0:
+		case 1:
+			fmt.Fprintf(w, "  unique prefix of '%s' (prefix_match)\n\n", matches[0])
+			return r.subRunner(matches[0], r.Args).Explain(w)
+		default:
+			fmt.Fprintf(w, "  prefix of %s (prefix_match), so ambiguous\n", strings.Join(matches, ", "))
+		}
+	}
+	return r.commandNotFound(r.Command)
+}
+
+// explainLookup asks every source for command, reporting each answer, and
+// returns the first match
+func (r *CommandRunner) explainLookup(w io.Writer, command string) *exec.Cmd {
+	variants := GetCommandVariants(command)
+	fmt.Fprintf(w, "  variants: %s\n", strings.Join(variants, ", "))
+	var found *exec.Cmd
+	strict := r.strict()
+	for _, project := range r.projects() {
+		fmt.Fprintf(w, "  %s:\n", project.Dir)
+		if len(project.CommandSources) == 0 {
+			fmt.Fprintf(w, "      (no command sources)\n")
+		}
+		for _, source := range project.CommandSources {
+			cmd := source.FindCommand(command, r.Args)
+			if cmd == nil {
+				fmt.Fprintf(w, "      %-14s no match\n", source.Name())
+				continue
+			}
+			if strict && !definesCommand(source, command) {
+				fmt.Fprintf(w, "      %-14s %s (a tool default; synthesized commands are disabled)\n", source.Name(), strings.Join(cmd.Args, " "))
+				continue
+			}
+			match := "built-in default"
+			listed := source.ListCommands()
+			for _, variant := range variants {
+				if _, ok := listed[variant]; ok {
+					match = variant
+					break
+				}
+			}
+			line := fmt.Sprintf("%-14s %s → %s", source.Name(), match, strings.Join(cmd.Args, " "))
+			if found == nil {
+				found = cmd
+				fmt.Fprintf(w, "    %s %s\n", passMark(w, true), line)
+			} else {
+				fmt.Fprintf(w, "      %s (shadowed)\n", line)
+			}
+		}
+	}
+	return found
+}
+
+// explainProvisioning writes the toolchain installs that precede install,
+// which is nil if no source has one
+func (r *CommandRunner) explainProvisioning(w io.Writer, install *exec.Cmd) error {
+	fmt.Fprintf(w, "  %s synthesized by cmd-runner: provisions the pinned toolchains first\n\nRuns:\n", passMark(w, true))
+	steps, missing := r.provisionSteps()
+	for _, step := range steps {
+		fmt.Fprintf(w, "  %s (for %s)\n", shellCommandLine(r.invocation(r.provisionCommand(step))), step.name)
+	}
+	for _, message := range missing {
+		fmt.Fprintf(w, "  skipped: %s\n", message)
+	}
+	if install != nil {
+		fmt.Fprintf(w, "  %s\n", shellCommandLine(r.invocation(install)))
+	}
+	return nil
+}
+
+// explainResult writes the command that runs
+func (r *CommandRunner) explainResult(w io.Writer, cmd *exec.Cmd) error {
+	fmt.Fprintf(w, "\nRuns: %s\n", shellCommandLine(r.invocation(cmd)))
+	return nil
+}
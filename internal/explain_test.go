@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("test:\n\techo make test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"test": "jest"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	runner := &CommandRunner{Command: "t", CurrentDir: dir, ProjectRoot: dir}
+	if err := runner.Explain(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"variants: t, test, tests",
+		"✓ make           test → make test",
+		"npm            test → npm run test (shadowed)",
+		"Runs: ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Explain() output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	runner = &CommandRunner{Command: "deploy", CurrentDir: dir, ProjectRoot: dir}
+	out.Reset()
+	if err := runner.Explain(&out); ExitCode(err) != ExitNotFound {
+		t.Errorf("Explain(deploy) error = %v, want a not-found error", err)
+	}
+	if !strings.Contains(out.String(), "make           no match") {
+		t.Errorf("Explain(deploy) output is missing the sources:\n%s", out.String())
+	}
+}
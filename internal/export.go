@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormats lists the formats supported by ExportGroup
+var ExportFormats = []string{"procfile", "compose"}
+
+// groupExport converts a configured group into another tool's format
+type groupExport struct {
+	runner *CommandRunner
+	name   string
+	group  *GroupConfig
+	dir    string   // directory of the config file
+	order  []string // start order
+}
+
+// ExportGroup writes a group from .cmdr.toml as a Procfile or a Compose
+// override file. The group name may be omitted when only one is defined.
+// Settings the format can't express are reported as warnings.
+func (r *CommandRunner) ExportGroup(w io.Writer, format, name string) error {
+	switch format {
+	case "procfile", "compose":
+	default:
+		return fmt.Errorf("unknown format %q (expected one of: %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	export, err := r.groupExport(name)
+	if err != nil {
+		return err
+	}
+	if format == "procfile" {
+		return export.writeProcfile(w)
+	}
+	return export.writeCompose(w)
+}
+
+// groupExport finds the group to export
+func (r *CommandRunner) groupExport(name string) (*groupExport, error) {
+	if name == "" {
+		names := r.groupNames()
+		switch len(names) {
+		case 0:
+			return nil, notFoundError(fmt.Errorf("no groups are defined in %s", ProjectConfigFile))
+		case 1:
+			name = names[0]
+		default:
+			return nil, fmt.Errorf("several groups are defined; name one of: %s", strings.Join(names, ", "))
+		}
+	}
+	group, dir := r.groupConfig(name)
+	if group == nil {
+		return nil, notFoundError(fmt.Errorf("no group named '%s'", name))
+	}
+	order, err := group.startOrder()
+	if err != nil {
+		return nil, err
+	}
+	return &groupExport{runner: r, name: name, group: group, dir: dir, order: order}, nil
+}
+
+// groupNames returns the names of the groups in the current directory and
+// project root configs
+func (r *CommandRunner) groupNames() []string {
+	seen := make(map[string]bool)
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil {
+			for name := range config.Groups {
+				seen[name] = true
+			}
+		}
+	}
+	return sortCommands(seen)
+}
+
+// warnf reports a setting the export format can't express
+func (e *groupExport) warnf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", fmt.Sprintf(format, args...))
+}
+
+// commandLine returns the shell command line that runs a process, relative
+// to the config file's directory
+func (e *groupExport) commandLine(name string) (string, error) {
+	p := e.group.Processes[name]
+	dir := filepath.Join(e.dir, p.Dir)
+	line := p.Run
+	if p.Command != "" {
+		sub := e.runner.subRunner(p.Command, nil)
+		sub.CurrentDir = dir
+		cmd, err := sub.Resolve()
+		if err != nil {
+			return "", fmt.Errorf("processes.%s: %w", name, err)
+		}
+		args := []string{}
+		for _, arg := range cmd.Args {
+			args = append(args, shellQuote(arg))
+		}
+		line = strings.Join(args, " ")
+		if cmd.Dir != "" {
+			dir = cmd.Dir
+		}
+	}
+	if rel, err := filepath.Rel(e.dir, dir); err == nil && rel != "." {
+		line = "cd " + shellQuote(filepath.ToSlash(rel)) + " && " + line
+	}
+	return line, nil
+}
+
+// environment returns the variables a process receives from the group: its
+// fixed port and the exports of its dependencies
+func (e *groupExport) environment(name string) map[string]string {
+	p := e.group.Processes[name]
+	env := make(map[string]string)
+	for _, depName := range sortCommands(p.DependsOn) {
+		dep := e.group.Processes[depName]
+		for _, variable := range sortCommands(dep.Exports) {
+			value, ok := exportedValue(dep, dep.Exports[variable])
+			if !ok {
+				e.warnf("%s: %s from %s depends on a port or URL chosen at run time; set it yourself", name, variable, depName)
+				continue
+			}
+			env[variable] = value
+		}
+	}
+	if p.Port != nil && *p.Port != 0 {
+		env["PORT"] = strconv.Itoa(*p.Port)
+	}
+	return env
+}
+
+// exportedValue substitutes a process's fixed port into an export. Other
+// references are kept for the shell. It returns false if the value needs a
+// port or URL that is only known at run time.
+func exportedValue(p ProcessConfig, value string) (string, bool) {
+	ok := true
+	expanded := os.Expand(value, func(name string) string {
+		switch {
+		case name == "PORT" && p.Port != nil && *p.Port != 0:
+			return strconv.Itoa(*p.Port)
+		case name == "PORT", name == "URL":
+			ok = false
+		}
+		return "${" + name + "}"
+	})
+	return expanded, ok
+}
+
+// writeProcfile writes one line per process, in start order. Procfile
+// runners start processes together, so dependencies become comments.
+func (e *groupExport) writeProcfile(w io.Writer) error {
+	fmt.Fprintf(w, "# Generated from the %s group in %s by `cmdr export procfile`\n", e.name, ProjectConfigFile)
+	for _, name := range e.order {
+		p := e.group.Processes[name]
+		line, err := e.commandLine(name)
+		if err != nil {
+			return err
+		}
+		env := e.environment(name)
+		if len(env) > 0 {
+			assignments := []string{}
+			for _, variable := range sortCommands(env) {
+				assignments = append(assignments, variable+"="+quoteExportValue(env[variable]))
+			}
+			line = "export " + strings.Join(assignments, " ") + "; " + line
+		}
+
+		if len(p.DependsOn) > 0 {
+			deps := []string{}
+			for _, dep := range sortCommands(p.DependsOn) {
+				deps = append(deps, fmt.Sprintf("%s (%s)", dep, p.DependsOn[dep]))
+			}
+			fmt.Fprintf(w, "# %s starts after %s\n", name, strings.Join(deps, ", "))
+		}
+		fmt.Fprintf(w, "%s: %s\n", name, line)
+
+		for _, dep := range sortCommands(p.DependsOn) {
+			if p.DependsOn[dep] == conditionCompleted {
+				e.warnf("%s runs once before %s; Procfile runners stop every process when one exits", dep, name)
+			}
+		}
+		if p.Stop != nil && p.Stop.Run != "" {
+			e.warnf("%s: the stop command %q has no Procfile equivalent", name, p.Stop.Run)
+		}
+	}
+	return nil
+}
+
+// quoteExportValue quotes a value for the shell, leaving ${VAR} references
+// to be expanded
+func quoteExportValue(value string) string {
+	if shellSafePattern.MatchString(value) {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`").Replace(value) + `"`
+}
+
+// composeConditions maps depends_on conditions to Compose's
+var composeConditions = map[string]string{
+	conditionStarted:   "service_started",
+	conditionHealthy:   "service_healthy",
+	conditionCompleted: "service_completed_successfully",
+}
+
+// writeCompose writes a Compose file with a service for each process, meant
+// to be layered over a base file that gives each service its image, e.g.
+// `docker compose -f compose.yaml -f compose.cmdr.yaml up`
+func (e *groupExport) writeCompose(w io.Writer) error {
+	fmt.Fprintf(w, "# Generated from the %s group in %s by `cmdr export compose`\n", e.name, ProjectConfigFile)
+	fmt.Fprintf(w, "# Layer it over a Compose file that defines each service's image or build.\n")
+	fmt.Fprintf(w, "services:\n")
+	for _, name := range e.order {
+		p := e.group.Processes[name]
+		line, err := e.commandLine(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  %s:\n", name)
+		fmt.Fprintf(w, "    command: [\"sh\", \"-c\", %s]\n", strconv.Quote(composeEscape(line)))
+
+		if env := e.environment(name); len(env) > 0 {
+			fmt.Fprintf(w, "    environment:\n")
+			for _, variable := range sortCommands(env) {
+				fmt.Fprintf(w, "      %s: %s\n", variable, strconv.Quote(env[variable]))
+			}
+		}
+		if len(p.DependsOn) > 0 {
+			fmt.Fprintf(w, "    depends_on:\n")
+			for _, dep := range sortCommands(p.DependsOn) {
+				fmt.Fprintf(w, "      %s:\n        condition: %s\n", dep, composeConditions[p.DependsOn[dep]])
+			}
+		}
+		if p.Ready != nil {
+			e.writeHealthcheck(w, p)
+		}
+		if stop := p.Stop; stop != nil {
+			if stop.Run != "" {
+				e.warnf("%s: the stop command %q has no Compose equivalent", name, stop.Run)
+			}
+			if stop.Signal != "" {
+				signalName := strings.ToUpper(stop.Signal)
+				if !strings.HasPrefix(signalName, "SIG") {
+					signalName = "SIG" + signalName
+				}
+				fmt.Fprintf(w, "    stop_signal: %s\n", signalName)
+			}
+			fmt.Fprintf(w, "    stop_grace_period: %s\n", stop.timeout())
+		}
+	}
+	return nil
+}
+
+// composeEscape keeps Compose from interpolating variables in a shell
+// command, leaving them to the shell in the container
+func composeEscape(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+// writeHealthcheck converts a ready check to a Compose healthcheck that is
+// retried until the check's timeout
+func (e *groupExport) writeHealthcheck(w io.Writer, p ProcessConfig) {
+	check := p.Ready
+	expand := func(s string) string {
+		value, _ := exportedValue(p, s)
+		return value
+	}
+	var test string
+	switch {
+	case check.TCP != "":
+		host, port, found := strings.Cut(expand(check.TCP), ":")
+		if !found {
+			host, port = "localhost", host
+		}
+		test = fmt.Sprintf("nc -z %s %s", shellQuote(host), shellQuote(port))
+	case check.HTTP != "":
+		test = fmt.Sprintf("curl -fsS %s > /dev/null", shellQuote(expand(check.HTTP)))
+	default:
+		test = expand(check.Run)
+	}
+	interval := time.Duration(check.Interval)
+	if interval <= 0 {
+		interval = defaultReadyInterval
+	}
+	timeout := time.Duration(check.Timeout)
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	fmt.Fprintf(w, "    healthcheck:\n")
+	fmt.Fprintf(w, "      test: [\"CMD-SHELL\", %s]\n", strconv.Quote(composeEscape(test)))
+	fmt.Fprintf(w, "      interval: %s\n", interval)
+	fmt.Fprintf(w, "      retries: %d\n", max(int(timeout/interval), 1))
+}
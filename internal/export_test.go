@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const exportConfig = `[groups.dev.processes.db]
+run = "postgres -p $PORT"
+port = 5432
+ready = { tcp = "localhost:${PORT}", interval = "2s", timeout = "10s" }
+exports = { DATABASE_URL = "postgres://localhost:${PORT}/dev" }
+stop = { signal = "int", timeout = "30s" }
+
+[groups.dev.processes.migrate]
+run = "./migrate up"
+depends_on = { db = "healthy" }
+
+[groups.dev.processes.web]
+run = "npm run dev"
+dir = "web"
+depends_on = { db = "healthy", migrate = "completed" }
+`
+
+func TestExportProcfile(t *testing.T) {
+	tempDir := t.TempDir()
+	writeConfig(t, tempDir, exportConfig)
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+
+	var out bytes.Buffer
+	if err := runner.ExportGroup(&out, "procfile", ""); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"# Generated from the dev group in .cmdr.toml by `cmdr export procfile`",
+		"db: export PORT=5432; postgres -p $PORT",
+		"# migrate starts after db (healthy)",
+		`migrate: export DATABASE_URL=postgres://localhost:5432/dev; ./migrate up`,
+		"# web starts after db (healthy), migrate (completed)",
+		`web: export DATABASE_URL=postgres://localhost:5432/dev; cd web && npm run dev`,
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); !slicesEqual(lines, expected) {
+		t.Errorf("procfile =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestExportCompose(t *testing.T) {
+	tempDir := t.TempDir()
+	writeConfig(t, tempDir, exportConfig)
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+
+	var out bytes.Buffer
+	if err := runner.ExportGroup(&out, "compose", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"  db:\n    command: [\"sh\", \"-c\", \"postgres -p $$PORT\"]\n    environment:\n      PORT: \"5432\"\n",
+		"    healthcheck:\n      test: [\"CMD-SHELL\", \"nc -z localhost 5432\"]\n      interval: 2s\n      retries: 5\n",
+		"    stop_signal: SIGINT\n    stop_grace_period: 30s\n",
+		"  web:\n    command: [\"sh\", \"-c\", \"cd web && npm run dev\"]\n",
+		"    depends_on:\n      db:\n        condition: service_healthy\n      migrate:\n        condition: service_completed_successfully\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("compose output is missing\n%s\nin\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	writeConfig(t, tempDir, exportConfig+`
+[groups.test.processes.db]
+run = "postgres"
+`)
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+	tests := []struct {
+		format, group, message string
+	}{
+		{"procfile", "", "name one of: dev, test"},
+		{"procfile", "prod", "no group named 'prod'"},
+		{"yaml", "dev", "unknown format"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := runner.ExportGroup(&out, tt.format, tt.group)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("ExportGroup(%s, %q) error = %v, want %q", tt.format, tt.group, err, tt.message)
+		}
+	}
+}
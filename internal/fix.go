@@ -29,7 +29,7 @@ func HandleFixCommand(r *CommandRunner) error {
 // findNativeFixCommand looks for a native fix command in the project
 func (r *CommandRunner) findNativeFixCommand(dir string) *exec.Cmd {
 	// Check if there's a native fix command
-	project := ResolveProject(dir)
+	project := r.resolveProject(dir)
 	for _, source := range project.CommandSources {
 		if cmd := source.FindCommand("fix", r.Args); cmd != nil {
 			return cmd
@@ -61,6 +61,7 @@ func (r *CommandRunner) synthesizeFixCommand() error {
 	var foundAny bool
 	var executedCommands []string
 	var hasErrors bool
+	var steps []stepTime
 
 	// First check if any fix-related commands are available
 	for _, fc := range fixCommands {
@@ -70,10 +71,10 @@ func (r *CommandRunner) synthesizeFixCommand() error {
 	}
 
 	if !foundAny {
-		return fmt.Errorf("no fix, format, or lint commands found")
+		return missingCommandError(fmt.Errorf("no fix, format, or lint commands found"))
 	}
 
-	fmt.Fprintf(os.Stderr, "Running fix (synthesizing from available commands)...\n")
+	r.progressf("%sRunning fix (synthesizing from available commands)...\n", r.badge())
 
 	// Track what we've already run to avoid duplicates
 	executedTypes := make(map[string]bool)
@@ -93,19 +94,17 @@ func (r *CommandRunner) synthesizeFixCommand() error {
 			cmdDisplay = fmt.Sprintf("%s %s", fc.command, strings.Join(fc.args, " "))
 		}
 
-		fmt.Fprintf(os.Stderr, "\n→ Running %s...\n", cmdDisplay)
+		r.progressf("\n→ Running %s...\n", cmdDisplay)
 
-		tempRunner := &CommandRunner{
-			Command:     fc.command,
-			Args:        append(fc.args, r.Args...),
-			CurrentDir:  r.CurrentDir,
-			ProjectRoot: r.ProjectRoot,
-		}
+		tempRunner := r.subRunner(fc.command, append(fc.args, r.Args...))
+		tempRunner.inner = true
 
-		if err := tempRunner.Run(); err != nil {
+		elapsed, err := r.runStep(cmdDisplay, tempRunner)
+		steps = append(steps, stepTime{cmdDisplay, elapsed, err != nil})
+		if err != nil {
 			// For fix commands, we often want to continue even if one fails
 			hasErrors = true
-			fmt.Fprintf(os.Stderr, "  ✗ %s failed: %v\n", cmdDisplay, err)
+			fmt.Fprintf(r.statusWriter(), "  %s %s failed: %v\n", passMark(r.statusWriter(), false), cmdDisplay, err)
 		} else {
 			executedCommands = append(executedCommands, cmdDisplay)
 			// Mark format as executed for both format and fmt commands
@@ -115,6 +114,8 @@ func (r *CommandRunner) synthesizeFixCommand() error {
 		}
 	}
 
+	r.reportSteps(steps)
+
 	if len(executedCommands) == 0 && hasErrors {
 		return fmt.Errorf("fix failed: no commands succeeded")
 	}
@@ -122,6 +123,21 @@ func (r *CommandRunner) synthesizeFixCommand() error {
 	return nil
 }
 
+// fixSteps returns the command lines synthesized fix runs: format (or fmt),
+// then lint --fix where the linter supports it
+func (r *CommandRunner) fixSteps() []string {
+	var steps []string
+	if r.hasCommand("format") {
+		steps = append(steps, "format")
+	} else if r.hasCommand("fmt") {
+		steps = append(steps, "fmt")
+	}
+	if r.supportsLintFix() && r.hasCommand("lint") {
+		steps = append(steps, "lint --fix")
+	}
+	return steps
+}
+
 // supportsLintFix checks if the project's lint command supports a --fix flag
 func (r *CommandRunner) supportsLintFix() bool {
 	// Go projects don't support lint --fix (go vet has no --fix flag)
@@ -0,0 +1,33 @@
+package internal
+
+// formatCheck returns a runner for fmt-check when the command is format
+// with --check, or nil. fmt-check wins unless the project defines format
+// and only a tool default defines fmt-check, since the project's format may
+// take --check itself.
+func (r *CommandRunner) formatCheck() *CommandRunner {
+	if NormalizeCommand(r.Command) != "format" {
+		return nil
+	}
+	var rest []string
+	found := false
+	for _, arg := range r.Args {
+		if arg == "--check" && !found {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	if !found {
+		return nil
+	}
+
+	sub := r.subRunner("fmt-check", rest)
+	check, checkSource := sub.lookupCommand(sub.Command)
+	if check == nil {
+		return nil
+	}
+	if format, formatSource := r.lookupCommand(r.Command); format != nil && definesCommand(formatSource, r.Command) && !definesCommand(checkSource, sub.Command) {
+		return nil
+	}
+	return sub
+}
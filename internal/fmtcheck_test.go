@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFmtCheckCommands(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"go.mod":         "module x\n",
+		"Cargo.toml":     "[package]\nname = \"x\"\n",
+		"package.json":   `{"devDependencies": {"prettier": "^3.0.0"}}`,
+		"pnpm-lock.yaml": "",
+		"pyproject.toml": "[tool.uv]\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		source  CommandSource
+		command string
+		want    []string
+	}{
+		{NewGoSource(dir), "fmt-check", []string{"gofmt", "-l", "-d", "."}},
+		{NewCargoSource(dir), "format-check", []string{"cargo", "fmt", "--check"}},
+		{NewUvSource(dir), "fmt-check", []string{"uv", "run", "ruff", "format", "--check"}},
+		{NewPnpmSource(dir), "fmt-check", []string{"pnpm", "exec", "prettier", "--check", "."}},
+		{NewNpmSource(dir), "fmt-check", []string{"npx", "prettier", "--check", "."}},
+	} {
+		cmd := tc.source.FindCommand(tc.command, nil)
+		var got []string
+		if cmd != nil {
+			got = cmd.Args
+		}
+		if !slicesEqual(got, tc.want) {
+			t.Errorf("%s FindCommand(%s) = %v, want %v", tc.source.Name(), tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestFormatCheckFlag(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{Command: "fmt", Args: []string{"--check"}, CurrentDir: dir, ProjectRoot: dir}
+	cmd, err := runner.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd.Args, " "); got != "gofmt -l -d ." {
+		t.Errorf("Resolve() = %q, want gofmt -l -d .", got)
+	}
+
+	// A project's own format takes --check itself, unless it defines
+	// fmt-check too
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, "[commands]\nformat = \"golines -w .\"\n")
+	runner = &CommandRunner{Command: "format", Args: []string{"--check"}, CurrentDir: dir, ProjectRoot: dir}
+	if sub := runner.formatCheck(); sub != nil {
+		t.Errorf("formatCheck() = %s, want nil", sub.Command)
+	}
+	dir = t.TempDir()
+	writeConfig(t, dir, "[commands]\nformat = \"golines -w .\"\n\"format:check\" = \"golines --dry-run .\"\n")
+	runner = &CommandRunner{Command: "format", Args: []string{"--check"}, CurrentDir: dir, ProjectRoot: dir}
+	if sub := runner.formatCheck(); sub == nil || sub.Command != "fmt-check" {
+		t.Errorf("formatCheck() = %v, want fmt-check", sub)
+	}
+}
+
+func TestCheckRunsDefinedFmtCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\n\"check-format\" = \"echo fmt >> log\"\ntest = \"echo test >> log\"\n")
+	runner := &CommandRunner{Command: "check", CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "log")); string(data) != "fmt\ntest\n" {
+		t.Errorf("check ran %q, want fmt then test", data)
+	}
+}
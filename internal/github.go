@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxAnnotations limits the annotations cmdr writes for one step; GitHub
+// shows only the first few of a step anyway
+const maxAnnotations = 50
+
+// githubActions reports whether cmdr is running in a GitHub Actions job
+func githubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// runStep runs sub as a step of a synthesized command or recursive run:
+// folded into a log group under GitHub Actions, with annotations for the
+// errors in its output if it fails, and recorded in the JUnit report
+func (r *CommandRunner) runStep(name string, sub *CommandRunner) (time.Duration, error) {
+	sub.capture = r.stepCapture()
+	start := time.Now()
+	err := inGroup(name, sub.Run)
+	elapsed := time.Since(start)
+	if err != nil {
+		sub.annotate()
+	}
+	r.report.addStep(name, elapsed, err, sub.capture)
+	return elapsed, err
+}
+
+// inGroup runs fn inside a collapsible log group under GitHub Actions
+func inGroup(name string, fn func() error) error {
+	if !githubActions() {
+		return fn()
+	}
+	fmt.Printf("::group::%s\n", escapeWorkflowData(name))
+	defer fmt.Println("::endgroup::")
+	return fn()
+}
+
+// annotation is a diagnostic found in a command's output
+type annotation struct {
+	level   string // error, warning, or notice
+	file    string
+	line    int
+	col     int
+	message string
+	rule    string // the linter's rule or code, if known
+}
+
+// String formats the annotation as a workflow command
+func (a annotation) String() string {
+	props := "file=" + escapeWorkflowProperty(a.file)
+	if a.line > 0 {
+		props += ",line=" + strconv.Itoa(a.line)
+	}
+	if a.col > 0 {
+		props += ",col=" + strconv.Itoa(a.col)
+	}
+	return fmt.Sprintf("::%s %s::%s", a.level, props, escapeWorkflowData(a.message))
+}
+
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// annotate writes GitHub Actions annotations for the diagnostics in the
+// captured output of a failed run, when running under Actions
+func (r *CommandRunner) annotate() {
+	if !githubActions() || r.capture == nil {
+		return
+	}
+	root := os.Getenv("GITHUB_WORKSPACE")
+	if root == "" {
+		root = r.ProjectRoot
+	}
+	for i, a := range parseAnnotations(r.capture.Bytes(), r.CurrentDir, root) {
+		if i == maxAnnotations {
+			break
+		}
+		fmt.Println(a)
+	}
+}
+
+var (
+	// src/main.c:12:5: error: expected ';' (gcc, clang, go vet, ruff,
+	// mypy, eslint -f unix)
+	compilerPattern = regexp.MustCompile(`^\s*([^\s:][^:]*?):(\d+):(?:(\d+):)?\s*(?:(error|warning|note)(?:\[[^\]]*\])?:\s*)?(.+)$`)
+	// src/app.ts(12,5): error TS2322: Type ... (tsc)
+	tscPattern = regexp.MustCompile(`^\s*([^\s(][^(]*)\((\d+),(\d+)\): (error|warning) (.+)$`)
+	// /src/app.py:12:5 - error: ... (pyright)
+	pyrightPattern = regexp.MustCompile(`^\s*(\S+?):(\d+):(\d+) - (error|warning|information): (.+)$`)
+	// error[E0308]: mismatched types, then  --> src/main.rs:2:5 (rustc)
+	rustHeaderPattern   = regexp.MustCompile(`^(error|warning)(?:\[\w+\])?: (.+)$`)
+	rustLocationPattern = regexp.MustCompile(`^\s*--> (\S+?):(\d+):(\d+)$`)
+	// A path on a line of its own, then   12:5  error  message  rule
+	// (eslint's default format)
+	eslintPattern = regexp.MustCompile(`^\s+(\d+):(\d+)\s+(error|warning)\s+(.+?)\s*$`)
+)
+
+// diagnosticPath resolves a path that a command run in dir printed, and
+// makes it relative to root if it is under root. It reports false if the
+// file doesn't exist.
+func diagnosticPath(file, dir, root string) (string, bool) {
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", false
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	return filepath.ToSlash(path), true
+}
+
+// parseAnnotations finds diagnostics in the output of a command run in dir
+// that point at files that exist. Paths are made relative to root, as
+// GitHub expects.
+func parseAnnotations(output []byte, dir, root string) []annotation {
+	var filter logFilter
+	output = filter.strip(output)
+
+	var found []annotation
+	seen := make(map[string]bool)
+	add := func(level, file, line, col, message string) {
+		path, ok := diagnosticPath(file, dir, root)
+		if !ok {
+			return
+		}
+		a := annotation{level: annotationLevel(level), file: path, message: strings.TrimSpace(message)}
+		a.line, _ = strconv.Atoi(line)
+		a.col, _ = strconv.Atoi(col)
+		if key := a.String(); !seen[key] {
+			seen[key] = true
+			found = append(found, a)
+		}
+	}
+
+	var rustLevel, rustMessage, eslintFile string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if m := rustHeaderPattern.FindStringSubmatch(line); m != nil {
+			rustLevel, rustMessage = m[1], m[2]
+			continue
+		}
+		if m := rustLocationPattern.FindStringSubmatch(line); m != nil && rustMessage != "" {
+			add(rustLevel, m[1], m[2], m[3], rustMessage)
+			rustMessage = ""
+			continue
+		}
+		if m := eslintPattern.FindStringSubmatch(line); m != nil && eslintFile != "" {
+			add(m[3], eslintFile, m[1], m[2], m[4])
+			continue
+		}
+		if m := tscPattern.FindStringSubmatch(line); m != nil {
+			add(m[4], m[1], m[2], m[3], m[5])
+			continue
+		}
+		if m := pyrightPattern.FindStringSubmatch(line); m != nil {
+			add(m[4], m[1], m[2], m[3], m[5])
+			continue
+		}
+		if m := compilerPattern.FindStringSubmatch(line); m != nil {
+			add(m[4], m[1], m[2], m[3], m[5])
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); filepath.IsAbs(trimmed) {
+			eslintFile = trimmed
+		} else if trimmed == "" {
+			eslintFile = ""
+		}
+	}
+	return found
+}
+
+// annotationLevel maps a tool's severity to an annotation command
+func annotationLevel(severity string) string {
+	switch severity {
+	case "warning":
+		return "warning"
+	case "note", "information":
+		return "notice"
+	}
+	return "error"
+}
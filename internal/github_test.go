@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "web")
+	for _, file := range []string{"main.go", "src/app.ts", "src/lib.rs", "app.py", "index.js"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := "" +
+		"./main.go:12:5: undefined: foo\n" +
+		"\x1b[31msrc/app.ts(3,7): error TS2322: Type 'string' is not assignable to type 'number'.\x1b[0m\n" +
+		"error[E0308]: mismatched types\n" +
+		"  --> src/lib.rs:2:5\n" +
+		"  " + filepath.Join(dir, "app.py") + ":4:1 - warning: Import \"x\" is not accessed\n" +
+		"app.py:9: error: Incompatible return value  [return-value]\n" +
+		filepath.Join(dir, "index.js") + "\n" +
+		"  1:10  error  'x' is defined but never used  no-unused-vars\n" +
+		"\n" +
+		"missing.go:1:1: not a file here\n" +
+		"ok  \tgithub.com/example/pkg\t0.01s\n"
+
+	got := parseAnnotations([]byte(output), dir, root)
+	want := []string{
+		"::error file=web/main.go,line=12,col=5::undefined: foo",
+		"::error file=web/src/app.ts,line=3,col=7::TS2322: Type 'string' is not assignable to type 'number'.",
+		"::error file=web/src/lib.rs,line=2,col=5::mismatched types",
+		"::warning file=web/app.py,line=4,col=1::Import \"x\" is not accessed",
+		"::error file=web/app.py,line=9::Incompatible return value  [return-value]",
+		"::error file=web/index.js,line=1,col=10::'x' is defined but never used  no-unused-vars",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseAnnotations() = %v, want %d annotations", got, len(want))
+	}
+	for i, a := range got {
+		if a.String() != want[i] {
+			t.Errorf("annotation %d = %q, want %q", i, a.String(), want[i])
+		}
+	}
+}
+
+func TestAnnotationEscaping(t *testing.T) {
+	a := annotation{level: "error", file: "a,b:c.go", line: 1, message: "100% wrong\nsee above"}
+	if got, want := a.String(), "::error file=a%2Cb%3Ac.go,line=1::100%25 wrong%0Asee above"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
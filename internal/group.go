@@ -0,0 +1,725 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// GroupConfig defines processes that run together, such as the services of
+// a development environment
+type GroupConfig struct {
+	Description string                   `toml:"description"`
+	Processes   map[string]ProcessConfig `toml:"processes"`
+}
+
+// ProcessConfig is one process in a group
+type ProcessConfig struct {
+	Run       string            `toml:"run"`        // Shell command line
+	Command   string            `toml:"command"`    // cmdr command to run instead of Run
+	Dir       string            `toml:"dir"`        // Working directory, relative to the config file
+	DependsOn map[string]string `toml:"depends_on"` // Process name → condition
+	Ready     *ReadyCheck       `toml:"ready"`      // Health check, for depends_on "healthy"
+	Stop      *StopConfig       `toml:"stop"`       // How to stop the process
+
+	// Service endpoints, which dependents receive through Exports
+	Port       *int              `toml:"port"`        // Passed to the process as $PORT; 0 picks a free port
+	URLPattern string            `toml:"url_pattern"` // Regexp whose first group, matched in the output, is ${URL}
+	Exports    map[string]string `toml:"exports"`     // Variables set for dependents; may use ${PORT} and ${URL}
+}
+
+// ReadyCheck decides when a process is healthy. Exactly one of TCP, HTTP,
+// and Run is set.
+type ReadyCheck struct {
+	TCP      string   `toml:"tcp"`  // host:port that accepts connections
+	HTTP     string   `toml:"http"` // URL that responds with a non-error status
+	Run      string   `toml:"run"`  // Shell command that exits successfully
+	Interval duration `toml:"interval"`
+	Timeout  duration `toml:"timeout"`
+}
+
+// Conditions a process can wait for in depends_on
+const (
+	conditionStarted   = "started"   // the dependency has been launched
+	conditionHealthy   = "healthy"   // its ready check has passed
+	conditionCompleted = "completed" // it has exited successfully
+)
+
+const (
+	defaultReadyInterval = time.Second
+	defaultReadyTimeout  = time.Minute
+)
+
+// validate checks process definitions and dependencies
+func (g *GroupConfig) validate() error {
+	if len(g.Processes) == 0 {
+		return fmt.Errorf("no processes")
+	}
+	for _, name := range sortCommands(g.Processes) {
+		process := g.Processes[name]
+		if (process.Run == "") == (process.Command == "") {
+			return fmt.Errorf("processes.%s: exactly one of run or command is required", name)
+		}
+		if check := process.Ready; check != nil {
+			set := 0
+			for _, value := range []string{check.TCP, check.HTTP, check.Run} {
+				if value != "" {
+					set++
+				}
+			}
+			if set != 1 {
+				return fmt.Errorf("processes.%s.ready: exactly one of tcp, http, or run is required", name)
+			}
+		}
+		if err := process.validateEndpoints(); err != nil {
+			return fmt.Errorf("processes.%s: %w", name, err)
+		}
+		if process.Stop != nil {
+			if err := process.Stop.validate(); err != nil {
+				return fmt.Errorf("processes.%s.stop: %w", name, err)
+			}
+		}
+		for _, dep := range sortCommands(process.DependsOn) {
+			target, ok := g.Processes[dep]
+			if !ok {
+				return fmt.Errorf("processes.%s.depends_on: unknown process %q", name, dep)
+			}
+			switch condition := process.DependsOn[dep]; condition {
+			case conditionStarted, conditionCompleted:
+			case conditionHealthy:
+				if target.Ready == nil {
+					return fmt.Errorf("processes.%s.depends_on: %s has no ready check", name, dep)
+				}
+			default:
+				return fmt.Errorf("processes.%s.depends_on.%s: unknown condition %q (expected started, healthy, or completed)", name, dep, condition)
+			}
+		}
+	}
+	_, err := g.startOrder()
+	return err
+}
+
+// startOrder returns the process names ordered so that each follows its
+// dependencies
+func (g *GroupConfig) startOrder() ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	order := []string{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s → %s", joinArrow(path), name)
+		}
+		state[name] = visiting
+		for _, dep := range sortCommands(g.Processes[name].DependsOn) {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range sortCommands(g.Processes) {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func joinArrow(names []string) string {
+	return strings.Join(names, " → ")
+}
+
+// groupConfig returns the group named command from the current directory or
+// project root config, and the directory of that config
+func (r *CommandRunner) groupConfig(command string) (*GroupConfig, string) {
+	for _, dir := range r.searchDirs() {
+		config := loadProjectConfig(dir)
+		if config == nil {
+			continue
+		}
+		if group, ok := config.Groups[command]; ok {
+			return &group, dir
+		}
+	}
+	return nil, ""
+}
+
+// groupProcess is the runtime state of a process in a running group
+type groupProcess struct {
+	name    string
+	config  ProcessConfig
+	cmd     *exec.Cmd
+	started chan struct{} // closed once the process is launched
+	healthy chan struct{} // closed once its ready check passes
+	exited  chan struct{} // closed once it exits for good
+	err     error         // exit status of the latest run
+
+	// Guarded by groupRun.mu
+	running    chan struct{} // closed when the current run of cmd exits
+	restarting bool          // the current run is being stopped to restart
+	restarts   int
+
+	port     int           // allocated port, or 0
+	url      string        // URL captured from the output
+	urlFound chan struct{} // closed once url is set
+}
+
+// groupRun coordinates the processes of a running group
+type groupRun struct {
+	runner    *CommandRunner
+	dir       string
+	processes map[string]*groupProcess
+	oneShot   map[string]bool // processes that others wait on to complete
+	order     []string        // start order
+	output    *prefixedOutput
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu       sync.Mutex
+	failure  error
+	stopping bool // set once shutdown begins
+}
+
+// fail records the first reason the group stopped and stops it
+func (g *groupRun) fail(err error) {
+	g.mu.Lock()
+	if g.failure == nil {
+		g.failure = err
+	}
+	g.mu.Unlock()
+	g.cancel()
+}
+
+// RunGroup runs a group's processes, starting each once its dependencies
+// meet their conditions. The group stops when interrupted or when any
+// process exits, other than one-shot processes that exit successfully.
+func (r *CommandRunner) RunGroup(name string, group *GroupConfig, dir string) error {
+	if err := refuseToRun("group " + name); err != nil {
+		return err
+	}
+	order, err := group.startOrder()
+	if err != nil {
+		return err
+	}
+	defer writeGroupPidFile(dir, name)()
+	if r.Dashboard {
+		return r.runGroupDashboard(name, group, dir)
+	}
+	fmt.Fprintf(os.Stderr, "%sStarting group %s: %s\n", r.badge(), name, joinArrow(order))
+	return r.runGroup(group, dir, r.tee(os.Stdout))
+}
+
+// runGroup runs the group, writing the processes' prefixed output to out
+func (r *CommandRunner) runGroup(group *GroupConfig, dir string, out io.Writer) error {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	run, ctx, err := r.startGroup(group, dir, out)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-interrupt:
+		run.cancel()
+	case <-ctx.Done():
+	}
+	if err := run.stop(); err != nil {
+		return err
+	}
+	return r.context().Err()
+}
+
+// startGroup launches a supervisor for each of the group's processes. The
+// returned context is done once the group should stop.
+func (r *CommandRunner) startGroup(group *GroupConfig, dir string, out io.Writer) (*groupRun, context.Context, error) {
+	order, err := group.startOrder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(r.context())
+	run := &groupRun{
+		runner:    r,
+		dir:       dir,
+		processes: make(map[string]*groupProcess),
+		oneShot:   make(map[string]bool),
+		order:     order,
+		output:    newPrefixedOutput(out, order),
+		cancel:    cancel,
+	}
+	if !r.Dashboard && colorEnabled(os.Stdout) {
+		run.output.colorize(order)
+	}
+	for _, processName := range order {
+		process := group.Processes[processName]
+		run.processes[processName] = &groupProcess{
+			name:     processName,
+			config:   process,
+			started:  make(chan struct{}),
+			healthy:  make(chan struct{}),
+			exited:   make(chan struct{}),
+			urlFound: make(chan struct{}),
+		}
+		for dep, condition := range process.DependsOn {
+			if condition == conditionCompleted {
+				run.oneShot[dep] = true
+			}
+		}
+	}
+
+	for _, processName := range order {
+		run.wg.Add(1)
+		go func(p *groupProcess) {
+			defer run.wg.Done()
+			run.supervise(ctx, p)
+		}(run.processes[processName])
+	}
+	return run, ctx, nil
+}
+
+// stop shuts the group down, waits for its supervisors, and returns the
+// reason it stopped, if any
+func (g *groupRun) stop() error {
+	g.cancel()
+	g.shutdown(g.order)
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.failure
+}
+
+// supervise waits for a process's dependencies, then runs it to completion
+func (g *groupRun) supervise(ctx context.Context, p *groupProcess) {
+	defer close(p.exited)
+
+	for _, dep := range sortCommands(p.config.DependsOn) {
+		if err := g.waitFor(ctx, g.processes[dep], p.config.DependsOn[dep]); err != nil {
+			if ctx.Err() == nil {
+				g.output.logf(p.name, "not started: %v", err)
+				g.fail(fmt.Errorf("%s: %w", p.name, err))
+			}
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	if p.config.Port != nil {
+		port, err := allocatePort(*p.config.Port)
+		if err != nil {
+			g.output.logf(p.name, "no free port: %v", err)
+			g.fail(fmt.Errorf("%s: %w", p.name, err))
+			return
+		}
+		p.port = port
+	}
+
+	writer := g.output.writer(p.name)
+	if p.config.URLPattern != "" {
+		writer.onLine = g.captureURL(p)
+	}
+	// The dashboard draws output itself, and escape sequences would garble it
+	usePTY := g.runner.usePTY() && !g.runner.Dashboard
+	for {
+		cmd, err := g.command(p)
+		if err != nil {
+			g.output.logf(p.name, "%v", err)
+			g.fail(fmt.Errorf("%s: %w", p.name, err))
+			return
+		}
+		if !usePTY {
+			cmd.Stdout = writer
+			cmd.Stderr = writer
+		}
+		setProcessGroup(cmd)
+		// Don't wait indefinitely for output from children that outlive a
+		// stopped process
+		cmd.WaitDelay = time.Second
+
+		// Starting is serialized with shutdown, so that a process can't start
+		// after shutdown has passed it
+		g.mu.Lock()
+		if g.stopping {
+			g.mu.Unlock()
+			return
+		}
+		start := time.Now()
+		var session *ptySession
+		if usePTY {
+			session, err = startPTY(cmd, writer)
+		} else {
+			err = cmd.Start()
+		}
+		if err != nil {
+			g.mu.Unlock()
+			g.output.logf(p.name, "failed to start: %v", err)
+			g.fail(fmt.Errorf("%s: %w", p.name, err))
+			return
+		}
+		p.cmd = cmd
+		p.running = make(chan struct{})
+		first := p.restarts == 0
+		if first {
+			close(p.started)
+		}
+		g.mu.Unlock()
+
+		if first && p.config.Ready != nil {
+			go g.pollReady(ctx, p)
+		}
+
+		err = cmd.Wait()
+		if session != nil {
+			session.close()
+		}
+		writer.flush()
+		g.runner.audit("process", cmd, start, err)
+		g.mu.Lock()
+		p.err = err
+		close(p.running)
+		restarting := p.restarting
+		p.restarting = false
+		g.mu.Unlock()
+		if !restarting || ctx.Err() != nil {
+			break
+		}
+		g.output.logf(p.name, "restarting")
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if p.err == nil && g.oneShot[p.name] {
+		g.output.logf(p.name, "completed")
+		return
+	}
+	if p.err != nil {
+		g.output.logf(p.name, "exited: %v", p.err)
+		g.fail(fmt.Errorf("%s exited: %w", p.name, p.err))
+	} else {
+		g.output.logf(p.name, "exited")
+		g.fail(nil)
+	}
+}
+
+// restart stops a running process and starts it again without stopping the
+// rest of the group. Dependents are not restarted.
+func (g *groupRun) restart(p *groupProcess) error {
+	g.mu.Lock()
+	if g.stopping || p.cmd == nil || p.restarting || isClosed(p.running) {
+		g.mu.Unlock()
+		return fmt.Errorf("%s is not running", p.name)
+	}
+	p.restarting = true
+	p.restarts++
+	cmd, running := p.cmd, p.running
+	g.mu.Unlock()
+	g.terminate(p, cmd, running)
+	return nil
+}
+
+// isClosed reports whether a channel that is only ever closed has been
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitFor blocks until dep meets condition, and has printed its URL if it
+// declares a url_pattern, returning an error if it can't
+func (g *groupRun) waitFor(ctx context.Context, dep *groupProcess, condition string) error {
+	if err := g.waitForCondition(ctx, dep, condition); err != nil {
+		return err
+	}
+	if dep.config.URLPattern == "" || condition == conditionCompleted {
+		return nil
+	}
+	select {
+	case <-dep.urlFound:
+		return nil
+	case <-dep.exited:
+		return fmt.Errorf("%s exited before printing its URL", dep.name)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *groupRun) waitForCondition(ctx context.Context, dep *groupProcess, condition string) error {
+	var reached chan struct{}
+	switch condition {
+	case conditionStarted:
+		reached = dep.started
+	case conditionHealthy:
+		reached = dep.healthy
+	case conditionCompleted:
+		select {
+		case <-dep.exited:
+			if dep.err != nil || dep.cmd == nil {
+				return fmt.Errorf("%s did not complete successfully", dep.name)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-reached:
+		return nil
+	case <-dep.exited:
+		return fmt.Errorf("%s exited before it was %s", dep.name, condition)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// command builds the process's command line, running either its shell
+// command or a command resolved by cmdr
+func (g *groupRun) command(p *groupProcess) (*exec.Cmd, error) {
+	dir := g.processDir(p.config)
+	if p.config.Command != "" {
+		sub := g.runner.subRunner(p.config.Command, nil)
+		sub.CurrentDir = dir
+		cmd, err := sub.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = withEnv(sub.commandEnv(), g.processEnv(p))
+		return cmd, nil
+	}
+	cmd := g.runner.executor.shell(p.config.Run, nil)
+	cmd.Dir = dir
+	cmd.Env = withEnv(g.runner.commandEnv(), g.processEnv(p))
+	return cmd, nil
+}
+
+// processDir returns the working directory of a process
+func (g *groupRun) processDir(config ProcessConfig) string {
+	if config.Dir != "" {
+		return filepath.Join(g.dir, config.Dir)
+	}
+	return g.dir
+}
+
+// pollReady runs the process's ready check until it passes, the process
+// exits, or the check times out
+func (g *groupRun) pollReady(ctx context.Context, p *groupProcess) {
+	check := p.config.Ready
+	interval := time.Duration(check.Interval)
+	if interval <= 0 {
+		interval = defaultReadyInterval
+	}
+	timeout := time.Duration(check.Timeout)
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if g.isReady(ctx, p) {
+			g.output.logf(p.name, "ready")
+			close(p.healthy)
+			return
+		}
+		if time.Now().After(deadline) {
+			g.output.logf(p.name, "not ready after %s", timeout)
+			g.fail(timeoutError(fmt.Errorf("%s not ready after %s", p.name, timeout)))
+			return
+		}
+		select {
+		case <-time.After(interval):
+		case <-p.exited:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isReady runs one attempt of a ready check
+func (g *groupRun) isReady(ctx context.Context, p *groupProcess) bool {
+	check := p.config.Ready
+	attemptCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	switch {
+	case check.TCP != "":
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(attemptCtx, "tcp", p.expand(check.TCP))
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	case check.HTTP != "":
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, p.expand(check.HTTP), nil)
+		if err != nil {
+			return false
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode < 400
+	default:
+		cmd := g.runner.executor.shell(p.expand(check.Run), nil)
+		cmd.Dir = g.processDir(p.config)
+		cmd.Env = g.runner.commandEnv()
+		start := time.Now()
+		err := cmd.Run()
+		g.runner.audit("ready check", cmd, start, err)
+		return err == nil
+	}
+}
+
+// prefixedOutput interleaves the output of several processes line by line,
+// labelling each line with the process name
+type prefixedOutput struct {
+	mu     sync.Mutex
+	out    io.Writer
+	width  int
+	colors map[string]string   // SGR codes of the labels, if colored
+	logs   map[string][]string // recent lines of each process, for the dashboard
+}
+
+// labelColors are the colors labels take in turn
+var labelColors = []string{"cyan", "magenta", "yellow", "blue", "green", "bright-cyan", "bright-magenta", "bright-yellow", "bright-blue", "bright-green"}
+
+// colorize gives each name's label a color of its own, cycling through
+// labelColors
+func (o *prefixedOutput) colorize(names []string) {
+	o.colors = make(map[string]string)
+	for i, name := range names {
+		o.colors[name] = themeColors[labelColors[i%len(labelColors)]]
+	}
+}
+
+// label returns name padded to the width of the longest name, and colored
+// if labels are. The caller holds o.mu.
+func (o *prefixedOutput) label(name string) string {
+	padded := fmt.Sprintf("%-*s", o.width, name)
+	if code := o.colors[name]; code != "" {
+		return "\x1b[" + code + "m" + padded + "\x1b[0m"
+	}
+	return padded
+}
+
+// maxLogLines is how many lines of each process's output are kept
+const maxLogLines = 1000
+
+func newPrefixedOutput(out io.Writer, names []string) *prefixedOutput {
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	return &prefixedOutput{out: out, width: width, logs: make(map[string][]string)}
+}
+
+// logf prints a status line for a process
+func (o *prefixedOutput) logf(name string, format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	line := "▸ " + fmt.Sprintf(format, args...)
+	o.record(name, line)
+	fmt.Fprintf(o.out, "%s | %s\n", o.label(name), line)
+}
+
+// record keeps a line of a process's output. The caller holds o.mu.
+func (o *prefixedOutput) record(name, line string) {
+	lines := append(o.logs[name], line)
+	if len(lines) > maxLogLines {
+		lines = slices.Clone(lines[len(lines)-maxLogLines:])
+	}
+	o.logs[name] = lines
+}
+
+// recent returns up to n of a process's most recent lines
+func (o *prefixedOutput) recent(name string, n int) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines := o.logs[name]
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return slices.Clone(lines)
+}
+
+// writer returns a writer that prefixes each complete line with name
+func (o *prefixedOutput) writer(name string) *prefixWriter {
+	return &prefixWriter{output: o, name: name}
+}
+
+// prefixWriter buffers a process's output until a line is complete
+type prefixWriter struct {
+	mu      sync.Mutex
+	output  *prefixedOutput
+	name    string
+	pending []byte
+	onLine  func(line []byte) // called with each line before it is written
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes any final unterminated line
+func (w *prefixWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.writeLine(w.pending)
+		w.pending = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	if w.onLine != nil {
+		w.onLine(line)
+	}
+	text := string(bytes.TrimRight(line, "\r"))
+	if strings.Contains(text, "\x1b[") {
+		// Don't let a color carry over to the next line's prefix
+		text += "\x1b[0m"
+	}
+	w.output.mu.Lock()
+	defer w.output.mu.Unlock()
+	w.output.record(w.name, text)
+	fmt.Fprintf(w.output.out, "%s | %s\n", w.output.label(w.name), text)
+}
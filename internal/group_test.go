@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupStartOrder(t *testing.T) {
+	group := &GroupConfig{Processes: map[string]ProcessConfig{
+		"web":     {Run: "web", DependsOn: map[string]string{"db": "healthy", "migrate": "completed"}},
+		"migrate": {Run: "migrate", DependsOn: map[string]string{"db": "healthy"}},
+		"db":      {Run: "db", Ready: &ReadyCheck{TCP: "localhost:5432"}},
+		"worker":  {Run: "worker"},
+	}}
+	if err := group.validate(); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := group.startOrder()
+	expected := []string{"db", "migrate", "web", "worker"}
+	if !slicesEqual(order, expected) {
+		t.Errorf("startOrder() = %v, want %v", order, expected)
+	}
+}
+
+func TestGroupValidateErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes map[string]ProcessConfig
+		message   string
+	}{
+		{"no command", map[string]ProcessConfig{"a": {}}, "exactly one of run or command"},
+		{"unknown dependency", map[string]ProcessConfig{"a": {Run: "x", DependsOn: map[string]string{"b": "started"}}}, "unknown process"},
+		{"healthy without check", map[string]ProcessConfig{
+			"a": {Run: "x", DependsOn: map[string]string{"b": "healthy"}},
+			"b": {Run: "y"},
+		}, "no ready check"},
+		{"bad condition", map[string]ProcessConfig{
+			"a": {Run: "x", DependsOn: map[string]string{"b": "ready"}},
+			"b": {Run: "y"},
+		}, "unknown condition"},
+		{"bad stop", map[string]ProcessConfig{
+			"a": {Run: "x", Stop: &StopConfig{Signal: "TERM", Run: "down"}},
+		}, "mutually exclusive"},
+		{"cycle", map[string]ProcessConfig{
+			"a": {Run: "x", DependsOn: map[string]string{"b": "started"}},
+			"b": {Run: "y", DependsOn: map[string]string{"a": "started"}},
+		}, "dependency cycle"},
+		{"export without port", map[string]ProcessConfig{
+			"a": {Run: "x", Exports: map[string]string{"A_PORT": "${PORT}"}},
+		}, "has no port"},
+		{"url pattern without group", map[string]ProcessConfig{
+			"a": {Run: "x", URLPattern: "http://\\S+"},
+		}, "captures the URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &GroupConfig{Processes: tt.processes}
+			err := group.validate()
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("validate() error = %v, want it to mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestRunGroupWaitsForDependencies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	tempDir := t.TempDir()
+	marker := filepath.Join(tempDir, "db-ready")
+	group := &GroupConfig{Processes: map[string]ProcessConfig{
+		"db":      {Run: "sleep 0.2; touch " + marker + "; sleep 10", Ready: &ReadyCheck{Run: "test -f " + marker, Interval: duration(50 * time.Millisecond)}},
+		"migrate": {Run: "echo migrated", DependsOn: map[string]string{"db": "healthy"}},
+		"web":     {Run: "echo serving", DependsOn: map[string]string{"db": "healthy", "migrate": "completed"}},
+	}}
+
+	var out bytes.Buffer
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+	if err := runner.runGroup(group, tempDir, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	expected := []string{
+		"db | ▸ ready",
+		"migrate | migrated",
+		"migrate | ▸ completed",
+		"web | serving",
+		"web | ▸ exited",
+		"db | ▸ stopping",
+	}
+	if !slicesEqual(lines, expected) {
+		t.Errorf("output = %q, want %q", lines, expected)
+	}
+}
+
+func TestRunGroupExportsEndpoints(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	tempDir := t.TempDir()
+	port := 0
+	group := &GroupConfig{Processes: map[string]ProcessConfig{
+		"api": {
+			Run:        "printf '\\033[1mListening on http://127.0.0.1:%s/\\033[0m\\n' \"$PORT\"; sleep 10",
+			Port:       &port,
+			URLPattern: `Listening on (\S+)`,
+			Exports:    map[string]string{"API_BASE_URL": "${URL}api", "API_PORT": "${PORT}"},
+		},
+		"client": {
+			Run:       `echo "$API_BASE_URL $API_PORT"`,
+			DependsOn: map[string]string{"api": "started"},
+		},
+	}}
+
+	var out bytes.Buffer
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+	if err := runner.runGroup(group, tempDir, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var url, client string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if _, value, ok := strings.Cut(line, "▸ url "); ok {
+			url = value
+		}
+		if name, value, ok := strings.Cut(line, " | "); ok && strings.TrimSpace(name) == "client" && !strings.HasPrefix(value, "▸") {
+			client = value
+		}
+	}
+	if !strings.HasPrefix(url, "http://127.0.0.1:") {
+		t.Fatalf("captured url = %q in output %q", url, out.String())
+	}
+	port = 0
+	if _, err := fmt.Sscanf(strings.TrimPrefix(url, "http://127.0.0.1:"), "%d", &port); err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprintf("%sapi %d", url, port); client != expected {
+		t.Errorf("client output = %q, want %q", client, expected)
+	}
+}
+
+func TestRunGroupStopsInReverseOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell and signals")
+	}
+	tempDir := t.TempDir()
+	group := &GroupConfig{Processes: map[string]ProcessConfig{
+		"db": {
+			Run:  "sleep 30",
+			Stop: &StopConfig{Run: "echo db down", Timeout: duration(200 * time.Millisecond)},
+		},
+		"api": {
+			Run:       "trap 'echo api stopping; exit 0' INT; while :; do sleep 0.05; done",
+			DependsOn: map[string]string{"db": "started"},
+			Stop:      &StopConfig{Signal: "INT"},
+		},
+		"web": {
+			Run:       "sleep 0.3; exit 3",
+			DependsOn: map[string]string{"api": "started"},
+		},
+	}}
+
+	var out bytes.Buffer
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+	err := runner.runGroup(group, tempDir, &out)
+	if err == nil || !strings.Contains(err.Error(), "web exited") {
+		t.Errorf("runGroup() error = %v, want web exited", err)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	expected := []string{
+		"web | ▸ exited: exit status 3",
+		"api | ▸ stopping",
+		"api | api stopping",
+		"db | ▸ stopping: echo db down",
+		"db | db down",
+		"db | ▸ still running after 200ms; killing",
+	}
+	if !slicesEqual(lines, expected) {
+		t.Errorf("output = %q, want %q", lines, expected)
+	}
+}
+
+func TestParseStopSignal(t *testing.T) {
+	for _, name := range []string{"", "TERM", "SIGINT", "hup"} {
+		if _, err := parseStopSignal(name); err != nil {
+			t.Errorf("parseStopSignal(%q) error = %v", name, err)
+		}
+	}
+	if _, err := parseStopSignal("SIGWINCH"); err == nil {
+		t.Error("parseStopSignal(SIGWINCH) error = nil")
+	}
+}
+
+func TestRunGroupRestartsProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell and signals")
+	}
+	tempDir := t.TempDir()
+	group := &GroupConfig{Processes: map[string]ProcessConfig{
+		"api": {Run: "echo up; sleep 30", Stop: &StopConfig{Timeout: duration(200 * time.Millisecond)}},
+	}}
+
+	var out bytes.Buffer
+	runner := &CommandRunner{CurrentDir: tempDir, ProjectRoot: tempDir}
+	run, _, err := runner.startGroup(group, tempDir, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := run.processes["api"]
+	waitForLines := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for len(run.output.recent("api", n)) < n && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForLines(1)
+	if err := run.restart(api); err != nil {
+		t.Fatal(err)
+	}
+	if status := run.status(api); !strings.HasSuffix(status, "↻1") {
+		t.Errorf("status after restart = %q", status)
+	}
+	// Wait for the second run's output before stopping the group
+	waitForLines(4)
+	if err := run.stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"up", "▸ stopping", "▸ restarting", "up", "▸ stopping"}
+	if lines := run.output.recent("api", 10); !slicesEqual(lines, expected) {
+		t.Errorf("output = %q, want %q", lines, expected)
+	}
+}
@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryEntry is a line of the history file: one cmdr invocation
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Cwd      string    `json:"cwd"`
+	Command  string    `json:"command"`
+	Args     []string  `json:"args,omitempty"`
+	Exec     []string  `json:"exec,omitempty"` // the command lines run, in order
+	ExitCode int       `json:"exit_code"`
+	Duration float64   `json:"duration_seconds"`
+}
+
+// runTrace collects the command lines a run executes, including those of
+// the runs inside it
+type runTrace struct {
+	mu   sync.Mutex
+	exec []string
+}
+
+func (t *runTrace) add(argv []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exec = append(t.exec, strings.Join(argv, " "))
+}
+
+// historyMu serializes writes from this process; a file lock serializes them
+// with other cmdr processes
+var historyMu sync.Mutex
+
+// HistoryPath returns the history file, in $XDG_DATA_HOME/cmdr (by default
+// ~/.local/share/cmdr), or "" if history = false in the user config
+func HistoryPath() string {
+	if config := LoadUserConfig(); config != nil && config.History != nil && !*config.History {
+		return ""
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "cmdr", "history.jsonl")
+}
+
+// recordHistory appends the finished run to the history file. Failing to
+// write it is reported but doesn't fail the command.
+func (r *CommandRunner) recordHistory(start time.Time, err error) {
+	path := HistoryPath()
+	if path == "" {
+		return
+	}
+	entry := HistoryEntry{
+		Time:     start.UTC(),
+		Cwd:      r.CurrentDir,
+		Command:  r.Command,
+		Args:     r.Args,
+		ExitCode: ExitCode(err),
+		Duration: time.Since(start).Seconds(),
+	}
+	if r.trace != nil {
+		entry.Exec = r.trace.exec
+	}
+	if err := appendHistoryEntry(path, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: can't write history %s: %v\n", path, err)
+	}
+}
+
+func appendHistoryEntry(path string, entry HistoryEntry) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// HistoryFilter selects the entries `cmdr history` shows
+type HistoryFilter struct {
+	Limit  int    // the most recent entries to show, or 0 for all
+	Failed bool   // only runs that failed
+	Dir    string // only runs in this directory or below it
+	Match  string // only runs whose command or command lines contain this
+}
+
+func (f HistoryFilter) matches(entry HistoryEntry) bool {
+	if f.Failed && entry.ExitCode == 0 {
+		return false
+	}
+	if f.Dir != "" && entry.Cwd != f.Dir && !strings.HasPrefix(entry.Cwd, f.Dir+string(filepath.Separator)) {
+		return false
+	}
+	if f.Match != "" {
+		text := strings.Join(append(append([]string{entry.Command}, entry.Args...), entry.Exec...), " ")
+		if !strings.Contains(text, f.Match) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadHistory returns the entries of the history file that filter selects,
+// oldest first
+func ReadHistory(path string, filter HistoryFilter) ([]HistoryEntry, error) {
+	lines, err := readJSONLines(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []HistoryEntry
+	for _, line := range lines {
+		var entry HistoryEntry
+		if json.Unmarshal(line, &entry) != nil || !filter.matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// ShowHistory writes the entries filter selects, one per line: when, exit
+// code, duration, directory, and the command with what it ran
+func ShowHistory(w io.Writer, path string, filter HistoryFilter) error {
+	entries, err := ReadHistory(path, filter)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		command := strings.Join(append([]string{entry.Command}, entry.Args...), " ")
+		for _, line := range entry.Exec {
+			command += "  → " + line
+		}
+		fmt.Fprintf(w, "%s %3d %7s  %s  %s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.ExitCode,
+			roundDuration(time.Duration(entry.Duration*float64(time.Second))),
+			entry.Cwd,
+			command)
+	}
+	return nil
+}
+
+// runStats summarizes the recorded runs of command in the runner's project:
+// when it last ran, its exit code and duration, and how many runs there
+// are. It returns "" when history is off or there are none.
+func (r *CommandRunner) runStats(command string) string {
+	path := HistoryPath()
+	if path == "" {
+		return ""
+	}
+	dir := r.ProjectRoot
+	if dir == "" {
+		dir = r.CurrentDir
+	}
+	entries, err := ReadHistory(path, HistoryFilter{Dir: dir})
+	if err != nil {
+		return ""
+	}
+	var last *HistoryEntry
+	count := 0
+	for i := range entries {
+		if entries[i].Command == command {
+			last = &entries[i]
+			count++
+		}
+	}
+	if last == nil {
+		return ""
+	}
+	runs := "runs"
+	if count == 1 {
+		runs = "run"
+	}
+	return fmt.Sprintf("Last run: %s, exit %d, %s (%d %s)",
+		last.Time.Local().Format("2006-01-02 15:04:05"),
+		last.ExitCode,
+		roundDuration(time.Duration(last.Duration*float64(time.Second))),
+		count, runs)
+}
+
+// LastRun returns the most recent history entry for a run in the runner's
+// project, for `cmdr last`
+func (r *CommandRunner) LastRun() (*HistoryEntry, error) {
+	path := HistoryPath()
+	if path == "" {
+		return nil, fmt.Errorf("history is off; history = false in %s", UserConfigPath())
+	}
+	entries, err := ReadHistory(path, HistoryFilter{Limit: 1, Dir: r.ProjectRoot})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, notFoundError(fmt.Errorf("no runs recorded in %s", r.ProjectRoot))
+	}
+	return &entries[0], nil
+}
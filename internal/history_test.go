@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain keeps the runs in tests out of the real history file, and their
+// listings out of the real cache
+func TestMain(m *testing.M) {
+	dataHome, err := os.MkdirTemp("", "cmdr-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_DATA_HOME", dataHome)
+	// Keep listings out of the user's cache and away from their daemon
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dataHome, "cache"))
+	os.Setenv("CMDR_DAEMON_SOCKET", filepath.Join(dataHome, "daemon.sock"))
+	code := m.Run()
+	os.RemoveAll(dataHome)
+	os.Exit(code)
+}
+
+func TestRecordHistory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setUserConfig(t, "")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := HistoryPath()
+
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nhi = \"true\"\nbad = \"exit 4\"\n")
+	for _, command := range []string{"hi", "bad"} {
+		runner := &CommandRunner{Command: command, Args: []string{"x"}, CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+		runner.Run()
+	}
+
+	entries, err := ReadHistory(path, HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("ReadHistory() = %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Command != "hi" || e.Cwd != dir || e.ExitCode != 0 || len(e.Exec) != 1 || !strings.Contains(e.Exec[0], "true") {
+		t.Errorf("entries[0] = %+v", e)
+	}
+	if e := entries[1]; e.Command != "bad" || e.ExitCode != 4 {
+		t.Errorf("entries[1] = %+v", e)
+	}
+
+	setUserConfig(t, "history = false")
+	if path := HistoryPath(); path != "" {
+		t.Errorf("HistoryPath() with history = false = %q", path)
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	for _, entry := range []HistoryEntry{
+		{Time: now, Cwd: filepath.FromSlash("/src/app"), Command: "test", Exec: []string{"go test ./..."}},
+		{Time: now, Cwd: filepath.FromSlash("/src/app/web"), Command: "build", ExitCode: 1, Exec: []string{"npm run build"}},
+		{Time: now, Cwd: filepath.FromSlash("/src/application"), Command: "lint", ExitCode: 2},
+	} {
+		if err := appendHistoryEntry(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		filter HistoryFilter
+		want   []string
+	}{
+		{HistoryFilter{}, []string{"test", "build", "lint"}},
+		{HistoryFilter{Limit: 1}, []string{"lint"}},
+		{HistoryFilter{Failed: true}, []string{"build", "lint"}},
+		{HistoryFilter{Dir: filepath.FromSlash("/src/app")}, []string{"test", "build"}},
+		{HistoryFilter{Match: "npm"}, []string{"build"}},
+	}
+	for _, tt := range tests {
+		entries, err := ReadHistory(path, tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Command)
+		}
+		if !slicesEqual(got, tt.want) {
+			t.Errorf("ReadHistory(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestLastRun(t *testing.T) {
+	setUserConfig(t, "")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := HistoryPath()
+	root := t.TempDir()
+	other := t.TempDir()
+	sub := filepath.Join(root, "web")
+
+	runner := &CommandRunner{CurrentDir: root, ProjectRoot: root}
+	if _, err := runner.LastRun(); ExitCode(err) != ExitNotFound {
+		t.Errorf("LastRun() with no history: error = %v, want not found", err)
+	}
+	for _, entry := range []HistoryEntry{
+		{Cwd: root, Command: "build"},
+		{Cwd: sub, Command: "test", Args: []string{"-v"}},
+		{Cwd: other, Command: "lint"},
+	} {
+		if err := appendHistoryEntry(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry, err := runner.LastRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Command != "test" || entry.Cwd != sub || !slicesEqual(entry.Args, []string{"-v"}) {
+		t.Errorf("LastRun() = %+v, want test -v in %s", entry, sub)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	setUserConfig(t, "")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := HistoryPath()
+	root := t.TempDir()
+
+	runner := &CommandRunner{CurrentDir: root, ProjectRoot: root}
+	if stats := runner.runStats("test"); stats != "" {
+		t.Errorf("runStats() with no history = %q", stats)
+	}
+	last := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	for _, entry := range []HistoryEntry{
+		{Time: last.Add(-time.Hour), Cwd: root, Command: "test", Duration: 1},
+		{Time: last.Add(-time.Minute), Cwd: root, Command: "build"},
+		{Time: last, Cwd: filepath.Join(root, "web"), Command: "test", ExitCode: 2, Duration: 3.25},
+		{Time: last, Cwd: t.TempDir(), Command: "test"},
+	} {
+		if err := appendHistoryEntry(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := runner.runStats("test"), "Last run: 2026-03-01 09:30:00, exit 2, 3.3s (2 runs)"; got != want {
+		t.Errorf("runStats(test) = %q, want %q", got, want)
+	}
+	if got, want := runner.runStats("build"), "Last run: 2026-03-01 09:29:00, exit 0, 0s (1 run)"; got != want {
+		t.Errorf("runStats(build) = %q, want %q", got, want)
+	}
+	if stats := runner.runStats("lint"); stats != "" {
+		t.Errorf("runStats(lint) = %q, want nothing for a command that hasn't run", stats)
+	}
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// commandHook is a shell command from a config's [pre] or [post] table
+type commandHook struct {
+	run string
+	dir string // directory of the config file, where the hook runs
+}
+
+// commandHooks returns the pre and post hooks for command from the current
+// directory or project root config, checking command variants as resolution
+// does. The nearest config with a hook for the command wins.
+func (r *CommandRunner) commandHooks(command string) (pre, post *commandHook) {
+	find := func(hooks func(*ProjectConfig) map[string]string) *commandHook {
+		for _, dir := range r.searchDirs() {
+			config := loadProjectConfig(dir)
+			if config == nil {
+				continue
+			}
+			for _, variant := range GetCommandVariants(command) {
+				if run, ok := hooks(config)[variant]; ok {
+					return &commandHook{run: run, dir: dir}
+				}
+			}
+		}
+		return nil
+	}
+	pre = find(func(c *ProjectConfig) map[string]string { return c.Pre })
+	post = find(func(c *ProjectConfig) map[string]string { return c.Post })
+	return pre, post
+}
+
+// runWithHooks runs the command's pre hook, then run, then its post hook. A
+// failed pre hook aborts the run. The post hook runs whether or not the
+// command succeeded, with its exit code in CMDR_EXIT_CODE.
+func (r *CommandRunner) runWithHooks(run func() error) error {
+	pre, post := r.commandHooks(r.Command)
+	if pre != nil {
+		if err := r.runHook("pre", pre, nil); err != nil {
+			return fmt.Errorf("pre hook for '%s' failed: %w", r.Command, err)
+		}
+	}
+
+	err := run()
+
+	if post != nil {
+		exitCode := 0
+		if err != nil {
+			exitCode = 1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			}
+		}
+		env := map[string]string{"CMDR_EXIT_CODE": strconv.Itoa(exitCode)}
+		if hookErr := r.runHook("post", post, env); hookErr != nil {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: post hook for '%s' failed: %v\n", r.Command, hookErr)
+			} else {
+				err = fmt.Errorf("post hook for '%s' failed: %w", r.Command, hookErr)
+			}
+		}
+	}
+	return err
+}
+
+// runHook runs a hook in the foreground with the command's environment
+func (r *CommandRunner) runHook(kind string, hook *commandHook, vars map[string]string) error {
+	if err := refuseToRun(kind + " hook " + hook.run); err != nil {
+		return err
+	}
+	cmd := r.executor.shell(hook.run, nil)
+	cmd.Dir = hook.dir
+	cmd.Env = withEnv(r.commandEnv(), vars)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = r.tee(os.Stdout)
+	cmd.Stderr = r.tee(os.Stderr)
+	if r.output != nil {
+		output := r.captured(r.output)
+		cmd.Stdin = nil
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
+	r.progressf("%sRunning %s hook: %s\n", r.badge(), kind, hook.run)
+	r.outputLog.printf("\n$ %s\n", hook.run)
+	start := time.Now()
+	err := runForeground(r.context(), cmd)
+	r.audit(kind+" hook", cmd, start, err)
+	recordPhase(start, "run %s hook: %s", kind, hook.run)
+	return err
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunWithHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	tests := []struct {
+		name     string
+		config   string
+		command  string
+		expected string
+		wantErr  string
+	}{
+		{
+			name: "success",
+			config: `[commands]
+test = "echo test >> log"
+[pre]
+test = "echo pre >> log"
+[post]
+test = "echo post $CMDR_EXIT_CODE >> log"
+`,
+			command:  "test",
+			expected: "pre\ntest\npost 0\n",
+		},
+		{
+			name: "alias",
+			config: `[commands]
+test = "echo test >> log"
+[pre]
+test = "echo pre >> log"
+`,
+			command:  "t",
+			expected: "pre\ntest\n",
+		},
+		{
+			name: "failed pre hook",
+			config: `[commands]
+test = "echo test >> log"
+[pre]
+test = "echo pre >> log; exit 1"
+[post]
+test = "echo post >> log"
+`,
+			command:  "test",
+			expected: "pre\n",
+			wantErr:  "pre hook for 'test' failed",
+		},
+		{
+			name: "failed command",
+			config: `[commands]
+test = "echo test >> log; exit 3"
+[post]
+test = "echo post $CMDR_EXIT_CODE >> log"
+`,
+			command:  "test",
+			expected: "test\npost 3\n",
+			wantErr:  "exit status 3",
+		},
+		{
+			name: "failed post hook",
+			config: `[commands]
+test = "echo test >> log"
+[post]
+test = "exit 2"
+`,
+			command:  "test",
+			expected: "test\n",
+			wantErr:  "post hook for 'test' failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeConfig(t, tempDir, tt.config)
+			runner := &CommandRunner{Command: tt.command, CurrentDir: tempDir, ProjectRoot: tempDir}
+			err := runner.Run()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			log, _ := os.ReadFile(filepath.Join(tempDir, "log"))
+			if string(log) != tt.expected {
+				t.Errorf("log = %q, want %q", log, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scriptDirs are the directories whose scripts cmdr init suggests as
+// custom commands
+var scriptDirs = []string{"scripts", "bin"}
+
+// InitConfig writes a starter .cmdr.toml in the current directory, or with
+// dryRun writes it to w instead. It won't replace an existing config unless
+// force is set.
+func (r *CommandRunner) InitConfig(w io.Writer, force, dryRun bool) error {
+	content := r.starterConfig()
+	if dryRun {
+		_, err := io.WriteString(w, content)
+		return err
+	}
+	path := filepath.Join(r.CurrentDir, ProjectConfigFile)
+	if FileExists(path) && !force {
+		return fmt.Errorf("%s already exists (use --force to replace it, or --dry-run to see the starter config)", path)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %s\n", path)
+	return nil
+}
+
+// starterConfig returns the text of the .cmdr.toml that init writes: the
+// detected sources, check's steps, and suggested commands, all as comments,
+// so that the file changes nothing until it's edited
+func (r *CommandRunner) starterConfig() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# cmdr configuration for %s\n", filepath.Base(r.CurrentDir))
+	b.WriteString("# See https://github.com/osteele/cmd-runner for every setting.\n")
+
+	project := r.resolveProject(r.CurrentDir)
+	var detected []string
+	last := ""
+	for _, source := range project.CommandSources {
+		if _, ok := source.(*ConfigSource); ok {
+			continue
+		}
+		last = source.Name()
+		line := last
+		if triggers := sourceTriggers(project.Dir, source); len(triggers) > 0 {
+			line += " (" + strings.Join(triggers, ", ") + ")"
+		}
+		detected = append(detected, line)
+	}
+	if len(detected) > 0 {
+		b.WriteString("#\n# Detected command sources, in the order cmdr looks up commands:\n")
+		for _, line := range detected {
+			fmt.Fprintf(&b, "#   %s\n", line)
+		}
+		b.WriteString("# To ignore some of them here:\n")
+		fmt.Fprintf(&b, "# disabled_sources = [%q]\n", last)
+	}
+
+	b.WriteString("\n")
+	switch {
+	case r.strict():
+		b.WriteString("# Synthesized commands are turned off, so check runs only if the project\n# defines it.\n")
+	case r.hasListedCommand("check"):
+		b.WriteString("# The project defines check, so cmdr runs it instead of synthesizing one.\n")
+	default:
+		if steps := r.checkSteps(r.checkCandidates()); len(steps) > 0 {
+			fmt.Fprintf(&b, "# cmdr check runs these steps: %s. Define check under [commands]\n# to run something else, or stop at the first step that fails:\n", strings.Join(steps, ", "))
+		} else {
+			b.WriteString("# cmdr check runs lint, typecheck, and test, when the project has them.\n# To stop at the first step that fails:\n")
+		}
+		b.WriteString("# fail_fast = true\n")
+	}
+	b.WriteString("# To run only commands the project defines, without synthesized ones:\n# synthesize = false\n")
+
+	var managers []string
+	for _, source := range project.CommandSources {
+		switch source.(type) {
+		case *NpmSource, *PnpmSource, *YarnSource, *BunSource:
+			managers = append(managers, fmt.Sprintf("node = %q", strings.ToLower(source.Name())))
+		case *PoetrySource, *UvSource:
+			managers = append(managers, fmt.Sprintf("python = %q", strings.ToLower(source.Name())))
+		}
+	}
+	if len(managers) > 0 {
+		b.WriteString("\n# Pin the package manager, instead of choosing it from lockfiles:\n# [package_manager]\n")
+		for _, line := range managers {
+			fmt.Fprintf(&b, "# %s\n", line)
+		}
+	}
+
+	b.WriteString("\n[commands]\n")
+	b.WriteString("# A string is a shell command line, run with any arguments appended. A\n")
+	b.WriteString("# table can also set description, category, dir, sudo, and retry.\n")
+	suggestions := r.suggestedCommands()
+	if len(suggestions) == 0 {
+		suggestions = []string{`deploy = { run = "./deploy.sh", description = "Deploy to staging" }`}
+	}
+	for _, line := range suggestions {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	return b.String()
+}
+
+// suggestedCommands returns config lines for init to suggest: pinning a
+// command that several sources define to one of the sources it runs from
+// now, and running the scripts in scriptDirs by name
+func (r *CommandRunner) suggestedCommands() []string {
+	var lines []string
+	defined := make(map[string]bool)
+	for _, section := range r.listSections(true) {
+		for name := range section.commands {
+			defined[name] = true
+		}
+		if section.dir != r.CurrentDir {
+			continue
+		}
+		for _, name := range sortCommands(section.shadowed) {
+			lines = append(lines, fmt.Sprintf("%s = { source = %q }  # instead of %s", name, section.source, section.shadowed[name]))
+		}
+	}
+	for _, dir := range scriptDirs {
+		entries, err := os.ReadDir(filepath.Join(r.CurrentDir, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || (info.Mode()&0111 == 0 && filepath.Ext(entry.Name()) != ".sh") {
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if defined[name] || strings.HasPrefix(name, ".") {
+				continue
+			}
+			defined[name] = true
+			lines = append(lines, fmt.Sprintf("%s = %q", name, "./"+dir+"/"+entry.Name()))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitConfig(t *testing.T) {
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"Makefile":          "test:\n\techo test\nlint:\n\techo lint\n",
+		"package.json":      `{"scripts": {"test": "vitest", "dev": "vite"}}`,
+		"package-lock.json": "{}",
+		"scripts/deploy.sh": "#!/bin/sh\n",
+		"scripts/README.md": "",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+
+	var out strings.Builder
+	if err := runner.InitConfig(&out, false, false); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, ProjectConfigFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"#   make (Makefile)\n#   npm (package.json, package-lock.json)\n",
+		"# cmdr check runs these steps: lint, test.",
+		"# fail_fast = true\n",
+		"# [package_manager]\n# node = \"npm\"\n",
+		"[commands]\n",
+		"# test = { source = \"npm\" }  # instead of make\n",
+		"# deploy = \"./scripts/deploy.sh\"\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("starter config = %q, missing %q", data, want)
+		}
+	}
+	if strings.Contains(string(data), "README") {
+		t.Errorf("starter config suggests a non-script: %q", data)
+	}
+	config, err := parseProjectConfig(path)
+	if err != nil {
+		t.Fatalf("starter config doesn't parse: %v", err)
+	}
+	if len(config.Commands) != 0 {
+		t.Errorf("starter config defines commands: %v", config.Commands)
+	}
+
+	if err := runner.InitConfig(&out, false, false); err == nil {
+		t.Error("InitConfig() should refuse to replace a config")
+	}
+	if err := runner.InitConfig(&out, true, false); err != nil {
+		t.Errorf("InitConfig() with force = %v", err)
+	}
+}
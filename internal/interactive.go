@@ -1,24 +1,142 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
 	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
-// InteractiveSession manages the interactive mode state
+// interactiveRefresh is how often interactive mode checks for edited task
+// files
+const interactiveRefresh = 500 * time.Millisecond
+
+// shellEscape stands for a shell command typed after !, in place of a
+// command name. Its one argument is the command line.
+const shellEscape = "!"
+
+// spinnerFrames animate the run panel, a frame every spinnerInterval
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+const spinnerInterval = 100 * time.Millisecond
+
+// commonShortcuts are the keys that run common commands, in menu order
+var commonShortcuts = []struct {
+	key rune
+	cmd string
+}{
+	{'t', "test"}, {'b', "build"}, {'r', "run"},
+	{'f', "format"}, {'l', "lint"}, {'c', "check"},
+	{'x', "fix"}, {'s', "serve"},
+}
+
+// InteractiveSession is the state of interactive mode, as a bubbletea model
 type InteractiveSession struct {
 	runner            *CommandRunner
-	terminal          *TerminalManager
-	lastCommand       string
-	lastExitCode      int
-	viewingOutput     bool
 	availableCommands map[string]CommandInfo
+	commandSources    map[string]string
 	commandShortcuts  map[rune]string
 	numberCommands    []string
+
+	// entries is the menu: the commands with shortcuts, then the rest.
+	// shown is the entries that match what's typed, best first, and the
+	// cursor is an index in it.
+	entries []menuEntry
+	shown   []menuEntry
+	cursor  int
+	offset  int // first entry drawn, when they don't all fit
+
+	width, height int
+
+	// typing is set while a filter is being typed
+	typing bool
+	typed  string
+
+	// prompting is the command whose arguments are being typed, or ""
+	prompting string
+	argsInput string
+
+	lastCommand  string
+	lastArgs     []string
+	lastExitCode int
+	lastElapsed  time.Duration
+	// lastCancelled is set when the last run was stopped with Ctrl+C
+	lastCancelled bool
+	message       string // shown in the status bar until the next key
+
+	// history is every run of the session, oldest first. The history pane
+	// lists it newest first, and historyCursor is an index in that order.
+	history        []commandDoneMsg
+	showingHistory bool
+	historyCursor  int
+	historyOffset  int
+
+	// running is the command running from the menu, which the run panel
+	// shows in its place; nil between runs
+	running *runningCommand
+
+	// lastOutput is the last run's output, which the output view shows
+	// scrolled to outputOffset
+	lastOutput    []string
+	outputOffset  int
+	viewingOutput bool
+	showingHelp   bool
+
+	// details caches the detail pane's line for each command
+	details map[string]string
+
+	// listings refreshes the menu when task files change; nil if the
+	// files can't be watched
+	listings   *listingWatcher
+	generation int64
+}
+
+// menuEntry is a row of the menu
+type menuEntry struct {
+	key    string // "t", "1", or ""
+	name   string
+	source string // the source's name, or "synthesized"
+	info   CommandInfo
+
+	// positions are the runes of name that match the filter
+	positions []int
+}
+
+// runningCommand is a command running from the menu. Its output is kept
+// rather than written to the terminal, for the run panel to show as it
+// comes.
+type runningCommand struct {
+	command    string
+	args       []string
+	start      time.Time
+	output     *prefixedOutput
+	cancel     context.CancelFunc
+	cancelling bool
 }
 
+// commandDoneMsg reports that a command run from the menu finished
+type commandDoneMsg struct {
+	command   string
+	args      []string
+	err       error
+	elapsed   time.Duration
+	cancelled bool
+}
+
+// spinnerMsg redraws the run panel of run
+type spinnerMsg struct {
+	run *runningCommand
+}
+
+// refreshMsg asks the session to check for edited task files
+type refreshMsg struct{}
+
 // RunInteractive starts the interactive command runner mode
 func RunInteractive() error {
 	runner := New("", nil)
@@ -26,57 +144,38 @@ func RunInteractive() error {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
 
-	session := &InteractiveSession{
-		runner:           runner,
-		terminal:         NewTerminalManager(),
-		commandShortcuts: make(map[rune]string),
-		numberCommands:   make([]string, 0),
+	session := &InteractiveSession{runner: runner, width: 80, height: 24}
+	if listings, err := newListingWatcher(); err == nil {
+		session.listings = listings
+		defer listings.Close()
 	}
-
-	// Setup cleanup on exit
-	defer session.cleanup()
-	session.terminal.SetupSignalHandling(session.cleanup)
-
-	// Gather available commands
 	session.gatherCommands()
 
-	// Main interactive loop
-	for {
-		if session.viewingOutput {
-			if err := session.showOutputView(); err != nil {
-				if err.Error() == "quit" {
-					return nil
-				}
-			}
-		} else {
-			if err := session.showMenu(); err != nil {
-				if err.Error() == "quit" {
-					return nil
-				}
-			}
-		}
-	}
+	_, err := tea.NewProgram(session, tea.WithAltScreen(), tea.WithMouseCellMotion()).Run()
+	return err
 }
 
 // gatherCommands collects all available commands and sets up shortcuts
 func (s *InteractiveSession) gatherCommands() {
 	s.availableCommands = make(map[string]CommandInfo)
-
-	// Build projects for current dir and project root
-	projects := []*Project{}
-	projects = append(projects, ResolveProject(s.runner.CurrentDir))
-
-	if s.runner.ProjectRoot != s.runner.CurrentDir && s.runner.ProjectRoot != "" {
-		projects = append(projects, ResolveProject(s.runner.ProjectRoot))
+	s.commandSources = make(map[string]string)
+	s.commandShortcuts = make(map[rune]string)
+	s.details = make(map[string]string)
+	if s.listings != nil {
+		s.generation = s.listings.changed()
 	}
 
 	// Collect commands from all sources
-	for _, project := range projects {
+	for _, project := range s.runner.projects() {
+		if s.listings != nil {
+			s.listings.watch(project.Dir)
+		}
 		for _, source := range project.CommandSources {
 			commands := source.ListCommands()
 			for cmd, info := range commands {
-				if !isPrivateCommand(cmd) {
+				if _, exists := s.availableCommands[cmd]; !exists && !isPrivateCommand(cmd) {
 					s.availableCommands[cmd] = info
+					s.commandSources[cmd] = source.Name()
 				}
 			}
 		}
@@ -92,33 +191,35 @@ func (s *InteractiveSession) gatherCommands() {
 	if s.runner.hasTypecheckCapability() {
 		synth["typecheck"] = CommandInfo{Description: "Runs type checking", Execution: "synthesized"}
 	}
+	if _, ecosystems := s.runner.cleanProject(); len(ecosystems) > 0 {
+		synth["clean"] = CommandInfo{Description: "Removes build artifacts", Execution: "synthesized"}
+	}
+	if s.runner.provisions() {
+		synth["setup"] = CommandInfo{Description: "Provisions toolchains and installs dependencies", Execution: "synthesized"}
+	}
 
 	for cmd, info := range synth {
 		if _, exists := s.availableCommands[cmd]; !exists {
 			s.availableCommands[cmd] = info
+			s.commandSources[cmd] = "synthesized"
 		}
 	}
+	s.buildMenu()
+}
 
-	// Setup shortcuts for common commands
-	shortcuts := map[rune]string{
-		't': "test",
-		'b': "build",
-		'r': "run",
-		'f': "format",
-		'l': "lint",
-		'c': "check",
-		'x': "fix",
-		's': "serve",
-	}
-
-	// Only add shortcuts for commands that exist
-	for key, cmd := range shortcuts {
-		if _, exists := s.availableCommands[cmd]; exists {
-			s.commandShortcuts[key] = cmd
+// buildMenu assigns shortcuts to the available commands and orders the menu:
+// letter shortcuts for common commands, then the others alphabetically, the
+// first nine numbered
+func (s *InteractiveSession) buildMenu() {
+	s.commandShortcuts = make(map[rune]string)
+	s.entries = nil
+	for _, shortcut := range commonShortcuts {
+		if info, exists := s.availableCommands[shortcut.cmd]; exists {
+			s.commandShortcuts[shortcut.key] = shortcut.cmd
+			s.entries = append(s.entries, menuEntry{key: string(shortcut.key), name: shortcut.cmd, source: s.commandSources[shortcut.cmd], info: info})
 		}
 	}
 
-	// Setup number shortcuts for other commands
 	otherCommands := make([]string, 0)
 	for cmd := range s.availableCommands {
 		isShortcut := false
@@ -132,288 +233,922 @@ func (s *InteractiveSession) gatherCommands() {
 			otherCommands = append(otherCommands, cmd)
 		}
 	}
-
 	sort.Strings(otherCommands)
 	s.numberCommands = otherCommands
+	for i, cmd := range otherCommands {
+		key := ""
+		if i < 9 {
+			key = fmt.Sprint(i + 1)
+		}
+		s.entries = append(s.entries, menuEntry{key: key, name: cmd, source: s.commandSources[cmd], info: s.availableCommands[cmd]})
+	}
+	s.filter()
 }
 
-// showMenu displays the interactive menu
-func (s *InteractiveSession) showMenu() error {
-	fmt.Println("\ncmd-runner interactive mode")
-	fmt.Println("─────────────────────────────────────")
-	fmt.Println("Available commands:")
-	fmt.Println()
-
-	// Show common commands with shortcuts
-	if len(s.commandShortcuts) > 0 {
-		fmt.Println("Common:")
-		commonCmds := []struct {
-			key rune
-			cmd string
-		}{
-			{'t', "test"}, {'b', "build"}, {'r', "run"},
-			{'f', "format"}, {'l', "lint"}, {'c', "check"},
-			{'x', "fix"}, {'s', "serve"},
-		}
-
-		for i, item := range commonCmds {
-			if cmd, exists := s.commandShortcuts[item.key]; exists {
-				fmt.Printf("  [%c] %-10s", item.key, cmd)
-				if (i+1)%3 == 0 {
-					fmt.Println()
-				}
-			}
+// filter shows the entries that fuzzy match the typed filter, by name or
+// description, with an exact name first; or all of them when nothing is
+// typed
+func (s *InteractiveSession) filter() {
+	query := strings.TrimSpace(s.typed)
+	if !s.typing || query == "" {
+		s.shown = s.entries
+		s.cursor = min(s.cursor, max(len(s.shown)-1, 0))
+		return
+	}
+	s.shown = nil
+	for _, m := range fuzzyFilter(query, len(s.entries), func(i int) (string, string) {
+		return s.entries[i].name, s.entries[i].info.Description
+	}) {
+		entry := s.entries[m.item]
+		entry.positions = m.positions
+		if entry.name == query {
+			s.shown = append([]menuEntry{entry}, s.shown...)
+		} else {
+			s.shown = append(s.shown, entry)
 		}
-		fmt.Println()
 	}
+	s.cursor, s.offset = 0, 0
+}
 
-	// Show other commands with number shortcuts
-	if len(s.numberCommands) > 0 {
-		fmt.Println("\nOther commands:")
-		for i, cmd := range s.numberCommands {
-			if i >= 9 {
-				break // Only show first 9
-			}
-			fmt.Printf("  [%d] %-10s", i+1, cmd)
-			if (i+1)%3 == 0 {
-				fmt.Println()
-			}
+// Init starts polling for edited task files
+func (s *InteractiveSession) Init() tea.Cmd {
+	return refreshTick()
+}
+
+func refreshTick() tea.Cmd {
+	return tea.Tick(interactiveRefresh, func(time.Time) tea.Msg { return refreshMsg{} })
+}
+
+// Update applies a key press, a resize, or the end of a command
+func (s *InteractiveSession) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width, s.height = msg.Width, msg.Height
+	case refreshMsg:
+		// Edited task files change the menu
+		if s.listings != nil && s.listings.changed() != s.generation {
+			s.gatherCommands()
 		}
-		if len(s.numberCommands) > 9 {
-			fmt.Printf("\n  ... and %d more", len(s.numberCommands)-9)
+		return s, refreshTick()
+	case spinnerMsg:
+		if msg.run == s.running {
+			return s, spinnerTick(msg.run)
 		}
-		fmt.Println()
-	}
-
-	// Show controls
-	fmt.Println()
-	if s.lastCommand != "" {
-		status := "✓"
+	case commandDoneMsg:
+		if s.running != nil {
+			s.lastOutput = s.running.output.recent(s.running.command, maxLogLines)
+			s.running = nil
+		}
+		s.history = append(s.history, msg)
+		s.historyCursor = 0
+		s.lastCommand, s.lastArgs = msg.command, msg.args
+		s.lastExitCode = ExitCode(msg.err)
+		s.lastElapsed, s.lastCancelled = msg.elapsed, msg.cancelled
+		// A failure leaves its output on screen until the user returns
 		if s.lastExitCode != 0 {
-			status = "✗"
+			s.showOutput()
 		}
-		fmt.Printf("[.] repeat (%s %s)  ", s.lastCommand, status)
-		fmt.Printf("[/] toggle output  ")
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	case tea.MouseMsg:
+		return s.handleMouse(msg)
 	}
-	fmt.Println("[q] quit  [?] help")
-	fmt.Println()
-	fmt.Print("Select command (or type name): ")
+	return s, nil
+}
 
-	// Read user input
-	if err := s.terminal.SetRawMode(); err != nil {
-		return err
+// handleKey applies a key press to the view that is showing
+func (s *InteractiveSession) handleKey(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s.message = ""
+	if s.running != nil {
+		// Ctrl+C stops the command rather than cmdr, and other keys wait
+		// for it to finish
+		if key.Type == tea.KeyCtrlC {
+			s.running.cancel()
+			s.running.cancelling = true
+		}
+		return s, nil
+	}
+	if key.Type == tea.KeyCtrlC {
+		return s, tea.Quit
+	}
+	switch {
+	case s.showingHelp:
+		s.showingHelp = false
+		return s, nil
+	case s.viewingOutput:
+		return s.handleOutputKey(key)
+	case s.typing:
+		return s.handleTypingKey(key)
+	case s.prompting != "":
+		return s.handlePromptKey(key)
+	case s.showingHistory:
+		return s.handleHistoryKey(key)
 	}
-	defer func() {
-		_ = s.terminal.RestoreMode()
-	}()
 
-	key, err := s.terminal.ReadKey()
-	if err != nil {
-		if err.Error() == "interrupt" {
-			return fmt.Errorf("quit")
+	if navigate(key, &s.cursor, len(s.shown), s.listRows()) {
+		return s, nil
+	}
+	switch key.Type {
+	case tea.KeyEnter:
+		if len(s.shown) > 0 {
+			return s, s.runCommand(s.shown[s.cursor].name, nil)
+		}
+	case tea.KeyRunes:
+		if len(key.Runes) != 1 {
+			// Pasted text is a filter
+			s.startTyping(string(key.Runes))
+			return s, nil
 		}
-		return err
+		return s.handleMenuRune(key.Runes[0])
 	}
+	return s, nil
+}
+
+// navigate moves cursor through a list of count rows, a page of which
+// fit on screen, for the arrow and paging keys, and reports whether key was
+// one of them
+func navigate(key tea.KeyMsg, cursor *int, count, page int) bool {
+	switch key.Type {
+	case tea.KeyUp:
+		moveCursor(cursor, -1, count)
+	case tea.KeyDown:
+		moveCursor(cursor, 1, count)
+	case tea.KeyPgUp:
+		moveCursor(cursor, -page, count)
+	case tea.KeyPgDown:
+		moveCursor(cursor, page, count)
+	case tea.KeyHome:
+		*cursor = 0
+	case tea.KeyEnd:
+		*cursor = max(count-1, 0)
+	default:
+		return false
+	}
+	return true
+}
 
-	// Handle menu commands
+func moveCursor(cursor *int, delta, count int) {
+	*cursor = min(max(*cursor+delta, 0), max(count-1, 0))
+}
+
+func (s *InteractiveSession) startTyping(text string) {
+	s.typing, s.typed = true, text
+	s.filter()
+}
+
+func (s *InteractiveSession) stopTyping() {
+	s.typing, s.typed = false, ""
+	s.filter()
+}
+
+// handleMenuRune runs the command or control a key stands for, or starts
+// typing a command name
+func (s *InteractiveSession) handleMenuRune(key rune) (tea.Model, tea.Cmd) {
 	switch key {
 	case 'q', 'Q':
-		return fmt.Errorf("quit")
+		return s, tea.Quit
 	case '.':
 		if s.lastCommand != "" {
-			return s.runCommand(s.lastCommand)
+			return s, s.runCommand(s.lastCommand, s.lastArgs)
+		}
+		return s, nil
+	case 'a':
+		// Prompt for arguments to the selected command, starting from the
+		// ones it last ran with
+		if len(s.shown) > 0 {
+			s.prompting, s.argsInput = s.shown[s.cursor].name, ""
+			if s.prompting == s.lastCommand {
+				s.argsInput = strings.Join(quoteArgs(s.lastArgs), " ")
+			}
 		}
+		return s, nil
 	case '/':
 		if s.lastCommand != "" {
-			s.viewingOutput = true
-			return nil
+			s.showOutput()
 		}
+		return s, nil
 	case '?':
-		s.showHelp()
-		return nil
-	default:
-		// Check if it's a shortcut
-		if cmd, exists := s.commandShortcuts[key]; exists {
-			return s.runCommand(cmd)
+		s.showingHelp = true
+		return s, nil
+	case '!':
+		s.prompting, s.argsInput = shellEscape, ""
+		return s, nil
+	case 'h':
+		s.showingHistory = true
+		return s, nil
+	}
+
+	// Check if it's a shortcut
+	if cmd, exists := s.commandShortcuts[key]; exists {
+		return s, s.runCommand(cmd, nil)
+	}
+
+	// Check if it's a number
+	if key >= '1' && key <= '9' {
+		index := int(key - '1')
+		if index < len(s.numberCommands) {
+			return s, s.runCommand(s.numberCommands[index], nil)
+		}
+	}
+
+	// Otherwise, start filtering the menu
+	s.startTyping(string(key))
+	return s, nil
+}
+
+// handleTypingKey edits the filter, moves through the commands that match
+// it, and runs the selected one on Enter
+func (s *InteractiveSession) handleTypingKey(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if navigate(key, &s.cursor, len(s.shown), s.listRows()) {
+		return s, nil
+	}
+	switch key.Type {
+	case tea.KeyEsc:
+		s.stopTyping()
+	case tea.KeyBackspace:
+		_, size := utf8.DecodeLastRuneInString(s.typed)
+		if s.typed = s.typed[:len(s.typed)-size]; s.typed == "" {
+			s.stopTyping()
+		} else {
+			s.filter()
 		}
+	case tea.KeySpace:
+		s.typed += " "
+		s.filter()
+	case tea.KeyRunes:
+		s.typed += string(key.Runes)
+		s.filter()
+	case tea.KeyEnter:
+		if len(s.shown) == 0 {
+			s.message = fmt.Sprintf("No command matches '%s'", strings.TrimSpace(s.typed))
+			return s, nil
+		}
+		command := s.shown[s.cursor].name
+		s.stopTyping()
+		return s, s.runCommand(command, nil)
+	}
+	return s, nil
+}
 
-		// Check if it's a number
-		if key >= '1' && key <= '9' {
-			index := int(key - '1')
-			if index < len(s.numberCommands) {
-				return s.runCommand(s.numberCommands[index])
+// handlePromptKey edits the arguments being typed, and runs the command
+// with them on Enter
+func (s *InteractiveSession) handlePromptKey(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.Type {
+	case tea.KeyEsc:
+		s.prompting, s.argsInput = "", ""
+	case tea.KeyBackspace:
+		_, size := utf8.DecodeLastRuneInString(s.argsInput)
+		s.argsInput = s.argsInput[:len(s.argsInput)-size]
+	case tea.KeyCtrlU:
+		s.argsInput = ""
+	case tea.KeySpace:
+		s.argsInput += " "
+	case tea.KeyRunes:
+		s.argsInput += string(key.Runes)
+	case tea.KeyEnter:
+		command := s.prompting
+		if command == shellEscape {
+			line := strings.TrimSpace(s.argsInput)
+			s.prompting, s.argsInput = "", ""
+			if line == "" {
+				return s, nil
 			}
+			return s, s.runCommand(shellEscape, []string{line})
+		}
+		args, err := splitArgs(s.argsInput)
+		if err != nil {
+			s.message = err.Error()
+			return s, nil
 		}
+		s.prompting, s.argsInput = "", ""
+		return s, s.runCommand(command, args)
+	}
+	return s, nil
+}
 
-		// Otherwise, enter type mode
-		_ = s.terminal.RestoreMode()
-		return s.typeMode(key)
+// handleHistoryKey moves through the history pane, and runs the selected
+// entry again on Enter, with the same arguments
+func (s *InteractiveSession) handleHistoryKey(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if navigate(key, &s.historyCursor, len(s.history), s.listRows()) {
+		return s, nil
 	}
+	switch key.Type {
+	case tea.KeyEsc:
+		s.showingHistory = false
+	case tea.KeyEnter:
+		if run, ok := s.historyEntry(s.historyCursor); ok {
+			return s, s.runCommand(run.command, run.args)
+		}
+	case tea.KeyRunes:
+		if len(key.Runes) != 1 {
+			return s, nil
+		}
+		switch key.Runes[0] {
+		case 'h':
+			s.showingHistory = false
+		case 'a':
+			// Edit the entry's arguments before running it again
+			if run, ok := s.historyEntry(s.historyCursor); ok {
+				s.prompting = run.command
+				s.argsInput = strings.Join(quoteArgs(run.args), " ")
+				if run.command == shellEscape {
+					s.argsInput = run.args[0]
+				}
+			}
+		case '.', '/', '?', '!', 'q', 'Q':
+			return s.handleMenuRune(key.Runes[0])
+		}
+	}
+	return s, nil
+}
+
+// historyEntry returns the run at row i of the history pane, newest first
+func (s *InteractiveSession) historyEntry(i int) (commandDoneMsg, bool) {
+	if i < 0 || i >= len(s.history) {
+		return commandDoneMsg{}, false
+	}
+	return s.history[len(s.history)-1-i], true
+}
 
-	return nil
+// splitArgs splits a line of arguments into words at spaces, as a shell
+// would: single quotes keep text as is, and double quotes and backslashes
+// keep spaces in a word
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
 }
 
-// showOutputView shows the last command output
-func (s *InteractiveSession) showOutputView() error {
-	fmt.Println("\n[Last command output]")
-	fmt.Println("─────────────────────────────────────")
-	fmt.Printf("Command: %s (exit code: %d)\n", s.lastCommand, s.lastExitCode)
-	fmt.Println("─────────────────────────────────────")
-	fmt.Println()
-	fmt.Println("Press '/' to return to menu, 'q' to quit")
+// interactiveCommandLine shows a command and its arguments as they would be
+// typed
+func interactiveCommandLine(command string, args []string) string {
+	if command == shellEscape {
+		return shellEscape + " " + args[0]
+	}
+	return strings.Join(append([]string{command}, quoteArgs(args)...), " ")
+}
 
-	// Read user input
-	if err := s.terminal.SetRawMode(); err != nil {
-		return err
+func quoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
 	}
-	defer func() {
-		_ = s.terminal.RestoreMode()
-	}()
+	return quoted
+}
 
-	key, err := s.terminal.ReadKey()
-	if err != nil {
-		if err.Error() == "interrupt" {
-			return fmt.Errorf("quit")
+// handleOutputKey scrolls the output view, or returns to the menu
+func (s *InteractiveSession) handleOutputKey(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The cursor is the bottom row, so that the view scrolls a row at a time
+	rows := s.outputRows()
+	bottom := s.outputOffset + rows - 1
+	if navigate(key, &bottom, len(s.lastOutput), rows) {
+		s.outputOffset = max(bottom-rows+1, 0)
+		return s, nil
+	}
+	if key.Type != tea.KeyRunes || len(key.Runes) != 1 {
+		if key.Type == tea.KeyEsc || key.Type == tea.KeyEnter {
+			s.viewingOutput = false
 		}
-		return err
+		return s, nil
 	}
-
-	switch key {
+	switch key.Runes[0] {
 	case 'q', 'Q':
-		return fmt.Errorf("quit")
+		return s, tea.Quit
+	case '.':
+		return s, s.runCommand(s.lastCommand, s.lastArgs)
 	case '/':
 		s.viewingOutput = false
-		return nil
 	}
+	return s, nil
+}
 
-	return nil
+// showOutput shows the last run's output, scrolled to its end
+func (s *InteractiveSession) showOutput() {
+	s.viewingOutput = true
+	s.outputOffset = max(len(s.lastOutput)-s.outputRows(), 0)
 }
 
-// typeMode allows typing command names
-func (s *InteractiveSession) typeMode(firstKey rune) error {
-	fmt.Printf("\rType command name: %c", firstKey)
+// handleMouse runs a command that's clicked and moves through the menu with
+// the wheel
+func (s *InteractiveSession) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if s.running != nil || s.prompting != "" {
+		return s, nil
+	}
+	if s.viewingOutput {
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			s.outputOffset = max(s.outputOffset-1, 0)
+		case tea.MouseButtonWheelDown:
+			s.outputOffset = min(s.outputOffset+1, max(len(s.lastOutput)-s.outputRows(), 0))
+		}
+		return s, nil
+	}
+	cursor, count, offset := &s.cursor, len(s.shown), s.offset
+	if s.showingHistory {
+		cursor, count, offset = &s.historyCursor, len(s.history), s.historyOffset
+	}
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		moveCursor(cursor, -1, count)
+	case msg.Button == tea.MouseButtonWheelDown:
+		moveCursor(cursor, 1, count)
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		if s.showingHelp {
+			s.showingHelp = false
+			return s, nil
+		}
+		i, ok := s.rowAt(msg.Y, offset, count)
+		if ok && s.showingHistory {
+			s.historyCursor = i
+			run, _ := s.historyEntry(i)
+			return s, s.runCommand(run.command, run.args)
+		}
+		if ok {
+			s.cursor = i
+			command := s.shown[i].name
+			s.stopTyping()
+			return s, s.runCommand(command, nil)
+		}
+	}
+	return s, nil
+}
 
-	// Read the rest of the command name
-	input := string(firstKey)
-	var line string
-	_, _ = fmt.Scanln(&line)
-	input += line
+// rowAt returns the index of the list row drawn on screen row y, in a list
+// of count rows scrolled to offset
+func (s *InteractiveSession) rowAt(y, offset, count int) (int, bool) {
+	top := 2
+	if s.compact() {
+		top = 0
+	}
+	i := offset + y - top
+	if y < top || y >= top+s.listRows() || i >= count {
+		return 0, false
+	}
+	return i, true
+}
+
+// runCommand starts command, with its output going to the run panel until
+// it finishes
+func (s *InteractiveSession) runCommand(command string, args []string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &runningCommand{
+		command: command,
+		args:    args,
+		start:   time.Now(),
+		output:  newPrefixedOutput(io.Discard, []string{command}),
+		cancel:  cancel,
+	}
+	s.running = run
+	s.viewingOutput = false
+	execute := func() tea.Msg {
+		defer cancel()
+		writer := run.output.writer(command)
+		runner := s.runner.subRunner(command, args)
+		// Output written alongside the menu doesn't read the terminal
+		runner.output = writer
+		var err error
+		if command == shellEscape {
+			// A shell command runs in the project's root
+			WithContext(ctx)(runner)
+			cmd := runner.executor.shell(args[0], nil)
+			cmd.Dir = runner.ProjectRoot
+			err = runner.ExecuteCommand(cmd)
+		} else {
+			err = runner.RunContext(ctx)
+		}
+		cancelled := ctx.Err() != nil
+		if err != nil && !cancelled {
+			fmt.Fprintf(writer, "Error: %v\n", err)
+		}
+		writer.flush()
+		return commandDoneMsg{command: command, args: args, err: err, elapsed: time.Since(run.start), cancelled: cancelled}
+	}
+	return tea.Batch(execute, spinnerTick(run))
+}
+
+func spinnerTick(run *runningCommand) tea.Cmd {
+	return tea.Tick(spinnerInterval, func(time.Time) tea.Msg { return spinnerMsg{run} })
+}
 
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return nil
+// listRows is how many menu entries fit between the title and the detail
+// pane
+func (s *InteractiveSession) listRows() int {
+	if s.compact() {
+		return max(s.height-1, 1)
 	}
+	return max(s.height-5, 1)
+}
 
-	// Find matching command
-	if _, exists := s.availableCommands[input]; exists {
-		return s.runCommand(input)
+// outputRows is how many lines of output fit in the run panel and the
+// output view, under their heading
+func (s *InteractiveSession) outputRows() int {
+	if s.compact() {
+		return max(s.height-1, 1)
 	}
+	return max(s.height-3, 1)
+}
 
-	// Try to find partial match
-	var matches []string
-	for cmd := range s.availableCommands {
-		if strings.HasPrefix(cmd, input) {
-			matches = append(matches, cmd)
+// compact reports whether the terminal is too short for the title and
+// detail pane, which are left out so that the list keeps some rows
+func (s *InteractiveSession) compact() bool {
+	return s.height < 10
+}
+
+// narrowWidth is the width below which the menu leaves out sources
+const narrowWidth = 60
+
+// detail describes what entry runs: the command line it resolves to, or
+// what a synthesized command does
+func (s *InteractiveSession) detail(entry menuEntry) string {
+	if line, ok := s.details[entry.name]; ok {
+		return line
+	}
+	var line string
+	cmd, err := s.runner.subRunner(entry.name, nil).Resolve()
+	switch {
+	case err == nil:
+		line = "runs: " + strings.Join(quoteArgs(cmd.Args), " ")
+		if cmd.Dir != "" && cmd.Dir != s.runner.CurrentDir {
+			line += "  (in " + cmd.Dir + ")"
 		}
+	case entry.source == "synthesized":
+		line = "synthesized: " + entry.info.Description
+	case entry.info.Execution != "":
+		line = "runs: " + entry.info.Execution
+	default:
+		line = entry.info.Description
 	}
+	if s.details == nil {
+		s.details = make(map[string]string)
+	}
+	s.details[entry.name] = line
+	return line
+}
 
-	if len(matches) == 1 {
-		return s.runCommand(matches[0])
-	} else if len(matches) > 1 {
-		fmt.Printf("\nMultiple matches found: %s\n", strings.Join(matches, ", "))
-		fmt.Println("Press any key to continue...")
-		_ = s.terminal.SetRawMode()
-		_, _ = s.terminal.ReadKey()
-		_ = s.terminal.RestoreMode()
+// View draws the run panel, the output, the help, the history, or the menu
+func (s *InteractiveSession) View() string {
+	var lines []string
+	if s.running != nil {
+		lines = s.runLines()
+	} else if s.viewingOutput {
+		lines = s.outputLines()
+	} else if s.showingHistory {
+		lines = s.historyLines()
 	} else {
-		fmt.Printf("\nCommand '%s' not found\n", input)
-		fmt.Println("Press any key to continue...")
-		_ = s.terminal.SetRawMode()
-		_, _ = s.terminal.ReadKey()
-		_ = s.terminal.RestoreMode()
+		lines = s.menuLines()
 	}
+	if s.showingHelp {
+		lines = overlay(lines, frameBox("Interactive Mode Help", helpLines(), s.width, s.height), s.width)
+	}
+	// Lines are cut at the terminal's width rather than wrapped, so that the
+	// layout holds when the terminal is resized
+	for i, line := range lines {
+		lines[i] = truncateLine(line, s.width)
+	}
+	return strings.Join(lines, "\n")
+}
 
-	return nil
+// frameBox draws content in a box with title in its top border, as wide as
+// the widest line and no larger than width by height. Content that doesn't
+// fit loses lines from its end, but keeps its last line, which says how to
+// leave.
+func frameBox(title string, content []string, width, height int) []string {
+	if len(content) > height-2 {
+		content = append(content[:max(height-3, 0):max(height-3, 0)], content[len(content)-1])
+	}
+	inner := 0
+	for _, line := range content {
+		inner = max(inner, visibleWidth(line))
+	}
+	inner = max(min(inner, width-4), utf8.RuneCountInString(title)+2)
+	top := "╭─ " + style(os.Stdout, styleHeader, title) + " " + strings.Repeat("─", max(inner-utf8.RuneCountInString(title)-1, 0)) + "╮"
+	box := []string{top}
+	for _, line := range content {
+		line = truncateLine(line, inner)
+		box = append(box, "│ "+line+strings.Repeat(" ", max(inner-visibleWidth(line), 0))+" │")
+	}
+	return append(box, "╰"+strings.Repeat("─", inner+2)+"╯")
 }
 
-// runCommand executes a command and returns to menu
-func (s *InteractiveSession) runCommand(command string) error {
-	_ = s.terminal.RestoreMode()
+// overlay draws box centered over lines, which stay visible around it
+func overlay(lines, box []string, width int) []string {
+	if len(box) == 0 {
+		return lines
+	}
+	boxWidth := visibleWidth(box[0])
+	top, left := max((len(lines)-len(box))/2, 0), max((width-boxWidth)/2, 0)
+	for i, row := range box {
+		y := top + i
+		if y >= len(lines) {
+			break
+		}
+		before := truncateLine(lines[y], left)
+		before += strings.Repeat(" ", max(left-visibleWidth(before), 0))
+		lines[y] = before + row + "\033[0m" + dropColumns(lines[y], left+boxWidth)
+	}
+	return lines
+}
 
-	fmt.Println()
-	fmt.Println("─────────────────────────────────────")
-	fmt.Printf("Running: %s\n", command)
-	fmt.Println("─────────────────────────────────────")
+// visibleWidth is how many columns line takes, leaving out escape sequences
+func visibleWidth(line string) int {
+	return utf8.RuneCountInString(ansiEscapePattern.ReplaceAllString(line, ""))
+}
 
-	// Create a new runner for this command
-	runner := New(command, nil)
-	if err := runner.Init(); err != nil {
-		return err
+// dropColumns removes the first n columns of line, keeping the escape
+// sequences among them so that the rest keeps its style
+func dropColumns(line string, n int) string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			if loc := ansiEscapePattern.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+				b.WriteString(line[i : i+loc[1]])
+				i += loc[1]
+				continue
+			}
+		}
+		if n == 0 {
+			b.WriteString(line[i:])
+			break
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		n--
+		i += size
+	}
+	return b.String()
+}
+
+// menuLines draws the title, the part of the menu that fits, and the status
+// bar
+func (s *InteractiveSession) menuLines() []string {
+	rule := style(os.Stdout, styleDim, strings.Repeat("─", max(s.width, 1)))
+	var lines []string
+	if !s.compact() {
+		lines = append(lines, s.runner.badge()+style(os.Stdout, styleHeader, "cmd-runner interactive mode"), rule)
 	}
+	top := len(lines)
 
-	// Run the command
-	err := runner.Run()
+	rows := s.listRows()
+	s.offset = scrollTo(s.cursor, s.offset, rows, len(s.shown))
 
-	// Store last command info
-	s.lastCommand = command
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			s.lastExitCode = exitErr.ExitCode()
-		} else {
-			s.lastExitCode = 1
+	nameWidth, sourceWidth := 0, 0
+	for _, entry := range s.entries {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(entry.name))
+		sourceWidth = max(sourceWidth, utf8.RuneCountInString(entry.source))
+	}
+	nameWidth = min(nameWidth, 24, max(s.width/3, 8))
+	if s.width < narrowWidth {
+		sourceWidth = 0
+	}
+
+	switch {
+	case len(s.entries) == 0:
+		lines = append(lines, "  No commands found")
+	case len(s.shown) == 0:
+		lines = append(lines, "  No commands match")
+	}
+	for i := s.offset; i < len(s.shown) && i < s.offset+rows; i++ {
+		entry := s.shown[i]
+		key := "   "
+		if entry.key != "" {
+			key = "[" + entry.key + "]"
 		}
-	} else {
-		s.lastExitCode = 0
+		pad := strings.Repeat(" ", max(nameWidth-utf8.RuneCountInString(entry.name), 0))
+		source := ""
+		if sourceWidth > 0 {
+			source = "  " + style(os.Stdout, styleSource, fmt.Sprintf("%-*s", sourceWidth, entry.source))
+		}
+		line := fmt.Sprintf(" %s %s%s%s  %s", key, highlightRunes(entry.name, entry.positions), pad, source, style(os.Stdout, styleDim, entry.info.Description))
+		if i == s.cursor {
+			line = "\033[7m▸\033[0m" + line[1:]
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < top+rows {
+		lines = append(lines, "")
+	}
+
+	if !s.compact() {
+		detail := ""
+		if len(s.shown) > 0 {
+			detail = style(os.Stdout, styleDim, s.detail(s.shown[s.cursor]))
+		}
+		lines = append(lines, rule, detail)
+	}
+	return append(lines, s.statusBar())
+}
+
+// runLines draws the run panel: a spinner, the command, how long it has
+// been running, and the tail of its output
+func (s *InteractiveSession) runLines() []string {
+	run := s.running
+	elapsed := time.Since(run.start)
+	frame := spinnerFrames[int(elapsed/spinnerInterval)%len(spinnerFrames)]
+	heading := fmt.Sprintf("%s %s  %s", style(os.Stdout, styleWarning, string(frame)), interactiveCommandLine(run.command, run.args), style(os.Stdout, styleDim, elapsed.Truncate(time.Second).String()))
+	status := style(os.Stdout, styleDim, "ctrl+c cancel")
+	if run.cancelling {
+		status = "Cancelling…"
+	}
+	return s.paneLines(heading, run.output.recent(run.command, s.outputRows()), status)
+}
+
+// outputLines draws the output view: the last run's output, scrolled to
+// outputOffset
+func (s *InteractiveSession) outputLines() []string {
+	rows := s.outputRows()
+	s.outputOffset = min(s.outputOffset, max(len(s.lastOutput)-rows, 0))
+	shown := s.lastOutput[s.outputOffset:min(s.outputOffset+rows, len(s.lastOutput))]
+	keys := "↑↓ scroll · / menu · . repeat · q quit"
+	if len(s.lastOutput) > rows {
+		keys += fmt.Sprintf(" · %d/%d", s.outputOffset+len(shown), len(s.lastOutput))
+	}
+	return s.paneLines(s.lastStatus(), shown, style(os.Stdout, styleDim, keys))
+}
+
+// paneLines draws a heading and a rule over output, padded to fill the
+// screen, and a status bar. Output is shown as a terminal would leave it
+// after carriage returns, such as those that redraw progress bars.
+func (s *InteractiveSession) paneLines(heading string, output []string, status string) []string {
+	var lines []string
+	if !s.compact() {
+		lines = append(lines, heading, style(os.Stdout, styleDim, strings.Repeat("─", max(s.width, 1))))
+	}
+	top := len(lines)
+	for _, line := range output {
+		lines = append(lines, line[strings.LastIndex(line, "\r")+1:])
+	}
+	for len(lines) < top+s.outputRows() {
+		lines = append(lines, "")
+	}
+	return append(lines, status)
+}
+
+// historyLines draws the history pane: the session's runs, newest first,
+// with whether each passed, its exit code, and how long it took
+func (s *InteractiveSession) historyLines() []string {
+	rule := style(os.Stdout, styleDim, strings.Repeat("─", max(s.width, 1)))
+	var lines []string
+	if !s.compact() {
+		lines = append(lines, s.runner.badge()+style(os.Stdout, styleHeader, "Session history"), rule)
+	}
+	top := len(lines)
+
+	rows := s.listRows()
+	s.historyOffset = scrollTo(s.historyCursor, s.historyOffset, rows, len(s.history))
+
+	commandWidth := 0
+	for _, run := range s.history {
+		commandWidth = max(commandWidth, utf8.RuneCountInString(interactiveCommandLine(run.command, run.args)))
+	}
+	commandWidth = min(commandWidth, max(s.width/2, 8))
+
+	if len(s.history) == 0 {
+		lines = append(lines, "  No commands run yet")
+	}
+	for i := s.historyOffset; i < len(s.history) && i < s.historyOffset+rows; i++ {
+		run, _ := s.historyEntry(i)
+		code := ExitCode(run.err)
+		command := interactiveCommandLine(run.command, run.args)
+		pad := strings.Repeat(" ", max(commandWidth-utf8.RuneCountInString(command), 0))
+		result := style(os.Stdout, styleDim, fmt.Sprintf("exit %d", code))
+		if run.cancelled {
+			result = style(os.Stdout, styleFailure, "cancelled")
+		} else if code != 0 {
+			result = style(os.Stdout, styleFailure, fmt.Sprintf("exit %d", code))
+		}
+		line := fmt.Sprintf("   %s %s%s  %s  %s", passMark(os.Stdout, code == 0), command, pad, result, style(os.Stdout, styleDim, roundDuration(run.elapsed).String()))
+		if i == s.historyCursor {
+			line = "\033[7m▸\033[0m" + line[1:]
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < top+rows {
+		lines = append(lines, "")
 	}
 
-	// Show completion status
-	fmt.Println("─────────────────────────────────────")
+	if !s.compact() {
+		lines = append(lines, rule, style(os.Stdout, styleDim, fmt.Sprintf("%d runs this session", len(s.history))))
+	}
+	return append(lines, s.statusBar())
+}
+
+// scrollTo returns the offset that keeps cursor among the rows drawn of a
+// list of count rows, moving as little as it can from offset
+func scrollTo(cursor, offset, rows, count int) int {
+	if cursor < offset {
+		offset = cursor
+	} else if cursor >= offset+rows {
+		offset = cursor - rows + 1
+	}
+	return min(offset, max(count-rows, 0))
+}
+
+// statusBar is the bottom line: what's being typed, a message, or the last
+// run and the keys that are available
+func (s *InteractiveSession) statusBar() string {
+	if s.message != "" {
+		return s.message
+	}
+	if s.typing {
+		return fmt.Sprintf("Filter: %s█  %s", s.typed, style(os.Stdout, styleDim, fmt.Sprintf("%d/%d · enter run · esc clear", len(s.shown), len(s.entries))))
+	}
+	if s.prompting == shellEscape {
+		return fmt.Sprintf("Shell command: %s█", s.argsInput)
+	}
+	if s.prompting != "" {
+		return fmt.Sprintf("Arguments for %s: %s█", s.prompting, s.argsInput)
+	}
+	if s.showingHistory {
+		return style(os.Stdout, styleDim, "↑↓ move · enter run again · a args · esc back · q quit")
+	}
+	keys := style(os.Stdout, styleDim, "↑↓ move · enter run · a args · ? help · q quit")
+	if s.lastCommand != "" {
+		keys = s.lastStatus() + "  " + style(os.Stdout, styleDim, ". repeat · / output · h history · ? help · q quit")
+	}
+	if len(s.shown) > s.listRows() {
+		keys += "  " + style(os.Stdout, styleDim, fmt.Sprintf("%d/%d", s.cursor+1, len(s.shown)))
+	}
+	return keys
+}
+
+// lastStatus describes the last run: its command, whether it passed, and
+// how long it took
+func (s *InteractiveSession) lastStatus() string {
+	command := interactiveCommandLine(s.lastCommand, s.lastArgs)
 	if s.lastExitCode == 0 {
-		fmt.Printf("Command completed successfully\n")
-		// Auto-return to menu on success
-	} else {
-		fmt.Printf("Command failed (exit code: %d)\n", s.lastExitCode)
-		fmt.Println("Press any key to continue...")
-
-		// Wait for keypress on failure
-		_ = s.terminal.SetRawMode()
-		_, _ = s.terminal.ReadKey()
-		_ = s.terminal.RestoreMode()
-	}
-
-	return nil
-}
-
-// showHelp displays help information
-func (s *InteractiveSession) showHelp() {
-	_ = s.terminal.RestoreMode()
-
-	fmt.Println("\nInteractive Mode Help")
-	fmt.Println("─────────────────────────────────────")
-	fmt.Println("Shortcuts:")
-	fmt.Println("  t - test       b - build     r - run")
-	fmt.Println("  f - format     l - lint      c - check")
-	fmt.Println("  x - fix        s - serve")
-	fmt.Println()
-	fmt.Println("Controls:")
-	fmt.Println("  1-9 - Run numbered command")
-	fmt.Println("  .   - Repeat last command")
-	fmt.Println("  /   - Toggle between menu and last output")
-	fmt.Println("  q   - Quit interactive mode")
-	fmt.Println("  ?   - Show this help")
-	fmt.Println()
-	fmt.Println("You can also type the full command name")
-	fmt.Println("or a unique prefix to run it.")
-	fmt.Println()
-	fmt.Println("Press any key to continue...")
-
-	_ = s.terminal.SetRawMode()
-	_, _ = s.terminal.ReadKey()
-	_ = s.terminal.RestoreMode()
-}
-
-// cleanup restores terminal state
-func (s *InteractiveSession) cleanup() {
-	_ = s.terminal.RestoreMode()
-	fmt.Println("\nGoodbye!")
+		return fmt.Sprintf("%s %s %s", passMark(os.Stdout, true), command, roundDuration(s.lastElapsed))
+	}
+	if s.lastCancelled {
+		return fmt.Sprintf("%s %s cancelled after %s", passMark(os.Stdout, false), command, roundDuration(s.lastElapsed))
+	}
+	return fmt.Sprintf("%s %s failed (exit code: %d) after %s", passMark(os.Stdout, false), command, s.lastExitCode, roundDuration(s.lastElapsed))
+}
+
+// helpLines is the help, which the overlay shows over the menu until any
+// key dismisses it
+func helpLines() []string {
+	return []string{
+		"Shortcuts:",
+		"  t test · b build · r run · f format",
+		"  l lint · c check · x fix · s serve",
+		"",
+		"Controls:",
+		"  ↑ ↓ PgUp PgDn  Move through the commands",
+		"  Enter          Run the selected command",
+		"  a              Run it with arguments",
+		"  1-9            Run a numbered command",
+		"  .              Repeat the last command",
+		"  /              Toggle the last output",
+		"  h              Commands run this session",
+		"  !              Run a shell command",
+		"  Ctrl+C         Stop a running command",
+		"  q              Quit",
+		"",
+		"Any other key starts a filter on command",
+		"names and descriptions; Enter runs the",
+		"selected match.",
+		"",
+		style(os.Stdout, styleDim, "Press any key to close"),
+	}
 }
@@ -0,0 +1,414 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTestSession(t *testing.T, names ...string) *InteractiveSession {
+	dir := t.TempDir()
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	s := &InteractiveSession{runner: runner, width: 80, height: 12, availableCommands: map[string]CommandInfo{}, commandSources: map[string]string{}}
+	for _, name := range names {
+		s.availableCommands[name] = CommandInfo{Description: "runs " + name}
+		s.commandSources[name] = "just"
+	}
+	s.buildMenu()
+	return s
+}
+
+func typeKeys(s *InteractiveSession, text string) tea.Cmd {
+	var cmd tea.Cmd
+	for _, r := range text {
+		_, cmd = s.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return cmd
+}
+
+// finishRun ends the run the session started, as if it passed
+func finishRun(s *InteractiveSession) {
+	if s.running != nil {
+		s.Update(commandDoneMsg{command: s.running.command, args: s.running.args})
+	}
+}
+
+func TestInteractiveMenu(t *testing.T) {
+	s := newTestSession(t, "deploy", "deps", "test", "build", "docs")
+	var names []string
+	for _, entry := range s.entries {
+		names = append(names, entry.key+":"+entry.name)
+	}
+	if want := []string{"t:test", "b:build", "1:deploy", "2:deps", "3:docs"}; !slicesEqual(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+
+	// Only as many rows as fit are drawn
+	s.Update(tea.WindowSizeMsg{Width: 40, Height: 5})
+	view := s.View()
+	if lines := strings.Split(view, "\n"); len(lines) != s.height {
+		t.Errorf("View() has %d lines, want %d", len(lines), s.height)
+	}
+	if !strings.Contains(view, "deploy") || strings.Contains(view, "docs") {
+		t.Errorf("View() = %q, want the first rows only", view)
+	}
+	for i := 0; i < 4; i++ {
+		s.Update(tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if view := s.View(); !strings.Contains(view, "docs") || strings.Contains(view, "[t]") {
+		t.Errorf("View() after scrolling = %q, want the last rows", view)
+	}
+}
+
+func TestInteractiveResize(t *testing.T) {
+	s := newTestSession(t, "test", "a-rather-long-command-name")
+	for _, size := range []tea.WindowSizeMsg{{Width: 100, Height: 20}, {Width: 30, Height: 6}, {Width: 100, Height: 20}} {
+		s.Update(size)
+		lines := strings.Split(s.View(), "\n")
+		if len(lines) != size.Height {
+			t.Errorf("at %dx%d, View() has %d lines", size.Width, size.Height, len(lines))
+		}
+		for _, line := range lines {
+			if width := utf8.RuneCountInString(ansiEscapePattern.ReplaceAllString(line, "")); width > size.Width {
+				t.Errorf("at %dx%d, line %q is %d wide", size.Width, size.Height, line, width)
+			}
+		}
+		// Narrow terminals leave out the sources, short ones the title
+		view := s.View()
+		if wide := size.Width >= narrowWidth; strings.Contains(view, "just") != wide {
+			t.Errorf("at %dx%d, View() = %q; want sources: %v", size.Width, size.Height, view, wide)
+		}
+		if tall := !s.compact(); strings.Contains(view, "interactive mode") != tall {
+			t.Errorf("at %dx%d, View() = %q; want title: %v", size.Width, size.Height, view, tall)
+		}
+	}
+}
+
+func TestInteractiveFilter(t *testing.T) {
+	s := newTestSession(t, "deploy", "deps", "test", "docs")
+	s.availableCommands["docs"] = CommandInfo{Description: "Publish the site"}
+	s.buildMenu()
+	shown := func() []string {
+		var names []string
+		for _, entry := range s.shown {
+			names = append(names, entry.name)
+		}
+		return names
+	}
+
+	// A letter that isn't a shortcut starts filtering
+	typeKeys(s, "dep")
+	if !s.typing || s.typed != "dep" {
+		t.Fatalf("typing = %v, %q; want dep", s.typing, s.typed)
+	}
+	if want := []string{"deps", "deploy"}; !slicesEqual(shown(), want) {
+		t.Errorf("shown for dep = %v, want %v", shown(), want)
+	}
+	if view := s.View(); strings.Contains(view, "test") {
+		t.Errorf("View() = %q, want only the matches", view)
+	}
+
+	// Descriptions match too, after names
+	s.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	typeKeys(s, "pub")
+	if want := []string{"docs"}; !slicesEqual(shown(), want) {
+		t.Errorf("shown for pub = %v, want %v", shown(), want)
+	}
+
+	s.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if s.typing || len(s.shown) != len(s.entries) {
+		t.Errorf("Esc left typing = %v, %d shown", s.typing, len(s.shown))
+	}
+
+	typeKeys(s, "depl")
+	if _, cmd := s.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || s.typing {
+		t.Error("Enter should run the selected match")
+	}
+	finishRun(s)
+
+	typeKeys(s, "zz")
+	s.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if s.message != "No command matches 'zz'" {
+		t.Errorf("message = %q, want no match", s.message)
+	}
+}
+
+func TestInteractiveArguments(t *testing.T) {
+	s := newTestSession(t, "test", "build")
+	s.lastCommand, s.lastArgs = "test", []string{"-v"}
+
+	// a prompts for the selected command's arguments, starting from the last
+	typeKeys(s, "a")
+	if s.prompting != "test" || s.argsInput != "-v" {
+		t.Fatalf("prompting = %q, %q; want test, -v", s.prompting, s.argsInput)
+	}
+	typeKeys(s, " -run")
+	s.Update(tea.KeyMsg{Type: tea.KeySpace})
+	typeKeys(s, "'Test Foo'")
+	if s.argsInput != "-v -run 'Test Foo'" {
+		t.Errorf("argsInput = %q", s.argsInput)
+	}
+	if _, cmd := s.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || s.prompting != "" {
+		t.Error("Enter should run the command with the arguments")
+	}
+
+	s.Update(tea.KeyMsg{Type: tea.KeyDown})
+	typeKeys(s, "a")
+	s.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if s.prompting != "" {
+		t.Errorf("Esc left prompting = %q", s.prompting)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  -run   TestFoo ", []string{"-run", "TestFoo"}},
+		{`-run 'Test Foo|Bar' "a b" c\ d ''`, []string{"-run", "Test Foo|Bar", "a b", "c d", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.line)
+		if err != nil || !slicesEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v; want %q", tt.line, got, err, tt.want)
+		}
+	}
+	if _, err := splitArgs(`-run "TestFoo`); err == nil {
+		t.Error("splitArgs() should reject an unterminated quote")
+	}
+}
+
+func TestInteractiveDetail(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nhi = \"echo hi\"\n")
+	s := &InteractiveSession{runner: &CommandRunner{CurrentDir: dir, ProjectRoot: dir}, width: 80, height: 12}
+	s.gatherCommands()
+
+	if view := s.View(); !strings.Contains(view, "synthesized: Runs lint, typecheck, and test") {
+		t.Errorf("View() = %q, want check's detail", view)
+	}
+	s.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	if view := s.View(); !strings.Contains(view, ".cmdr.toml") || !strings.Contains(view, "runs: sh -c 'echo hi'") {
+		t.Errorf("View() = %q, want hi's source and command line", view)
+	}
+	for _, entry := range s.entries {
+		if entry.name == "check" && entry.source != "synthesized" {
+			t.Errorf("check source = %q, want synthesized", entry.source)
+		}
+	}
+}
+
+func TestInteractiveMouse(t *testing.T) {
+	s := newTestSession(t, "test", "build", "deploy")
+
+	s.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown})
+	if s.cursor != 1 {
+		t.Errorf("cursor after wheel = %d, want 1", s.cursor)
+	}
+
+	// Rows below the title and rule are the entries
+	if _, cmd := s.Update(tea.MouseMsg{Button: tea.MouseButtonLeft, Action: tea.MouseActionPress, Y: 0}); cmd != nil {
+		t.Error("clicking the title should do nothing")
+	}
+	if _, cmd := s.Update(tea.MouseMsg{Button: tea.MouseButtonLeft, Action: tea.MouseActionPress, Y: 4}); cmd == nil || s.cursor != 2 {
+		t.Errorf("clicking deploy: cursor = %d, want 2 and a run", s.cursor)
+	}
+	if _, cmd := s.Update(tea.MouseMsg{Button: tea.MouseButtonLeft, Action: tea.MouseActionPress, Y: 6}); cmd != nil {
+		t.Error("clicking below the entries should do nothing")
+	}
+}
+
+func TestInteractiveHistory(t *testing.T) {
+	s := newTestSession(t, "test", "build")
+	s.Update(commandDoneMsg{command: "test", args: []string{"-v"}, elapsed: 1200 * time.Millisecond})
+	s.Update(commandDoneMsg{command: "build", err: &exitError{code: 2}, elapsed: 300 * time.Millisecond})
+	s.Update(tea.KeyMsg{Type: tea.KeyEsc}) // back from the failure's output
+	typeKeys(s, "h")
+	if !s.showingHistory {
+		t.Fatal("h should show the history")
+	}
+
+	// Newest first, with exit codes and durations
+	view := ansiEscapePattern.ReplaceAllString(s.View(), "")
+	build, test := strings.Index(view, "✗ build    exit 2  300ms"), strings.Index(view, "✓ test -v  exit 0  1.2s")
+	if build < 0 || test < 0 || build > test {
+		t.Errorf("View() = %q, want build then test -v", view)
+	}
+
+	// Any entry runs again, with its arguments
+	s.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if run, _ := s.historyEntry(s.historyCursor); run.command != "test" {
+		t.Errorf("selected %q, want test", run.command)
+	}
+	if _, cmd := s.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || s.running == nil || s.running.command != "test" {
+		t.Error("Enter should run the entry again")
+	}
+	finishRun(s)
+	typeKeys(s, "a")
+	if s.prompting != "test" || s.argsInput != "-v" {
+		t.Errorf("prompting = %q, %q; want test, -v", s.prompting, s.argsInput)
+	}
+	s.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, cmd := s.Update(tea.MouseMsg{Button: tea.MouseButtonLeft, Action: tea.MouseActionPress, Y: 3}); cmd == nil || s.running == nil || s.running.command != "build" {
+		t.Error("clicking build should run it again")
+	}
+	finishRun(s)
+
+	s.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if s.showingHistory {
+		t.Error("Esc should close the history")
+	}
+}
+
+func TestInteractiveRunPanel(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nslow = \"echo one; echo two; sleep 10\"\n")
+	s := &InteractiveSession{runner: &CommandRunner{CurrentDir: dir, ProjectRoot: dir, Quiet: true}, width: 80, height: 12}
+
+	batch := s.runCommand("slow", nil)().(tea.BatchMsg)
+	done := make(chan tea.Msg)
+	go func() { done <- batch[0]() }()
+
+	// The panel shows the tail of the output as it comes
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(s.View(), "two") {
+		if time.Now().After(deadline) {
+			t.Fatalf("View() = %q, want the output so far", s.View())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if view := s.View(); !strings.Contains(view, "slow") || !strings.Contains(view, "ctrl+c cancel") {
+		t.Errorf("View() = %q, want the command and how to cancel it", view)
+	}
+
+	// Ctrl+C stops the command, not the session
+	if _, cmd := s.Update(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd != nil {
+		t.Error("Ctrl+C during a run should not quit")
+	}
+	select {
+	case msg := <-done:
+		s.Update(msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ctrl+C didn't stop the command")
+	}
+	if !s.lastCancelled || !s.viewingOutput || s.running != nil {
+		t.Errorf("after Ctrl+C: cancelled = %v, viewingOutput = %v", s.lastCancelled, s.viewingOutput)
+	}
+	if view := s.View(); !strings.Contains(view, "slow cancelled after") || !strings.Contains(view, "one") {
+		t.Errorf("View() = %q, want the cancelled run's output", view)
+	}
+}
+
+func TestInteractiveHelpOverlay(t *testing.T) {
+	s := newTestSession(t, "test", "build", "deploy")
+	s.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+	s.Update(tea.KeyMsg{Type: tea.KeyDown})
+	typeKeys(s, "?")
+
+	// The help is drawn over the menu, which shows around it
+	lines := strings.Split(ansiEscapePattern.ReplaceAllString(s.View(), ""), "\n")
+	if len(lines) != s.height || !strings.Contains(lines[0], "cmd-runner interactive mode") {
+		t.Fatalf("View() = %q, want the menu's title above the help", lines)
+	}
+	border := 0
+	for border < len(lines) && !strings.Contains(lines[border], "╭─ Interactive Mode Help") {
+		border++
+	}
+	if border == len(lines) || !strings.Contains(lines[border], "] ") {
+		t.Errorf("View() = %q, want the menu beside the help's border", lines)
+	}
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) > s.width {
+			t.Errorf("line %q is wider than the terminal", line)
+		}
+	}
+
+	// Any key dismisses it, leaving the menu as it was
+	s.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	if s.showingHelp || s.typing || s.cursor != 1 {
+		t.Errorf("after a key: showingHelp = %v, typing = %v, cursor = %d", s.showingHelp, s.typing, s.cursor)
+	}
+
+	// On a short terminal the help keeps its last line
+	s.Update(tea.WindowSizeMsg{Width: 80, Height: 8})
+	typeKeys(s, "?")
+	if view := s.View(); !strings.Contains(view, "Press any key to close") {
+		t.Errorf("View() = %q, want how to close the help", view)
+	}
+}
+
+func TestInteractiveShellEscape(t *testing.T) {
+	dir := t.TempDir()
+	s := &InteractiveSession{runner: &CommandRunner{CurrentDir: t.TempDir(), ProjectRoot: dir, Quiet: true}, width: 80, height: 12}
+	s.buildMenu()
+
+	typeKeys(s, "!")
+	if s.prompting != shellEscape {
+		t.Fatalf("prompting = %q, want the shell escape", s.prompting)
+	}
+	typeKeys(s, "pwd; echo hi")
+	if view := s.View(); !strings.Contains(view, "Shell command: pwd; echo hi") {
+		t.Errorf("View() = %q, want the shell command being typed", view)
+	}
+	_, cmd := s.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || s.running == nil {
+		t.Fatal("Enter should run the shell command")
+	}
+	s.Update(cmd().(tea.BatchMsg)[0]())
+
+	// It runs in the project's root, and repeats like any other command
+	if want := []string{dir, "hi"}; !slicesEqual(s.lastOutput, want) {
+		t.Errorf("output = %q, want %q", s.lastOutput, want)
+	}
+	if got := interactiveCommandLine(s.lastCommand, s.lastArgs); got != "! pwd; echo hi" {
+		t.Errorf("last command = %q", got)
+	}
+	if _, cmd := s.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'.'}}); cmd == nil || s.running == nil {
+		t.Error(". should run the shell command again")
+	}
+}
+
+func TestInteractiveRunDoesNotPrompt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sudo is refused on Windows before anything else")
+	}
+	// An answer waiting on stdin must still be there after the run
+	stdin, answer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := answer.WriteString("y\n"); err != nil {
+		t.Fatal(err)
+	}
+	answer.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+
+	dir := t.TempDir()
+	s := &InteractiveSession{runner: &CommandRunner{CurrentDir: dir, ProjectRoot: dir, Quiet: true, Sudo: true}, width: 80, height: 12}
+	s.buildMenu()
+	typeKeys(s, "!")
+	typeKeys(s, "echo hi")
+	_, cmd := s.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter should run the shell command")
+	}
+	s.Update(cmd().(tea.BatchMsg)[0]())
+
+	if output := strings.Join(s.lastOutput, "\n"); !strings.Contains(output, "run it from the shell") {
+		t.Errorf("output = %q, want a hint to run it from the shell", output)
+	}
+	if unread, err := io.ReadAll(stdin); err != nil || string(unread) != "y\n" {
+		t.Errorf("stdin after the run = %q, %v; want it unread", unread, err)
+	}
+}
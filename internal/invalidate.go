@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// invalidations counts calls to invalidateDir, so that runners know to
+// resolve their projects again
+var invalidations atomic.Int64
+
+// invalidateDir drops what is cached about dir: its sources' listings, its
+// parsed .cmdr.toml, and the projects runners resolved
+func invalidateDir(dir string) {
+	invalidations.Add(1)
+	forgetDirCommands(dir)
+	projectConfigCache.Lock()
+	delete(projectConfigCache.data, filepath.Join(dir, ProjectConfigFile))
+	projectConfigCache.Unlock()
+	debugf("invalidated the listings of %s", dir)
+}
+
+// invalidatePaths drops what is cached about the directories of the changed
+// paths
+func invalidatePaths(paths []string) {
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		for _, dir := range listingDirs(path) {
+			dirs[dir] = true
+		}
+	}
+	for dir := range dirs {
+		invalidateDir(dir)
+	}
+}
+
+// listingDirs returns the directories whose listings a change to path
+// affects: its own, and the project's if it's in a mise task directory
+func listingDirs(path string) []string {
+	dir := filepath.Dir(path)
+	dirs := []string{dir}
+	sep := string(filepath.Separator)
+	for _, tasksDir := range miseTaskDirs {
+		marker := sep + filepath.FromSlash(tasksDir) + sep
+		if i := strings.Index(dir+sep, marker); i >= 0 {
+			dirs = append(dirs, dir[:i])
+		}
+	}
+	return dirs
+}
+
+// listingWatcher invalidates the listings of the directories it watches
+// when their files change, for long-running modes that would otherwise
+// keep listing from a stale cache
+type listingWatcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+
+	mu      sync.Mutex
+	watched map[string]bool
+	// includers maps the directories of files that task files include to
+	// the directories whose listings include them
+	includers map[string][]string
+
+	// generation counts invalidations, so that a caller holding listings
+	// can tell when they went stale
+	generation atomic.Int64
+}
+
+// newListingWatcher starts a watcher. It fails if the platform's watches
+// are exhausted, in which case callers go without invalidation.
+func newListingWatcher() (*listingWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &listingWatcher{watcher: watcher, done: make(chan struct{}), watched: make(map[string]bool), includers: make(map[string][]string)}
+	go w.loop()
+	return w, nil
+}
+
+// watch invalidates dir's listings when a file in it, in its mise task
+// directories, or that its task files include, changes
+func (w *listingWatcher) watch(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watched[dir] {
+		return
+	}
+	w.watched[dir] = true
+	w.add(dir)
+	for _, tasksDir := range miseTaskDirs {
+		_ = filepath.WalkDir(filepath.Join(dir, tasksDir), func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && entry.IsDir() {
+				w.add(path)
+			}
+			return nil
+		})
+	}
+	w.watchIncludes(dir)
+}
+
+// watchIncludes watches the directories of the files dir's task files
+// include. w.mu must be held.
+func (w *listingWatcher) watchIncludes(dir string) {
+	for _, path := range listingIncludes(dir) {
+		_ = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if !entry.IsDir() {
+				path = filepath.Dir(path)
+			}
+			if !slices.Contains(w.includers[path], dir) {
+				w.includers[path] = append(w.includers[path], dir)
+				w.add(path)
+			}
+			return nil
+		})
+	}
+}
+
+// add watches one directory. A directory that can't be watched keeps its
+// listings until the process exits, as without a watcher.
+func (w *listingWatcher) add(dir string) {
+	if err := w.watcher.Add(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Warning: not watching %s for changes: %v\n", dir, err)
+	}
+}
+
+// changed returns a number that increases each time listings are invalidated
+func (w *listingWatcher) changed() int64 {
+	return w.generation.Load()
+}
+
+func (w *listingWatcher) Close() {
+	close(w.done)
+	_ = w.watcher.Close()
+}
+
+func (w *listingWatcher) loop() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() && len(listingDirs(event.Name)) > 1 {
+				// A new directory of mise tasks
+				w.add(event.Name)
+			}
+			invalidatePaths([]string{event.Name})
+			w.mu.Lock()
+			for _, dir := range w.includers[filepath.Dir(event.Name)] {
+				invalidateDir(dir)
+			}
+			// An edit may add an include
+			for _, dir := range listingDirs(event.Name) {
+				if w.watched[dir] {
+					w.watchIncludes(dir)
+				}
+			}
+			w.mu.Unlock()
+			w.generation.Add(1)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			debugf("listing watch error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListingDirs(t *testing.T) {
+	project := filepath.Join(string(filepath.Separator), "p")
+	for path, want := range map[string][]string{
+		filepath.Join(project, "justfile"):                     {project},
+		filepath.Join(project, ".mise", "tasks", "ci", "lint"): {filepath.Join(project, ".mise", "tasks", "ci"), project},
+		filepath.Join(project, "mise-tasks", "build"):          {filepath.Join(project, "mise-tasks"), project},
+	} {
+		if got := listingDirs(path); !slicesEqual(got, want) {
+			t.Errorf("listingDirs(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestListingWatcher(t *testing.T) {
+	dir := t.TempDir()
+	makefile := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(makefile, []byte("build:\n\tgo build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := NewMakeSource(dir)
+	if commands := source.ListCommands(); len(commands) != 1 {
+		t.Fatalf("ListCommands() = %v, want build", commands)
+	}
+
+	watcher, err := newListingWatcher()
+	if err != nil {
+		t.Skipf("can't watch files: %v", err)
+	}
+	defer watcher.Close()
+	watcher.watch(dir)
+	generation := watcher.changed()
+
+	if err := os.WriteFile(makefile, []byte("build:\n\tgo build\ntest:\n\tgo test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); watcher.changed() == generation; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("no invalidation after the Makefile changed")
+		}
+	}
+	if commands := source.ListCommands(); len(commands) != 2 {
+		t.Errorf("after an edit, ListCommands() = %v, want build and test", commands)
+	}
+}
+
+func TestListingWatcherIncludes(t *testing.T) {
+	dir := t.TempDir()
+	included := filepath.Join(dir, "mk", "test.mk")
+	if err := os.MkdirAll(filepath.Dir(included), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("include mk/*.mk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(included, []byte("test:\n\tgo test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key := "make:" + dir
+	getCachedCommands(key, dir, func() map[string]CommandInfo {
+		return map[string]CommandInfo{"test": {Execution: "make test"}}
+	})
+
+	watcher, err := newListingWatcher()
+	if err != nil {
+		t.Skipf("can't watch files: %v", err)
+	}
+	defer watcher.Close()
+	watcher.watch(dir)
+
+	// Editing the included file drops the listing of the directory that
+	// includes it
+	if err := os.WriteFile(included, []byte("test:\n\tgo test ./...\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		commandListCache.RLock()
+		_, cached := commandListCache.data[key]
+		commandListCache.RUnlock()
+		if !cached {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the listing is still cached after an included file changed")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultSourceJobs limits sources whose tools share a heavyweight daemon or
+// JVM, and slow down rather than speed up when run side by side
+var defaultSourceJobs = map[string]int{
+	"gradle": 1,
+	"maven":  1,
+}
+
+// jobSlots limits how many commands cmdr runs at once: in total, and for
+// each source. A nil channel means no limit.
+type jobSlots struct {
+	all     chan struct{}
+	limits  map[string]int
+	mu      sync.Mutex
+	sources map[string]chan struct{}
+}
+
+// newJobSlots returns the slots for commands started by r: --jobs (or the
+// user config's jobs) in total, and the source_jobs limits of the user and
+// project configs per source
+func (r *CommandRunner) newJobSlots() *jobSlots {
+	s := &jobSlots{limits: r.sourceJobLimits(), sources: make(map[string]chan struct{})}
+	if jobs := r.jobLimit(); jobs > 0 {
+		s.all = make(chan struct{}, jobs)
+	}
+	return s
+}
+
+// jobLimit returns the number of commands that may run at once, or 0 for no
+// limit
+func (r *CommandRunner) jobLimit() int {
+	if r.Jobs > 0 {
+		return r.Jobs
+	}
+	if config := LoadUserConfig(); config != nil {
+		return config.Jobs
+	}
+	return 0
+}
+
+// sourceJobLimits returns the per-source limits by lowercased source name.
+// The project configs override the user config, which overrides the defaults.
+func (r *CommandRunner) sourceJobLimits() map[string]int {
+	limits := make(map[string]int)
+	for name, limit := range defaultSourceJobs {
+		limits[name] = limit
+	}
+	configs := []map[string]int{}
+	if config := LoadUserConfig(); config != nil {
+		configs = append(configs, config.SourceJobs)
+	}
+	dirs := r.searchDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if config := loadProjectConfig(dirs[i]); config != nil {
+			configs = append(configs, config.SourceJobs)
+		}
+	}
+	for _, config := range configs {
+		for name, limit := range config {
+			limits[strings.ToLower(name)] = limit
+		}
+	}
+	return limits
+}
+
+// acquire waits for a slot for a command from source, and returns the
+// function that releases it. It fails if ctx is done first.
+func (s *jobSlots) acquire(ctx context.Context, source string) (func(), error) {
+	sourceSlots := s.sourceSlots(source)
+	if sourceSlots != nil {
+		select {
+		case sourceSlots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	// Wait for the source before taking a global slot, so that commands
+	// queued behind a busy source don't hold slots others could use
+	if s.all != nil {
+		select {
+		case s.all <- struct{}{}:
+		case <-ctx.Done():
+			if sourceSlots != nil {
+				<-sourceSlots
+			}
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		if s.all != nil {
+			<-s.all
+		}
+		if sourceSlots != nil {
+			<-sourceSlots
+		}
+	}, nil
+}
+
+func (s *jobSlots) sourceSlots(source string) chan struct{} {
+	key := strings.ToLower(source)
+	limit := s.limits[key]
+	if limit <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sources[key] == nil {
+		s.sources[key] = make(chan struct{}, limit)
+	}
+	return s.sources[key]
+}
+
+// validateSourceJobs checks a source_jobs table
+func validateSourceJobs(limits map[string]int) error {
+	for _, name := range sortCommands(limits) {
+		if limits[name] < 1 {
+			return fmt.Errorf("source_jobs.%s must be at least 1, got %d", name, limits[name])
+		}
+	}
+	return nil
+}
+
+// commandSource returns the name of the source command resolves from, or ""
+// if it doesn't resolve to a single source
+func (r *CommandRunner) commandSource(command string) string {
+	if cc := r.commandConfig(command); cc != nil && cc.Source != "" {
+		return cc.Source
+	}
+	for _, name := range []string{command, NormalizeCommand(command)} {
+		if _, source := r.lookupCommand(name); source != nil {
+			return source.Name()
+		}
+	}
+	return ""
+}
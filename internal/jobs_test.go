@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJobSlots(t *testing.T) {
+	slots := &jobSlots{all: make(chan struct{}, 2), limits: map[string]int{"gradle": 1}, sources: make(map[string]chan struct{})}
+	ctx := context.Background()
+
+	releaseGradle, err := slots.acquire(ctx, "Gradle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second Gradle command waits for the first, without taking the
+	// remaining global slot
+	waiting, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := slots.acquire(waiting, "gradle"); err == nil {
+		t.Error("second Gradle command should wait")
+	}
+	releaseMake, err := slots.acquire(ctx, "make")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both global slots are taken
+	waiting, cancel = context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := slots.acquire(waiting, "npm"); err == nil {
+		t.Error("third command should wait for a global slot")
+	}
+
+	releaseGradle()
+	releaseMake()
+	if release, err := slots.acquire(ctx, "Gradle"); err != nil {
+		t.Errorf("Gradle slot wasn't released: %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestSourceJobLimits(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeConfig(t, dir, "[source_jobs]\nmaven = 2\njust = 1\n")
+
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	limits := runner.sourceJobLimits()
+	if limits["gradle"] != 1 || limits["maven"] != 2 || limits["just"] != 1 {
+		t.Errorf("sourceJobLimits() = %v", limits)
+	}
+
+	bad := t.TempDir()
+	writeConfig(t, bad, "[source_jobs]\nGradle = 0\n")
+	if _, err := parseProjectConfig(filepath.Join(bad, ProjectConfigFile)); err == nil {
+		t.Error("source_jobs of 0 should be an error")
+	}
+}
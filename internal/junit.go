@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// junitOutputMax is how much of each step's output a JUnit report keeps,
+// from the end
+const junitOutputMax = 64 * 1024
+
+// junitReport collects the steps of a run for --junit. A run that records
+// no steps is reported as a single step named for the command.
+type junitReport struct {
+	mu    sync.Mutex
+	start time.Time
+	steps []junitStep
+}
+
+// junitStep is a testcase: a step that ran, or one skipped for skipReason
+type junitStep struct {
+	name       string
+	duration   time.Duration
+	err        error
+	output     []byte
+	skipReason string
+}
+
+// stepCapture returns a buffer for the output of a step of the run. Without
+// a JUnit report or GitHub Actions annotations, steps share the run's
+// buffer, if any.
+func (r *CommandRunner) stepCapture() *tailBuffer {
+	if r.report == nil && !githubActions() {
+		return r.capture
+	}
+	return &tailBuffer{max: junitOutputMax}
+}
+
+// addStep records a step that ran with the output captured by capture
+func (rep *junitReport) addStep(name string, duration time.Duration, err error, capture *tailBuffer) {
+	if rep == nil {
+		return
+	}
+	step := junitStep{name: name, duration: duration, err: err}
+	if capture != nil {
+		step.output = capture.Bytes()
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.steps = append(rep.steps, step)
+}
+
+// addSkipped records steps that didn't run
+func (rep *junitReport) addSkipped(reason string, names ...string) {
+	if rep == nil {
+		return
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, name := range names {
+		rep.steps = append(rep.steps, junitStep{name: name, skipReason: reason})
+	}
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Hostname  string      `xml:"hostname,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Skipped   *junitSkipped `xml:"skipped"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Output  string `xml:",cdata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes the run's report to r.JUnit
+func (r *CommandRunner) writeJUnit(elapsed time.Duration, err error, capture *tailBuffer) error {
+	rep := r.report
+	if len(rep.steps) == 0 {
+		rep.addStep(r.Command, elapsed, err, capture)
+	}
+	suite := junitSuite{
+		Name:      r.Command,
+		Time:      junitSeconds(elapsed),
+		Timestamp: rep.start.UTC().Format("2006-01-02T15:04:05"),
+	}
+	suite.Hostname, _ = os.Hostname()
+	for _, step := range rep.steps {
+		c := junitCase{Name: step.name, Classname: "cmdr." + r.Command, Time: junitSeconds(step.duration)}
+		switch {
+		case step.skipReason != "":
+			c.Skipped = &junitSkipped{Message: step.skipReason}
+			suite.Skipped++
+		case step.err != nil:
+			var filter logFilter
+			c.Failure = &junitFailure{
+				Message: step.err.Error(),
+				Type:    fmt.Sprintf("exit %d", ExitCode(step.err)),
+				Output:  strings.ToValidUTF8(string(filter.strip(step.output)), "�"),
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+	}
+	report := junitSuites{
+		Name:     "cmdr " + r.Command,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(r.JUnit); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(r.JUnit, append([]byte(xml.Header), append(data, '\n')...), 0o644)
+}
+
+// finishJUnit writes the run's report and returns the run's error, or, if
+// the run succeeded, the error writing the report
+func (r *CommandRunner) finishJUnit(elapsed time.Duration, err error, capture *tailBuffer) error {
+	if reportErr := r.writeJUnit(elapsed, err, capture); reportErr != nil {
+		if err == nil {
+			return fmt.Errorf("can't write JUnit report: %w", reportErr)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: can't write JUnit report %s: %v\n", r.JUnit, reportErr)
+	}
+	return err
+}
+
+// junitSeconds formats a duration as JUnit's decimal seconds
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
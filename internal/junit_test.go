@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestJUnitReport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setUserConfig(t, "")
+	yes := true
+	dir := t.TempDir()
+	writeConfig(t, dir, `[commands]
+lint = "echo linting"
+test = "echo 'expected 1 <got> 2'; exit 1"
+typecheck = "true"
+`)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "reports", "check.xml")
+	runner := &CommandRunner{Command: "check", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever, JUnit: path, FailFast: &yes}
+	if err := runner.Run(); err == nil {
+		t.Fatal("Run() should fail")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report doesn't parse: %v\n%s", err, data)
+	}
+	if report.Tests != 3 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	cases := report.Suites[0].Cases
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name)
+	}
+	if !slicesEqual(names, []string{"lint", "typecheck", "test"}) {
+		t.Errorf("testcases = %v", names)
+	}
+	if failure := cases[2].Failure; failure == nil || !strings.Contains(failure.Output, "expected 1 <got> 2") {
+		t.Errorf("test failure = %+v, want its output", failure)
+	}
+	if cases[0].Failure != nil || cases[1].Failure != nil {
+		t.Errorf("passing steps have failures: %+v", cases[:2])
+	}
+}
+
+func TestJUnitSingleCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nhi = \"echo hi\"\n")
+	path := filepath.Join(t.TempDir(), "hi.xml")
+	runner := &CommandRunner{Command: "hi", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever, JUnit: path}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `<testcase name="hi" classname="cmdr.hi"`) {
+		t.Errorf("report = %s", data)
+	}
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ListFormats are the formats WriteCommandList accepts
+var ListFormats = []string{"plain", "tsv", "json", "yaml"}
+
+// ListedCommand is a command as the machine-readable list formats report it
+type ListedCommand struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	Dir         string `json:"dir"`
+	Description string `json:"description"`
+	Execution   string `json:"execution"`
+}
+
+// listedCommands returns the commands a listing shows, in the same order
+func (r *CommandRunner) listedCommands(showAll bool) []ListedCommand {
+	commands := []ListedCommand{}
+	for _, section := range r.listSections(showAll) {
+		for _, name := range append(section.core, section.additional...) {
+			info := section.commands[name]
+			commands = append(commands, ListedCommand{
+				Name:        name,
+				Source:      section.source,
+				Dir:         section.dir,
+				Description: info.Description,
+				Execution:   info.Execution,
+			})
+		}
+	}
+	return commands
+}
+
+// WriteCommandList writes the commands --list shows in a format for scripts:
+// plain (one name per line), tsv (name, source, description, and execution,
+// tab-separated), json, or yaml
+func (r *CommandRunner) WriteCommandList(w io.Writer, format string, showAll bool) error {
+	commands := r.listedCommands(showAll)
+	switch format {
+	case "plain":
+		for _, c := range commands {
+			fmt.Fprintln(w, c.Name)
+		}
+	case "tsv":
+		for _, c := range commands {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", tsvField(c.Name), tsvField(c.Source), tsvField(c.Description), tsvField(c.Execution))
+		}
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(commands)
+	case "yaml":
+		if len(commands) == 0 {
+			fmt.Fprintln(w, "[]")
+		}
+		for _, c := range commands {
+			fmt.Fprintf(w, "- name: %s\n", yamlString(c.Name))
+			fmt.Fprintf(w, "  source: %s\n", yamlString(c.Source))
+			fmt.Fprintf(w, "  dir: %s\n", yamlString(c.Dir))
+			fmt.Fprintf(w, "  description: %s\n", yamlString(c.Description))
+			fmt.Fprintf(w, "  execution: %s\n", yamlString(c.Execution))
+		}
+	default:
+		return fmt.Errorf("unknown list format %q (expected %s)", format, strings.Join(ListFormats, ", "))
+	}
+	return nil
+}
+
+// CommandList is what --list --json writes: the commands the listing shows,
+// each with the command line it runs, which synthesized commands apply, and
+// the aliases as they resolve in the project, for tools that present cmdr's
+// commands as cmdr would run them
+type CommandList struct {
+	Dir         string               `json:"dir"`
+	ProjectRoot string               `json:"project_root"`
+	Commands    []CommandDetail      `json:"commands"`
+	Synthesized []SynthesizedCommand `json:"synthesized"`
+	Aliases     []ListedAlias        `json:"aliases"`
+}
+
+// CommandDetail is a listed command, with how it resolves
+type CommandDetail struct {
+	ListedCommand
+	Category string   `json:"category"`
+	AlsoIn   []string `json:"also_in,omitempty"` // sources whose command of the same name it shadows
+	Cwd      string   `json:"cwd,omitempty"`     // where it runs, for a single command
+	Argv     []string `json:"argv,omitempty"`
+	Path     string   `json:"path,omitempty"` // the program, as found on PATH
+}
+
+// SynthesizedCommand is a command cmd-runner can provide itself, and
+// whether it does in this project
+type SynthesizedCommand struct {
+	Name    string   `json:"name"`
+	Applies bool     `json:"applies"`
+	Reason  string   `json:"reason,omitempty"` // why it doesn't apply
+	Steps   []string `json:"steps,omitempty"`  // the commands check and fix run
+}
+
+// ListedAlias is a short name for a command, and what it runs here
+type ListedAlias struct {
+	Name        string   `json:"name"`
+	Expands     string   `json:"expands"`        // the command line it stands for
+	User        bool     `json:"user,omitempty"` // from the user config, rather than built in
+	Source      string   `json:"source,omitempty"`
+	Argv        []string `json:"argv,omitempty"`
+	Synthesized bool     `json:"synthesized,omitempty"` // runs a synthesized command
+}
+
+// builtinAliases are the short names the --list alias table shows
+var builtinAliases = []struct{ name, command string }{
+	{"f", "format"}, {"t", "test"}, {"tc", "typecheck"},
+	{"r", "run"}, {"s", "serve"}, {"b", "build"}, {"l", "lint"},
+}
+
+// synthesizedNames are the commands cmd-runner can synthesize
+var synthesizedNames = []string{"check", "fix", "typecheck", "clean", "setup"}
+
+// CommandList returns what --list --json writes
+func (r *CommandRunner) CommandList(showAll bool) *CommandList {
+	list := &CommandList{
+		Dir:         r.CurrentDir,
+		ProjectRoot: r.ProjectRoot,
+		Commands:    []CommandDetail{},
+		Synthesized: []SynthesizedCommand{},
+		Aliases:     []ListedAlias{},
+	}
+	synthesized := make(map[string]bool)
+	for _, section := range r.listSections(showAll) {
+		for _, name := range append(append([]string{}, section.core...), section.additional...) {
+			info := section.commands[name]
+			detail := CommandDetail{
+				ListedCommand: ListedCommand{Name: name, Source: section.source, Dir: section.dir, Description: info.Description, Execution: info.Execution},
+				Category:      r.categoryOf(name),
+				AlsoIn:        section.alsoIn[name],
+			}
+			if section.source == synthesizedSource {
+				synthesized[name] = true
+			} else if plan, err := r.subRunner(name, nil).Resolution(); err == nil {
+				detail.Cwd, detail.Argv, detail.Path = plan.Cwd, plan.Argv, plan.Path
+			}
+			list.Commands = append(list.Commands, detail)
+		}
+	}
+
+	for _, name := range synthesizedNames {
+		command := SynthesizedCommand{Name: name, Applies: synthesized[name]}
+		switch {
+		case command.Applies && name == "check":
+			command.Steps = r.checkSteps(r.checkCandidates())
+		case command.Applies && name == "fix":
+			command.Steps = r.fixSteps()
+		case command.Applies:
+		case r.strict():
+			command.Reason = "synthesized commands are disabled"
+		case r.hasListedCommand(name):
+			command.Reason = "the project defines it"
+		case name == "typecheck":
+			command.Reason = "no type checker for this project"
+		case name == "clean":
+			command.Reason = "no build artifacts to remove"
+		case name == "setup":
+			command.Reason = "no toolchains to provision"
+		}
+		list.Synthesized = append(list.Synthesized, command)
+	}
+
+	list.Aliases = r.listedAliases(synthesized)
+	return list
+}
+
+// listedAliases resolves the built-in aliases and then the user config's,
+// given the names of the synthesized commands that apply
+func (r *CommandRunner) listedAliases(synthesized map[string]bool) []ListedAlias {
+	aliases := []ListedAlias{}
+	for _, alias := range builtinAliases {
+		aliases = append(aliases, r.listedAlias(alias.name, alias.command, false, synthesized))
+	}
+	if config := LoadUserConfig(); config != nil {
+		for _, name := range sortCommands(config.Aliases) {
+			aliases = append(aliases, r.listedAlias(name, config.Aliases[name], true, synthesized))
+		}
+	}
+	return aliases
+}
+
+// listedAlias resolves an alias for CommandList
+func (r *CommandRunner) listedAlias(name, expands string, user bool, synthesized map[string]bool) ListedAlias {
+	alias := ListedAlias{Name: name, Expands: expands, User: user}
+	if plan, err := r.subRunner(name, nil).Resolution(); err == nil {
+		alias.Source, alias.Argv = plan.Source, plan.Argv
+	} else if fields := strings.Fields(expands); len(fields) > 0 {
+		alias.Synthesized = synthesized[NormalizeCommand(fields[0])]
+	}
+	return alias
+}
+
+// WriteCommandListJSON writes CommandList as indented JSON
+func (r *CommandRunner) WriteCommandListJSON(w io.Writer, showAll bool) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.CommandList(showAll))
+}
+
+// ListedAliases returns the alias table as it resolves in this project
+func (r *CommandRunner) ListedAliases() []ListedAlias {
+	synthesized := make(map[string]bool)
+	for _, section := range r.listSections(false) {
+		if section.source == synthesizedSource {
+			for name := range section.commands {
+				synthesized[name] = true
+			}
+		}
+	}
+	return r.listedAliases(synthesized)
+}
+
+// ListAliases writes ListedAliases: what each alias stands for, and the
+// command line it runs here
+func (r *CommandRunner) ListAliases(w io.Writer) {
+	aliases := r.ListedAliases()
+
+	nameWidth, expandsWidth := 0, 0
+	for _, alias := range aliases {
+		nameWidth = max(nameWidth, len(alias.Name))
+		expandsWidth = max(expandsWidth, len(alias.Expands))
+	}
+	fmt.Fprintln(w, style(w, styleHeader, "Command aliases in this project:"))
+	for _, alias := range aliases {
+		var runs string
+		switch {
+		case alias.Argv != nil:
+			runs = strings.Join(alias.Argv, " ") + "  " + style(w, styleDim, "("+alias.Source+")")
+		case alias.Synthesized:
+			runs = style(w, styleDim, "synthesized by "+synthesizedSource)
+		default:
+			runs = style(w, styleDim, "not available")
+		}
+		fmt.Fprintf(w, "  %-*s → %-*s  %s\n", nameWidth, alias.Name, expandsWidth, alias.Expands, runs)
+	}
+}
+
+// WriteAliasesJSON writes ListedAliases as indented JSON
+func (r *CommandRunner) WriteAliasesJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.ListedAliases())
+}
+
+// tsvField replaces the tabs and newlines that would break a TSV row
+func tsvField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// yamlString quotes s as a YAML double-quoted scalar, whose escapes are a
+// superset of Go's
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteCommandList(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("# Run the tests\ntest:\n\techo test\nlint:\n\techo lint\n_private:\n\techo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir, NoSynth: true}
+
+	var out strings.Builder
+	if err := runner.WriteCommandList(&out, "plain", false); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "lint\ntest\n"; got != want {
+		t.Errorf("plain = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := runner.WriteCommandList(&out, "tsv", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "test\tmake\t") || strings.Count(out.String(), "\n") != 2 {
+		t.Errorf("tsv = %q", out.String())
+	}
+
+	out.Reset()
+	if err := runner.WriteCommandList(&out, "json", false); err != nil {
+		t.Fatal(err)
+	}
+	var listed []ListedCommand
+	if err := json.Unmarshal([]byte(out.String()), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[1].Name != "test" || listed[1].Source != "make" || listed[1].Dir != dir {
+		t.Errorf("json = %+v", listed)
+	}
+
+	out.Reset()
+	if err := runner.WriteCommandList(&out, "yaml", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "- name: \"test\"\n  source: \"make\"\n") {
+		t.Errorf("yaml = %q", out.String())
+	}
+
+	if err := runner.WriteCommandList(&out, "xml", false); err == nil {
+		t.Error("WriteCommandList(xml) should fail")
+	}
+}
+
+func TestCommandList(t *testing.T) {
+	setUserConfig(t, "[aliases]\nut = \"test --unit\"\n")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("test:\n\techo test\nlint:\n\techo lint\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+
+	var out strings.Builder
+	if err := runner.WriteCommandListJSON(&out, false); err != nil {
+		t.Fatal(err)
+	}
+	var list CommandList
+	if err := json.Unmarshal([]byte(out.String()), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := make(map[string]CommandDetail)
+	for _, c := range list.Commands {
+		commands[c.Name] = c
+	}
+	if test := commands["test"]; test.Source != "make" || test.Category != "test" || !slicesEqual(test.Argv, []string{"make", "test"}) || test.Cwd != dir {
+		t.Errorf("test = %+v", test)
+	}
+	if check := commands["check"]; check.Source != synthesizedSource || check.Argv != nil {
+		t.Errorf("check = %+v", check)
+	}
+
+	synthesized := make(map[string]SynthesizedCommand)
+	for _, s := range list.Synthesized {
+		synthesized[s.Name] = s
+	}
+	if check := synthesized["check"]; !check.Applies || !slicesEqual(check.Steps, []string{"lint", "test"}) {
+		t.Errorf("synthesized check = %+v", check)
+	}
+	if typecheck := synthesized["typecheck"]; typecheck.Applies || typecheck.Reason == "" {
+		t.Errorf("synthesized typecheck = %+v, want a reason it doesn't apply", typecheck)
+	}
+
+	aliases := make(map[string]ListedAlias)
+	for _, a := range list.Aliases {
+		aliases[a.Name] = a
+	}
+	if a := aliases["t"]; a.Expands != "test" || a.Source != "make" || !slicesEqual(a.Argv, []string{"make", "test"}) {
+		t.Errorf("alias t = %+v", a)
+	}
+	if a := aliases["b"]; a.Source != "" || a.Argv != nil {
+		t.Errorf("alias b = %+v, want nothing to run", a)
+	}
+	if a := aliases["ut"]; !a.User || a.Expands != "test --unit" || !slicesEqual(a.Argv, []string{"make", "test", "--unit"}) {
+		t.Errorf("alias ut = %+v", a)
+	}
+}
+
+func TestListAliases(t *testing.T) {
+	setUserConfig(t, "[aliases]\nut = \"test --unit\"\n")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("test:\n\techo test\nlint:\n\techo lint\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+
+	var out strings.Builder
+	runner.ListAliases(&out)
+	lines := strings.Split(out.String(), "\n")
+	for _, want := range []string{
+		"  t  → test         make test  (make)",
+		"  b  → build        not available",
+		"  ut → test --unit  make test --unit  (make)",
+	} {
+		found := false
+		for _, line := range lines {
+			found = found || line == want
+		}
+		if !found {
+			t.Errorf("ListAliases() = %q, missing line %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputLog is the file a run's output is copied to, with --log-file or
+// log_dir in the user config. Terminal escape sequences are left out.
+type outputLog struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	filter logFilter
+}
+
+// logFilePath returns the file to copy the run's output to: --log-file, or
+// a new file in the user config's log_dir named for the command and time.
+// It returns "" to log nothing.
+func (r *CommandRunner) logFilePath(start time.Time) string {
+	if r.LogFile != "" {
+		return r.LogFile
+	}
+	config := LoadUserConfig()
+	if config == nil || config.LogDir == "" {
+		return ""
+	}
+	name := strings.Map(func(c rune) rune {
+		if c == '/' || c == '\\' || c == ' ' || c == ':' {
+			return '_'
+		}
+		return c
+	}, r.Command)
+	return filepath.Join(expandProjectDir(config.Path, config.LogDir), fmt.Sprintf("%s-%s.log", name, start.Format("20060102-150405")))
+}
+
+// openOutputLog creates the run's log file, if there is one, and writes a
+// header naming the command
+func (r *CommandRunner) openOutputLog(start time.Time) (*outputLog, error) {
+	path := r.logFilePath(start)
+	if path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l := &outputLog{path: path, file: file}
+	l.printf("# cmdr %s\n# in %s at %s\n", strings.Join(append([]string{r.Command}, r.Args...), " "), r.CurrentDir, start.Format(time.RFC3339))
+	return l, nil
+}
+
+// printf writes a line of cmdr's own to the log
+func (l *outputLog) printf(format string, args ...any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.file, format, args...)
+}
+
+// Write copies command output to the log without escape sequences
+func (l *outputLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(l.filter.strip(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// close writes how the run ended and closes the file
+func (l *outputLog) close(elapsed time.Duration, err error) {
+	if err != nil {
+		l.printf("\n# failed after %s: %v\n", roundDuration(elapsed), err)
+	} else {
+		l.printf("\n# finished in %s\n", roundDuration(elapsed))
+	}
+	l.file.Close()
+}
+
+// closeOutputLog finishes the run's log. When the log is one of log_dir's,
+// a failed run says where to find it.
+func (r *CommandRunner) closeOutputLog(elapsed time.Duration, err error) {
+	r.outputLog.close(elapsed, err)
+	if err != nil && r.LogFile == "" {
+		fmt.Fprintf(os.Stderr, "%sOutput logged to %s\n", r.badge(), r.outputLog.path)
+	}
+	r.outputLog = nil
+}
+
+// tee returns w, also copying to the run's log if there is one
+func (r *CommandRunner) tee(w io.Writer) io.Writer {
+	if r.outputLog == nil {
+		return r.captured(w)
+	}
+	return r.captured(io.MultiWriter(w, r.outputLog))
+}
+
+// captured returns w, also copying to the buffers that keep the run's
+// output for a JUnit report or SARIF log if there are any
+func (r *CommandRunner) captured(w io.Writer) io.Writer {
+	writers := []io.Writer{w}
+	if r.capture != nil {
+		writers = append(writers, r.capture)
+	}
+	if r.diagnostics != nil {
+		writers = append(writers, r.diagnostics)
+	}
+	if len(writers) == 1 {
+		return w
+	}
+	return io.MultiWriter(writers...)
+}
+
+// logFilter strips escape sequences from a stream, which may split them
+// across writes, and turns a pseudo-terminal's CRLF line endings into LF
+type logFilter struct {
+	state ansiState
+	cr    bool // a CR was held back, in case LF follows
+}
+
+type ansiState int
+
+const (
+	ansiText ansiState = iota
+	ansiEscape
+	ansiCSI
+	ansiOSC
+	ansiOSCEscape
+)
+
+func (f *logFilter) strip(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	s := &f.state
+	for _, c := range p {
+		switch *s {
+		case ansiText:
+			if f.cr && c != '\n' {
+				out = append(out, '\r')
+			}
+			f.cr = c == '\r'
+			switch c {
+			case 0x1b:
+				*s = ansiEscape
+			case '\r':
+			default:
+				out = append(out, c)
+			}
+		case ansiEscape:
+			switch c {
+			case '[':
+				*s = ansiCSI
+			case ']':
+				*s = ansiOSC
+			default:
+				*s = ansiText
+			}
+		case ansiCSI:
+			if c >= 0x40 && c <= 0x7e {
+				*s = ansiText
+			}
+		case ansiOSC:
+			switch c {
+			case 0x07:
+				*s = ansiText
+			case 0x1b:
+				*s = ansiOSCEscape
+			}
+		case ansiOSCEscape:
+			*s = ansiText
+		}
+	}
+	return out
+}
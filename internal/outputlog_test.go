@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLogFilter(t *testing.T) {
+	var f logFilter
+	var out []byte
+	// Sequences and line endings split across writes
+	for _, chunk := range []string{"\x1b[3", "1mred\x1b[0m\r", "\nbar\r50%\r100%\r\n", "\x1b]0;title\x07done\n"} {
+		out = append(out, f.strip([]byte(chunk))...)
+	}
+	if want := "red\nbar\r50%\r100%\ndone\n"; string(out) != want {
+		t.Errorf("strip() = %q, want %q", out, want)
+	}
+}
+
+func TestLogFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nhi = \"echo out; echo err >&2\"\n[pre]\nhi = \"echo pre\"\n")
+	path := filepath.Join(t.TempDir(), "hi.log")
+	runner := &CommandRunner{Command: "hi", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever, LogFile: path}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# cmdr hi\n", "$ echo pre\npre\n", "$ sh -c echo out; echo err >&2\n", "out\n", "err\n", "# finished in "} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log = %q, missing %q", data, want)
+		}
+	}
+}
+
+func TestLogDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	logDir := t.TempDir()
+	setUserConfig(t, "log_dir = '"+logDir+"'")
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\nbad = \"echo oops; exit 1\"\n")
+	runner := &CommandRunner{Command: "bad", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever}
+	if err := runner.Run(); err == nil {
+		t.Fatal("Run() should fail")
+	}
+	logs, _ := filepath.Glob(filepath.Join(logDir, "bad-*.log"))
+	if len(logs) != 1 {
+		t.Fatalf("logs = %v, want one bad-*.log", logs)
+	}
+	data, _ := os.ReadFile(logs[0])
+	if !strings.Contains(string(data), "oops\n") || !strings.Contains(string(data), "# failed after ") {
+		t.Errorf("log = %q", data)
+	}
+}
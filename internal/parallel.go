@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// labeledRun is one of several runs made at once, whose output lines are
+// prefixed with its label
+type labeledRun struct {
+	label  string
+	runner *CommandRunner
+}
+
+// runResult is how a labeled run ended; skipped runs never started
+type runResult struct {
+	duration time.Duration
+	err      error
+	skipped  bool
+}
+
+// parallelLimit returns how many runs --parallel makes at once: --jobs or
+// the user config's jobs, else one per CPU but at least two
+func (r *CommandRunner) parallelLimit() int {
+	if jobs := r.jobLimit(); jobs > 0 {
+		return jobs
+	}
+	return max(runtime.NumCPU(), 2)
+}
+
+// runParallel makes the runs at once, up to the parallel limit, and
+// multiplexes their output line by line, each line labeled with its run.
+// Once a run fails and stopOnFailure is set, or a run is interrupted, runs
+// that haven't started are skipped. Results are in the order of runs.
+func (r *CommandRunner) runParallel(runs []labeledRun, stopOnFailure bool) []runResult {
+	labels := make([]string, len(runs))
+	for i, run := range runs {
+		labels[i] = run.label
+	}
+	output := newPrefixedOutput(r.tee(os.Stdout), labels)
+	if colorEnabled(os.Stdout) {
+		output.colorize(labels)
+	}
+
+	results := make([]runResult, len(runs))
+	slots := make(chan struct{}, r.parallelLimit())
+	var mu sync.Mutex
+	failed, interrupted := false, false
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		slots <- struct{}{}
+		mu.Lock()
+		stop := interrupted || failed && stopOnFailure
+		mu.Unlock()
+		if stop {
+			<-slots
+			results[i] = runResult{skipped: true}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, run labeledRun) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			writer := output.writer(run.label)
+			run.runner.output = writer
+			start := time.Now()
+			err := run.runner.Run()
+			writer.flush()
+			results[i] = runResult{duration: time.Since(start), err: err}
+			if err != nil {
+				mu.Lock()
+				failed = true
+				interrupted = interrupted || killedBySignal(err)
+				mu.Unlock()
+			}
+		}(i, run)
+	}
+	wg.Wait()
+	return results
+}
+
+// statusWriter returns where cmdr's status lines for the run go: stderr,
+// or the labeled output of a run made alongside others
+func (r *CommandRunner) statusWriter() io.Writer {
+	if r.output != nil {
+		return r.output
+	}
+	return os.Stderr
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParallelCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	// Each step waits for the other to start, so they only pass together
+	writeConfig(t, dir, `[commands]
+lint = "touch lint.started; for i in $(seq 100); do [ -f test.started ] && exit 0; sleep 0.02; done; exit 1"
+test = "touch test.started; for i in $(seq 100); do [ -f lint.started ] && exit 0; sleep 0.02; done; exit 1"
+`)
+	runner := &CommandRunner{Command: "check", CurrentDir: dir, ProjectRoot: dir, Quiet: true, Parallel: true, Jobs: 2}
+	if err := runner.Run(); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestPrefixedOutputColors(t *testing.T) {
+	var out strings.Builder
+	output := newPrefixedOutput(&out, []string{"lint", "test"})
+	output.colorize([]string{"lint", "test"})
+	w := output.writer("lint")
+	w.Write([]byte("one\ntw"))
+	w.flush()
+	want := "\x1b[36mlint\x1b[0m | one\n\x1b[36mlint\x1b[0m | tw\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
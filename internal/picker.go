@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// picker is the state of the fuzzy command picker: the commands it offers,
+// the query typed so far, and the commands that match it, best first
+type picker struct {
+	items    []ListedCommand
+	query    string
+	matches  []pickerMatch
+	selected int
+	offset   int // first match shown, when they don't all fit
+}
+
+// pickerMatch is a command that matches the query, with the positions of
+// the matched runes in its name
+type pickerMatch struct {
+	item      int
+	score     int
+	positions []int
+}
+
+// RunPicker shows a fuzzy-searchable list of the project's commands and
+// runs the one the user picks. Escape or Ctrl+C leave without running
+// anything.
+func RunPicker() error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("--pick requires a terminal")
+	}
+	runner := New("", nil)
+	if err := runner.Init(); err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	p := newPicker(runner.listedCommands(true))
+	if len(p.items) == 0 {
+		return &exitError{ExitNotFound, fmt.Errorf("no commands to pick from in current directory or project root"), ErrNoSources}
+	}
+
+	terminal := NewTerminalManager()
+	if err := terminal.SetRawMode(); err != nil {
+		return err
+	}
+	fmt.Print("\033[?1049h") // alternate screen
+	// Keys are read here rather than by readKeys, so that nothing is left
+	// reading stdin once the chosen command runs
+	chosen := ""
+	buf := make([]byte, 16)
+	p.render()
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			break
+		}
+		done, name := p.handleKey(keyName(buf[:n]))
+		if done {
+			chosen = name
+			break
+		}
+		p.render()
+	}
+	fmt.Print("\033[?1049l")
+	_ = terminal.RestoreMode()
+
+	if chosen == "" {
+		return nil
+	}
+	return runner.subRunner(chosen, nil).Run()
+}
+
+// PickOnBareCommand reports whether cmdr with no arguments opens the picker
+// instead of listing commands: pick = true in the user config, and a
+// terminal to pick on
+func PickOnBareCommand() bool {
+	config := LoadUserConfig()
+	return config != nil && config.Pick &&
+		term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+func newPicker(items []ListedCommand) *picker {
+	p := &picker{items: items}
+	p.filter()
+	return p
+}
+
+// filter recomputes the matches for the query
+func (p *picker) filter() {
+	p.matches = fuzzyFilter(p.query, len(p.items), func(i int) (string, string) {
+		return p.items[i].Name, p.items[i].Description
+	})
+	p.selected, p.offset = 0, 0
+}
+
+// fuzzyFilter returns the candidates whose name or description matches
+// query, best first. An empty query matches every candidate, in order.
+func fuzzyFilter(query string, count int, candidate func(i int) (name, description string)) []pickerMatch {
+	var matches []pickerMatch
+	nameLength := make([]int, count)
+	for i := 0; i < count; i++ {
+		name, description := candidate(i)
+		nameLength[i] = len(name)
+		score, positions, ok := fuzzyMatch(query, name)
+		if !ok {
+			// A match in the description ranks below any match in a name
+			descScore, _, descOK := fuzzyMatch(query, description)
+			if !descOK {
+				continue
+			}
+			score, positions = descScore-1000, nil
+		}
+		matches = append(matches, pickerMatch{item: i, score: score, positions: positions})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return nameLength[a.item] < nameLength[b.item]
+	})
+	return matches
+}
+
+// handleKey applies a key press. It returns done when the picker should
+// close, with the name of the chosen command, or "" if it was cancelled.
+func (p *picker) handleKey(key string) (done bool, chosen string) {
+	switch key {
+	case "esc", "ctrl-c":
+		return true, ""
+	case "enter":
+		if len(p.matches) == 0 {
+			return false, ""
+		}
+		return true, p.items[p.matches[p.selected].item].Name
+	case "up":
+		if p.selected > 0 {
+			p.selected--
+		}
+	case "down":
+		if p.selected < len(p.matches)-1 {
+			p.selected++
+		}
+	case "backspace":
+		if p.query != "" {
+			_, size := utf8.DecodeLastRuneInString(p.query)
+			p.query = p.query[:len(p.query)-size]
+			p.filter()
+		}
+	case "ctrl-u":
+		p.query = ""
+		p.filter()
+	default:
+		typed := strings.Map(func(r rune) rune {
+			if unicode.IsPrint(r) {
+				return r
+			}
+			return -1
+		}, key)
+		if typed != "" {
+			p.query += typed
+			p.filter()
+		}
+	}
+	return false, ""
+}
+
+// fuzzyMatch reports whether the runes of query appear in order in text,
+// ignoring case. Its score favors runes that are consecutive or start a
+// word, and positions are the indexes of the matched runes in text, chosen
+// to give the best score.
+func fuzzyMatch(query, text string) (score int, positions []int, ok bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+	t := []rune(text)
+	lower := []rune(strings.ToLower(text))
+
+	// best[i][j] is the best score for q[:i+1] with q[i] matched at j, and
+	// from[i][j] is where q[i-1] was matched for that score
+	const none = -1 << 30
+	best := make([][]int, len(q))
+	from := make([][]int, len(q))
+	for i := range q {
+		best[i] = make([]int, len(t))
+		from[i] = make([]int, len(t))
+		for j := range t {
+			best[i][j] = none
+			if lower[j] != q[i] {
+				continue
+			}
+			bonus := 1
+			if wordStart(t, j) {
+				bonus += 6
+			}
+			if i == 0 {
+				// Matches that start later rank lower
+				best[i][j] = bonus - min(j, 3)
+				continue
+			}
+			for k := 0; k < j; k++ {
+				if best[i-1][k] == none {
+					continue
+				}
+				s := best[i-1][k] + bonus
+				if k == j-1 {
+					s += 4
+				}
+				if s > best[i][j] {
+					best[i][j], from[i][j] = s, k
+				}
+			}
+		}
+	}
+
+	last := -1
+	for j, s := range best[len(q)-1] {
+		if s != none && (last < 0 || s > best[len(q)-1][last]) {
+			last = j
+		}
+	}
+	if last < 0 {
+		return 0, nil, false
+	}
+	positions = make([]int, len(q))
+	for i, j := len(q)-1, last; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return best[len(q)-1][last], positions, true
+}
+
+// wordStart reports whether the rune at i begins a word: the first rune, one
+// after a separator, or an upper-case rune after a lower-case one
+func wordStart(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := text[i-1]
+	return strings.ContainsRune("-_:./ ", prev) || (unicode.IsLower(prev) && unicode.IsUpper(text[i]))
+}
+
+// render redraws the picker: the query, a count of matches, and as many
+// matches as fit, with the selected one highlighted
+func (p *picker) render() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	rows := max(height-2, 1)
+	if p.selected < p.offset {
+		p.offset = p.selected
+	} else if p.selected >= p.offset+rows {
+		p.offset = p.selected - rows + 1
+	}
+
+	nameWidth := 0
+	for _, m := range p.matches {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(p.items[m.item].Name))
+	}
+	nameWidth = min(nameWidth, 24)
+
+	lines := []string{
+		"> " + p.query,
+		"  " + style(os.Stdout, styleDim, fmt.Sprintf("%d/%d", len(p.matches), len(p.items))),
+	}
+	for i := p.offset; i < len(p.matches) && i < p.offset+rows; i++ {
+		m := p.matches[i]
+		item := p.items[m.item]
+		name := highlightRunes(item.Name, m.positions)
+		pad := strings.Repeat(" ", max(nameWidth-utf8.RuneCountInString(item.Name), 0))
+		line := fmt.Sprintf("  %s%s  %s", name, pad, style(os.Stdout, styleDim, item.Description))
+		if i == p.selected {
+			line = "\033[7m▸\033[0m " + line[2:]
+		}
+		lines = append(lines, line)
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(truncateLine(line, width))
+		b.WriteString("\033[K")
+	}
+	b.WriteString("\033[J")
+	// Leave the cursor after the query
+	fmt.Fprintf(&b, "\033[1;%dH", 3+utf8.RuneCountInString(p.query))
+	fmt.Print(b.String())
+}
+
+// highlightRunes bolds the runes of s at positions
+func highlightRunes(s string, positions []int) string {
+	if len(positions) == 0 {
+		return s
+	}
+	marked := make(map[int]bool, len(positions))
+	for _, i := range positions {
+		marked[i] = true
+	}
+	var b strings.Builder
+	for i, r := range []rune(s) {
+		if marked[i] {
+			b.WriteString("\033[1;4m")
+			b.WriteRune(r)
+			b.WriteString("\033[0m")
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
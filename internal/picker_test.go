@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        bool
+		positions   []int
+	}{
+		{"", "build", true, nil},
+		{"bd", "build-docs", true, []int{0, 6}},
+		{"BLD", "build", true, []int{0, 3, 4}},
+		{"tw", "testWatch", true, []int{0, 4}},
+		{"db", "build", false, nil},
+		{"xyz", "build", false, nil},
+	}
+	for _, tt := range tests {
+		_, positions, ok := fuzzyMatch(tt.query, tt.text)
+		if ok != tt.want || !slices.Equal(positions, tt.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v; want %v, %v", tt.query, tt.text, positions, ok, tt.positions, tt.want)
+		}
+	}
+}
+
+func TestPickerRanking(t *testing.T) {
+	p := newPicker([]ListedCommand{
+		{Name: "lint"},
+		{Name: "dist-build"},
+		{Name: "build"},
+		{Name: "deploy", Description: "Publish the build"},
+	})
+	for _, key := range []string{"b", "u", "i"} {
+		p.handleKey(key)
+	}
+	var names []string
+	for _, m := range p.matches {
+		names = append(names, p.items[m.item].Name)
+	}
+	// A prefix match beats a later word start, and names beat descriptions
+	if want := []string{"build", "dist-build", "deploy"}; !slicesEqual(names, want) {
+		t.Errorf("matches for %q = %v, want %v", p.query, names, want)
+	}
+
+	p.handleKey("down")
+	if done, chosen := p.handleKey("enter"); !done || chosen != "dist-build" {
+		t.Errorf("enter = %v, %q; want dist-build", done, chosen)
+	}
+	p.handleKey("backspace")
+	if p.query != "bu" {
+		t.Errorf("query after backspace = %q, want bu", p.query)
+	}
+	if done, chosen := p.handleKey("esc"); !done || chosen != "" {
+		t.Errorf("esc = %v, %q; want cancelled", done, chosen)
+	}
+}
@@ -0,0 +1,316 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PackageManagerPins are the package managers .cmdr.toml pins, consulted
+// before lockfiles and other heuristics
+type PackageManagerPins struct {
+	Node   string `toml:"node"`
+	Python string `toml:"python"`
+}
+
+// packageManagers lists the managers cmdr can pin, by ecosystem
+var packageManagers = map[string][]string{
+	"node":   {"npm", "pnpm", "yarn", "bun"},
+	"python": {"poetry", "uv"},
+}
+
+func (p *PackageManagerPins) validate() error {
+	if p.Node != "" && !slices.Contains(packageManagers["node"], p.Node) {
+		return fmt.Errorf("node must be one of %s, got %q", strings.Join(packageManagers["node"], ", "), p.Node)
+	}
+	if p.Python != "" && !slices.Contains(packageManagers["python"], p.Python) {
+		return fmt.Errorf("python must be one of %s, got %q", strings.Join(packageManagers["python"], ", "), p.Python)
+	}
+	return nil
+}
+
+// pinnedManager returns the package manager .cmdr.toml in dir pins for
+// ecosystem, or ""
+func pinnedManager(dir, ecosystem string) string {
+	config := loadProjectConfig(dir)
+	if config == nil || config.PackageManager == nil {
+		return ""
+	}
+	if ecosystem == "node" {
+		return config.PackageManager.Node
+	}
+	return config.PackageManager.Python
+}
+
+// managerEcosystem returns the ecosystem of a package manager, or ""
+func managerEcosystem(manager string) string {
+	for ecosystem, managers := range packageManagers {
+		if slices.Contains(managers, manager) {
+			return ecosystem
+		}
+	}
+	return ""
+}
+
+// pmEvidence is a file or setting that points to a package manager
+type pmEvidence struct {
+	what    string
+	manager string
+}
+
+// nodeManagerEvidence returns what points to a Node package manager in dir,
+// in the order detection consults it
+func nodeManagerEvidence(dir string) []pmEvidence {
+	evidence := []pmEvidence{}
+	if pin := pinnedManager(dir, "node"); pin != "" {
+		evidence = append(evidence, pmEvidence{ProjectConfigFile + " package_manager.node", pin})
+	}
+	if pkg, err := readPackageJSON(dir); err == nil && pkg.PackageManager != "" {
+		name, _ := pinnedPackageManager(dir)
+		evidence = append(evidence, pmEvidence{"package.json packageManager " + pkg.PackageManager, name})
+	}
+	for _, lock := range nodeLockfiles {
+		if FileExists(filepath.Join(dir, lock.file)) {
+			evidence = append(evidence, pmEvidence{lock.file, lock.manager})
+		}
+	}
+	for _, file := range []string{".yarnrc.yml", ".yarnrc"} {
+		if FileExists(filepath.Join(dir, file)) {
+			evidence = append(evidence, pmEvidence{file, "yarn"})
+		}
+	}
+	return evidence
+}
+
+// pythonManagerEvidence returns what points to a Python package manager in
+// dir, in the order detection consults it
+func pythonManagerEvidence(dir string) []pmEvidence {
+	evidence := []pmEvidence{}
+	if pin := pinnedManager(dir, "python"); pin != "" {
+		evidence = append(evidence, pmEvidence{ProjectConfigFile + " package_manager.python", pin})
+	}
+	if FileExists(filepath.Join(dir, "poetry.lock")) {
+		evidence = append(evidence, pmEvidence{"poetry.lock", "poetry"})
+	}
+	for _, file := range []string{"uv.lock", ".uv"} {
+		if FileExists(filepath.Join(dir, file)) {
+			evidence = append(evidence, pmEvidence{file, "uv"})
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "pyproject.toml")); err == nil {
+		for _, tool := range []string{"poetry", "uv"} {
+			if strings.Contains(string(data), "[tool."+tool+"]") {
+				evidence = append(evidence, pmEvidence{"pyproject.toml [tool." + tool + "]", tool})
+			}
+		}
+	}
+	return evidence
+}
+
+// ShowPackageManagers writes the package manager cmdr uses for the Node and
+// Python projects in the current directory and project root, with the
+// evidence for each and the one that decided it
+func (r *CommandRunner) ShowPackageManagers(w io.Writer) error {
+	found := false
+	for _, project := range r.projects() {
+		var lines []string
+		for _, source := range project.CommandSources {
+			switch source.(type) {
+			case *NpmSource, *PnpmSource, *YarnSource, *BunSource:
+				lines = append(lines, describeManagerChoice("Node", strings.ToLower(source.Name()), nodeManagerEvidence(project.Dir), "npm")...)
+			case *DenoSource:
+				lines = append(lines, "Node: deno (deno.json takes precedence over package managers)")
+			case *PoetrySource, *UvSource:
+				lines = append(lines, describeManagerChoice("Python", strings.ToLower(source.Name()), pythonManagerEvidence(project.Dir), "uv")...)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if found {
+			fmt.Fprintln(w)
+		}
+		found = true
+		fmt.Fprintln(w, project.Dir)
+		for _, line := range lines {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	if !found {
+		return notFoundError(fmt.Errorf("no Node or Python project in the current directory or project root"))
+	}
+	return nil
+}
+
+// describeManagerChoice explains the choice of manager: the first piece of
+// evidence for it decided, and the rest is listed after it
+func describeManagerChoice(ecosystem, manager string, evidence []pmEvidence, fallback string) []string {
+	decided := -1
+	for i, e := range evidence {
+		if e.manager == manager {
+			decided = i
+			break
+		}
+	}
+	reason := fmt.Sprintf("the default when nothing else decides (%s)", fallback)
+	if decided >= 0 {
+		reason = evidence[decided].what
+	}
+	lines := []string{fmt.Sprintf("%s: %s, from %s", ecosystem, manager, reason)}
+	for i, e := range evidence {
+		if i == decided {
+			continue
+		}
+		note := "agrees"
+		switch {
+		case e.manager == "":
+			note = "not a manager cmdr runs"
+		case e.manager != manager && i > decided:
+			note = fmt.Sprintf("suggests %s, outranked", e.manager)
+		case e.manager != manager:
+			note = fmt.Sprintf("suggests %s", e.manager)
+		}
+		lines = append(lines, fmt.Sprintf("  also %s (%s)", e.what, note))
+	}
+	return lines
+}
+
+// SetPackageManager pins manager in the .cmdr.toml next to the nearest
+// project it applies to, so that later runs use it regardless of lockfiles
+func (r *CommandRunner) SetPackageManager(w io.Writer, manager string) error {
+	ecosystem := managerEcosystem(manager)
+	if ecosystem == "" {
+		return fmt.Errorf("unknown package manager %q (expected one of %s, %s)", manager,
+			strings.Join(packageManagers["node"], ", "), strings.Join(packageManagers["python"], ", "))
+	}
+	marker := map[string]string{"node": "package.json", "python": "pyproject.toml"}[ecosystem]
+	for _, dir := range r.searchDirs() {
+		if !FileExists(filepath.Join(dir, marker)) {
+			continue
+		}
+		path := filepath.Join(dir, ProjectConfigFile)
+		if err := setConfigTableKey(path, "package_manager", ecosystem, manager); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Pinned %s for %s in %s\n", manager, filepath.Join(dir, marker), path)
+		if name, _ := pinnedPackageManager(dir); ecosystem == "node" && name != "" && name != manager {
+			fmt.Fprintf(w, "Warning: package.json's packageManager field names %s; cmdr will use %s, but Corepack and other tools will not\n", name, manager)
+		}
+		return nil
+	}
+	return notFoundError(fmt.Errorf("no %s in the current directory or project root", marker))
+}
+
+// UnsetPackageManager removes the pin for ecosystem ("node" or "python")
+func (r *CommandRunner) UnsetPackageManager(w io.Writer, ecosystem string) error {
+	if _, ok := packageManagers[ecosystem]; !ok {
+		return fmt.Errorf("unknown ecosystem %q (expected node or python)", ecosystem)
+	}
+	for _, dir := range r.searchDirs() {
+		if pinnedManager(dir, ecosystem) == "" {
+			continue
+		}
+		path := filepath.Join(dir, ProjectConfigFile)
+		if err := setConfigTableKey(path, "package_manager", ecosystem, ""); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed the %s package manager pin from %s\n", ecosystem, path)
+		return nil
+	}
+	return fmt.Errorf("no %s package manager is pinned", ecosystem)
+}
+
+var tableHeaderPattern = regexp.MustCompile(`^\s*\[`)
+
+// setConfigTableKey sets key to a string value in a table of the TOML file
+// at path, or removes it if value is "", editing the text so that comments
+// and formatting survive. The file must still parse afterwards.
+func setConfigTableKey(path, table, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	keyPattern := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(key) + `\s*=`)
+	entry := fmt.Sprintf("%s = %q", key, value)
+
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		if start < 0 && strings.TrimSpace(line) == "["+table+"]" {
+			start = i
+		} else if start >= 0 && tableHeaderPattern.MatchString(line) {
+			end = i
+			break
+		}
+	}
+	switch {
+	case start < 0 && value == "":
+		return nil
+	case start < 0:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+table+"]", entry)
+	default:
+		replaced := false
+		for i := start + 1; i < end; i++ {
+			if keyPattern.MatchString(lines[i]) {
+				if value == "" {
+					lines = slices.Delete(lines, i, i+1)
+				} else {
+					lines[i] = entry
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced && value != "" {
+			lines = slices.Insert(lines, start+1, entry)
+		}
+		if value == "" && tableIsEmpty(lines[start+1:end-1]) {
+			lines = slices.Delete(lines, start, end-1)
+			if start > 0 && strings.TrimSpace(lines[start-1]) == "" {
+				lines = slices.Delete(lines, start-1, start)
+			}
+		}
+	}
+
+	content := strings.Join(lines, "\n") + "\n"
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		return err
+	}
+	if _, err := parseProjectConfig(tmp); err != nil {
+		os.Remove(tmp)
+		return configError(fmt.Errorf("%s: can't update: %w", path, err))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	forgetProjectConfig(path)
+	return nil
+}
+
+// tableIsEmpty reports whether the lines of a table hold no keys
+func tableIsEmpty(lines []string) bool {
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// forgetProjectConfig drops a config from the cache after it is rewritten
+func forgetProjectConfig(path string) {
+	projectConfigCache.Lock()
+	defer projectConfigCache.Unlock()
+	delete(projectConfigCache.data, path)
+}
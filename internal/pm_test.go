@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackageManagerPin(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"package.json":      `{"packageManager": "yarn@4.1.0", "scripts": {"build": "tsc"}}`,
+		"package-lock.json": "{}",
+		"pyproject.toml":    "[project]\nname = \"x\"\n\n[tool.poetry]\n",
+		"poetry.lock":       "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(t, dir, "[package_manager]\nnode = \"pnpm\"\npython = \"uv\"\n")
+
+	if pm := detectPackageManager(dir); pm != "pnpm" {
+		t.Errorf("detectPackageManager() = %q, want pnpm", pm)
+	}
+	if source := detectNodeProject(dir); source.Name() != "pnpm" {
+		t.Errorf("detectNodeProject().Name() = %q, want pnpm", source.Name())
+	}
+	if source := detectPythonProject(dir); source == nil || source.Name() != "uv" {
+		t.Errorf("detectPythonProject() = %v, want uv", source)
+	}
+
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir}
+	var out strings.Builder
+	if err := runner.ShowPackageManagers(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Node: pnpm, from .cmdr.toml package_manager.node",
+		"also package.json packageManager yarn@4.1.0 (suggests yarn, outranked)",
+		"Python: uv, from .cmdr.toml package_manager.python",
+		"also poetry.lock (suggests poetry, outranked)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("ShowPackageManagers() = %q, missing %q", out.String(), want)
+		}
+	}
+}
+
+func TestInvalidPackageManagerPin(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "[package_manager]\nnode = \"npx\"\n")
+	if _, err := parseProjectConfig(filepath.Join(dir, ProjectConfigFile)); err == nil {
+		t.Error("parseProjectConfig() should reject an unknown package manager")
+	}
+}
+
+func TestSetConfigTableKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ProjectConfigFile)
+	writeConfig(t, dir, "# my config\n[commands]\nhi = \"echo hi\"\n")
+
+	if err := setConfigTableKey(path, "package_manager", "node", "pnpm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setConfigTableKey(path, "package_manager", "node", "bun"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "# my config\n[commands]\nhi = \"echo hi\"\n\n[package_manager]\nnode = \"bun\"\n"; string(data) != want {
+		t.Errorf("after set = %q, want %q", data, want)
+	}
+	if pin := pinnedManager(dir, "node"); pin != "bun" {
+		t.Errorf("pinnedManager() = %q, want bun", pin)
+	}
+
+	// Removing the last key removes the table
+	if err := setConfigTableKey(path, "package_manager", "node", ""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if want := "# my config\n[commands]\nhi = \"echo hi\"\n"; string(data) != want {
+		t.Errorf("after unset = %q, want %q", data, want)
+	}
+
+	// An edit that leaves the file invalid is not written
+	if err := setConfigTableKey(path, "package_manager", "node", "npx"); err == nil {
+		t.Error("setConfigTableKey() should fail for an invalid value")
+	}
+	data, _ = os.ReadFile(path)
+	if strings.Contains(string(data), "npx") {
+		t.Errorf("invalid edit was written: %q", data)
+	}
+}
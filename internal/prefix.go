@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// prefixMatching reports whether the user config lets a unique prefix of a
+// command name run that command
+func prefixMatching() bool {
+	config := LoadUserConfig()
+	return config != nil && config.PrefixMatch
+}
+
+// prefixMatches returns the listed commands whose names start with prefix,
+// sorted
+func (r *CommandRunner) prefixMatches(prefix string) []string {
+	seen := map[string]bool{}
+	var matches []string
+	for _, c := range r.listedCommands(true) {
+		if strings.HasPrefix(c.Name, prefix) && !seen[c.Name] {
+			seen[c.Name] = true
+			matches = append(matches, c.Name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// runPrefixMatch runs the only listed command that starts with r.Command.
+// It returns handled = false when no command does.
+func (r *CommandRunner) runPrefixMatch() (handled bool, err error) {
+	if r.Command == "" || !prefixMatching() {
+		return false, nil
+	}
+	matches := r.prefixMatches(r.Command)
+	switch len(matches) {
+	case 0:
+		return false, nil
+	case 1:
+		debugf("%s is a unique prefix of %s", r.Command, matches[0])
+		sub := r.subRunner(matches[0], r.Args)
+		sub.inner = true
+		return true, sub.Run()
+	}
+	return true, missingCommandError(fmt.Errorf("'%s' is ambiguous: it could be %s", r.Command, strings.Join(matches, ", ")))
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPrefixMatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\ndeploy = \"echo deploy >> log\"\ndeps = \"echo deps >> log\"\ndocs = \"echo docs >> log\"\n")
+
+	// Off by default
+	setUserConfig(t, "")
+	runner := &CommandRunner{Command: "depl", CurrentDir: dir, ProjectRoot: dir}
+	if err := runner.Run(); !isNotFound(err) {
+		t.Fatalf("Run() without prefix_match = %v, want not found", err)
+	}
+
+	setUserConfig(t, "prefix_match = true\n")
+	runner = &CommandRunner{Command: "depl", CurrentDir: dir, ProjectRoot: dir}
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if log, _ := os.ReadFile(filepath.Join(dir, "log")); string(log) != "deploy\n" {
+		t.Errorf("log = %q, want deploy", log)
+	}
+
+	runner = &CommandRunner{Command: "dep", CurrentDir: dir, ProjectRoot: dir}
+	err := runner.Run()
+	if !isNotFound(err) || !strings.Contains(err.Error(), "'dep' is ambiguous: it could be deploy, deps") {
+		t.Errorf("Run() = %v, want an ambiguity error", err)
+	}
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// needsEscalation reports whether commands should run through sudo
+func (r *CommandRunner) needsEscalation() bool {
+	return r.Sudo || r.AsUser != ""
+}
+
+// escalateCommand wraps cmd so that it runs through sudo, as root or as
+// r.AsUser. The user is asked to confirm first, and sudo is invoked with -k so
+// that credentials are never cached for later commands.
+func (r *CommandRunner) escalateCommand(cmd *exec.Cmd) (*exec.Cmd, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("running commands with sudo is not supported on Windows")
+	}
+	if r.sharesTerminal() {
+		// Both the confirmation and sudo's password prompt need the terminal
+		return nil, fmt.Errorf("refusing to run with elevated privileges from the menu or beside other commands; run it from the shell")
+	}
+	if !r.prompt().isTerminal() {
+		return nil, fmt.Errorf("refusing to run with elevated privileges without an interactive terminal")
+	}
+
+	// Resolve the program now: sudo resets PATH, which would otherwise break
+	// tools installed in user or project directories
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	program := cmd.Path
+	if !filepath.IsAbs(program) && cmd.Dir != "" {
+		program = filepath.Join(cmd.Dir, program)
+	}
+
+	target := "root"
+	sudoArgs := []string{"-k", "--preserve-env"}
+	if r.AsUser != "" {
+		target = r.AsUser
+		sudoArgs = append(sudoArgs, "-u", r.AsUser)
+	}
+	sudoArgs = append(sudoArgs, "--", program)
+	sudoArgs = append(sudoArgs, cmd.Args[1:]...)
+
+	if !r.prompt().confirm(fmt.Sprintf("Run '%s' as %s?", strings.Join(cmd.Args, " "), target)) {
+		return nil, fmt.Errorf("cancelled")
+	}
+
+	escalated := r.executor.command("sudo", sudoArgs...)
+	escalated.Dir = cmd.Dir
+	escalated.Env = cmd.Env
+	return escalated, nil
+}
+
+// sharesTerminal reports whether r's output goes to the menu's run panel or
+// beside other commands', so that it can't prompt on the terminal
+func (r *CommandRunner) sharesTerminal() bool {
+	return r.output != nil
+}
+
+// prompter asks the user yes/no questions
+type prompter interface {
+	isTerminal() bool // whether there is a user to ask
+	confirm(prompt string) bool
+}
+
+// prompt returns the runner's prompter, by default the terminal
+func (r *CommandRunner) prompt() prompter {
+	if r.prompter != nil {
+		return r.prompter
+	}
+	return terminalPrompter{}
+}
+
+// terminalPrompter asks on stderr and reads the answer from stdin
+type terminalPrompter struct{}
+
+func (terminalPrompter) isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func (terminalPrompter) confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
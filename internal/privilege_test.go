@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// testPrompter answers confirmations in place of the user
+type testPrompter struct {
+	terminal bool
+	answer   bool
+	asked    []string
+}
+
+func (p *testPrompter) isTerminal() bool { return p.terminal }
+
+func (p *testPrompter) confirm(prompt string) bool {
+	p.asked = append(p.asked, prompt)
+	return p.answer
+}
+
+func TestEscalateCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sudo isn't supported on Windows")
+	}
+	var ran [][]string
+	factory := func(name string, arg ...string) *exec.Cmd {
+		ran = append(ran, append([]string{name}, arg...))
+		return exec.Command(name, arg...)
+	}
+	dir := t.TempDir()
+	newCommand := func() *exec.Cmd {
+		cmd := exec.Command("./deploy.sh", "--prod")
+		cmd.Dir = dir
+		cmd.Env = []string{"DEPLOY_ENV=prod"}
+		return cmd
+	}
+
+	// The program is resolved before sudo resets PATH, and credentials
+	// aren't cached
+	prompter := &testPrompter{terminal: true, answer: true}
+	runner := New("deploy", nil, WithCommandFactory(factory))
+	runner.AsUser = "deploy"
+	runner.prompter = prompter
+	escalated, err := runner.escalateCommand(newCommand())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"sudo", "-k", "--preserve-env", "-u", "deploy", "--", filepath.Join(dir, "deploy.sh"), "--prod"}
+	if len(ran) != 1 || !slicesEqual(ran[0], want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+	if escalated.Dir != dir || !slicesEqual(escalated.Env, []string{"DEPLOY_ENV=prod"}) {
+		t.Errorf("escalated Dir, Env = %q, %q; want the command's", escalated.Dir, escalated.Env)
+	}
+	if want := []string{"Run './deploy.sh --prod' as deploy?"}; !slicesEqual(prompter.asked, want) {
+		t.Errorf("asked %q, want %q", prompter.asked, want)
+	}
+
+	// Declining runs nothing
+	ran = nil
+	runner.prompter = &testPrompter{terminal: true, answer: false}
+	if _, err := runner.escalateCommand(newCommand()); err == nil || err.Error() != "cancelled" {
+		t.Errorf("escalateCommand() after declining: error = %v, want cancelled", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("ran %q after declining", ran)
+	}
+
+	// Without a terminal there's no one to ask
+	prompter = &testPrompter{answer: true}
+	runner.prompter = prompter
+	if _, err := runner.escalateCommand(newCommand()); err == nil || !strings.Contains(err.Error(), "without an interactive terminal") {
+		t.Errorf("escalateCommand() without a terminal: error = %v", err)
+	}
+	if len(prompter.asked) != 0 || len(ran) != 0 {
+		t.Errorf("without a terminal, asked %q and ran %q", prompter.asked, ran)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build !windows
+
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// setProcessGroup starts cmd in its own process group, so that signals can
+// be delivered to it and every process it spawns
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup sends sig to the process group of a command started
+// with setProcessGroup
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// forwardSignal sends a signal cmdr received to the process group of cmd
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) {
+	if s, ok := sig.(syscall.Signal); ok {
+		_ = signalProcessGroup(cmd, s)
+	}
+}
+
+// takeTerminal makes the process group of cmd the terminal's foreground
+// group, as a shell does for a job, so that the command can read the
+// terminal and Ctrl+C reaches every process in it. It returns the function
+// that gives the terminal back to cmdr after the command exits. It does
+// nothing unless cmd reads cmdr's terminal and cmdr is in the foreground.
+func takeTerminal(cmd *exec.Cmd) func() {
+	fd := int(os.Stdin.Fd())
+	if cmd.Stdin != os.Stdin || !term.IsTerminal(fd) {
+		return func() {}
+	}
+	pgrp := syscall.Getpgrp()
+	if foreground, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err != nil || foreground != pgrp {
+		return func() {}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = 0 // the command's stdin
+	return func() {
+		// Setting the foreground group from the background raises SIGTTOU
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, pgrp)
+	}
+}
+
+// signalPid sends sig to a process by id
+func signalPid(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// processAlive reports whether a process with the given id exists
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	return err == nil && process.Signal(syscall.Signal(0)) == nil
+}
+
+// lockFile takes an exclusive lock on f, waiting for other processes to
+// release theirs
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
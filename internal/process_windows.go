@@ -0,0 +1,47 @@
+//go:build windows
+
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup terminates the command. Windows has no equivalent of
+// SIGTERM for console processes, so every signal kills.
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Kill()
+}
+
+// forwardSignal does nothing on Windows, where the console delivers Ctrl+C
+// to the command as well as to cmdr. Catching it keeps cmdr waiting until
+// the command exits.
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) {}
+
+// takeTerminal is a no-op on Windows
+func takeTerminal(cmd *exec.Cmd) func() { return func() {} }
+
+// signalPid terminates a process by id
+func signalPid(pid int, sig syscall.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// processAlive reports whether a process with the given id exists
+func processAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
+
+// lockFile is a no-op on Windows; concurrent writers aren't serialized
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile is a no-op on Windows
+func unlockFile(f *os.File) error { return nil }
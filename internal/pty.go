@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// PTYMode says when cmdr runs commands under a pseudo-terminal
+type PTYMode int
+
+const (
+	// PTYAuto uses a pseudo-terminal for output cmdr passes on, when its
+	// own stdout is a terminal
+	PTYAuto PTYMode = iota
+	// PTYAlways uses one whenever the platform supports it (--pty)
+	PTYAlways
+	// PTYNever never uses one (--no-pty)
+	PTYNever
+)
+
+// usePTY reports whether a command whose output cmdr captures, prefixes, or
+// copies should run under a pseudo-terminal. Tools such as cargo, jest, and
+// vitest turn off colors and progress bars when their output isn't a
+// terminal.
+func (r *CommandRunner) usePTY() bool {
+	switch {
+	case !ptySupported || r.PTY == PTYNever:
+		return false
+	case r.PTY == PTYAlways:
+		return true
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// ptySession is a command started under a pseudo-terminal, whose output is
+// copied to a writer
+type ptySession struct {
+	ptmx   *os.File
+	copied chan struct{}
+	stop   func()
+}
+
+// startPTY starts cmd under a pseudo-terminal and copies what it writes to
+// output. Its stdout and stderr both go to the terminal, so output keeps
+// them interleaved; stdin is left alone, or /dev/null if unset.
+func startPTY(cmd *exec.Cmd, output io.Writer) (*ptySession, error) {
+	if cmd.Stdin == nil {
+		devNull, err := os.Open(os.DevNull)
+		if err != nil {
+			return nil, err
+		}
+		defer devNull.Close()
+		cmd.Stdin = devNull
+	}
+	ptmx, stop, err := openPTY(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("can't start under a pseudo-terminal: %w", err)
+	}
+	s := &ptySession{ptmx: ptmx, copied: make(chan struct{}), stop: stop}
+	go func() {
+		// Reading fails (EIO on Linux) once every process holding the
+		// terminal has exited
+		_, _ = io.Copy(output, ptmx)
+		close(s.copied)
+	}()
+	return s, nil
+}
+
+// close finishes copying output after the command exits. Children that
+// outlive the command and still hold the terminal aren't waited for.
+func (s *ptySession) close() {
+	select {
+	case <-s.copied:
+	case <-time.After(time.Second):
+	}
+	s.stop()
+	s.ptmx.Close()
+}
+
+// runInPTY runs cmd under a pseudo-terminal, as runForeground runs it
+// directly, copying its output to output
+func runInPTY(ctx context.Context, cmd *exec.Cmd, output io.Writer) error {
+	var session *ptySession
+	err := superviseCommand(ctx, cmd, func() error {
+		var err error
+		session, err = startPTY(cmd, output)
+		return err
+	})
+	if session != nil {
+		session.close()
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunInPTY(t *testing.T) {
+	if !ptySupported {
+		t.Skip("no pseudo-terminals on this platform")
+	}
+	var out strings.Builder
+	cmd := exec.Command("sh", "-c", `test -t 1 && test -t 2 && echo terminal; read line || echo eof`)
+	if err := runInPTY(context.Background(), cmd, &out); err != nil {
+		t.Fatal(err)
+	}
+	// Output is a terminal, and stdin is left unset rather than waiting on it
+	if got := strings.ReplaceAll(out.String(), "\r", ""); got != "terminal\neof\n" {
+		t.Errorf("output = %q, want terminal and eof", got)
+	}
+}
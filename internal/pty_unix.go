@@ -0,0 +1,69 @@
+//go:build !windows
+
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+const ptySupported = true
+
+// openPTY starts cmd in a new session, with a new pseudo-terminal as its
+// controlling terminal, stdout, and stderr. The terminal is sized like
+// cmdr's and resized with it. openPTY returns the terminal's master side and
+// a function that stops resizing.
+func openPTY(cmd *exec.Cmd) (*os.File, func(), error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tty.Close()
+	size := &pty.Winsize{Rows: 24, Cols: 80}
+	if s, err := pty.GetsizeFull(os.Stdout); err == nil {
+		size = s
+	}
+	if err := pty.Setsize(ptmx, size); err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// The new session is also a process group, and its leader can't join
+	// another one
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Foreground = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 1 // the command's stdout
+	if err := cmd.Start(); err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-resized:
+				_ = pty.InheritSize(os.Stdout, ptmx)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ptmx, func() {
+		signal.Stop(resized)
+		close(done)
+	}, nil
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// ptySupported is false on Windows, where cmdr doesn't create pseudo
+// consoles
+const ptySupported = false
+
+func openPTY(cmd *exec.Cmd) (*os.File, func(), error) {
+	return nil, nil, errors.New("pseudo-terminals aren't supported on Windows")
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PublishConfig adjusts the checks that run before a tool's default
+// publish command. Each is on unless set to false.
+type PublishConfig struct {
+	Check        *bool `toml:"check"`         // Run check first
+	RequireClean *bool `toml:"require_clean"` // Refuse with uncommitted changes
+	Confirm      *bool `toml:"confirm"`       // Ask before publishing
+}
+
+// publishConfig returns the [publish] table of the nearest config that has
+// one, or an empty one
+func (r *CommandRunner) publishConfig() PublishConfig {
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.Publish != nil {
+			return *config.Publish
+		}
+	}
+	return PublishConfig{}
+}
+
+// enabled reports whether an optional setting is on
+func enabled(setting *bool) bool {
+	return setting == nil || *setting
+}
+
+// preparePublish runs the checks before a tool's publish command: that the
+// working tree has no uncommitted changes, that check passes, and that the
+// user confirms
+func (r *CommandRunner) preparePublish(cmd *exec.Cmd) error {
+	config := r.publishConfig()
+	command := strings.Join(cmd.Args, " ")
+	if enabled(config.Confirm) && !r.Yes && r.sharesTerminal() {
+		return fmt.Errorf("'%s' publishes the project; run it from the shell to confirm, or use --yes", command)
+	}
+	if enabled(config.Confirm) && !r.Yes && !r.prompt().isTerminal() {
+		return fmt.Errorf("'%s' publishes the project; use --yes to run it without a terminal", command)
+	}
+	if enabled(config.RequireClean) {
+		if err := r.requireCleanTree(); err != nil {
+			return err
+		}
+	}
+	if enabled(config.Check) {
+		r.progressf("%sChecking before publishing...\n", r.badge())
+		check := r.subRunner("check", nil)
+		check.inner = true
+		if err := check.Run(); err != nil {
+			if isNotFound(err) {
+				return fmt.Errorf("no check to run before publishing (%v); set publish.check = false in %s to publish without one", err, ProjectConfigFile)
+			}
+			return fmt.Errorf("not publishing: %w", err)
+		}
+	}
+	if enabled(config.Confirm) && !r.Yes {
+		if !r.prompt().confirm(fmt.Sprintf("Publish with '%s'?", command)) {
+			return fmt.Errorf("cancelled")
+		}
+	}
+	return nil
+}
+
+// requireCleanTree returns an error if the project's git working tree has
+// uncommitted changes. Outside a git repository there is nothing to check.
+func (r *CommandRunner) requireCleanTree() error {
+	git := r.executor.command("git", "status", "--porcelain")
+	git.Dir = r.ProjectRoot
+	output, err := git.Output()
+	if err != nil {
+		debugf("can't check for uncommitted changes: %v", err)
+		return nil
+	}
+	if changes := strings.TrimRight(string(output), "\n"); changes != "" {
+		lines := strings.Split(changes, "\n")
+		if len(lines) > 5 {
+			lines = append(lines[:5], fmt.Sprintf("... and %d more", len(lines)-5))
+		}
+		return fmt.Errorf("not publishing with uncommitted changes:\n%s\nCommit or stash them, or set publish.require_clean = false in %s", strings.Join(lines, "\n"), ProjectConfigFile)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPreparePublish(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("needs git")
+	}
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\ncheck = \"test -f passing\"\n")
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\nname = \"x\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	source := NewCargoSource(dir)
+	cmd := source.FindCommand("release", nil)
+	if cmd == nil || !slicesEqual(cmd.Args, []string{"cargo", "publish"}) {
+		t.Fatalf("FindCommand(release) = %v", cmd)
+	}
+	runner := &CommandRunner{Command: "release", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever}
+
+	// Tests run without a terminal, so publishing needs --yes
+	if err := runner.guardDefault(cmd, source); err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("guardDefault() = %v, want a hint to use --yes", err)
+	}
+	runner.Yes = true
+
+	// The check fails until the passing file exists, which also dirties the
+	// tree until it is committed
+	if err := runner.guardDefault(cmd, source); err == nil || !strings.Contains(err.Error(), "not publishing") {
+		t.Errorf("guardDefault() with a failing check = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "passing"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.guardDefault(cmd, source); err == nil || !strings.Contains(err.Error(), "uncommitted changes:\n?? passing") {
+		t.Errorf("guardDefault() with uncommitted changes = %v", err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "pass")
+	if err := runner.guardDefault(cmd, source); err != nil {
+		t.Errorf("guardDefault() = %v", err)
+	}
+
+	// Each check can be turned off
+	dir = t.TempDir()
+	writeConfig(t, dir, "[commands]\ncheck = \"false\"\n\n[publish]\ncheck = false\nrequire_clean = false\nconfirm = false\n")
+	runner = &CommandRunner{Command: "publish", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever}
+	if err := runner.guardDefault(cmd, source); err != nil {
+		t.Errorf("guardDefault() with checks off = %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// projectManifests mark a directory as a project of its own for --recursive
+var projectManifests = []string{"package.json", "Cargo.toml", "pyproject.toml", "go.mod"}
+
+// recursiveSkipDirs are never searched for projects: dependencies, build
+// output, and virtual environments
+var recursiveSkipDirs = map[string]bool{
+	"node_modules": true,
+	"target":       true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"venv":         true,
+	"__pycache__":  true,
+}
+
+// FindProjects returns root and the directories below it that have a
+// project manifest of their own, in path order. Hidden directories and
+// those in recursiveSkipDirs are not searched.
+func FindProjects(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return fs.SkipDir
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || recursiveSkipDirs[d.Name()]) {
+			return fs.SkipDir
+		}
+		for _, manifest := range projectManifests {
+			if FileExists(filepath.Join(path, manifest)) {
+				dirs = append(dirs, path)
+				break
+			}
+		}
+		return nil
+	})
+	sort.Strings(dirs)
+	return dirs, err
+}
+
+// ValidateProjectFilter reports whether pattern is a valid --filter or
+// --exclude glob
+func ValidateProjectFilter(pattern string) error {
+	return validateGlob(pattern)
+}
+
+// matchesProject reports whether a --filter or --exclude pattern matches
+// the project in dir: its path relative to the root, as a [watch] glob
+// matches a file, or the name in its manifest
+func matchesProject(pattern, rel, dir string) bool {
+	if matchGlob(pattern, rel) {
+		return true
+	}
+	if name := projectName(dir); name != "" {
+		ok, _ := pathpkg.Match(pattern, name)
+		return ok
+	}
+	return false
+}
+
+// filterProjects keeps the projects that match a --filter pattern, if
+// there are any, and don't match an --exclude pattern
+func (r *CommandRunner) filterProjects(dirs []string) []string {
+	var kept []string
+	for _, dir := range dirs {
+		rel := r.relativeProject(dir)
+		included := len(r.ProjectFilters) == 0
+		for _, pattern := range r.ProjectFilters {
+			included = included || matchesProject(pattern, rel, dir)
+		}
+		for _, pattern := range r.ProjectExcludes {
+			included = included && !matchesProject(pattern, rel, dir)
+		}
+		if included {
+			kept = append(kept, dir)
+		} else {
+			debugf("recursive: %s filtered out", rel)
+		}
+	}
+	return kept
+}
+
+// projectName returns the name a project's manifest gives it: the package
+// name in package.json, Cargo.toml, or pyproject.toml, or the module path
+// in go.mod
+func projectName(dir string) string {
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(data, &pkg) == nil && pkg.Name != "" {
+			return pkg.Name
+		}
+	}
+	var manifest struct {
+		Package struct{ Name string } `toml:"package"`
+		Project struct{ Name string } `toml:"project"`
+		Tool    struct {
+			Poetry struct{ Name string } `toml:"poetry"`
+		} `toml:"tool"`
+	}
+	for _, file := range []string{"Cargo.toml", "pyproject.toml"} {
+		if _, err := toml.DecodeFile(filepath.Join(dir, file), &manifest); err == nil {
+			for _, name := range []string{manifest.Package.Name, manifest.Project.Name, manifest.Tool.Poetry.Name} {
+				if name != "" {
+					return name
+				}
+			}
+		}
+	}
+	if f, err := os.Open(filepath.Join(dir, "go.mod")); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+				return strings.Trim(strings.TrimSpace(module), `"`)
+			}
+		}
+	}
+	return ""
+}
+
+// projectResult is the outcome of the command in one project of a
+// recursive run
+type projectResult struct {
+	dir      string
+	duration time.Duration
+	err      error
+}
+
+// RunRecursive runs the command in every project under the project root,
+// one after another, then writes a summary. Projects without the command
+// are skipped; the run fails if the command failed in any project.
+func (r *CommandRunner) RunRecursive() error {
+	dirs, err := FindProjects(r.ProjectRoot)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return notFoundError(fmt.Errorf("no projects (%s) under %s", strings.Join(projectManifests, ", "), r.ProjectRoot))
+	}
+	if dirs = r.filterProjects(dirs); len(dirs) == 0 {
+		return notFoundError(fmt.Errorf("no projects under %s match the --filter and --exclude patterns", r.ProjectRoot))
+	}
+	debugf("recursive %s: %d projects under %s", r.Command, len(dirs), r.ProjectRoot)
+
+	start := time.Now()
+	log, err := r.openOutputLog(start)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %w", err)
+	}
+	r.outputLog = log
+
+	if r.JUnit != "" {
+		// Each project is a testcase of one report
+		r.report = &junitReport{start: start}
+		defer func() { r.report = nil }()
+	}
+	subs := make([]*CommandRunner, len(dirs))
+	for i, dir := range dirs {
+		subs[i] = r.subRunner(r.Command, r.Args)
+		subs[i].CurrentDir = dir
+		subs[i].ProjectRoot = dir
+		subs[i].JUnit = ""
+		subs[i].capture = r.stepCapture()
+		subs[i].report = nil
+		subs[i].SARIF = ""
+		if r.SARIF != "" {
+			subs[i].diagnostics = &tailBuffer{max: sarifOutputMax}
+		}
+	}
+
+	var results []projectResult
+	if r.Parallel {
+		runs := make([]labeledRun, len(dirs))
+		for i, dir := range dirs {
+			runs[i] = labeledRun{r.relativeProject(dir), subs[i]}
+		}
+		for i, result := range r.runParallel(runs, false) {
+			if result.err != nil && !isNotFound(result.err) {
+				subs[i].annotate()
+			}
+			results = append(results, projectResult{dirs[i], result.duration, result.err})
+		}
+	} else {
+		for i, dir := range dirs {
+			r.progressf("\n→ %s: %s\n", r.relativeProject(dir), r.Command)
+			r.outputLog.printf("\n# %s\n", r.relativeProject(dir))
+			start := time.Now()
+			err := inGroup(r.relativeProject(dir), subs[i].Run)
+			if err != nil && !isNotFound(err) {
+				subs[i].annotate()
+			}
+			results = append(results, projectResult{dir, time.Since(start), err})
+			if killedBySignal(err) {
+				break
+			}
+		}
+	}
+	err = r.reportProjects(results)
+	if r.report != nil {
+		for i, result := range results {
+			name := r.relativeProject(result.dir)
+			if isNotFound(result.err) {
+				r.report.addSkipped(fmt.Sprintf("no %s command", r.Command), name)
+			} else {
+				r.report.addStep(name, result.duration, result.err, subs[i].capture)
+			}
+		}
+		err = r.finishJUnit(time.Since(start), err, nil)
+	}
+	if r.SARIF != "" {
+		var runs []sarifRun
+		for _, sub := range subs {
+			runs = append(runs, parseDiagnostics(sub.diagnostics.Bytes(), sub.CurrentDir, r.ProjectRoot)...)
+		}
+		err = r.finishSARIF(err, runs)
+	}
+	if r.outputLog != nil {
+		r.closeOutputLog(time.Since(start), err)
+	}
+	return err
+}
+
+// relativeProject returns dir relative to the project root, for display
+func (r *CommandRunner) relativeProject(dir string) string {
+	if rel, err := filepath.Rel(r.ProjectRoot, dir); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return dir
+}
+
+// projectCount returns "1 project" or "n projects"
+func projectCount(n int) string {
+	if n == 1 {
+		return "1 project"
+	}
+	return fmt.Sprintf("%d projects", n)
+}
+
+// reportProjects writes the outcome in each project of a recursive run, and
+// returns the error the run fails with
+func (r *CommandRunner) reportProjects(results []projectResult) error {
+	var ran, failed []string
+	fmt.Fprintf(os.Stderr, "\n%s%s in %s:\n", r.badge(), r.Command, projectCount(len(results)))
+	for _, result := range results {
+		name := r.relativeProject(result.dir)
+		switch {
+		case isNotFound(result.err):
+			fmt.Fprintf(os.Stderr, "  - %-24s no %s command\n", name, r.Command)
+		case result.err != nil:
+			ran = append(ran, name)
+			failed = append(failed, name)
+			fmt.Fprintf(os.Stderr, "  %s %-24s %s  %v\n", passMark(os.Stderr, false), name, roundDuration(result.duration), result.err)
+		default:
+			ran = append(ran, name)
+			fmt.Fprintf(os.Stderr, "  %s %-24s %s\n", passMark(os.Stderr, true), name, roundDuration(result.duration))
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		return fmt.Errorf("%s failed in %d of %s: %s", r.Command, len(failed), projectCount(len(ran)), strings.Join(failed, ", "))
+	case len(ran) == 0:
+		return missingCommandError(fmt.Errorf("no project under %s has a '%s' command", r.ProjectRoot, r.Command))
+	}
+	return nil
+}
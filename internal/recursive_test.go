@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFindProjects(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"go.mod",
+		"web/package.json",
+		"web/node_modules/dep/package.json",
+		"crates/tool/Cargo.toml",
+		"py/lib/pyproject.toml",
+		".cache/pkg/package.json",
+		"docs/README.md",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs, err := FindProjects(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{root}
+	for _, dir := range []string{"crates/tool", "py/lib", "web"} {
+		want = append(want, filepath.Join(root, filepath.FromSlash(dir)))
+	}
+	if !slicesEqual(dirs, want) {
+		t.Errorf("FindProjects() = %v, want %v", dirs, want)
+	}
+}
+
+func TestRunRecursive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	root := t.TempDir()
+	for dir, config := range map[string]string{
+		"a": "smoke = \"echo a >> ../log\"\n",
+		"b": "smoke = \"echo b >> ../log; exit 2\"\n",
+		"c": "other = \"true\"\n",
+	} {
+		dir = filepath.Join(root, dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		writeConfig(t, dir, "[commands]\n"+config)
+	}
+
+	runner := &CommandRunner{Command: "smoke", CurrentDir: root, ProjectRoot: root, Quiet: true}
+	err := runner.RunRecursive()
+	if err == nil || !strings.Contains(err.Error(), "smoke failed in 1 of 2 projects: b") {
+		t.Errorf("RunRecursive() error = %v", err)
+	}
+	log, _ := os.ReadFile(filepath.Join(root, "log"))
+	if string(log) != "a\nb\n" {
+		t.Errorf("log = %q, want a and b", log)
+	}
+
+	runner = &CommandRunner{Command: "missing", CurrentDir: root, ProjectRoot: root, Quiet: true}
+	if err := runner.RunRecursive(); ExitCode(err) != ExitNotFound {
+		t.Errorf("RunRecursive() of a command no project has: error = %v, want not found", err)
+	}
+}
+
+func TestProjectCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0 projects", 1: "1 project", 2: "2 projects"} {
+		if got := projectCount(n); got != want {
+			t.Errorf("projectCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestFilterProjects(t *testing.T) {
+	root := t.TempDir()
+	for file, content := range map[string]string{
+		"package.json":               `{"name": "root"}`,
+		"packages/web/package.json":  `{"name": "@acme/web"}`,
+		"packages/api/package.json":  `{"name": "@acme/api"}`,
+		"examples/demo/package.json": `{"name": "demo"}`,
+		"crates/cli/Cargo.toml":      "[package]\nname = \"acme-cli\"\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs, err := FindProjects(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filters, excludes []string
+		want              []string
+	}{
+		{nil, nil, []string{".", "crates/cli", "examples/demo", "packages/api", "packages/web"}},
+		{[]string{"packages/*"}, nil, []string{"packages/api", "packages/web"}},
+		{nil, []string{"examples/*", "."}, []string{"crates/cli", "packages/api", "packages/web"}},
+		{[]string{"@acme/*", "acme-cli"}, []string{"api"}, []string{"crates/cli", "packages/web"}},
+	}
+	for _, tt := range tests {
+		runner := &CommandRunner{ProjectRoot: root, ProjectFilters: tt.filters, ProjectExcludes: tt.excludes}
+		var got []string
+		for _, dir := range runner.filterProjects(dirs) {
+			got = append(got, runner.relativeProject(dir))
+		}
+		if !slicesEqual(got, tt.want) {
+			t.Errorf("filterProjects(%v, %v) = %v, want %v", tt.filters, tt.excludes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// CommandResolution is the plan for a command: what Run would execute, and
+// why, without executing it
+type CommandResolution struct {
+	Command  string            `json:"command"`  // the command as asked for
+	Resolved string            `json:"resolved"` // the name it was found under, after aliases and normalization
+	Source   string            `json:"source"`   // the source that provides it, such as "npm" or "just"
+	Dir      string            `json:"dir"`      // the directory the source was found in
+	Cwd      string            `json:"cwd"`      // the working directory the command runs in
+	Argv     []string          `json:"argv"`
+	Path     string            `json:"path,omitempty"` // the program, as found on PATH
+	Env      []string          `json:"-"`              // the command's full environment, as KEY=value
+	AddedEnv map[string]string `json:"env"`            // the variables cmd-runner adds to its own environment
+
+	// Cmd is the command Run would start
+	Cmd *exec.Cmd `json:"-"`
+}
+
+// Resolve resolves command with args in the current directory, or the one
+// given with WithDir, as cmdr would run it
+func Resolve(command string, args []string, opts ...RunnerOption) (*CommandResolution, error) {
+	r := New(command, args, opts...)
+	if r.CurrentDir == "" {
+		if err := r.Init(); err != nil {
+			return nil, err
+		}
+	}
+	return r.Resolution()
+}
+
+// Plan returns what cmdr would run for command in dir, without starting any
+// process: sources that usually ask their tool for its tasks (mise, just,
+// deno) parse their files instead. A relative dir is taken from the working
+// directory; otherwise the result doesn't depend on it.
+func Plan(dir, command string, args ...string) (*CommandResolution, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Resolve(command, args, WithDir(abs), withStatic())
+}
+
+// withStatic makes the runner's sources read their files instead of
+// running their tools
+func withStatic() RunnerOption {
+	return func(r *CommandRunner) {
+		x := r.executor.clone()
+		x.static = true
+		r.executor = x
+	}
+}
+
+// WithDir makes the runner resolve commands in dir, and the project root
+// above it, instead of the current directory
+func WithDir(dir string) RunnerOption {
+	return func(r *CommandRunner) {
+		r.CurrentDir = dir
+		r.ProjectRoot = r.FindProjectRoot(dir)
+	}
+}
+
+// Resolution resolves the runner's command into a plan. It fails as Resolve
+// does for commands that don't resolve to a single invocation.
+func (r *CommandRunner) Resolution() (*CommandResolution, error) {
+	cmd, source, resolved, err := r.resolve()
+	if err != nil {
+		return nil, err
+	}
+	plan := &CommandResolution{
+		Command:  r.Command,
+		Resolved: resolved,
+		Cwd:      cmd.Dir,
+		Argv:     cmd.Args,
+		Path:     cmd.Path,
+		Env:      cmd.Env,
+		AddedEnv: r.invocation(cmd).Env,
+		Cmd:      cmd,
+	}
+	if source != nil {
+		plan.Source = source.Name()
+	}
+	if s, ok := source.(interface{ sourceDir() string }); ok {
+		plan.Dir = s.sourceDir()
+	}
+	if plan.Cwd == "" {
+		plan.Cwd = r.CurrentDir
+	}
+	if plan.Env == nil {
+		plan.Env = r.commandEnv()
+	}
+	if plan.Env == nil {
+		plan.Env = os.Environ()
+	}
+	return plan, nil
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"dev": "vite"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, "[env]\nPORT = \"3000\"\n")
+
+	plan, err := Resolve("serve", []string{"--open"}, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Command != "serve" || plan.Resolved != "serve" || plan.Source != "npm" || plan.Dir != dir || plan.Cwd != dir {
+		t.Errorf("Resolve() = %+v", plan)
+	}
+	if got := strings.Join(plan.Argv, " "); got != "npm run dev -- --open" {
+		t.Errorf("Resolve().Argv = %q, want npm run dev -- --open", got)
+	}
+	if plan.AddedEnv["PORT"] != "3000" {
+		t.Errorf("Resolve().AddedEnv = %v, want PORT", plan.AddedEnv)
+	}
+	found := false
+	for _, v := range plan.Env {
+		found = found || v == "PORT=3000"
+	}
+	if !found {
+		t.Error("Resolve().Env is missing PORT=3000")
+	}
+
+	// Resolving a synthesized command fails, as Resolve does
+	if _, err := Resolve("check", nil, WithDir(dir)); err == nil {
+		t.Error("Resolve(check) should fail for a synthesized command")
+	}
+	if _, err := Resolve("deploy", nil, WithDir(dir)); !isNotFound(err) {
+		t.Errorf("Resolve(deploy) = %v, want not found", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "justfile"), []byte("# Say hello\nhello name:\n    echo hello {{name}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Without just on PATH, only parsing the justfile finds the recipe
+	t.Setenv("PATH", "")
+
+	plan, err := Plan(dir, "hello", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(plan.Argv, " "); got != "just hello world" || plan.Source != "just" || plan.Cwd != dir {
+		t.Errorf("Plan() = %q from %s in %s", got, plan.Source, plan.Cwd)
+	}
+	if _, err := Plan(dir, "deploy"); !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("Plan(deploy) = %v, want ErrCommandNotFound", err)
+	}
+
+	// Nothing runs, even where running direnv or just would tell more
+	if err := os.WriteFile(filepath.Join(dir, ".envrc"), []byte("export FOO=bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	started := filepath.Join(t.TempDir(), "started")
+	factory := func(name string, arg ...string) *exec.Cmd {
+		// Creating the command is planning it; starting it leaves a record
+		return exec.Command("/bin/sh", "-c", `echo "$@" >> "$0"`, started, name)
+	}
+	lookPath := func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	if _, err := Resolve("hello", []string{"world"}, WithDir(dir), withStatic(), WithCommandFactory(factory), WithLookPath(lookPath)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(started); err == nil {
+		t.Errorf("planning started %q", data)
+	}
+}
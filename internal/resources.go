@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resourceUsage is the combined CPU and memory use of a process group
+type resourceUsage struct {
+	cpu    float64 // percent of one core
+	memory int64   // resident set size in bytes
+}
+
+// usageSampler measures the resource usage of process groups. It is best
+// effort: on Linux it reads /proc, on other Unix systems it runs ps, and on
+// Windows it reports nothing.
+type usageSampler struct {
+	lastTicks map[int]int64 // CPU ticks of each group at the last sample
+	lastTime  time.Time
+	executor  *Executor // runs ps
+}
+
+// linuxClockTicks is USER_HZ, the unit of CPU times in /proc
+const linuxClockTicks = 100
+
+func newUsageSampler(x *Executor) *usageSampler {
+	return &usageSampler{lastTicks: make(map[int]int64), executor: x}
+}
+
+// sample returns the usage of each of the process groups that it can measure
+func (s *usageSampler) sample(pgids []int) map[int]resourceUsage {
+	switch runtime.GOOS {
+	case "windows":
+		return nil
+	case "linux":
+		return s.sampleProc(pgids)
+	default:
+		return s.samplePs(pgids)
+	}
+}
+
+// sampleProc totals /proc/<pid>/stat by process group. CPU use is averaged
+// since the previous sample.
+func (s *usageSampler) sampleProc(pgids []int) map[int]resourceUsage {
+	wanted := make(map[int]bool)
+	for _, pgid := range pgids {
+		wanted[pgid] = true
+	}
+	ticks := make(map[int]int64)
+	memory := make(map[int]int64)
+	paths, _ := filepath.Glob("/proc/[0-9]*/stat")
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// Fields follow the parenthesized command name, starting with the
+		// state (field 3 in proc(5))
+		i := bytes.LastIndexByte(data, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(data[i+1:]))
+		if len(fields) < 22 {
+			continue
+		}
+		pgid, _ := strconv.Atoi(fields[2])
+		if !wanted[pgid] {
+			continue
+		}
+		utime, _ := strconv.ParseInt(fields[11], 10, 64)
+		stime, _ := strconv.ParseInt(fields[12], 10, 64)
+		rss, _ := strconv.ParseInt(fields[21], 10, 64)
+		ticks[pgid] += utime + stime
+		memory[pgid] += rss * int64(os.Getpagesize())
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	usage := make(map[int]resourceUsage)
+	for pgid := range memory {
+		u := resourceUsage{memory: memory[pgid]}
+		if last, ok := s.lastTicks[pgid]; ok && elapsed > 0 && ticks[pgid] >= last {
+			u.cpu = float64(ticks[pgid]-last) / linuxClockTicks / elapsed * 100
+		}
+		usage[pgid] = u
+	}
+	s.lastTicks = ticks
+	s.lastTime = now
+	return usage
+}
+
+// samplePs totals the output of ps by process group
+func (s *usageSampler) samplePs(pgids []int) map[int]resourceUsage {
+	wanted := make(map[int]bool)
+	for _, pgid := range pgids {
+		wanted[pgid] = true
+	}
+	output, err := s.executor.command("ps", "-A", "-o", "pgid=,pcpu=,rss=").Output()
+	if err != nil {
+		return nil
+	}
+	usage := make(map[int]resourceUsage)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		pgid, _ := strconv.Atoi(fields[0])
+		if !wanted[pgid] {
+			continue
+		}
+		cpu, _ := strconv.ParseFloat(fields[1], 64)
+		rss, _ := strconv.ParseInt(fields[2], 10, 64)
+		u := usage[pgid]
+		u.cpu += cpu
+		u.memory += rss * 1024
+		usage[pgid] = u
+	}
+	return usage
+}
+
+// formatBytes formats a size as a short human-readable string
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return strconv.FormatFloat(float64(n)/(1<<30), 'f', 1, 64) + "G"
+	case n >= 1<<20:
+		return strconv.FormatFloat(float64(n)/(1<<20), 'f', 1, 64) + "M"
+	case n >= 1<<10:
+		return strconv.FormatInt(n>>10, 10) + "K"
+	default:
+		return strconv.FormatInt(n, 10) + "B"
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how a failed command is retried. Only failures whose
+// output matches a known transient error signature are retried.
+type RetryPolicy struct {
+	Attempts int           // Total attempts, including the first; <= 1 disables retries
+	Backoff  string        // "constant", "linear", or "exponential" (the default)
+	Delay    time.Duration // Base delay between attempts; defaults to one second
+}
+
+// delay returns how long to wait before the given retry (1 for the first retry)
+func (p RetryPolicy) delay(retry int) time.Duration {
+	base := p.Delay
+	if base <= 0 {
+		base = time.Second
+	}
+	switch p.Backoff {
+	case "constant":
+		return base
+	case "linear":
+		return base * time.Duration(retry)
+	default:
+		return base << (retry - 1)
+	}
+}
+
+// ValidateBackoff reports an error for unknown backoff names
+func ValidateBackoff(backoff string) error {
+	switch backoff {
+	case "", "constant", "linear", "exponential":
+		return nil
+	}
+	return fmt.Errorf("unknown backoff %q (expected constant, linear, or exponential)", backoff)
+}
+
+// transientErrorPatterns match output from network failures that are
+// usually worth retrying: registry 5xx responses, timeouts, and DNS or
+// connection errors
+var transientErrorPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(ETIMEDOUT|ECONNRESET|ECONNREFUSED|EAI_AGAIN|ENOTFOUND|ESOCKETTIMEDOUT)\b`),
+	regexp.MustCompile(`(?i)\b5\d\d (Internal Server Error|Bad Gateway|Service Unavailable|Gateway Time-?out)\b`),
+	regexp.MustCompile(`(?i)\b(status|code)[ :]*5\d\d\b`),
+	regexp.MustCompile(`npm ERR! code E5\d\d`),
+	regexp.MustCompile(`(?i)socket hang up`),
+	regexp.MustCompile(`(?i)connection (reset|refused|timed out)`),
+	regexp.MustCompile(`(?i)(temporary failure in name resolution|could not resolve host)`),
+	regexp.MustCompile(`(?i)(i/o timeout|read timed out|TLS handshake timeout|network is unreachable)`),
+}
+
+// isTransientFailure reports whether command output contains a known
+// transient error signature
+func isTransientFailure(output []byte) bool {
+	for _, pattern := range transientErrorPatterns {
+		if pattern.Match(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	data []byte
+	max  int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data
+}
+
+// cloneCommand returns an unstarted copy of cmd, since an exec.Cmd can only
+// be run once
+func (r *CommandRunner) cloneCommand(cmd *exec.Cmd) *exec.Cmd {
+	clone := r.executor.command(cmd.Args[0], cmd.Args[1:]...)
+	clone.Path = cmd.Path
+	clone.Dir = cmd.Dir
+	clone.Env = cmd.Env
+	clone.Stdin = cmd.Stdin
+	clone.Stdout = cmd.Stdout
+	clone.Stderr = cmd.Stderr
+	return clone
+}
+
+// runWithRetry runs cmd, retrying according to r.Retry while failures look
+// transient
+func (r *CommandRunner) runWithRetry(cmd *exec.Cmd) error {
+	stdout, stderr := cmd.Stdout, cmd.Stderr
+	for attempt := 1; ; attempt++ {
+		output := &tailBuffer{max: 64 * 1024}
+		current := r.cloneCommand(cmd)
+		var err error
+		if r.usePTY() {
+			err = runInPTY(r.context(), current, io.MultiWriter(stdout, output))
+		} else {
+			current.Stdout = io.MultiWriter(stdout, output)
+			current.Stderr = io.MultiWriter(stderr, output)
+			err = runForeground(r.context(), current)
+		}
+		if err == nil || attempt >= r.Retry.Attempts {
+			return err
+		}
+		if _, ok := err.(*exec.ExitError); !ok || !isTransientFailure(output.Bytes()) {
+			return err
+		}
+
+		wait := r.Retry.delay(attempt)
+		fmt.Fprintf(os.Stderr, "Transient failure (attempt %d/%d); retrying in %s...\n", attempt, r.Retry.Attempts, wait)
+		select {
+		case <-time.After(wait):
+		case <-r.context().Done():
+			return r.context().Err()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		backoff  string
+		retry    int
+		expected time.Duration
+	}{
+		{"constant", 3, time.Second},
+		{"linear", 3, 3 * time.Second},
+		{"exponential", 1, time.Second},
+		{"exponential", 3, 4 * time.Second},
+		{"", 2, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		policy := RetryPolicy{Attempts: 5, Backoff: tt.backoff}
+		if result := policy.delay(tt.retry); result != tt.expected {
+			t.Errorf("RetryPolicy{Backoff: %q}.delay(%d) = %v, want %v", tt.backoff, tt.retry, result, tt.expected)
+		}
+	}
+}
+
+func TestIsTransientFailure(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected bool
+	}{
+		{"npm ERR! code ETIMEDOUT\nnpm ERR! errno ETIMEDOUT", true},
+		{"npm ERR! code E503\nnpm ERR! 503 Service Unavailable", true},
+		{"error: failed to download: Connection reset by peer", true},
+		{"fatal: unable to access: Could not resolve host: github.com", true},
+		{"FAIL src/app.test.ts\nExpected 2, received 3", false},
+		{"error[E0308]: mismatched types", false},
+	}
+
+	for _, tt := range tests {
+		if result := isTransientFailure([]byte(tt.output)); result != tt.expected {
+			t.Errorf("isTransientFailure(%q) = %v, want %v", tt.output, result, tt.expected)
+		}
+	}
+}
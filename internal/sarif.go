@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sarifOutputMax limits the output kept for finding diagnostics for a
+// SARIF log; linters' JSON modes can be verbose
+const sarifOutputMax = 16 << 20
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifRun is a run of a SARIF log: the diagnostics of one tool. A run that
+// a command wrote as SARIF itself is passed through as raw.
+type sarifRun struct {
+	tool    string
+	results []annotation
+	raw     json.RawMessage
+}
+
+// parseDiagnostics finds the diagnostics in the output of a command run in
+// dir, by tool. It reads the JSON modes of eslint (-f json), ruff
+// (--output-format json), golangci-lint (--out-format json), and cargo
+// (--message-format json, as for clippy), and SARIF itself; if the output
+// has none of these, it falls back to the text formats parseAnnotations
+// reads. Paths are made relative to root.
+func parseDiagnostics(output []byte, dir, root string) []sarifRun {
+	var runs []sarifRun
+	// run returns the run for tool, so that a tool that reported nothing
+	// still has one
+	run := func(tool string) *sarifRun {
+		for i := range runs {
+			if runs[i].tool == tool && runs[i].raw == nil {
+				return &runs[i]
+			}
+		}
+		runs = append(runs, sarifRun{tool: tool})
+		return &runs[len(runs)-1]
+	}
+	found := false
+	for _, value := range jsonValues(output) {
+		if sarifRuns, ok := parseSARIF(value); ok {
+			for _, raw := range sarifRuns {
+				runs = append(runs, sarifRun{raw: raw})
+			}
+			found = true
+		} else if tool, results, ok := parseLinterJSON(value); ok {
+			found = true
+			if tool == "" {
+				continue
+			}
+			r := run(tool)
+			for _, a := range results {
+				if path, ok := diagnosticPath(a.file, dir, root); ok {
+					a.file = path
+					r.results = append(r.results, a)
+				}
+			}
+		}
+	}
+	if results := parseAnnotations(output, dir, root); !found && len(results) > 0 {
+		run("cmdr").results = results
+	}
+	return runs
+}
+
+// jsonValues returns the JSON objects and arrays in output, which may be
+// mixed with other text, such as the lines a package manager prints before
+// running a script
+func jsonValues(output []byte) []json.RawMessage {
+	var values []json.RawMessage
+	for len(output) > 0 {
+		line := output
+		if i := bytes.IndexByte(output, '\n'); i >= 0 {
+			line = output[:i+1]
+		}
+		if trimmed := bytes.TrimLeft(line, " \t"); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			start := output[len(line)-len(trimmed):]
+			dec := json.NewDecoder(bytes.NewReader(start))
+			var value json.RawMessage
+			if err := dec.Decode(&value); err == nil {
+				values = append(values, value)
+				output = start[dec.InputOffset():]
+				continue
+			}
+		}
+		output = output[len(line):]
+	}
+	return values
+}
+
+// parseSARIF returns the runs of a SARIF log
+func parseSARIF(value json.RawMessage) ([]json.RawMessage, bool) {
+	var log struct {
+		Version string            `json:"version"`
+		Runs    []json.RawMessage `json:"runs"`
+	}
+	if json.Unmarshal(value, &log) != nil || log.Version == "" || log.Runs == nil {
+		return nil, false
+	}
+	return log.Runs, true
+}
+
+// parseLinterJSON reads a value written by a linter's JSON mode
+func parseLinterJSON(value json.RawMessage) (tool string, results []annotation, ok bool) {
+	var eslint []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   string `json:"ruleId"`
+			Severity int    `json:"severity"`
+			Message  string `json:"message"`
+			Line     int    `json:"line"`
+			Column   int    `json:"column"`
+		} `json:"messages"`
+	}
+	if json.Unmarshal(value, &eslint) == nil && len(eslint) > 0 && eslint[0].FilePath != "" {
+		for _, file := range eslint {
+			for _, m := range file.Messages {
+				level := "warning"
+				if m.Severity == 2 {
+					level = "error"
+				}
+				results = append(results, annotation{level: level, file: file.FilePath, line: m.Line, col: m.Column, message: m.Message, rule: m.RuleID})
+			}
+		}
+		return "eslint", results, true
+	}
+
+	var ruff []struct {
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		Filename string `json:"filename"`
+		Location struct {
+			Row    int `json:"row"`
+			Column int `json:"column"`
+		} `json:"location"`
+	}
+	if json.Unmarshal(value, &ruff) == nil && len(ruff) > 0 && ruff[0].Filename != "" {
+		for _, d := range ruff {
+			results = append(results, annotation{level: "error", file: d.Filename, line: d.Location.Row, col: d.Location.Column, message: d.Message, rule: d.Code})
+		}
+		return "ruff", results, true
+	}
+
+	var golangci struct {
+		Issues *[]struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Severity   string `json:"Severity"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+	if json.Unmarshal(value, &golangci) == nil && golangci.Issues != nil {
+		for _, issue := range *golangci.Issues {
+			level := "error"
+			if issue.Severity == "warning" {
+				level = "warning"
+			}
+			results = append(results, annotation{level: level, file: issue.Pos.Filename, line: issue.Pos.Line, col: issue.Pos.Column, message: issue.Text, rule: issue.FromLinter})
+		}
+		return "golangci-lint", results, true
+	}
+
+	var cargo struct {
+		Reason  string `json:"reason"`
+		Message *struct {
+			Level   string `json:"level"`
+			Message string `json:"message"`
+			Code    *struct {
+				Code string `json:"code"`
+			} `json:"code"`
+			Spans []struct {
+				FileName    string `json:"file_name"`
+				LineStart   int    `json:"line_start"`
+				ColumnStart int    `json:"column_start"`
+				IsPrimary   bool   `json:"is_primary"`
+			} `json:"spans"`
+		} `json:"message"`
+	}
+	if json.Unmarshal(value, &cargo) == nil && cargo.Reason != "" {
+		// Other records, such as compiler-artifact, belong to no tool
+		if m := cargo.Message; cargo.Reason == "compiler-message" && m != nil && (m.Level == "error" || m.Level == "warning") {
+			tool = "rustc"
+			a := annotation{level: m.Level, message: m.Message}
+			if m.Code != nil {
+				a.rule = m.Code.Code
+				if strings.HasPrefix(a.rule, "clippy::") {
+					tool = "clippy"
+				}
+			}
+			for _, span := range m.Spans {
+				if span.IsPrimary {
+					a.file, a.line, a.col = span.FileName, span.LineStart, span.ColumnStart
+					results = append(results, a)
+					break
+				}
+			}
+		}
+		return tool, results, true
+	}
+	return "", nil, false
+}
+
+// writeSARIF writes the diagnostics found in the run's output to r.SARIF
+func (r *CommandRunner) writeSARIF(runs []sarifRun) error {
+	if len(runs) == 0 {
+		// An empty run tells code scanning that earlier alerts are fixed
+		runs = []sarifRun{{tool: "cmdr"}}
+	}
+	log := sarifLog{Schema: sarifSchema, Version: "2.1.0"}
+	root := (&url.URL{Scheme: "file", Path: fileURLPath(r.ProjectRoot) + "/"}).String()
+	for _, run := range mergeRuns(runs) {
+		if run.raw != nil {
+			log.Runs = append(log.Runs, run.raw)
+			continue
+		}
+		log.Runs = append(log.Runs, run.log(root))
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(r.SARIF); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(r.SARIF, append(data, '\n'), 0o644)
+}
+
+// mergeRuns combines the runs of each tool, such as those of the projects
+// of a recursive run, since code scanning expects one run per tool
+func mergeRuns(runs []sarifRun) []sarifRun {
+	var merged []sarifRun
+	index := make(map[string]int)
+	for _, run := range runs {
+		if i, ok := index[run.tool]; ok && run.raw == nil {
+			merged[i].results = append(merged[i].results, run.results...)
+			continue
+		}
+		if run.raw == nil {
+			index[run.tool] = len(merged)
+		}
+		merged = append(merged, run)
+	}
+	return merged
+}
+
+// SARIF 2.1.0 log structure, as much of it as cmdr writes
+
+type sarifLog struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []any  `json:"runs"`
+}
+
+type sarifRunLog struct {
+	Tool               sarifTool                `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifact `json:"originalUriBaseIds"`
+	Results            []sarifResult            `json:"results"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules,omitempty"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId,omitempty"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation sarifArtifact `json:"artifactLocation"`
+		Region           sarifRegion   `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+type sarifArtifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// log returns the run as SARIF, with paths relative to the root URI
+func (run sarifRun) log(root string) sarifRunLog {
+	out := sarifRunLog{
+		OriginalURIBaseIDs: map[string]sarifArtifact{"%SRCROOT%": {URI: root}},
+		Results:            []sarifResult{},
+	}
+	out.Tool.Driver.Name = run.tool
+	seenRules := make(map[string]bool)
+	for _, a := range run.results {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation = sarifArtifact{URI: a.file, URIBaseID: "%SRCROOT%"}
+		if filepath.IsAbs(filepath.FromSlash(a.file)) {
+			loc.PhysicalLocation.ArtifactLocation = sarifArtifact{URI: (&url.URL{Scheme: "file", Path: fileURLPath(a.file)}).String()}
+		}
+		loc.PhysicalLocation.Region = sarifRegion{StartLine: max(a.line, 1), StartColumn: a.col}
+		result := sarifResult{RuleID: a.rule, Level: sarifLevel(a.level), Locations: []sarifLocation{loc}}
+		result.Message.Text = a.message
+		if a.rule != "" && !seenRules[a.rule] {
+			seenRules[a.rule] = true
+			out.Tool.Driver.Rules = append(out.Tool.Driver.Rules, sarifRule{ID: a.rule})
+		}
+		out.Results = append(out.Results, result)
+	}
+	return out
+}
+
+// fileURLPath returns the path of a file URL for an absolute path
+func fileURLPath(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // a Windows drive letter
+	}
+	return path
+}
+
+// sarifLevel maps an annotation level to a SARIF result level
+func sarifLevel(level string) string {
+	if level == "notice" {
+		return "note"
+	}
+	return level
+}
+
+// finishSARIF writes the SARIF log of the run's output and returns the
+// run's error, or, if the run succeeded, the error writing the log
+func (r *CommandRunner) finishSARIF(err error, runs []sarifRun) error {
+	if logErr := r.writeSARIF(runs); logErr != nil {
+		if err == nil {
+			return fmt.Errorf("can't write SARIF log: %w", logErr)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: can't write SARIF log %s: %v\n", r.SARIF, logErr)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{"web/index.js", "app.py", "main.go", "src/lib.rs"} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := "\n> web@1.0.0 lint\n> eslint -f json .\n\n" +
+		`[{"filePath":"` + filepath.ToSlash(filepath.Join(root, "web/index.js")) + `","messages":[{"ruleId":"no-unused-vars","severity":2,"message":"'x' is unused","line":1,"column":10}]}]` + "\n" +
+		`[{"code":"F401","message":"os imported but unused","filename":"app.py","location":{"row":1,"column":8}}]` + "\n" +
+		`{"Issues":[{"FromLinter":"errcheck","Text":"error not checked","Pos":{"Filename":"main.go","Line":7,"Column":2}}]}` + "\n" +
+		`{"reason":"compiler-artifact","target":{}}` + "\n" +
+		`{"reason":"compiler-message","message":{"level":"warning","message":"needless return","code":{"code":"clippy::needless_return"},"spans":[{"file_name":"src/lib.rs","line_start":3,"column_start":5,"is_primary":true}]}}` + "\n" +
+		`{"reason":"build-finished","success":true}` + "\n"
+
+	runs := parseDiagnostics([]byte(output), root, root)
+	var got []string
+	for _, run := range runs {
+		for _, a := range run.results {
+			got = append(got, run.tool+" "+a.level+" "+a.file+" "+a.rule)
+		}
+	}
+	want := []string{
+		"eslint error web/index.js no-unused-vars",
+		"ruff error app.py F401",
+		"golangci-lint error main.go errcheck",
+		"clippy warning src/lib.rs clippy::needless_return",
+	}
+	if !slicesEqual(got, want) {
+		t.Errorf("parseDiagnostics() = %v, want %v", got, want)
+	}
+
+	// Output without a JSON mode falls back to compiler-style lines
+	runs = parseDiagnostics([]byte("main.go:3:1: undefined: x\n"), root, root)
+	if len(runs) != 1 || runs[0].tool != "cmdr" || len(runs[0].results) != 1 || runs[0].results[0].line != 3 {
+		t.Errorf("parseDiagnostics(text) = %+v", runs)
+	}
+
+	// A tool that reports nothing still has a run
+	runs = parseDiagnostics([]byte(`{"Issues":[]}`), root, root)
+	if len(runs) != 1 || runs[0].tool != "golangci-lint" || len(runs[0].results) != 0 {
+		t.Errorf("parseDiagnostics(no issues) = %+v", runs)
+	}
+}
+
+func TestSARIFLog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	writeConfig(t, dir, `[commands]
+lint = "echo 'main.go:3:1: undefined: x' >&2; exit 1"
+`)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "lint.sarif")
+	runner := &CommandRunner{Command: "lint", CurrentDir: dir, ProjectRoot: dir, Quiet: true, PTY: PTYNever, SARIF: path}
+	if err := runner.Run(); err == nil {
+		t.Fatal("Run() should fail")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool    sarifTool     `json:"tool"`
+			Results []sarifResult `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("log doesn't parse: %v\n%s", err, data)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("log = %s", data)
+	}
+	result := log.Runs[0].Results[0]
+	loc := result.Locations[0].PhysicalLocation
+	if result.Level != "error" || result.Message.Text != "undefined: x" || loc.ArtifactLocation.URI != "main.go" || loc.Region.StartLine != 3 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestSARIFPassthrough(t *testing.T) {
+	output := "Linting...\n" + `{"version":"2.1.0","runs":[{"tool":{"driver":{"name":"other"}},"results":[]}]}` + "\nmain.go:1:1: ignored\n"
+	runs := parseDiagnostics([]byte(output), t.TempDir(), "/")
+	if len(runs) != 1 || !strings.Contains(string(runs[0].raw), `"other"`) {
+		t.Errorf("parseDiagnostics() = %+v, want the SARIF run", runs)
+	}
+}
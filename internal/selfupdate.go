@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latestReleaseURL is the GitHub API endpoint for cmd-runner's latest release
+var latestReleaseURL = "https://api.github.com/repos/osteele/cmd-runner/releases/latest"
+
+// checksumsAsset is the release asset that lists the SHA-256 of each binary,
+// in sha256sum's format
+const checksumsAsset = "checksums.txt"
+
+// githubRelease is the part of a GitHub release that self-update reads
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the release's asset named name, or ""
+func (r *githubRelease) assetURL(name string) string {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL
+		}
+	}
+	return ""
+}
+
+// releaseBinaryName is the name of the release asset for this platform, as
+// the release task builds it
+func releaseBinaryName() string {
+	name := "cmdr-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// SelfUpdate replaces the running cmdr with the binary from the latest
+// GitHub release, if that is newer than current, after checking it against
+// the release's checksums. With checkOnly it only reports whether there is
+// a newer release.
+func SelfUpdate(w io.Writer, current string, checkOnly bool) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return selfUpdate(w, current, checkOnly, exe)
+}
+
+// selfUpdate is SelfUpdate, replacing the executable at exe
+func selfUpdate(w io.Writer, current string, checkOnly bool, exe string) error {
+	release, err := fetchLatestRelease()
+	if err != nil {
+		return fmt.Errorf("checking for a new release: %w", err)
+	}
+	latest := strings.TrimPrefix(release.TagName, "v")
+	if !versionLess(current, latest) {
+		fmt.Fprintf(w, "cmdr %s is up to date\n", current)
+		return nil
+	}
+	if checkOnly {
+		fmt.Fprintf(w, "cmdr %s is available (this is %s); run cmdr self-update to install it\n", latest, current)
+		return nil
+	}
+
+	binaryURL := release.assetURL(releaseBinaryName())
+	if binaryURL == "" {
+		return fmt.Errorf("release %s has no binary for %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, releaseBinaryName())
+	}
+	checksumsURL := release.assetURL(checksumsAsset)
+	if checksumsURL == "" {
+		return fmt.Errorf("release %s has no %s, so its binary can't be verified", release.TagName, checksumsAsset)
+	}
+	want, err := releaseChecksum(checksumsURL, releaseBinaryName())
+	if err != nil {
+		return err
+	}
+
+	// Download next to the executable, so that the rename that replaces it
+	// stays on one file system and is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".cmdr-update-*")
+	if err != nil {
+		return fmt.Errorf("can't write to %s: %w", filepath.Dir(exe), err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	hash := sha256.New()
+	err = download(binaryURL, io.MultiWriter(tmp, hash))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", releaseBinaryName(), err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", releaseBinaryName(), got, want)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	if err := replaceExecutable(exe, tmp.Name()); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Updated cmdr from %s to %s (%s)\n", current, latest, exe)
+	return nil
+}
+
+// replaceExecutable renames the file at src over exe. Windows doesn't allow
+// replacing a running executable, but does allow renaming it out of the way.
+func replaceExecutable(exe, src string) error {
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+		if err := os.Rename(src, exe); err != nil {
+			_ = os.Rename(old, exe)
+			return err
+		}
+		// Fails while this process runs; the next update removes it
+		_ = os.Remove(old)
+		return nil
+	}
+	return os.Rename(src, exe)
+}
+
+// fetchLatestRelease asks the GitHub API for the latest release
+func fetchLatestRelease() (*githubRelease, error) {
+	var body strings.Builder
+	if err := download(latestReleaseURL, &body); err != nil {
+		return nil, err
+	}
+	var release githubRelease
+	if err := json.Unmarshal([]byte(body.String()), &release); err != nil {
+		return nil, err
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("no release found")
+	}
+	return &release, nil
+}
+
+// releaseChecksum returns the SHA-256 the checksums file at url lists for
+// name
+func releaseChecksum(url, name string) (string, error) {
+	var body strings.Builder
+	if err := download(url, &body); err != nil {
+		return "", fmt.Errorf("downloading %s: %w", checksumsAsset, err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(body.String()))
+	for scanner.Scan() {
+		// sha256sum writes "HASH  NAME", or "HASH *NAME" in binary mode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s doesn't list %s", checksumsAsset, name)
+}
+
+// download writes the body of a GET of url to w. A GITHUB_TOKEN in the
+// environment is sent to GitHub, for its higher rate limit.
+func download(url string, w io.Writer) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// versionLess reports whether version a is older than b, comparing their
+// dot-separated numbers. A pre-release suffix (1.2.0-rc1) sorts before the
+// release.
+func versionLess(a, b string) bool {
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return aPre != "" && (bPre == "" || aPre < bPre)
+}
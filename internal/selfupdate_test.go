@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVersionLess(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"0.2.0", "0.3.0", true},
+		{"0.10.0", "0.9.0", false},
+		{"1.2", "1.2.1", true},
+		{"1.2.0", "1.2.0", false},
+		{"1.2.0-rc1", "1.2.0", true},
+		{"1.2.0", "1.2.0-rc1", false},
+	} {
+		if got := versionLess(tc.a, tc.b); got != tc.want {
+			t.Errorf("versionLess(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	binary := []byte("new cmdr")
+	sum := sha256.Sum256(binary)
+	checksums := hex.EncodeToString(sum[:]) + "  " + releaseBinaryName() + "\n"
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			fmt.Fprintf(w, `{"tag_name": "v0.3.0", "assets": [
+				{"name": %q, "browser_download_url": "%s/bin"},
+				{"name": "checksums.txt", "browser_download_url": "%s/sums"}]}`,
+				releaseBinaryName(), server.URL, server.URL)
+		case "/bin":
+			_, _ = w.Write(binary)
+		case "/sums":
+			fmt.Fprint(w, checksums)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { latestReleaseURL = url }(latestReleaseURL)
+	latestReleaseURL = server.URL + "/latest"
+	t.Setenv("GITHUB_TOKEN", "")
+
+	exe := filepath.Join(t.TempDir(), "cmdr")
+	if err := os.WriteFile(exe, []byte("old cmdr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := selfUpdate(&out, "0.3.0", false, exe); err != nil || !strings.Contains(out.String(), "up to date") {
+		t.Errorf("selfUpdate() from the latest = %v, %q", err, out.String())
+	}
+	out.Reset()
+	if err := selfUpdate(&out, "0.2.0", true, exe); err != nil || !strings.Contains(out.String(), "cmdr 0.3.0 is available") {
+		t.Errorf("selfUpdate() with checkOnly = %v, %q", err, out.String())
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old cmdr" {
+		t.Errorf("checkOnly replaced the executable")
+	}
+
+	checksums = strings.Repeat("0", 64) + "  " + releaseBinaryName() + "\n"
+	if err := selfUpdate(&out, "0.2.0", false, exe); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("selfUpdate() with a bad checksum = %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old cmdr" {
+		t.Errorf("a bad checksum replaced the executable")
+	}
+
+	checksums = hex.EncodeToString(sum[:]) + "  " + releaseBinaryName() + "\n"
+	out.Reset()
+	if err := selfUpdate(&out, "0.2.0", false, exe); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new cmdr" {
+		t.Errorf("executable = %q after update", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Errorf("update left files behind: %v", entries)
+	}
+}
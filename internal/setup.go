@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// provisionStep is a command that setup runs, before the package manager's
+// install, to provide the toolchains the project pins
+type provisionStep struct {
+	name string // the file that asks for it
+	dir  string
+	args []string
+}
+
+// provisionCommand returns the command that runs step
+func (r *CommandRunner) provisionCommand(step provisionStep) *exec.Cmd {
+	cmd := r.executor.command(step.args[0], step.args[1:]...)
+	cmd.Dir = step.dir
+	return cmd
+}
+
+// provisionSteps returns the toolchain installs the project calls for, in
+// the nearest of the current directory and project root that has any. A
+// pin whose tool isn't installed is reported in missing instead.
+func (r *CommandRunner) provisionSteps() (steps []provisionStep, missing []string) {
+	for _, dir := range r.searchDirs() {
+		add := func(name, program string, args ...string) {
+			if _, err := r.executor.lookPath(program); err != nil {
+				missing = append(missing, fmt.Sprintf("%s needs %s, which isn't installed", name, program))
+				return
+			}
+			steps = append(steps, provisionStep{name, dir, append([]string{program}, args...)})
+		}
+
+		// mise reads asdf's .tool-versions too
+		if name := firstExisting(dir, ".mise.toml", "mise.toml", ".tool-versions"); name != "" {
+			if _, err := r.executor.lookPath("mise"); err != nil && name == ".tool-versions" {
+				add(name, "asdf", "install")
+			} else {
+				add(name, "mise", "install")
+			}
+		}
+		if name, _ := pinnedPackageManager(dir); corepackManages(name) {
+			add("package.json packageManager", "corepack", "enable")
+		}
+		if name := firstExisting(dir, "rust-toolchain.toml", "rust-toolchain"); name != "" {
+			add(name, "rustup", "toolchain", "install")
+		}
+		if len(steps) > 0 || len(missing) > 0 {
+			return steps, missing
+		}
+	}
+	return nil, nil
+}
+
+// provisions reports whether the project pins any toolchains
+func (r *CommandRunner) provisions() bool {
+	steps, missing := r.provisionSteps()
+	return len(steps) > 0 || len(missing) > 0
+}
+
+// firstExisting returns the first of names that exists in dir, or ""
+func firstExisting(dir string, names ...string) string {
+	for _, name := range names {
+		if FileExists(filepath.Join(dir, name)) {
+			return name
+		}
+	}
+	return ""
+}
+
+// HandleSetupCommand handles setup when the project doesn't define it and
+// pins toolchains: it installs them, then runs the package manager's
+// install, if there is one
+func HandleSetupCommand(r *CommandRunner) error {
+	steps, missing := r.provisionSteps()
+	for _, message := range missing {
+		fmt.Fprintf(r.statusWriter(), "Warning: %s; skipping\n", message)
+	}
+	install, _ := r.lookupCommand(r.Command)
+	if len(steps) == 0 && install == nil {
+		return r.commandNotFound(r.Command)
+	}
+
+	r.progressf("%sRunning setup (provisioning toolchains, then installing dependencies)...\n", r.badge())
+	for _, step := range steps {
+		r.progressf("\n→ Provisioning from %s...\n", step.name)
+		if err := r.ExecuteCommand(r.provisionCommand(step)); err != nil {
+			return fmt.Errorf("setup failed at %s: %w", strings.Join(step.args, " "), err)
+		}
+	}
+	if install != nil {
+		r.progressf("\n→ Installing dependencies...\n")
+		return r.ExecuteCommand(install)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSetupProvisionsToolchains(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		".tool-versions":      "nodejs 20.11.0\n",
+		"package.json":        `{"packageManager": "pnpm@9.0.0", "scripts": {"build": "tsc"}}`,
+		"pnpm-lock.yaml":      "",
+		"rust-toolchain.toml": "[toolchain]\nchannel = \"1.77\"\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each command that runs appends its command line to a log
+	log := filepath.Join(t.TempDir(), "log")
+	factory := func(name string, arg ...string) *exec.Cmd {
+		line := strings.Join(append([]string{name}, arg...), " ")
+		return exec.Command("sh", "-c", `echo "$1" >> "$2"`, "sh", line, log)
+	}
+	// asdf stands in for mise, and rustup is missing
+	lookPath := func(file string) (string, error) {
+		switch file {
+		case "asdf", "corepack", "pnpm":
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	runner := New("setup", nil, WithCommandFactory(factory), WithLookPath(lookPath))
+	runner.CurrentDir, runner.ProjectRoot = dir, dir
+	if _, err := runner.Resolve(); err == nil || !strings.Contains(err.Error(), "synthesized") {
+		t.Errorf("Resolve() error = %v, want synthesized", err)
+	}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(log)
+	ran := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if want := []string{"asdf install", "corepack enable", "corepack pnpm install"}; !slicesEqual(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+}
+
+func TestSetupWithoutPins(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &CommandRunner{Command: "setup", CurrentDir: dir, ProjectRoot: dir}
+	if runner.provisions() {
+		t.Error("provisions() = true for a project without pins")
+	}
+	cmd, err := runner.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd.Args, " "); got != "go mod download" {
+		t.Errorf("Resolve() = %q, want go mod download", got)
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// forwardedSignals are the signals cmdr passes on to the command it runs
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// runForeground runs cmd in its own process group and forwards the signals
+// cmdr receives to the whole group, so that stopping cmdr also stops what
+// the command spawned (the watchers behind `npm run dev`, say) instead of
+// leaving them orphaned. If ctx is done first, the group is killed.
+func runForeground(ctx context.Context, cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	restore := takeTerminal(cmd)
+	defer restore()
+	return superviseCommand(ctx, cmd, cmd.Start)
+}
+
+// superviseCommand starts cmd with start and waits for it, forwarding
+// signals to its process group meanwhile, and killing the group and
+// returning ctx.Err() if ctx is done first
+func superviseCommand(ctx context.Context, cmd *exec.Cmd, start func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	interrupted := false
+	for {
+		select {
+		case sig := <-signals:
+			interrupted = true
+			forwardSignal(cmd, sig)
+		case err := <-done:
+			// Shells start background jobs ignoring SIGINT, so stop what
+			// outlived an interrupted command
+			if interrupted || killedBySignal(err) {
+				forwardSignal(cmd, syscall.SIGTERM)
+			}
+			if ctx.Err() != nil {
+				// The command died of the cancellation, perhaps by
+				// exec.CommandContext
+				return ctx.Err()
+			}
+			return err
+		case <-ctx.Done():
+			_ = signalProcessGroup(cmd, syscall.SIGKILL)
+			<-done
+			return ctx.Err()
+		}
+	}
+}
+
+// killedBySignal reports whether err is from a command a signal killed,
+// such as Ctrl+C when the command has the terminal
+func killedBySignal(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled()
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunForegroundForwardsSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no process groups on Windows")
+	}
+	dir := t.TempDir()
+	// The grandchild notes the signal that reaches it
+	script := `sh -c 'trap "echo stopped > stopped; exit" TERM; touch started; while :; do sleep 0.05; done' & wait`
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = dir
+
+	go func() {
+		for i := 0; i < 100 && !FileExists(filepath.Join(dir, "started")); i++ {
+			time.Sleep(20 * time.Millisecond)
+		}
+		if self, err := os.FindProcess(os.Getpid()); err == nil {
+			_ = self.Signal(syscall.SIGTERM)
+		}
+	}()
+	if err := runForeground(context.Background(), cmd); !killedBySignal(err) {
+		t.Fatalf("runForeground() = %v, want a command killed by a signal", err)
+	}
+	for i := 0; i < 100 && !FileExists(filepath.Join(dir, "stopped")); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !FileExists(filepath.Join(dir, "stopped")) {
+		t.Error("the signal didn't reach the command's child")
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// sourceMarkers are the files whose presence makes cmdr look for each
+// source, in the order it checks them
+var sourceMarkers = map[string][]string{
+	"mise":       {".mise.toml"},
+	"just":       {"justfile", "Justfile"},
+	"make":       {"Makefile", "makefile"},
+	"npm":        {"package.json"},
+	"pnpm":       {"package.json"},
+	"yarn":       {"package.json"},
+	"bun":        {"package.json"},
+	"Deno":       {"deno.json", "deno.jsonc", "package.json"},
+	"Poetry":     {"pyproject.toml"},
+	"uv":         {"pyproject.toml"},
+	"poe":        {"pyproject.toml"},
+	"Cargo":      {"Cargo.toml"},
+	"cargo-make": {"Cargo.toml", "Makefile.toml"},
+	"xtask":      {"Cargo.toml", filepath.Join("xtask", "Cargo.toml"), filepath.Join(".cargo", "config.toml"), filepath.Join(".cargo", "config")},
+	"Go":         {"go.mod"},
+	"Gradle":     {"build.gradle", "build.gradle.kts"},
+	"Maven":      {"pom.xml"},
+}
+
+// sourceTriggers returns what made cmdr detect source in dir: the marker
+// files that exist, the config file for config sources, and for package
+// managers the evidence that chose this one
+func sourceTriggers(dir string, source CommandSource) []string {
+	if config, ok := source.(*ConfigSource); ok {
+		if rel, err := filepath.Rel(dir, config.path); err == nil && !strings.HasPrefix(rel, "..") {
+			return []string{rel}
+		}
+		return []string{config.path}
+	}
+	var triggers []string
+	for _, file := range sourceMarkers[source.Name()] {
+		if FileExists(filepath.Join(dir, file)) {
+			triggers = append(triggers, file)
+		}
+	}
+	var evidence []pmEvidence
+	switch source.(type) {
+	case *NpmSource, *PnpmSource, *YarnSource, *BunSource:
+		evidence = nodeManagerEvidence(dir)
+	case *PoetrySource, *UvSource:
+		evidence = pythonManagerEvidence(dir)
+	case *PoeSource:
+		triggers = []string{"pyproject.toml [tool.poe.tasks]"}
+	}
+	for _, e := range evidence {
+		if e.manager == strings.ToLower(source.Name()) {
+			triggers = append(triggers, e.what)
+			break
+		}
+	}
+	return triggers
+}
+
+// ShowSources writes the command sources detected in the current directory
+// and project root, in the order lookup consults them, with each one's
+// priority, the files that triggered it, and whether its tool is installed
+func (r *CommandRunner) ShowSources(w io.Writer) error {
+	found := false
+	for _, project := range r.projects() {
+		if len(project.CommandSources) == 0 {
+			continue
+		}
+		if found {
+			fmt.Fprintln(w)
+		}
+		found = true
+		fmt.Fprintln(w, project.Dir)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for i, source := range project.CommandSources {
+			line := fmt.Sprintf("  %d. %s\tpriority %d\t%s", i+1, source.Name(), source.Priority(), strings.Join(sourceTriggers(project.Dir, source), ", "))
+			if tools := r.sourceTools(w, project.Dir, source); tools != "" {
+				line += "\t" + tools
+			}
+			fmt.Fprintln(tw, line)
+		}
+		_ = tw.Flush()
+	}
+	if !found {
+		return &exitError{ExitNotFound, fmt.Errorf("no command sources in current directory or project root"), ErrNoSources}
+	}
+	return nil
+}
+
+// sourceTools describes whether the programs a source runs are available,
+// as doctor checks them, or is "" for sources that don't run one
+func (r *CommandRunner) sourceTools(w io.Writer, dir string, source CommandSource) string {
+	if _, ok := sourcePrograms[source.Name()]; !ok {
+		return ""
+	}
+	var tools []string
+	for _, program := range append([]string{sourceProgram(source)}, sourceRequires[source.Name()]...) {
+		switch path, err := r.executor.lookPath(program); {
+		case strings.HasPrefix(program, "./") && FileExists(filepath.Join(dir, program)):
+			tools = append(tools, passMark(w, true)+" "+program)
+		case strings.HasPrefix(program, "./"):
+			tools = append(tools, passMark(w, false)+" "+program+" is missing")
+		case err != nil:
+			tools = append(tools, passMark(w, false)+" "+program+" not on PATH")
+		default:
+			tools = append(tools, passMark(w, true)+" "+path)
+		}
+	}
+	return strings.Join(tools, ", ")
+}
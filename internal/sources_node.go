@@ -14,22 +14,23 @@ type nodeBaseSource struct {
 }
 
 func (n *nodeBaseSource) ListCommands() map[string]CommandInfo {
-	scripts, err := parsePackageJsonScripts(n.dir)
+	pkg, err := readPackageJSON(n.dir)
 	if err != nil {
 		return map[string]CommandInfo{}
 	}
 
+	executable := n.executable()
 	commands := make(map[string]CommandInfo)
-	for script, content := range scripts {
+	for script := range pkg.Scripts {
 		execution := ""
 		if n.packageManager == "deno" {
 			execution = "deno task " + script
 		} else {
-			execution = n.packageManager + " run " + script
+			execution = executable + " run " + script
 		}
 
 		commands[script] = CommandInfo{
-			Description: content,
+			Description: scriptDescription(pkg, script),
 			Execution:   execution,
 		}
 	}
@@ -38,7 +39,7 @@ func (n *nodeBaseSource) ListCommands() map[string]CommandInfo {
 	if _, exists := commands["setup"]; !exists && n.packageManager != "deno" {
 		commands["setup"] = CommandInfo{
 			Description: "Install dependencies",
-			Execution:   n.packageManager + " install",
+			Execution:   executable + " install",
 		}
 	}
 	if _, exists := commands["install"]; !exists && n.packageManager != "deno" {
@@ -48,13 +49,51 @@ func (n *nodeBaseSource) ListCommands() map[string]CommandInfo {
 		}
 		commands["install"] = CommandInfo{
 			Description: "Link binary globally",
-			Execution:   n.packageManager + " " + linkCmd,
+			Execution:   executable + " " + linkCmd,
+		}
+	}
+	for name, description := range map[string]string{
+		"outdated": "List outdated dependencies",
+		"update":   "Update dependencies",
+		"publish":  "Publish the package to the registry",
+	} {
+		if _, exists := commands[name]; exists || n.packageManager == "deno" {
+			continue
+		}
+		if managerArgs := n.managerDefault(name); managerArgs != nil {
+			commands[name] = CommandInfo{
+				Description: description,
+				Execution:   executable + " " + strings.Join(managerArgs, " "),
+			}
+		}
+	}
+
+	if !n.DefinesCommand("fmt-check") && n.packageManager != "deno" && n.usesPrettier() {
+		commands["fmt-check"] = CommandInfo{
+			Description: "Check formatting with Prettier",
+			Execution:   strings.Join(n.binArgs([]string{"prettier", "--check", "."}), " "),
 		}
 	}
 
 	return commands
 }
 
+// DefinesCommand reports whether package.json has a script for command;
+// setup, install, and typecheck otherwise fall back to package manager
+// defaults
+func (n *nodeBaseSource) DefinesCommand(command string) bool {
+	scripts, err := parsePackageJsonScripts(n.dir)
+	if err != nil {
+		return false
+	}
+	for _, variant := range GetCommandVariants(command) {
+		if _, ok := scripts[variant]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *nodeBaseSource) FindCommand(command string, args []string) *exec.Cmd {
 	scripts, err := parsePackageJsonScripts(n.dir)
 	if err != nil {
@@ -73,10 +112,7 @@ func (n *nodeBaseSource) FindCommand(command string, args []string) *exec.Cmd {
 
 	// Special handling for setup command
 	if !scriptExists && command == "setup" && n.packageManager != "deno" {
-		cmdArgs := append([]string{"install"}, args...)
-		cmd := exec.Command(n.packageManager, cmdArgs...)
-		cmd.Dir = n.dir
-		return cmd
+		return n.managerCommand(append([]string{"install"}, args...))
 	}
 
 	// Special handling for install command (link binary globally)
@@ -87,50 +123,28 @@ func (n *nodeBaseSource) FindCommand(command string, args []string) *exec.Cmd {
 		} else {
 			cmdArgs = append([]string{"link"}, args...)
 		}
-		cmd := exec.Command(n.packageManager, cmdArgs...)
-		cmd.Dir = n.dir
-		return cmd
+		return n.managerCommand(cmdArgs)
+	}
+
+	// Dependency and publish commands use the package manager's own
+	if !scriptExists && n.packageManager != "deno" {
+		if managerArgs := n.managerDefault(command); managerArgs != nil {
+			return n.managerCommand(append(managerArgs, args...))
+		}
 	}
 
 	// Special handling for typecheck in TypeScript projects
 	if !scriptExists && command == "typecheck" {
 		if FileExists(filepath.Join(n.dir, "tsconfig.json")) {
-			// Use tsc for TypeScript type checking with appropriate package manager syntax
-			var cmdName string
-			var cmdArgs []string
-
-			switch n.packageManager {
-			case "npm":
-				// npm requires npx to run node_modules/.bin executables
-				cmdName = "npx"
-				cmdArgs = append([]string{"tsc", "--noEmit"}, args...)
-			case "pnpm":
-				// pnpm exec is the equivalent of npx
-				cmdName = "pnpm"
-				cmdArgs = append([]string{"exec", "tsc", "--noEmit"}, args...)
-			case "yarn":
-				// yarn run works for node_modules/.bin executables
-				cmdName = "yarn"
-				cmdArgs = append([]string{"run", "tsc", "--noEmit"}, args...)
-			case "bun":
-				// bun run works for node_modules/.bin executables
-				cmdName = "bun"
-				cmdArgs = append([]string{"run", "tsc", "--noEmit"}, args...)
-			case "deno":
-				// Deno projects should use "deno check" instead - skip tsc
-				return nil
-			default:
-				// Fallback: try npx
-				cmdName = "npx"
-				cmdArgs = append([]string{"tsc", "--noEmit"}, args...)
-			}
-
-			cmd := exec.Command(cmdName, cmdArgs...)
-			cmd.Dir = n.dir
-			return cmd
+			return n.binCommand(append([]string{"tsc", "--noEmit"}, args...))
 		}
 	}
 
+	// Without a script, a project that uses Prettier checks with it
+	if !scriptExists && NormalizeCommand(command) == "fmt-check" && n.usesPrettier() {
+		return n.binCommand(append([]string{"prettier", "--check", "."}, args...))
+	}
+
 	if !scriptExists {
 		return nil
 	}
@@ -138,7 +152,7 @@ func (n *nodeBaseSource) FindCommand(command string, args []string) *exec.Cmd {
 	// Deno uses "task" instead of "run"
 	if n.packageManager == "deno" {
 		cmdArgs := append([]string{"task", command}, args...)
-		cmd := exec.Command(n.packageManager, cmdArgs...)
+		cmd := n.command(n.packageManager, cmdArgs...)
 		cmd.Dir = n.dir
 		return cmd
 	}
@@ -150,11 +164,146 @@ func (n *nodeBaseSource) FindCommand(command string, args []string) *exec.Cmd {
 	} else {
 		cmdArgs = append([]string{"run", command}, args...)
 	}
-	cmd := exec.Command(n.packageManager, cmdArgs...)
+	return n.managerCommand(cmdArgs)
+}
+
+// binCommand runs a node_modules/.bin executable with the package manager's
+// equivalent of npx. Deno projects have none.
+func (n *nodeBaseSource) binCommand(args []string) *exec.Cmd {
+	argv := n.binArgs(args)
+	if argv == nil {
+		return nil
+	}
+	cmd := n.command(argv[0], argv[1:]...)
+	cmd.Dir = n.dir
+	return cmd
+}
+
+// binArgs returns the command line of binCommand
+func (n *nodeBaseSource) binArgs(args []string) []string {
+	switch n.packageManager {
+	case "npm":
+		// npm requires npx to run node_modules/.bin executables
+		return append([]string{"npx"}, args...)
+	case "pnpm":
+		// pnpm exec is the equivalent of npx
+		return append([]string{"pnpm", "exec"}, args...)
+	case "yarn":
+		// yarn run works for node_modules/.bin executables
+		return append([]string{"yarn", "run"}, args...)
+	case "bun":
+		// bun run works for node_modules/.bin executables
+		return append([]string{"bun", "run"}, args...)
+	case "deno":
+		// Deno projects use their own built-ins instead
+		return nil
+	default:
+		// Fallback: try npx
+		return append([]string{"npx"}, args...)
+	}
+}
+
+// usesPrettier reports whether package.json depends on Prettier or the
+// project has a Prettier config
+func (n *nodeBaseSource) usesPrettier() bool {
+	if pkg, err := readPackageJSON(n.dir); err == nil {
+		if _, ok := pkg.DevDependencies["prettier"]; ok {
+			return true
+		}
+		if _, ok := pkg.Dependencies["prettier"]; ok {
+			return true
+		}
+		if pkg.Prettier != nil {
+			return true
+		}
+	}
+	for _, name := range []string{".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml", ".prettierrc.js", ".prettierrc.cjs", ".prettierrc.mjs", ".prettierrc.toml", "prettier.config.js", "prettier.config.cjs", "prettier.config.mjs"} {
+		if FileExists(filepath.Join(n.dir, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// launcher returns the program and leading arguments used to invoke the
+// package manager. When package.json pins this manager with its
+// "packageManager" field and Corepack is installed, commands go through
+// Corepack so that the pinned version is used.
+func (n *nodeBaseSource) launcher() []string {
+	if name, _ := pinnedPackageManager(n.dir); name == n.packageManager && corepackManages(name) {
+		if _, err := n.executor.lookPath("corepack"); err == nil {
+			return []string{"corepack", n.packageManager}
+		}
+	}
+	return []string{n.packageManager}
+}
+
+// executable returns the package manager invocation for display
+func (n *nodeBaseSource) executable() string {
+	return strings.Join(n.launcher(), " ")
+}
+
+// managerDefault returns the package manager's arguments for the outdated,
+// update, or publish command, or nil if it has none
+func (n *nodeBaseSource) managerDefault(command string) []string {
+	// Yarn 2+ replaced outdated and upgrade with up, and moved publish
+	berry := n.packageManager == "yarn" && FileExists(filepath.Join(n.dir, ".yarnrc.yml"))
+	switch NormalizeCommand(command) {
+	case "publish":
+		// A private package can't be published
+		if pkg, err := readPackageJSON(n.dir); err != nil || pkg.Private {
+			return nil
+		}
+		if berry {
+			return []string{"npm", "publish"}
+		}
+		return []string{"publish"}
+	case "outdated":
+		if berry {
+			return nil
+		}
+		return []string{"outdated"}
+	case "update":
+		switch {
+		case berry:
+			return []string{"up", "*"}
+		case n.packageManager == "yarn":
+			return []string{"upgrade"}
+		}
+		return []string{"update"}
+	}
+	return nil
+}
+
+// managerCommand returns a command that runs the package manager with args
+func (n *nodeBaseSource) managerCommand(args []string) *exec.Cmd {
+	launcher := n.launcher()
+	cmd := n.command(launcher[0], append(launcher[1:], args...)...)
 	cmd.Dir = n.dir
 	return cmd
 }
 
+// pinnedPackageManager returns the name and version from the package.json
+// "packageManager" field (e.g. "pnpm@8.15.0+sha512..."), or "" if unset
+func pinnedPackageManager(dir string) (string, string) {
+	pkg, err := readPackageJSON(dir)
+	if err != nil || pkg.PackageManager == "" {
+		return "", ""
+	}
+	name, version, _ := strings.Cut(pkg.PackageManager, "@")
+	version, _, _ = strings.Cut(version, "+")
+	switch name {
+	case "npm", "pnpm", "yarn", "bun":
+		return name, version
+	}
+	return "", ""
+}
+
+// corepackManages reports whether Corepack can provide the package manager
+func corepackManages(name string) bool {
+	return name == "npm" || name == "pnpm" || name == "yarn"
+}
+
 // NpmSource for npm projects
 type NpmSource struct {
 	nodeBaseSource
@@ -247,27 +396,42 @@ func (d *DenoSource) ListCommands() map[string]CommandInfo {
 
 	// Check if there's a package.json (Deno can use it)
 	if FileExists(filepath.Join(d.dir, "package.json")) {
-		if scripts, err := parsePackageJsonScripts(d.dir); err == nil {
-			for script, content := range scripts {
+		if pkg, err := readPackageJSON(d.dir); err == nil {
+			for script := range pkg.Scripts {
 				commands[script] = CommandInfo{
-					Description: content,
+					Description: scriptDescription(pkg, script),
 					Execution:   "deno task " + script,
 				}
 			}
 		}
 	}
 
-	// Add standard Deno commands
+	for name, info := range d.tasks() {
+		commands[name] = info
+	}
+
+	// Add standard Deno commands, which take precedence over tasks
 	commands["run"] = CommandInfo{Description: "Run a script", Execution: "deno run"}
 	commands["test"] = CommandInfo{Description: "Run tests", Execution: "deno test"}
 	commands["lint"] = CommandInfo{Description: "Run linter", Execution: "deno lint"}
 	commands["format"] = CommandInfo{Description: "Format code", Execution: "deno fmt"}
+	commands["fmt-check"] = CommandInfo{Description: "Check formatting", Execution: "deno fmt --check"}
 	commands["check"] = CommandInfo{Description: "Type-check code", Execution: "deno check"}
 	commands["build"] = CommandInfo{Description: "Compile to executable", Execution: "deno compile"}
+	commands["outdated"] = CommandInfo{Description: "List outdated dependencies", Execution: "deno outdated"}
+	commands["update"] = CommandInfo{Description: "Update dependencies", Execution: "deno outdated --update"}
+	commands["publish"] = CommandInfo{Description: "Publish to JSR", Execution: "deno publish"}
 
 	return commands
 }
 
+// DefinesCommand reports whether command runs a deno.json task rather than
+// a Deno built-in
+func (d *DenoSource) DefinesCommand(command string) bool {
+	cmd := d.FindCommand(command, nil)
+	return cmd != nil && len(cmd.Args) > 1 && cmd.Args[1] == "task"
+}
+
 func (d *DenoSource) FindCommand(command string, args []string) *exec.Cmd {
 	// Deno built-in commands
 	denoCommands := map[string]string{
@@ -278,11 +442,15 @@ func (d *DenoSource) FindCommand(command string, args []string) *exec.Cmd {
 		"lint":      "lint",
 		"format":    "fmt",
 		"fmt":       "fmt",
+		"fmt-check": "fmt",
 		"typecheck": "check",
 		"tc":        "check",
 		"check":     "check",
 		"build":     "compile",
 		"install":   "install",
+		"outdated":  "outdated",
+		"update":    "outdated",
+		"publish":   "publish",
 	}
 
 	for _, variant := range GetCommandVariants(command) {
@@ -293,45 +461,83 @@ func (d *DenoSource) FindCommand(command string, args []string) *exec.Cmd {
 				for _, entry := range []string{"main.ts", "main.js", "mod.ts", "mod.js", "index.ts", "index.js"} {
 					if FileExists(filepath.Join(d.dir, entry)) {
 						cmdArgs := append([]string{"run", "--allow-all", entry}, args...)
-						cmd := exec.Command("deno", cmdArgs...)
+						cmd := d.command("deno", cmdArgs...)
 						cmd.Dir = d.dir
 						return cmd
 					}
 				}
 			}
 			cmdArgs := append([]string{denoCmd}, args...)
-			cmd := exec.Command("deno", cmdArgs...)
+			if variant == "update" {
+				cmdArgs = append([]string{denoCmd, "--update"}, args...)
+			} else if variant == "fmt-check" {
+				cmdArgs = append([]string{denoCmd, "--check"}, args...)
+			}
+			cmd := d.command("deno", cmdArgs...)
 			cmd.Dir = d.dir
 			return cmd
 		}
 	}
 
 	// Check if there's a task defined in deno.json
-	if FileExists(filepath.Join(d.dir, "deno.json")) || FileExists(filepath.Join(d.dir, "deno.jsonc")) {
-		for _, variant := range GetCommandVariants(command) {
-			// Try to run as a task
-			testCmd := exec.Command("deno", "task", "--list")
-			testCmd.Dir = d.dir
-			output, err := testCmd.Output()
-			if err == nil && strings.Contains(string(output), variant) {
-				cmdArgs := append([]string{"task", variant}, args...)
-				cmd := exec.Command("deno", cmdArgs...)
-				cmd.Dir = d.dir
-				return cmd
-			}
-		}
+	if variant, ok := findVariant(command, d.tasks()); ok {
+		cmdArgs := append([]string{"task", variant}, args...)
+		cmd := d.command("deno", cmdArgs...)
+		cmd.Dir = d.dir
+		return cmd
 	}
 
 	return nil
 }
 
+// tasks returns the tasks in deno.json or deno.jsonc, asking deno for them
+// only if the file can't be parsed
+func (d *DenoSource) tasks() map[string]CommandInfo {
+	commands := make(map[string]CommandInfo)
+	if !FileExists(filepath.Join(d.dir, "deno.json")) && !FileExists(filepath.Join(d.dir, "deno.jsonc")) {
+		return commands
+	}
+	if tasks := parseDenoTasks(d.dir); tasks != nil || d.static() || !d.hasTool("deno") {
+		for name, task := range tasks {
+			commands[name] = CommandInfo{Description: task, Execution: "deno task " + name}
+		}
+		return commands
+	}
+	testCmd := d.command("deno", "task", "--list")
+	testCmd.Dir = d.dir
+	output, err := listOutput(testCmd)
+	if err != nil {
+		return commands
+	}
+	// Each task is a line "- name" (or "  name" in older versions),
+	// followed by its command indented further
+	for _, line := range strings.Split(string(output), "\n") {
+		name, ok := strings.CutPrefix(line, "- ")
+		if !ok && strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "   ") {
+			name, ok = strings.TrimSpace(line), true
+		}
+		if fields := strings.Fields(name); ok && len(fields) > 0 {
+			commands[fields[0]] = CommandInfo{Execution: "deno task " + fields[0]}
+		}
+	}
+	return commands
+}
+
 // detectPackageManager determines which Node.js package manager to use
 func detectPackageManager(dir string) string {
+	// A pin in .cmdr.toml, then the packageManager field, is authoritative
+	if name := pinnedManager(dir, "node"); name != "" {
+		return name
+	}
+	if name, _ := pinnedPackageManager(dir); name != "" {
+		return name
+	}
+
 	// Priority order: bun > pnpm > yarn > npm > deno
 	// Based on lockfiles first, then config files
 
 	// Check lockfiles first for accurate detection
-	if FileExists(filepath.Join(dir, "bun.lockb")) {
+	if FileExists(filepath.Join(dir, "bun.lockb")) || FileExists(filepath.Join(dir, "bun.lock")) {
 		return "bun"
 	}
 
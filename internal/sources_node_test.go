@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNodeScriptDescriptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		packageJSON string
+		expected    map[string]string
+	}{
+		{
+			name: "no description metadata",
+			packageJSON: `{
+				"scripts": {"build": "tsc -p ."}
+			}`,
+			expected: map[string]string{"build": "tsc -p ."},
+		},
+		{
+			name: "scripts-info",
+			packageJSON: `{
+				"scripts": {"build": "tsc -p .", "test": "vitest"},
+				"scripts-info": {"build": "Compile TypeScript"}
+			}`,
+			expected: map[string]string{"build": "Compile TypeScript", "test": "vitest"},
+		},
+		{
+			name: "ntl descriptions",
+			packageJSON: `{
+				"scripts": {"dev": "vite"},
+				"ntl": {"descriptions": {"dev": "Start the dev server"}}
+			}`,
+			expected: map[string]string{"dev": "Start the dev server"},
+		},
+		{
+			name: "scripts-description",
+			packageJSON: `{
+				"scripts": {"lint": "eslint ."},
+				"scripts-description": {"lint": "Lint sources"}
+			}`,
+			expected: map[string]string{"lint": "Lint sources"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tempDir, "package.json"), []byte(tt.packageJSON), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			commands := NewNpmSource(tempDir).ListCommands()
+			for script, description := range tt.expected {
+				if info := commands[script]; info.Description != description {
+					t.Errorf("ListCommands()[%q].Description = %q, want %q", script, info.Description, description)
+				}
+			}
+		})
+	}
+}
+
+func TestPackageManagerField(t *testing.T) {
+	tempDir := t.TempDir()
+	pkg := `{"packageManager": "pnpm@8.15.0+sha512.abc", "scripts": {"build": "tsc"}}`
+	if err := os.WriteFile(filepath.Join(tempDir, "package.json"), []byte(pkg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A stale lockfile from another manager should not win over the pinned field
+	if err := os.WriteFile(filepath.Join(tempDir, "package-lock.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	name, version := pinnedPackageManager(tempDir)
+	if name != "pnpm" || version != "8.15.0" {
+		t.Errorf("pinnedPackageManager() = %q, %q, want pnpm, 8.15.0", name, version)
+	}
+	if pm := detectPackageManager(tempDir); pm != "pnpm" {
+		t.Errorf("detectPackageManager() = %q, want pnpm", pm)
+	}
+
+	source := detectNodeProject(tempDir)
+	if source.Name() != "pnpm" {
+		t.Fatalf("detectNodeProject().Name() = %q, want pnpm", source.Name())
+	}
+
+	// Without corepack on PATH, the manager is invoked directly
+	t.Setenv("PATH", t.TempDir())
+	cmd := source.FindCommand("build", nil)
+	if cmd == nil || !slicesEqual(cmd.Args, []string{"pnpm", "run", "build"}) {
+		t.Errorf("FindCommand(build) = %v", cmd)
+	}
+
+	// With corepack on PATH, the pinned manager runs through it
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "corepack"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+	cmd = source.FindCommand("build", nil)
+	if cmd == nil || !slicesEqual(cmd.Args, []string{"corepack", "pnpm", "run", "build"}) {
+		t.Errorf("FindCommand(build) with corepack = %v", cmd)
+	}
+}
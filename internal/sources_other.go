@@ -7,92 +7,6 @@ import (
 	"strings"
 )
 
-// CargoSource for Rust projects
-type CargoSource struct {
-	baseSource
-}
-
-func NewCargoSource(dir string) CommandSource {
-	if !FileExists(filepath.Join(dir, "Cargo.toml")) {
-		return nil
-	}
-
-	return &CargoSource{
-		baseSource: baseSource{
-			dir:      dir,
-			name:     "Cargo",
-			priority: 10,
-		},
-	}
-}
-
-func (c *CargoSource) ListCommands() map[string]CommandInfo {
-	return map[string]CommandInfo{
-		"build":   {Description: "Build the project", Execution: "cargo build"},
-		"run":     {Description: "Run the project", Execution: "cargo run"},
-		"test":    {Description: "Run tests", Execution: "cargo test"},
-		"check":   {Description: "Check code for errors", Execution: "cargo check"},
-		"format":  {Description: "Format code", Execution: "cargo fmt"},
-		"lint":    {Description: "Run clippy linter", Execution: "cargo clippy"},
-		"clean":   {Description: "Clean build artifacts", Execution: "cargo clean"},
-		"setup":   {Description: "Download dependencies", Execution: "cargo fetch"},
-		"install": {Description: "Install binary globally", Execution: "cargo install --path ."},
-	}
-}
-
-func (c *CargoSource) FindCommand(command string, args []string) *exec.Cmd {
-	cargoCommands := map[string]string{
-		"build":     "build",
-		"run":       "run",
-		"test":      "test",
-		"lint":      "clippy",
-		"format":    "fmt",
-		"fmt":       "fmt",
-		"clean":     "clean",
-		"typecheck": "check",
-		"tc":        "check",
-		"check":     "check",
-		"fix":       "fix",
-		"setup":     "fetch",
-		"install":   "install",
-		"publish":   "publish",
-	}
-
-	for _, variant := range GetCommandVariants(command) {
-		if cargoCmd, ok := cargoCommands[variant]; ok {
-			var cmdArgs []string
-			if cargoCmd == "install" {
-				// Modern cargo requires --path for installing from current directory
-				cmdArgs = append([]string{"install", "--path", "."}, args...)
-			} else {
-				cmdArgs = append([]string{cargoCmd}, args...)
-			}
-			cmd := exec.Command("cargo", cmdArgs...)
-			cmd.Dir = c.dir
-			return cmd
-		}
-	}
-
-	// Try to handle custom binary targets
-	cargoToml := filepath.Join(c.dir, "Cargo.toml")
-	if data, err := os.ReadFile(cargoToml); err == nil {
-		content := string(data)
-
-		// Check for binary targets (run:binary-name pattern)
-		if strings.HasPrefix(command, "run:") {
-			binName := strings.TrimPrefix(command, "run:")
-			if strings.Contains(content, `name = "`+binName+`"`) {
-				cmdArgs := append([]string{"run", "--bin", binName}, args...)
-				cmd := exec.Command("cargo", cmdArgs...)
-				cmd.Dir = c.dir
-				return cmd
-			}
-		}
-	}
-
-	return nil
-}
-
 // GoSource for Go projects
 type GoSource struct {
 	baseSource
@@ -113,16 +27,39 @@ func NewGoSource(dir string) CommandSource {
 }
 
 func (g *GoSource) ListCommands() map[string]CommandInfo {
-	return map[string]CommandInfo{
-		"build":   {Description: "Build the project", Execution: "go build"},
-		"run":     {Description: "Run the project", Execution: "go run ."},
-		"test":    {Description: "Run tests", Execution: "go test ./..."},
-		"format":  {Description: "Format code", Execution: "go fmt ./..."},
-		"lint":    {Description: "Run linter", Execution: "go vet ./..."},
-		"clean":   {Description: "Clean build artifacts", Execution: "go clean"},
-		"setup":   {Description: "Download dependencies", Execution: "go mod download"},
-		"install": {Description: "Install binary globally", Execution: "go install ."},
+	commands := map[string]CommandInfo{
+		"build":     {Description: "Build the project", Execution: "go build"},
+		"run":       {Description: "Run the project", Execution: "go run ."},
+		"test":      {Description: "Run tests", Execution: "go test ./..."},
+		"format":    {Description: "Format code", Execution: "go fmt ./..."},
+		"lint":      {Description: "Run linter", Execution: "go vet ./..."},
+		"clean":     {Description: "Clean build artifacts", Execution: "go clean"},
+		"setup":     {Description: "Download dependencies", Execution: "go mod download"},
+		"install":   {Description: "Install binary globally", Execution: "go install ."},
+		"outdated":  {Description: "List modules with available updates", Execution: "go list -u -m all"},
+		"update":    {Description: "Update dependencies", Execution: "go get -u ./..."},
+		"fmt-check": {Description: "List unformatted files", Execution: "gofmt -l -d ."},
+	}
+	if g.usesGoReleaser() {
+		commands["publish"] = CommandInfo{Description: "Release with GoReleaser", Execution: "goreleaser release --clean"}
+	}
+	return commands
+}
+
+// usesGoReleaser reports whether the project has a GoReleaser config, which
+// makes publish a GoReleaser release
+func (g *GoSource) usesGoReleaser() bool {
+	for _, name := range []string{".goreleaser.yaml", ".goreleaser.yml", "goreleaser.yaml", "goreleaser.yml"} {
+		if FileExists(filepath.Join(g.dir, name)) {
+			return true
+		}
 	}
+	return false
+}
+
+// DefinesCommand is false: every Go command is a toolchain default
+func (g *GoSource) DefinesCommand(command string) bool {
+	return false
 }
 
 func (g *GoSource) FindCommand(command string, args []string) *exec.Cmd {
@@ -138,12 +75,25 @@ func (g *GoSource) FindCommand(command string, args []string) *exec.Cmd {
 		"lint":      {"vet", "./..."},
 		"typecheck": {"build", "-o", os.DevNull, "./..."},
 		"tc":        {"build", "-o", os.DevNull, "./..."},
+		"outdated":  {"list", "-u", "-m", "all"},
+		"update":    {"get", "-u", "./..."},
 	}
 
 	for _, variant := range GetCommandVariants(command) {
+		// gofmt -d, unlike go fmt -n, fails when a file isn't formatted
+		if variant == "fmt-check" {
+			cmd := g.command("gofmt", append([]string{"-l", "-d", "."}, args...)...)
+			cmd.Dir = g.dir
+			return cmd
+		}
+		if variant == "publish" && g.usesGoReleaser() {
+			cmd := g.command("goreleaser", append([]string{"release", "--clean"}, args...)...)
+			cmd.Dir = g.dir
+			return cmd
+		}
 		if goCmd, ok := goCommands[variant]; ok {
 			cmdArgs := append(goCmd, args...)
-			cmd := exec.Command("go", cmdArgs...)
+			cmd := g.command("go", cmdArgs...)
 			cmd.Dir = g.dir
 			return cmd
 		}
@@ -188,6 +138,11 @@ func (g *GradleSource) ListCommands() map[string]CommandInfo {
 	}
 }
 
+// DefinesCommand is false: Gradle commands are standard lifecycle tasks
+func (g *GradleSource) DefinesCommand(command string) bool {
+	return false
+}
+
 func (g *GradleSource) FindCommand(command string, args []string) *exec.Cmd {
 	gradleExec := "gradle"
 	if FileExists(filepath.Join(g.dir, "gradlew")) {
@@ -214,7 +169,7 @@ func (g *GradleSource) FindCommand(command string, args []string) *exec.Cmd {
 			} else {
 				cmdArgs = append([]string{gradleCmd}, args...)
 			}
-			cmd := exec.Command(gradleExec, cmdArgs...)
+			cmd := g.command(gradleExec, cmdArgs...)
 			cmd.Dir = g.dir
 			return cmd
 		}
@@ -258,6 +213,11 @@ func (m *MavenSource) ListCommands() map[string]CommandInfo {
 	}
 }
 
+// DefinesCommand is false: Maven commands are standard lifecycle phases
+func (m *MavenSource) DefinesCommand(command string) bool {
+	return false
+}
+
 func (m *MavenSource) FindCommand(command string, args []string) *exec.Cmd {
 	mvnExec := "mvn"
 	if FileExists(filepath.Join(m.dir, "mvnw")) {
@@ -277,7 +237,7 @@ func (m *MavenSource) FindCommand(command string, args []string) *exec.Cmd {
 	for _, variant := range GetCommandVariants(command) {
 		if mvnCmd, ok := mavenCommands[variant]; ok {
 			cmdArgs := append([]string{mvnCmd}, args...)
-			cmd := exec.Command(mvnExec, cmdArgs...)
+			cmd := m.command(mvnExec, cmdArgs...)
 			cmd.Dir = m.dir
 			return cmd
 		}
@@ -5,16 +5,97 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/BurntSushi/toml"
 )
 
+// pyprojectManifest holds the parts of pyproject.toml that cmd-runner cares about
+type pyprojectManifest struct {
+	Project struct {
+		Scripts map[string]string `toml:"scripts"`
+	} `toml:"project"`
+	Tool struct {
+		Poetry struct {
+			Scripts map[string]any `toml:"scripts"`
+		} `toml:"poetry"`
+		Poe struct {
+			Tasks map[string]any `toml:"tasks"`
+		} `toml:"poe"`
+	} `toml:"tool"`
+}
+
+// parsePyproject reads and decodes pyproject.toml in dir
+func parsePyproject(dir string) (*pyprojectManifest, error) {
+	var manifest pyprojectManifest
+	if _, err := toml.DecodeFile(filepath.Join(dir, "pyproject.toml"), &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// entryPoints returns console script names mapped to their "module:function"
+// targets, from both [project.scripts] and [tool.poetry.scripts]
+func (m *pyprojectManifest) entryPoints() map[string]string {
+	scripts := make(map[string]string)
+	for name, target := range m.Tool.Poetry.Scripts {
+		switch t := target.(type) {
+		case string:
+			scripts[name] = t
+		case map[string]any:
+			// Table form: { callable = "pkg.module:main" } or { reference = ..., type = "file" }
+			if callable, ok := t["callable"].(string); ok {
+				scripts[name] = callable
+			} else if reference, ok := t["reference"].(string); ok {
+				scripts[name] = reference
+			}
+		}
+	}
+	for name, target := range m.Project.Scripts {
+		scripts[name] = target
+	}
+	return scripts
+}
+
+// addEntryPointCommands adds a run:<name> command for each console script,
+// executed through runner (e.g. "uv run" or "poetry run")
+func addEntryPointCommands(commands map[string]CommandInfo, dir string, runner string) {
+	manifest, err := parsePyproject(dir)
+	if err != nil {
+		return
+	}
+	for name, target := range manifest.entryPoints() {
+		commands["run:"+name] = CommandInfo{
+			Description: "Run " + target,
+			Execution:   runner + " " + name,
+		}
+	}
+}
+
+// findEntryPoint returns the console script name for a run:<name> command,
+// or "" if command does not name an entry point in dir
+func findEntryPoint(dir string, command string) string {
+	name, ok := strings.CutPrefix(command, "run:")
+	if !ok {
+		return ""
+	}
+	manifest, err := parsePyproject(dir)
+	if err != nil {
+		return ""
+	}
+	if _, exists := manifest.entryPoints()[name]; !exists {
+		return ""
+	}
+	return name
+}
+
 // PoetrySource for Poetry projects
 type PoetrySource struct {
 	baseSource
 }
 
 func NewPoetrySource(dir string) CommandSource {
-	// Verify it's actually a Poetry project
-	if !FileExists(filepath.Join(dir, "poetry.lock")) {
+	// Verify it's actually a Poetry project, or one pinned to Poetry
+	if !FileExists(filepath.Join(dir, "poetry.lock")) && pinnedManager(dir, "python") != "poetry" {
 		// Check if pyproject.toml contains [tool.poetry]
 		if FileExists(filepath.Join(dir, "pyproject.toml")) {
 			data, err := os.ReadFile(filepath.Join(dir, "pyproject.toml"))
@@ -36,7 +117,7 @@ func NewPoetrySource(dir string) CommandSource {
 }
 
 func (p *PoetrySource) ListCommands() map[string]CommandInfo {
-	return map[string]CommandInfo{
+	commands := map[string]CommandInfo{
 		"setup":     {Description: "Install dependencies for development", Execution: "poetry install"},
 		"install":   {Description: "Install package globally", Execution: "pip install ."},
 		"run":       {Description: "Run Python interpreter", Execution: "poetry run python"},
@@ -46,7 +127,18 @@ func (p *PoetrySource) ListCommands() map[string]CommandInfo {
 		"typecheck": {Description: "Run type checker", Execution: "poetry run pyright"},
 		"build":     {Description: "Build distribution", Execution: "poetry build"},
 		"publish":   {Description: "Publish to PyPI", Execution: "poetry publish"},
+		"outdated":  {Description: "List outdated dependencies", Execution: "poetry show --outdated"},
+		"update":    {Description: "Update dependencies", Execution: "poetry update"},
+		"fmt-check": {Description: "Check formatting", Execution: "poetry run ruff format --check"},
 	}
+	addEntryPointCommands(commands, p.dir, "poetry run")
+	return commands
+}
+
+// DefinesCommand reports whether command runs an entry point from
+// pyproject.toml; other commands run Poetry defaults
+func (p *PoetrySource) DefinesCommand(command string) bool {
+	return findEntryPoint(p.dir, command) != ""
 }
 
 func (p *PoetrySource) FindCommand(command string, args []string) *exec.Cmd {
@@ -62,13 +154,23 @@ func (p *PoetrySource) FindCommand(command string, args []string) *exec.Cmd {
 		"tc":        {"run", "pyright"},
 		"build":     {"build"},
 		"publish":   {"publish"},
+		"outdated":  {"show", "--outdated"},
+		"update":    {"update"},
+		"fmt-check": {"run", "ruff", "format", "--check"},
 	}
 
 	// Check for install first (before variant matching)
 	// This ensures "install" doesn't accidentally match "setup" as a variant
 	if command == "install" {
 		cmdArgs := append([]string{"install", "."}, args...)
-		cmd := exec.Command("pip", cmdArgs...)
+		cmd := p.command("pip", cmdArgs...)
+		cmd.Dir = p.dir
+		return cmd
+	}
+
+	if script := findEntryPoint(p.dir, command); script != "" {
+		cmdArgs := append([]string{"run", script}, args...)
+		cmd := p.command("poetry", cmdArgs...)
 		cmd.Dir = p.dir
 		return cmd
 	}
@@ -76,7 +178,7 @@ func (p *PoetrySource) FindCommand(command string, args []string) *exec.Cmd {
 	for _, variant := range GetCommandVariants(command) {
 		if poetryCmd, ok := poetryCommands[variant]; ok {
 			cmdArgs := append(poetryCmd, args...)
-			cmd := exec.Command("poetry", cmdArgs...)
+			cmd := p.command("poetry", cmdArgs...)
 			cmd.Dir = p.dir
 			return cmd
 		}
@@ -84,7 +186,7 @@ func (p *PoetrySource) FindCommand(command string, args []string) *exec.Cmd {
 
 	// Try to run any command through poetry run
 	cmdArgs := append([]string{"run", command}, args...)
-	cmd := exec.Command("poetry", cmdArgs...)
+	cmd := p.command("poetry", cmdArgs...)
 	cmd.Dir = p.dir
 	return cmd
 }
@@ -95,10 +197,12 @@ type UvSource struct {
 }
 
 func NewUvSource(dir string) CommandSource {
-	// Verify it's actually a uv project
+	// Verify it's actually a uv project, or one pinned to uv
 	hasUv := false
 
-	if FileExists(filepath.Join(dir, "uv.lock")) || FileExists(filepath.Join(dir, ".uv")) {
+	if pinnedManager(dir, "python") == "uv" && FileExists(filepath.Join(dir, "pyproject.toml")) {
+		hasUv = true
+	} else if FileExists(filepath.Join(dir, "uv.lock")) || FileExists(filepath.Join(dir, ".uv")) {
 		hasUv = true
 	} else if FileExists(filepath.Join(dir, "pyproject.toml")) {
 		data, err := os.ReadFile(filepath.Join(dir, "pyproject.toml"))
@@ -121,7 +225,7 @@ func NewUvSource(dir string) CommandSource {
 }
 
 func (u *UvSource) ListCommands() map[string]CommandInfo {
-	return map[string]CommandInfo{
+	commands := map[string]CommandInfo{
 		"setup":     {Description: "Install dependencies for development", Execution: "uv sync"},
 		"install":   {Description: "Install tool globally", Execution: "uv tool install ."},
 		"run":       {Description: "Run a command", Execution: "uv run"},
@@ -129,7 +233,18 @@ func (u *UvSource) ListCommands() map[string]CommandInfo {
 		"format":    {Description: "Format code", Execution: "uv run ruff format"},
 		"lint":      {Description: "Run linter", Execution: "uv run ruff check"},
 		"typecheck": {Description: "Run type checker", Execution: "uv run pyright"},
+		"outdated":  {Description: "List outdated direct dependencies", Execution: "uv tree --outdated --depth 1"},
+		"update":    {Description: "Upgrade dependencies in uv.lock", Execution: "uv lock --upgrade"},
+		"fmt-check": {Description: "Check formatting", Execution: "uv run ruff format --check"},
 	}
+	addEntryPointCommands(commands, u.dir, "uv run")
+	return commands
+}
+
+// DefinesCommand reports whether command runs an entry point from
+// pyproject.toml; other commands run uv defaults
+func (u *UvSource) DefinesCommand(command string) bool {
+	return findEntryPoint(u.dir, command) != ""
 }
 
 func (u *UvSource) FindCommand(command string, args []string) *exec.Cmd {
@@ -144,12 +259,22 @@ func (u *UvSource) FindCommand(command string, args []string) *exec.Cmd {
 		"fix":       {"run", "ruff", "check", "--fix"},
 		"typecheck": {"run", "pyright"},
 		"tc":        {"run", "pyright"},
+		"outdated":  {"tree", "--outdated", "--depth", "1"},
+		"update":    {"lock", "--upgrade"},
+		"fmt-check": {"run", "ruff", "format", "--check"},
+	}
+
+	if script := findEntryPoint(u.dir, command); script != "" {
+		cmdArgs := append([]string{"run", script}, args...)
+		cmd := u.command("uv", cmdArgs...)
+		cmd.Dir = u.dir
+		return cmd
 	}
 
 	for _, variant := range GetCommandVariants(command) {
 		if uvCmd, ok := uvCommands[variant]; ok {
 			cmdArgs := append(uvCmd, args...)
-			cmd := exec.Command("uv", cmdArgs...)
+			cmd := u.command("uv", cmdArgs...)
 			cmd.Dir = u.dir
 			return cmd
 		}
@@ -157,3 +282,104 @@ func (u *UvSource) FindCommand(command string, args []string) *exec.Cmd {
 
 	return nil
 }
+
+// PoeSource represents poethepoet tasks from [tool.poe.tasks]
+type PoeSource struct {
+	baseSource
+}
+
+func NewPoeSource(dir string) CommandSource {
+	manifest, err := parsePyproject(dir)
+	if err != nil || len(manifest.Tool.Poe.Tasks) == 0 {
+		return nil
+	}
+
+	return &PoeSource{
+		baseSource: baseSource{
+			dir:      dir,
+			name:     "poe",
+			priority: 5,
+		},
+	}
+}
+
+// poeTaskDescription returns the help text for a poe task definition,
+// falling back to the command it runs
+func poeTaskDescription(task any) string {
+	switch t := task.(type) {
+	case string:
+		return t
+	case []any:
+		parts := []string{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return "Sequence: " + strings.Join(parts, ", ")
+	case map[string]any:
+		if help, ok := t["help"].(string); ok && help != "" {
+			return help
+		}
+		for _, key := range []string{"cmd", "shell", "script", "expr", "ref"} {
+			if value, ok := t[key].(string); ok {
+				return value
+			}
+		}
+		if _, ok := t["sequence"]; ok {
+			return poeTaskDescription(t["sequence"])
+		}
+	}
+	return ""
+}
+
+// poeCommand returns the command prefix used to invoke poe, running it
+// through the project's package manager when there is one
+func (p *PoeSource) poeCommand() []string {
+	switch detectPythonProject(p.dir).(type) {
+	case *PoetrySource:
+		return []string{"poetry", "run", "poe"}
+	case *UvSource:
+		return []string{"uv", "run", "poe"}
+	}
+	return []string{"poe"}
+}
+
+func (p *PoeSource) ListCommands() map[string]CommandInfo {
+	return p.cachedCommands(func() map[string]CommandInfo {
+		commands := make(map[string]CommandInfo)
+
+		manifest, err := parsePyproject(p.dir)
+		if err != nil {
+			return commands
+		}
+		prefix := strings.Join(p.poeCommand(), " ")
+		for name, task := range manifest.Tool.Poe.Tasks {
+			description := poeTaskDescription(task)
+			if description == "" {
+				description = name
+			}
+			commands[name] = CommandInfo{
+				Description: description,
+				Execution:   prefix + " " + name,
+			}
+		}
+
+		return commands
+	})
+}
+
+func (p *PoeSource) FindCommand(command string, args []string) *exec.Cmd {
+	commands := p.ListCommands()
+
+	for _, variant := range GetCommandVariants(command) {
+		if _, exists := commands[variant]; exists {
+			poe := p.poeCommand()
+			cmdArgs := append(append(poe[1:], variant), args...)
+			cmd := p.command(poe[0], cmdArgs...)
+			cmd.Dir = p.dir
+			return cmd
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPythonEntryPoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		lockfile  string
+		pyproject string
+		command   string
+		expected  []string
+	}{
+		{
+			name:     "uv project.scripts",
+			lockfile: "uv.lock",
+			pyproject: `[project]
+name = "demo"
+
+[project.scripts]
+demo-cli = "demo.cli:main"
+`,
+			command:  "run:demo-cli",
+			expected: []string{"uv", "run", "demo-cli", "--help"},
+		},
+		{
+			name:     "poetry tool.poetry.scripts",
+			lockfile: "poetry.lock",
+			pyproject: `[tool.poetry]
+name = "demo"
+
+[tool.poetry.scripts]
+serve = "demo.server:run"
+migrate = { callable = "demo.db:migrate" }
+`,
+			command:  "run:migrate",
+			expected: []string{"poetry", "run", "migrate", "--help"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tempDir, "pyproject.toml"), []byte(tt.pyproject), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(tempDir, tt.lockfile), []byte(""), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			source := detectPythonProject(tempDir)
+			if _, ok := source.ListCommands()[tt.command]; !ok {
+				t.Errorf("ListCommands() missing %q", tt.command)
+			}
+
+			cmd := source.FindCommand(tt.command, []string{"--help"})
+			if cmd == nil {
+				t.Fatalf("FindCommand(%q) = nil", tt.command)
+			}
+			if !slicesEqual(cmd.Args, tt.expected) {
+				t.Errorf("FindCommand(%q).Args = %v, want %v", tt.command, cmd.Args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPoeSource(t *testing.T) {
+	tempDir := t.TempDir()
+	pyproject := `[project]
+name = "demo"
+
+[tool.uv]
+
+[tool.poe.tasks]
+lint = "ruff check ."
+serve = { cmd = "uvicorn demo:app", help = "Start the dev server" }
+ci = ["lint", "test"]
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewPoeSource(tempDir)
+	if source == nil {
+		t.Fatal("NewPoeSource() = nil")
+	}
+
+	commands := source.ListCommands()
+	expected := map[string]string{
+		"lint":  "ruff check .",
+		"serve": "Start the dev server",
+		"ci":    "Sequence: lint, test",
+	}
+	for name, description := range expected {
+		if info := commands[name]; info.Description != description {
+			t.Errorf("ListCommands()[%q].Description = %q, want %q", name, info.Description, description)
+		}
+	}
+
+	cmd := source.FindCommand("serve", []string{"--reload"})
+	if cmd == nil || !slicesEqual(cmd.Args, []string{"uv", "run", "poe", "serve", "--reload"}) {
+		t.Errorf("FindCommand(serve) = %v", cmd)
+	}
+
+	if NewPoeSource(t.TempDir()) != nil {
+		t.Error("NewPoeSource() should be nil without pyproject.toml")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -24,12 +25,19 @@ func NewMiseSource(dir string) CommandSource {
 }
 
 func (m *MiseSource) ListCommands() map[string]CommandInfo {
-	return getCachedCommands(m.cacheKey(), func() map[string]CommandInfo {
+	// Without mise, its tasks are still listed from its files
+	if !m.static() && !m.hasTool("mise") {
+		return parseMiseTasks(m.dir)
+	}
+	return m.cachedCommands(func() map[string]CommandInfo {
+		if m.static() {
+			return parseMiseTasks(m.dir)
+		}
 		commands := make(map[string]CommandInfo)
 
-		testCmd := exec.Command("mise", "tasks", "ls")
+		testCmd := m.command("mise", "tasks", "ls")
 		testCmd.Dir = m.dir
-		if output, err := testCmd.Output(); err == nil {
+		if output, err := listOutput(testCmd); err == nil {
 			lines := strings.Split(string(output), "\n")
 			for _, line := range lines {
 				line = strings.TrimSpace(line)
@@ -58,19 +66,20 @@ func (m *MiseSource) ListCommands() map[string]CommandInfo {
 }
 
 func (m *MiseSource) FindCommand(command string, args []string) *exec.Cmd {
-	// Use ListCommands to get parsed command list (eliminates false positives from string matching)
-	commands := m.ListCommands()
-
-	// Check each variant against the parsed command list
-	for _, variant := range GetCommandVariants(command) {
-		if _, exists := commands[variant]; exists {
-			cmdArgs := append([]string{"run", variant}, args...)
-			cmd := exec.Command("mise", cmdArgs...)
-			cmd.Dir = m.dir
-			return cmd
-		}
+	// The task files answer most lookups without running mise, which is
+	// asked only about names they don't define when other config files,
+	// such as global ones, may define them
+	variant, ok := findVariant(command, parseMiseTasks(m.dir))
+	if !ok && !miseTasksComplete(m.dir) {
+		variant, ok = findVariant(command, m.ListCommands())
 	}
-	return nil
+	if !ok {
+		return nil
+	}
+	cmdArgs := append([]string{"run", variant}, args...)
+	cmd := m.command("mise", cmdArgs...)
+	cmd.Dir = m.dir
+	return cmd
 }
 
 // JustSource represents commands from justfile
@@ -89,12 +98,19 @@ func NewJustSource(dir string) CommandSource {
 }
 
 func (j *JustSource) ListCommands() map[string]CommandInfo {
-	return getCachedCommands(j.cacheKey(), func() map[string]CommandInfo {
+	// Without just, its recipes are still listed from the justfile
+	if !j.static() && !j.hasTool("just") {
+		return parseJustRecipes(j.dir)
+	}
+	return j.cachedCommands(func() map[string]CommandInfo {
+		if j.static() {
+			return parseJustRecipes(j.dir)
+		}
 		commands := make(map[string]CommandInfo)
 
-		testCmd := exec.Command("just", "--list")
+		testCmd := j.command("just", "--list")
 		testCmd.Dir = j.dir
-		if output, err := testCmd.Output(); err == nil {
+		if output, err := listOutput(testCmd); err == nil {
 			lines := strings.Split(string(output), "\n")
 			for _, line := range lines {
 				line = strings.TrimSpace(line)
@@ -123,19 +139,20 @@ func (j *JustSource) ListCommands() map[string]CommandInfo {
 }
 
 func (j *JustSource) FindCommand(command string, args []string) *exec.Cmd {
-	// Use ListCommands to get parsed command list (eliminates false positives from string matching)
-	commands := j.ListCommands()
-
-	// Check each variant against the parsed command list
-	for _, variant := range GetCommandVariants(command) {
-		if _, exists := commands[variant]; exists {
-			cmdArgs := append([]string{variant}, args...)
-			cmd := exec.Command("just", cmdArgs...)
-			cmd.Dir = j.dir
-			return cmd
-		}
+	// The justfile answers most lookups without running just, which is
+	// asked only about names it doesn't define when it imports recipes
+	// or defines aliases
+	variant, ok := findVariant(command, parseJustRecipes(j.dir))
+	if !ok && !justRecipesComplete(j.dir) {
+		variant, ok = findVariant(command, j.ListCommands())
 	}
-	return nil
+	if !ok {
+		return nil
+	}
+	cmdArgs := append([]string{variant}, args...)
+	cmd := j.command("just", cmdArgs...)
+	cmd.Dir = j.dir
+	return cmd
 }
 
 // MakeSource represents commands from Makefile
@@ -154,7 +171,7 @@ func NewMakeSource(dir string) CommandSource {
 }
 
 func (m *MakeSource) ListCommands() map[string]CommandInfo {
-	return getCachedCommands(m.cacheKey(), func() map[string]CommandInfo {
+	return m.cachedCommands(func() map[string]CommandInfo {
 		commands := make(map[string]CommandInfo)
 
 		makefiles := []string{"Makefile", "makefile"}
@@ -192,6 +209,18 @@ func (m *MakeSource) ListCommands() map[string]CommandInfo {
 	})
 }
 
+// completeArguments offers the other targets, since make runs each target
+// it's given
+func (m *MakeSource) completeArguments(command string, args []string) []completion {
+	var candidates []completion
+	for _, target := range sortCommands(m.ListCommands()) {
+		if target != command && !slices.Contains(args, target) {
+			candidates = append(candidates, completion{name: target})
+		}
+	}
+	return candidates
+}
+
 func (m *MakeSource) FindCommand(command string, args []string) *exec.Cmd {
 	// Use ListCommands to get parsed command list (eliminates false positives and resource leaks)
 	commands := m.ListCommands()
@@ -200,7 +229,7 @@ func (m *MakeSource) FindCommand(command string, args []string) *exec.Cmd {
 	for _, variant := range GetCommandVariants(command) {
 		if _, exists := commands[variant]; exists {
 			cmdArgs := append([]string{variant}, args...)
-			cmd := exec.Command("make", cmdArgs...)
+			cmd := m.command("make", cmdArgs...)
 			cmd.Dir = m.dir
 			return cmd
 		}
@@ -0,0 +1,368 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// cargoManifest holds the parts of Cargo.toml that cmd-runner cares about
+type cargoManifest struct {
+	Package struct {
+		Name string `toml:"name"`
+	} `toml:"package"`
+	Bin []struct {
+		Name string `toml:"name"`
+	} `toml:"bin"`
+	Workspace struct {
+		Members []string `toml:"members"`
+	} `toml:"workspace"`
+}
+
+// parseCargoManifest reads and decodes Cargo.toml in dir
+func parseCargoManifest(dir string) (*cargoManifest, error) {
+	var manifest cargoManifest
+	if _, err := toml.DecodeFile(filepath.Join(dir, "Cargo.toml"), &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// binaryNames returns the names of the binary targets declared in the manifest
+func (m *cargoManifest) binaryNames() []string {
+	names := []string{}
+	for _, bin := range m.Bin {
+		if bin.Name != "" {
+			names = append(names, bin.Name)
+		}
+	}
+	return names
+}
+
+// CargoSource for Rust projects
+type CargoSource struct {
+	baseSource
+}
+
+func NewCargoSource(dir string) CommandSource {
+	if !FileExists(filepath.Join(dir, "Cargo.toml")) {
+		return nil
+	}
+
+	return &CargoSource{
+		baseSource: baseSource{
+			dir:      dir,
+			name:     "Cargo",
+			priority: 10,
+		},
+	}
+}
+
+func (c *CargoSource) ListCommands() map[string]CommandInfo {
+	commands := map[string]CommandInfo{
+		"build":     {Description: "Build the project", Execution: "cargo build"},
+		"run":       {Description: "Run the project", Execution: "cargo run"},
+		"test":      {Description: "Run tests", Execution: "cargo test"},
+		"check":     {Description: "Check code for errors", Execution: "cargo check"},
+		"format":    {Description: "Format code", Execution: "cargo fmt"},
+		"lint":      {Description: "Run clippy linter", Execution: "cargo clippy"},
+		"clean":     {Description: "Clean build artifacts", Execution: "cargo clean"},
+		"setup":     {Description: "Download dependencies", Execution: "cargo fetch"},
+		"install":   {Description: "Install binary globally", Execution: "cargo install --path ."},
+		"outdated":  {Description: "Show the updates update would make", Execution: "cargo update --dry-run"},
+		"update":    {Description: "Update dependencies in Cargo.lock", Execution: "cargo update"},
+		"fmt-check": {Description: "Check formatting", Execution: "cargo fmt --check"},
+	}
+
+	// Expose each binary target as run:<name>
+	if manifest, err := parseCargoManifest(c.dir); err == nil {
+		for _, bin := range manifest.binaryNames() {
+			commands["run:"+bin] = CommandInfo{
+				Description: "Run the " + bin + " binary",
+				Execution:   "cargo run --bin " + bin,
+			}
+		}
+	}
+
+	return commands
+}
+
+// DefinesCommand reports whether command runs a binary target from the
+// manifest; other Cargo commands are toolchain defaults
+func (c *CargoSource) DefinesCommand(command string) bool {
+	return strings.HasPrefix(command, "run:")
+}
+
+func (c *CargoSource) FindCommand(command string, args []string) *exec.Cmd {
+	cargoCommands := map[string]string{
+		"build":     "build",
+		"run":       "run",
+		"test":      "test",
+		"lint":      "clippy",
+		"format":    "fmt",
+		"fmt":       "fmt",
+		"clean":     "clean",
+		"typecheck": "check",
+		"tc":        "check",
+		"check":     "check",
+		"fix":       "fix",
+		"setup":     "fetch",
+		"install":   "install",
+		"publish":   "publish",
+		"outdated":  "update",
+		"update":    "update",
+		"fmt-check": "fmt",
+	}
+
+	for _, variant := range GetCommandVariants(command) {
+		if cargoCmd, ok := cargoCommands[variant]; ok {
+			var cmdArgs []string
+			if cargoCmd == "install" {
+				// Modern cargo requires --path for installing from current directory
+				cmdArgs = append([]string{"install", "--path", "."}, args...)
+			} else if variant == "fmt-check" {
+				cmdArgs = append([]string{"fmt", "--check"}, args...)
+			} else if variant == "outdated" {
+				cmdArgs = append([]string{"update", "--dry-run"}, args...)
+			} else {
+				cmdArgs = append([]string{cargoCmd}, args...)
+			}
+			cmd := c.command("cargo", cmdArgs...)
+			cmd.Dir = c.dir
+			return cmd
+		}
+	}
+
+	// Check for binary targets (run:binary-name pattern)
+	if binName, ok := strings.CutPrefix(command, "run:"); ok {
+		manifest, err := parseCargoManifest(c.dir)
+		if err != nil {
+			return nil
+		}
+		for _, bin := range manifest.binaryNames() {
+			if bin == binName {
+				cmdArgs := append([]string{"run", "--bin", binName}, args...)
+				cmd := c.command("cargo", cmdArgs...)
+				cmd.Dir = c.dir
+				return cmd
+			}
+		}
+	}
+
+	return nil
+}
+
+// cargoTargetCommands are the commands whose cargo subcommand takes --bin
+var cargoTargetCommands = map[string]bool{"build": true, "run": true, "test": true, "install": true}
+
+// completeArguments offers --bin and --release to the cargo subcommands that
+// take them, and binary target names after --bin. Arguments after -- go to
+// the program, so they complete as files.
+func (c *CargoSource) completeArguments(command string, args []string) []completion {
+	if slices.Contains(args, "--") || !cargoTargetCommands[NormalizeCommand(command)] {
+		return nil
+	}
+	if len(args) > 0 && args[len(args)-1] == "--bin" {
+		manifest, err := parseCargoManifest(c.dir)
+		if err != nil {
+			return nil
+		}
+		var candidates []completion
+		for _, bin := range manifest.binaryNames() {
+			candidates = append(candidates, completion{name: bin})
+		}
+		return candidates
+	}
+	return []completion{
+		{"--bin", "Build or run only this binary target"},
+		{"--release", "Build with optimizations"},
+	}
+}
+
+// CargoMakeSource represents tasks from a cargo-make Makefile.toml
+type CargoMakeSource struct {
+	baseSource
+}
+
+func NewCargoMakeSource(dir string) CommandSource {
+	if !FileExists(filepath.Join(dir, "Makefile.toml")) {
+		return nil
+	}
+
+	return &CargoMakeSource{
+		baseSource: baseSource{
+			dir:      dir,
+			name:     "cargo-make",
+			priority: 4,
+		},
+	}
+}
+
+// parseCargoMakeTasks reads the [tasks.*] tables from Makefile.toml
+func parseCargoMakeTasks(dir string) (map[string]string, error) {
+	var makefile struct {
+		Tasks map[string]struct {
+			Description string `toml:"description"`
+			Private     bool   `toml:"private"`
+		} `toml:"tasks"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(dir, "Makefile.toml"), &makefile); err != nil {
+		return nil, err
+	}
+
+	tasks := make(map[string]string)
+	for name, task := range makefile.Tasks {
+		if task.Private {
+			continue
+		}
+		tasks[name] = task.Description
+	}
+	return tasks, nil
+}
+
+func (m *CargoMakeSource) ListCommands() map[string]CommandInfo {
+	return m.cachedCommands(func() map[string]CommandInfo {
+		commands := make(map[string]CommandInfo)
+
+		tasks, err := parseCargoMakeTasks(m.dir)
+		if err != nil {
+			return commands
+		}
+		for name, description := range tasks {
+			if description == "" {
+				description = name
+			}
+			commands[name] = CommandInfo{
+				Description: description,
+				Execution:   "cargo make " + name,
+			}
+		}
+
+		return commands
+	})
+}
+
+func (m *CargoMakeSource) FindCommand(command string, args []string) *exec.Cmd {
+	commands := m.ListCommands()
+
+	for _, variant := range GetCommandVariants(command) {
+		if _, exists := commands[variant]; exists {
+			cmdArgs := append([]string{"make", variant}, args...)
+			cmd := m.command("cargo", cmdArgs...)
+			cmd.Dir = m.dir
+			return cmd
+		}
+	}
+	return nil
+}
+
+// XtaskSource represents tasks implemented by an xtask crate
+// (https://github.com/matklad/cargo-xtask)
+type XtaskSource struct {
+	baseSource
+}
+
+func NewXtaskSource(dir string) CommandSource {
+	if !hasXtask(dir) {
+		return nil
+	}
+
+	return &XtaskSource{
+		baseSource: baseSource{
+			dir:      dir,
+			name:     "xtask",
+			priority: 11,
+		},
+	}
+}
+
+// hasXtask reports whether dir contains an xtask crate or a cargo alias for one
+func hasXtask(dir string) bool {
+	if FileExists(filepath.Join(dir, "xtask", "Cargo.toml")) {
+		return true
+	}
+
+	for _, name := range []string{"config.toml", "config"} {
+		var config struct {
+			Alias map[string]any `toml:"alias"`
+		}
+		if _, err := toml.DecodeFile(filepath.Join(dir, ".cargo", name), &config); err == nil {
+			if _, ok := config.Alias["xtask"]; ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// xtaskTaskPattern matches string match arms such as `"dist" =>` or
+// `Some("dist") =>`, the conventional way xtask binaries dispatch tasks
+var xtaskTaskPattern = regexp.MustCompile(`"([A-Za-z][\w:-]*)"\)?\s*=>`)
+
+// parseXtaskTasks scans the xtask sources for task names. xtask has no
+// listing protocol, so this is a best-effort heuristic.
+func parseXtaskTasks(dir string) []string {
+	seen := make(map[string]bool)
+	srcDir := filepath.Join(dir, "xtask", "src")
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".rs" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(srcDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, match := range xtaskTaskPattern.FindAllStringSubmatch(string(data), -1) {
+			seen[match[1]] = true
+		}
+	}
+
+	tasks := make([]string, 0, len(seen))
+	for task := range seen {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+func (x *XtaskSource) ListCommands() map[string]CommandInfo {
+	return x.cachedCommands(func() map[string]CommandInfo {
+		commands := make(map[string]CommandInfo)
+		for _, task := range parseXtaskTasks(x.dir) {
+			commands[task] = CommandInfo{
+				Description: "xtask " + task,
+				Execution:   "cargo xtask " + task,
+			}
+		}
+		return commands
+	})
+}
+
+func (x *XtaskSource) FindCommand(command string, args []string) *exec.Cmd {
+	commands := x.ListCommands()
+
+	for _, variant := range GetCommandVariants(command) {
+		if _, exists := commands[variant]; exists {
+			command = variant
+			break
+		}
+	}
+
+	// Task discovery is heuristic, so route any command through cargo xtask;
+	// the xtask binary reports unknown tasks itself
+	cmdArgs := append([]string{"xtask", command}, args...)
+	cmd := x.command("cargo", cmdArgs...)
+	cmd.Dir = x.dir
+	return cmd
+}
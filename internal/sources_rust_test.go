@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCargoSourceBinaryTargets(t *testing.T) {
+	tempDir := t.TempDir()
+	manifest := `[package]
+name = "demo"
+
+[[bin]]
+name = "server"
+path = "src/bin/server.rs"
+
+[[bin]]
+name = "cli"
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "Cargo.toml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewCargoSource(tempDir)
+	commands := source.ListCommands()
+	for _, name := range []string{"run:server", "run:cli"} {
+		if _, ok := commands[name]; !ok {
+			t.Errorf("ListCommands() missing %q", name)
+		}
+	}
+
+	cmd := source.FindCommand("run:server", []string{"--port", "80"})
+	if cmd == nil {
+		t.Fatal("FindCommand(run:server) = nil")
+	}
+	expected := []string{"cargo", "run", "--bin", "server", "--port", "80"}
+	if !slicesEqual(cmd.Args, expected) {
+		t.Errorf("FindCommand(run:server).Args = %v, want %v", cmd.Args, expected)
+	}
+
+	// A name that only appears as the package name is not a binary target
+	if cmd := source.FindCommand("run:demo", nil); cmd != nil {
+		t.Errorf("FindCommand(run:demo) = %v, want nil", cmd.Args)
+	}
+}
+
+func TestCargoMakeSource(t *testing.T) {
+	tempDir := t.TempDir()
+	makefile := `[tasks.ci-flow]
+description = "Run the CI flow"
+
+[tasks.internal]
+private = true
+
+[tasks.docs]
+command = "cargo"
+args = ["doc"]
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "Makefile.toml"), []byte(makefile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewCargoMakeSource(tempDir)
+	if source == nil {
+		t.Fatal("NewCargoMakeSource() = nil")
+	}
+
+	commands := source.ListCommands()
+	if info, ok := commands["ci-flow"]; !ok || info.Description != "Run the CI flow" {
+		t.Errorf("ListCommands()[ci-flow] = %+v, %v", info, ok)
+	}
+	if _, ok := commands["docs"]; !ok {
+		t.Error("ListCommands() missing docs")
+	}
+	if _, ok := commands["internal"]; ok {
+		t.Error("ListCommands() should omit private tasks")
+	}
+
+	cmd := source.FindCommand("docs", nil)
+	if cmd == nil || !slicesEqual(cmd.Args, []string{"cargo", "make", "docs"}) {
+		t.Errorf("FindCommand(docs) = %v", cmd)
+	}
+}
+
+func TestXtaskSource(t *testing.T) {
+	tempDir := t.TempDir()
+	srcDir := filepath.Join(tempDir, "xtask", "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "xtask", "Cargo.toml"), []byte("[package]\nname = \"xtask\""), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mainRs := `fn main() {
+    let task = std::env::args().nth(1);
+    match task.as_deref() {
+        Some("dist") => dist(),
+        Some("codegen") => codegen(),
+        _ => print_help(),
+    }
+}
+`
+	if err := os.WriteFile(filepath.Join(srcDir, "main.rs"), []byte(mainRs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewXtaskSource(tempDir)
+	if source == nil {
+		t.Fatal("NewXtaskSource() = nil")
+	}
+
+	commands := source.ListCommands()
+	for _, name := range []string{"dist", "codegen"} {
+		if _, ok := commands[name]; !ok {
+			t.Errorf("ListCommands() missing %q", name)
+		}
+	}
+
+	cmd := source.FindCommand("dist", []string{"--release"})
+	expected := []string{"cargo", "xtask", "dist", "--release"}
+	if cmd == nil || !slicesEqual(cmd.Args, expected) {
+		t.Errorf("FindCommand(dist) = %v, want %v", cmd, expected)
+	}
+}
+
+func TestHasXtaskCargoAlias(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, ".cargo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[alias]\nxtask = \"run --package xtask --\"\n"
+	if err := os.WriteFile(filepath.Join(tempDir, ".cargo", "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasXtask(tempDir) {
+		t.Error("hasXtask() = false, want true for cargo alias")
+	}
+	if hasXtask(t.TempDir()) {
+		t.Error("hasXtask() = true for empty directory")
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShowSources(t *testing.T) {
+	setUserConfig(t, "")
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"Makefile":       "test:\n\techo test\n",
+		"package.json":   `{"scripts": {"dev": "vite"}}`,
+		"pnpm-lock.yaml": "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(t, dir, "[commands]\nhi = \"echo hi\"\n")
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir, executor: &Executor{
+		LookPath: func(file string) (string, error) {
+			if file == "make" {
+				return "/usr/bin/make", nil
+			}
+			return "", os.ErrNotExist
+		},
+	}}
+
+	var out strings.Builder
+	if err := runner.ShowSources(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != dir {
+		t.Fatalf("ShowSources() = %q, want the directory and three sources", out.String())
+	}
+	for i, want := range [][]string{
+		{"1. .cmdr.toml", ".cmdr.toml"},
+		{"2. make", "priority", "Makefile", "✓ /usr/bin/make"},
+		{"3. pnpm", "package.json, pnpm-lock.yaml", "✗ pnpm not on PATH"},
+	} {
+		for _, part := range want {
+			if !strings.Contains(lines[i+1], part) {
+				t.Errorf("line %d = %q, missing %q", i+1, lines[i+1], part)
+			}
+		}
+	}
+
+	empty := &CommandRunner{CurrentDir: t.TempDir()}
+	empty.ProjectRoot = empty.CurrentDir
+	if err := empty.ShowSources(&out); err == nil {
+		t.Error("ShowSources() should fail without sources")
+	}
+}
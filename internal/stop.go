@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StopConfig controls how a group process is stopped. By default it is sent
+// SIGTERM and killed if it hasn't exited after Timeout.
+type StopConfig struct {
+	Signal  string   `toml:"signal"`  // Signal to send, e.g. "SIGINT"
+	Run     string   `toml:"run"`     // Shell command to run instead, e.g. "docker compose down"
+	Timeout duration `toml:"timeout"` // Grace period before the process is killed
+}
+
+// defaultStopTimeout is how long a process has to exit after being asked to stop
+const defaultStopTimeout = 10 * time.Second
+
+// stopSignals are the signals a stop config may name
+var stopSignals = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// parseStopSignal parses a signal name, with or without the SIG prefix
+func parseStopSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return syscall.SIGTERM, nil
+	}
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+	if sig, ok := stopSignals[upper]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q (expected one of: INT, TERM, HUP, QUIT, KILL)", name)
+}
+
+func (c *StopConfig) validate() error {
+	if c.Signal != "" && c.Run != "" {
+		return fmt.Errorf("signal and run are mutually exclusive")
+	}
+	_, err := parseStopSignal(c.Signal)
+	return err
+}
+
+func (c *StopConfig) timeout() time.Duration {
+	if c == nil || c.Timeout <= 0 {
+		return defaultStopTimeout
+	}
+	return time.Duration(c.Timeout)
+}
+
+// shutdown stops the started processes in reverse dependency order, so that
+// each process stops before the ones it depends on. No process starts once
+// shutdown has begun.
+func (g *groupRun) shutdown(order []string) {
+	g.mu.Lock()
+	g.stopping = true
+	g.mu.Unlock()
+
+	for i := len(order) - 1; i >= 0; i-- {
+		p := g.processes[order[i]]
+		select {
+		case <-p.started:
+			g.stopProcess(p)
+		default:
+		}
+	}
+}
+
+// stopProcess applies a process's stop config and waits for it to exit,
+// killing it after the timeout. A stop command also runs for processes that
+// have already exited successfully, to tear down what they started.
+func (g *groupRun) stopProcess(p *groupProcess) {
+	stop := p.config.Stop
+	if isClosed(p.exited) && (stop == nil || stop.Run == "" || p.err != nil) {
+		return
+	}
+	g.mu.Lock()
+	cmd, running := p.cmd, p.running
+	g.mu.Unlock()
+	g.terminate(p, cmd, running)
+}
+
+// terminate stops one run of a process, by its stop command or signal, and
+// waits for running to close, killing the process after the timeout
+func (g *groupRun) terminate(p *groupProcess, cmd *exec.Cmd, running chan struct{}) {
+	stop := p.config.Stop
+	timeout := stop.timeout()
+
+	if stop != nil && stop.Run != "" {
+		g.output.logf(p.name, "stopping: %s", stop.Run)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		stopCmd := g.runner.executor.shell(stop.Run, nil)
+		stopCmd.Dir = g.processDir(p.config)
+		stopCmd.Env = g.runner.commandEnv()
+		writer := g.output.writer(p.name)
+		stopCmd.Stdout = writer
+		stopCmd.Stderr = writer
+		start := time.Now()
+		err := runContext(ctx, stopCmd)
+		g.runner.audit("stop", stopCmd, start, err)
+		if err != nil {
+			g.output.logf(p.name, "stop command failed: %v", err)
+		}
+		writer.flush()
+		if isClosed(running) {
+			return
+		}
+	} else {
+		sig := syscall.SIGTERM
+		if stop != nil {
+			sig, _ = parseStopSignal(stop.Signal)
+		}
+		g.output.logf(p.name, "stopping")
+		_ = signalProcessGroup(cmd, sig)
+	}
+
+	select {
+	case <-running:
+	case <-time.After(timeout):
+		g.output.logf(p.name, "still running after %s; killing", timeout)
+		_ = signalProcessGroup(cmd, syscall.SIGKILL)
+		<-running
+	}
+}
+
+// runContext runs cmd in its own process group, killing the group if ctx
+// is done first
+func runContext(ctx context.Context, cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	// Don't wait indefinitely for output from children that outlive a
+	// killed process
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = signalProcessGroup(cmd, syscall.SIGKILL)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// groupStateDir holds a pid file for each running group
+func groupStateDir() string {
+	return filepath.Join(os.TempDir(), "cmdr-groups")
+}
+
+// groupPidFile returns the pid file for a group defined in dir
+func groupPidFile(dir, name string) string {
+	sum := sha1.Sum([]byte(dir))
+	return filepath.Join(groupStateDir(), hex.EncodeToString(sum[:6])+"-"+name+".pid")
+}
+
+// writeGroupPidFile records that this process is running a group. The
+// returned function removes the record.
+func writeGroupPidFile(dir, name string) func() {
+	path := groupPidFile(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return func() {}
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		return func() {}
+	}
+	return func() { _ = os.Remove(path) }
+}
+
+// runningGroupPid returns the pid of the cmdr running a group, or 0
+func runningGroupPid(dir, name string) int {
+	data, err := os.ReadFile(groupPidFile(dir, name))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
+
+// StopGroups asks running groups of this project to shut down: the named
+// groups, or every running group if names is empty
+func (r *CommandRunner) StopGroups(names []string) error {
+	if err := refuseToRun("stop commands"); err != nil {
+		return err
+	}
+	stopped := 0
+	for _, dir := range r.searchDirs() {
+		config := loadProjectConfig(dir)
+		if config == nil {
+			continue
+		}
+		for _, name := range sortCommands(config.Groups) {
+			if len(names) > 0 && !slices.Contains(names, name) {
+				continue
+			}
+			pid := runningGroupPid(dir, name)
+			if pid == 0 {
+				continue
+			}
+			if err := signalPid(pid, syscall.SIGTERM); err != nil {
+				return fmt.Errorf("stopping %s: %w", name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Stopping %s (pid %d)\n", name, pid)
+			stopped++
+		}
+	}
+	if stopped == 0 {
+		if len(names) > 0 {
+			return fmt.Errorf("no running group named %s", strings.Join(names, ", "))
+		}
+		return fmt.Errorf("no running groups")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// strict reports whether only commands the project defines may run, because
+// of --no-synth or synthesize = false in the nearest config that sets it
+func (r *CommandRunner) strict() bool {
+	if r.NoSynth {
+		return true
+	}
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.Synthesize != nil {
+			return !*config.Synthesize
+		}
+	}
+	return false
+}
+
+// strictNotFound explains that command would have run, had synthesized
+// commands and tool defaults been allowed
+func (r *CommandRunner) strictNotFound(command string) error {
+	would := ""
+	switch command {
+	case "check", "fix", "typecheck", "clean":
+		would = "cmd-runner would synthesize it"
+	default:
+		for _, name := range []string{command, NormalizeCommand(command)} {
+			for _, project := range r.projects() {
+				for _, source := range project.CommandSources {
+					if cmd := source.FindCommand(name, r.Args); cmd != nil && would == "" {
+						would = fmt.Sprintf("%s would run `%s`", source.Name(), strings.Join(cmd.Args, " "))
+					}
+				}
+			}
+		}
+	}
+	if would == "" {
+		return nil
+	}
+	return missingCommandError(fmt.Errorf("'%s' isn't defined by this project (%s, but synthesized commands are disabled); define it under [commands] in %s or in the project's task runner", command, would, ProjectConfigFile))
+}
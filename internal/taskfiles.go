@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseMiseTasks reads the tasks defined in .mise.toml and in mise's task
+// directories, without running mise
+func parseMiseTasks(dir string) map[string]CommandInfo {
+	commands := make(map[string]CommandInfo)
+	var config struct {
+		Tasks map[string]any `toml:"tasks"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(dir, ".mise.toml"), &config); err == nil {
+		for name, task := range config.Tasks {
+			info := CommandInfo{Execution: "mise run " + name}
+			if table, ok := task.(map[string]any); ok {
+				if hide, _ := table["hide"].(bool); hide {
+					continue
+				}
+				info.Description, _ = table["description"].(string)
+			}
+			commands[name] = info
+		}
+	}
+
+	// File tasks are named by their path below the task directory, with
+	// ":" separating directories
+	for _, tasksDir := range miseTaskDirs {
+		root := filepath.Join(dir, tasksDir)
+		_ = filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			name := strings.ReplaceAll(filepath.ToSlash(rel), "/", ":")
+			commands[name] = CommandInfo{
+				Description: miseFileTaskDescription(path),
+				Execution:   "mise run " + name,
+			}
+			return nil
+		})
+	}
+	return commands
+}
+
+// miseDescriptionPattern matches a `#MISE description="..."` header comment
+var miseDescriptionPattern = regexp.MustCompile(`^#\s*(?:MISE|\[MISE\]|mise)\s+description\s*=\s*"(.*)"`)
+
+func miseFileTaskDescription(path string) string {
+	for _, line := range readLines(path) {
+		if match := miseDescriptionPattern.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// parseJustRecipes reads the public recipes of a justfile, with the comment
+// above each as its description, without running just
+func parseJustRecipes(dir string) map[string]CommandInfo {
+	commands := make(map[string]CommandInfo)
+	for _, jf := range []string{"justfile", "Justfile", ".justfile"} {
+		lines := readLines(filepath.Join(dir, jf))
+		private := false
+		comment := ""
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "[") && strings.Contains(line, "private"):
+				private = true
+				continue
+			case strings.HasPrefix(line, "["):
+				continue
+			case strings.HasPrefix(line, "#"):
+				comment = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+				continue
+			}
+			if match := justRecipePattern.FindStringSubmatch(line); match != nil {
+				name := match[1]
+				if !private && !strings.HasPrefix(name, "_") {
+					commands[name] = CommandInfo{Description: comment, Execution: "just " + name}
+				}
+			}
+			if trimmed == "" || !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+				private = false
+				comment = ""
+			}
+		}
+		if len(lines) > 0 {
+			break
+		}
+	}
+	return commands
+}
+
+// miseConfigFiles are the files mise reads tasks from, besides .mise.toml
+var miseConfigFiles = []string{"mise.toml", ".mise.local.toml", "mise.local.toml", ".config/mise.toml", ".config/mise/config.toml", "mise/config.toml"}
+
+// miseTasksComplete reports whether parseMiseTasks finds every task mise
+// would: there are no other config files in dir or its parents, no global
+// tasks, and .mise.toml doesn't include task files
+func miseTasksComplete(dir string) bool {
+	for _, line := range readLines(filepath.Join(dir, ".mise.toml")) {
+		if strings.Contains(line, "task_config") || strings.HasPrefix(strings.TrimSpace(line), "includes") {
+			return false
+		}
+	}
+	for _, name := range miseConfigFiles {
+		if FileExists(filepath.Join(dir, filepath.FromSlash(name))) {
+			return false
+		}
+	}
+	// mise merges the config files of parent directories
+	for parent := filepath.Dir(dir); parent != filepath.Dir(parent); parent = filepath.Dir(parent) {
+		for _, name := range append([]string{".mise.toml"}, miseConfigFiles...) {
+			if FileExists(filepath.Join(parent, filepath.FromSlash(name))) {
+				return false
+			}
+		}
+	}
+	// Global tasks, in the directory next to cmdr's own config
+	configDir := os.Getenv("MISE_CONFIG_DIR")
+	if path := UserConfigPath(); configDir == "" && path != "" {
+		configDir = filepath.Join(filepath.Dir(filepath.Dir(path)), "mise")
+	}
+	globalConfigs := []string{"/etc/mise/config.toml"}
+	if configDir != "" {
+		if FileExists(filepath.Join(configDir, "tasks")) {
+			return false
+		}
+		globalConfigs = append(globalConfigs, filepath.Join(configDir, "config.toml"))
+	}
+	for _, path := range globalConfigs {
+		for _, line := range readLines(path) {
+			if strings.Contains(line, "tasks") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// justRecipesComplete reports whether parseJustRecipes finds every recipe
+// just would run: the justfile doesn't import files, declare modules, fall
+// back to a parent justfile, or define aliases
+func justRecipesComplete(dir string) bool {
+	for _, jf := range []string{"justfile", "Justfile", ".justfile"} {
+		lines := readLines(filepath.Join(dir, jf))
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "import", "import?", "mod", "mod?", "!include", "alias":
+				return false
+			case "set":
+				if len(fields) > 1 && strings.HasPrefix(fields[1], "fallback") {
+					return false
+				}
+			}
+		}
+		if len(lines) > 0 {
+			break
+		}
+	}
+	return true
+}
+
+// parseDenoTasks reads the tasks in deno.json or deno.jsonc, without running
+// deno. It returns nil if neither can be read.
+func parseDenoTasks(dir string) map[string]string {
+	var config struct {
+		Tasks map[string]any `json:"tasks"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "deno.json"))
+	if err != nil {
+		data, err = os.ReadFile(filepath.Join(dir, "deno.jsonc"))
+		data = stripJSONComments(data)
+	}
+	if err != nil || json.Unmarshal(data, &config) != nil {
+		return nil
+	}
+	tasks := make(map[string]string)
+	for name, task := range config.Tasks {
+		switch task := task.(type) {
+		case string:
+			tasks[name] = task
+		case map[string]any:
+			tasks[name], _ = task["command"].(string)
+		}
+	}
+	return tasks
+}
+
+// stripJSONComments removes // and /* */ comments and trailing commas from
+// JSONC, leaving strings alone
+func stripJSONComments(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '"':
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			out = append(out, data[start:min(i+1, len(data))]...)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == '}' || c == ']':
+			// Drop a comma before the closing bracket
+			trimmed := bytes.TrimRight(out, " \t\r\n")
+			if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+				out = append(trimmed[:len(trimmed)-1], out[len(trimmed):]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindCommandParsesTaskFiles(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"justfile":   "# Build it\nbuild:\n\tgo build\n",
+		".mise.toml": "[tasks.lint]\nrun = \"golangci-lint run\"\n",
+		"deno.jsonc": "{\n  // tasks\n  \"tasks\": {\"dev\": \"deno run -A main.ts\", /* url */ \"docs\": \"open https://x.dev\",},\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// No tool is installed, and nothing may be run to find the tasks
+	var spawned []string
+	executor := &Executor{
+		Command: func(name string, arg ...string) *exec.Cmd {
+			spawned = append(spawned, name)
+			return exec.Command(name, arg...)
+		},
+		LookPath: func(string) (string, error) { return "", errors.New("not found") },
+	}
+	for _, test := range []struct {
+		source  CommandSource
+		command string
+		want    []string
+	}{
+		{NewJustSource(dir), "build", []string{"just", "build"}},
+		{NewMiseSource(dir), "lint", []string{"mise", "run", "lint"}},
+		{NewDenoSource(dir), "docs", []string{"deno", "task", "docs"}},
+	} {
+		test.source.(interface{ setExecutor(*Executor) }).setExecutor(executor)
+		cmd := test.source.FindCommand(test.command, nil)
+		if cmd == nil || !slicesEqual(cmd.Args, test.want) {
+			t.Errorf("%s FindCommand(%q) = %v, want %v", test.source.Name(), test.command, cmd, test.want)
+		}
+		spawned = spawned[:len(spawned)-1]
+		if commands := test.source.ListCommands(); commands[test.command].Execution != strings.Join(test.want, " ") {
+			t.Errorf("%s ListCommands() = %v, missing %s", test.source.Name(), commands, test.command)
+		}
+	}
+	if len(spawned) > 0 {
+		t.Errorf("ran %v to find tasks", spawned)
+	}
+}
+
+func TestFindCommandSkipsToolWhenFilesAreComplete(t *testing.T) {
+	t.Setenv("MISE_CONFIG_DIR", t.TempDir())
+	for _, test := range []struct {
+		file, content string
+		source        func(string) CommandSource
+		wantSpawn     bool
+	}{
+		{"justfile", "build:\n\tgo build\n", NewJustSource, false},
+		{"justfile", "import 'ci.just'\nbuild:\n\tgo build\n", NewJustSource, true},
+		{"justfile", "alias t := test\ntest:\n\tgo test\n", NewJustSource, true},
+		{".mise.toml", "[tasks.build]\nrun = \"go build\"\n", NewMiseSource, false},
+		{".mise.toml", "[task_config]\nincludes = [\"tasks.toml\"]\n", NewMiseSource, true},
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, test.file), []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		spawned := false
+		source := test.source(dir)
+		source.(interface{ setExecutor(*Executor) }).setExecutor(&Executor{
+			Command: func(name string, arg ...string) *exec.Cmd {
+				spawned = true
+				return exec.Command("true")
+			},
+			LookPath: func(file string) (string, error) { return "/usr/bin/" + file, nil },
+		})
+		if cmd := source.FindCommand("lint", nil); cmd != nil {
+			t.Errorf("%q: FindCommand(lint) = %v", test.content, cmd.Args)
+		}
+		if spawned != test.wantSpawn {
+			t.Errorf("%q: asked %s = %v, want %v", test.content, source.Name(), spawned, test.wantSpawn)
+		}
+	}
+}
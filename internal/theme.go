@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ProjectTheme identifies a project in cmdr's output, so that output from
+// several projects can be told apart
+type ProjectTheme struct {
+	Name  string `toml:"name"`  // Badge text; defaults to the directory name
+	Color string `toml:"color"` // Color name (e.g. "cyan", "bright-magenta") or "#rrggbb"
+	Emoji string `toml:"emoji"` // Shown before the name
+	Title *bool  `toml:"title"` // Set the terminal and tmux pane title (default true)
+}
+
+// themeColors maps color names to SGR foreground codes
+var themeColors = map[string]string{
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"gray": "90", "grey": "90",
+	"bright-red": "91", "bright-green": "92", "bright-yellow": "93",
+	"bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+}
+
+func (t *ProjectTheme) validate() error {
+	if _, err := colorCode(t.Color); err != nil {
+		return err
+	}
+	return nil
+}
+
+// colorCode returns the SGR parameters for a color name or #rrggbb
+func colorCode(color string) (string, error) {
+	if color == "" {
+		return "", nil
+	}
+	if code, ok := themeColors[strings.ToLower(color)]; ok {
+		return code, nil
+	}
+	if hex, ok := strings.CutPrefix(color, "#"); ok && len(hex) == 6 {
+		if rgb, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return fmt.Sprintf("38;2;%d;%d;%d", rgb>>16, rgb>>8&0xff, rgb&0xff), nil
+		}
+	}
+	return "", fmt.Errorf("unknown color %q (expected a name such as cyan or bright-blue, or #rrggbb)", color)
+}
+
+// Style roles name the parts of cmdr's output that are colored
+const (
+	styleHeader  = "header"  // headings, such as "Available commands"
+	styleSource  = "source"  // source names
+	styleCommand = "command" // command names in listings
+	styleSuccess = "success" // ✓
+	styleFailure = "failure" // ✗
+	styleWarning = "warning"
+	styleDim     = "dim" // descriptions and hints
+)
+
+// defaultStyles are the SGR parameters for each role, which [colors] in the
+// user config overrides
+var defaultStyles = map[string]string{
+	styleHeader:  "1",
+	styleSource:  "36",
+	styleCommand: "1",
+	styleSuccess: "32",
+	styleFailure: "31",
+	styleWarning: "33",
+	styleDim:     "2",
+}
+
+// styleAttributes maps attribute names allowed in a style to SGR codes
+var styleAttributes = map[string]string{"bold": "1", "dim": "2", "italic": "3", "underline": "4"}
+
+// parseStyle returns the SGR parameters for a style: attributes and at most
+// one color, separated by spaces (e.g. "bold cyan"), or "none"
+func parseStyle(style string) (string, error) {
+	var codes []string
+	for _, word := range strings.Fields(style) {
+		if word == "none" {
+			continue
+		}
+		if code, ok := styleAttributes[word]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		code, err := colorCode(word)
+		if err != nil {
+			return "", err
+		}
+		codes = append(codes, code)
+	}
+	return strings.Join(codes, ";"), nil
+}
+
+// validateColors checks the [colors] table of the user config
+func validateColors(colors map[string]string) error {
+	for role, style := range colors {
+		if _, ok := defaultStyles[role]; !ok {
+			return fmt.Errorf("colors: unknown role %q (expected one of: %s)", role, strings.Join(sortCommands(defaultStyles), ", "))
+		}
+		if _, err := parseStyle(style); err != nil {
+			return fmt.Errorf("colors.%s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// style returns text in role's style when output to w is colored
+func style(w io.Writer, role, text string) string {
+	f, ok := w.(*os.File)
+	if !ok || !colorEnabled(f) {
+		return text
+	}
+	code := defaultStyles[role]
+	if config := LoadUserConfig(); config != nil {
+		if custom, ok := config.Colors[role]; ok {
+			code, _ = parseStyle(custom)
+		}
+	}
+	if code == "" {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+// passMark returns ✓ or ✗ for a result, styled for w
+func passMark(w io.Writer, passed bool) string {
+	if passed {
+		return style(w, styleSuccess, "✓")
+	}
+	return style(w, styleFailure, "✗")
+}
+
+// colorMode is set by --color, and takes precedence over NO_COLOR and the
+// user config
+var colorMode string
+
+// SetColorMode applies --color: auto, always, or never
+func SetColorMode(mode string) error {
+	switch mode {
+	case "auto", "always", "never":
+		colorMode = mode
+		return nil
+	}
+	return fmt.Errorf("--color expects auto, always, or never, got %q", mode)
+}
+
+// colorEnabled reports whether to color output written to f, following
+// --color, NO_COLOR, and the user config's color setting
+func colorEnabled(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if config := LoadUserConfig(); config != nil {
+		switch config.Color {
+		case "always":
+			return true
+		case "never":
+			return false
+		}
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// projectTheme returns the theme from the nearest config that has one, with
+// its name defaulted, or nil
+func (r *CommandRunner) projectTheme() *ProjectTheme {
+	for _, dir := range r.searchDirs() {
+		config := loadProjectConfig(dir)
+		if config == nil || config.Theme == nil {
+			continue
+		}
+		theme := *config.Theme
+		if theme.Name == "" {
+			theme.Name = filepath.Base(dir)
+		}
+		return &theme
+	}
+	return nil
+}
+
+// badge returns the project's badge followed by a space, for the start of a
+// status line on stderr, or "" if the project has no theme
+func (r *CommandRunner) badge() string {
+	theme := r.projectTheme()
+	if theme == nil {
+		return ""
+	}
+	text := strings.TrimSpace(theme.Emoji + " " + theme.Name)
+	code, _ := colorCode(theme.Color)
+	if code == "" || !colorEnabled(os.Stderr) {
+		return "[" + text + "] "
+	}
+	return "\033[1;" + code + "m[" + text + "]\033[0m "
+}
+
+// setTitle sets the terminal title, which tmux uses as the pane title, to
+// the project's badge and the command being run
+func (r *CommandRunner) setTitle(command string) {
+	theme := r.projectTheme()
+	if theme == nil || (theme.Title != nil && !*theme.Title) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	title := strings.TrimSpace(theme.Emoji + " " + theme.Name)
+	if command != "" {
+		title += ": " + command
+	}
+	fmt.Fprintf(os.Stderr, "\033]2;%s\033\\", title)
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stepTime is how long one step of a synthesized command took
+type stepTime struct {
+	name     string
+	duration time.Duration
+	failed   bool
+}
+
+// timed reports whether to print how long the run took: with --time, or
+// time = true in the user config. Runs inside another run, such as the
+// steps of check, leave that to the outer run.
+func (r *CommandRunner) timed() bool {
+	if r.inner {
+		return false
+	}
+	if r.Time {
+		return true
+	}
+	config := LoadUserConfig()
+	return config != nil && config.Time
+}
+
+// reportTime writes the wall time of a run
+func (r *CommandRunner) reportTime(elapsed time.Duration, err error) {
+	if err != nil {
+		fmt.Fprintf(r.statusWriter(), "%s%s failed after %s\n", r.badge(), r.Command, roundDuration(elapsed))
+	} else {
+		fmt.Fprintf(r.statusWriter(), "%s%s finished in %s\n", r.badge(), r.Command, roundDuration(elapsed))
+	}
+}
+
+// reportSteps writes how long each step of a synthesized command took
+func (r *CommandRunner) reportSteps(steps []stepTime) {
+	if !r.timed() || len(steps) == 0 {
+		return
+	}
+	fmt.Fprintln(r.statusWriter())
+	for _, step := range steps {
+		fmt.Fprintf(r.statusWriter(), "  %s %-12s %s\n", passMark(r.statusWriter(), !step.failed), step.name, roundDuration(step.duration))
+	}
+}
+
+// roundDuration rounds d for display: to the millisecond under a second,
+// and to a tenth of a second above
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
+// phaseTimings records how long each phase of resolving and running a
+// command took, for --timings
+var phaseTimings struct {
+	sync.Mutex
+	enabled bool
+	start   time.Time
+	phases  []phaseTiming
+}
+
+// phaseTiming is one recorded phase
+type phaseTiming struct {
+	name       string
+	start, end time.Time
+}
+
+// SetTimings turns recording phase timings on or off. The total that
+// WriteTimings reports starts now.
+func SetTimings(on bool) {
+	phaseTimings.Lock()
+	defer phaseTimings.Unlock()
+	phaseTimings.enabled = on
+	phaseTimings.start = time.Now()
+	phaseTimings.phases = nil
+}
+
+// recordPhase records a phase that began at start and ends now, as in
+// `defer recordPhase(time.Now(), "look up %s", command)`
+func recordPhase(start time.Time, format string, args ...any) {
+	phaseTimings.Lock()
+	defer phaseTimings.Unlock()
+	if phaseTimings.enabled {
+		phaseTimings.phases = append(phaseTimings.phases, phaseTiming{fmt.Sprintf(format, args...), start, time.Now()})
+	}
+}
+
+// WriteTimings writes the recorded phases in the order they started, each
+// indented under the phase it happened during, and the total
+func WriteTimings(w io.Writer) {
+	phaseTimings.Lock()
+	defer phaseTimings.Unlock()
+	if !phaseTimings.enabled {
+		return
+	}
+	phases := slices.Clone(phaseTimings.phases)
+	sort.SliceStable(phases, func(i, j int) bool {
+		if !phases[i].start.Equal(phases[j].start) {
+			return phases[i].start.Before(phases[j].start)
+		}
+		// Of phases that start together, the longer one contains the other
+		return phases[i].end.After(phases[j].end)
+	})
+	fmt.Fprintln(w, "Timings:")
+	var open []time.Time // ends of the phases that contain the current one
+	for _, phase := range phases {
+		for len(open) > 0 && phase.end.After(open[len(open)-1]) {
+			open = open[:len(open)-1]
+		}
+		fmt.Fprintf(w, "  %10s  %s%s\n", formatPhase(phase.end.Sub(phase.start)), strings.Repeat("  ", len(open)), phase.name)
+		open = append(open, phase.end)
+	}
+	fmt.Fprintf(w, "  %10s  total\n", formatPhase(time.Since(phaseTimings.start)))
+}
+
+// formatPhase shows a phase's duration in milliseconds, which the phases
+// before a command runs usually take a fraction of
+func formatPhase(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
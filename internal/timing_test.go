@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimed(t *testing.T) {
+	setUserConfig(t, "")
+	if (&CommandRunner{}).timed() {
+		t.Error("timed() without --time or config")
+	}
+	if !(&CommandRunner{Time: true}).timed() {
+		t.Error("timed() with --time = false")
+	}
+	if (&CommandRunner{Time: true, inner: true}).timed() {
+		t.Error("an inner run should leave timing to the outer run")
+	}
+	setUserConfig(t, "time = true")
+	if !(&CommandRunner{}).timed() {
+		t.Error("timed() with time = true in config = false")
+	}
+}
+
+func TestRoundDuration(t *testing.T) {
+	tests := map[time.Duration]time.Duration{
+		123456 * time.Microsecond: 123 * time.Millisecond,
+		3456 * time.Millisecond:   3500 * time.Millisecond,
+	}
+	for d, want := range tests {
+		if got := roundDuration(d); got != want {
+			t.Errorf("roundDuration(%s) = %s, want %s", d, got, want)
+		}
+	}
+}
+
+func TestWriteTimings(t *testing.T) {
+	SetTimings(true)
+	defer SetTimings(false)
+	base := time.Now()
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	phaseTimings.phases = []phaseTiming{
+		{"list make in .", at(1), at(3)},
+		{"detect sources in .", at(0), at(1)},
+		{"look up build", at(0), at(4)},
+		{"run make build", at(5), at(105)},
+	}
+	var out strings.Builder
+	WriteTimings(&out)
+	want := "Timings:\n" +
+		"       4.0ms  look up build\n" +
+		"       1.0ms    detect sources in .\n" +
+		"       2.0ms    list make in .\n" +
+		"     100.0ms  run make build\n"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("WriteTimings() = %q, want it to start with %q", out.String(), want)
+	}
+}
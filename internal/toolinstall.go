@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// installMethod is one way to install a tool
+type installMethod struct {
+	// manager is the program the command needs, such as brew
+	manager string
+	command string
+}
+
+// toolInstalls lists ways to install the tools cmdr runs, in order of
+// preference
+var toolInstalls = map[string][]installMethod{
+	"just": {
+		{"brew", "brew install just"},
+		{"mise", "mise use -g just"},
+		{"cargo", "cargo install just"},
+		{"apt-get", "sudo apt install just"},
+		{"scoop", "scoop install just"},
+		{"winget", "winget install Casey.Just"},
+	},
+	"mise": {
+		{"brew", "brew install mise"},
+		{"curl", "curl https://mise.run | sh"},
+		{"scoop", "scoop install mise"},
+		{"winget", "winget install jdx.mise"},
+	},
+	"make": {
+		{"xcode-select", "xcode-select --install"},
+		{"apt-get", "sudo apt install make"},
+		{"dnf", "sudo dnf install make"},
+		{"scoop", "scoop install make"},
+	},
+	"npm": {
+		{"brew", "brew install node"},
+		{"mise", "mise use -g node"},
+		{"apt-get", "sudo apt install nodejs npm"},
+		{"winget", "winget install OpenJS.NodeJS"},
+	},
+	"pnpm": {
+		{"corepack", "corepack enable pnpm"},
+		{"brew", "brew install pnpm"},
+		{"mise", "mise use -g pnpm"},
+		{"npm", "npm install -g pnpm"},
+	},
+	"yarn": {
+		{"corepack", "corepack enable yarn"},
+		{"brew", "brew install yarn"},
+		{"npm", "npm install -g yarn"},
+	},
+	"bun": {
+		{"brew", "brew install oven-sh/bun/bun"},
+		{"mise", "mise use -g bun"},
+		{"curl", "curl -fsSL https://bun.sh/install | bash"},
+		{"npm", "npm install -g bun"},
+		{"scoop", "scoop install bun"},
+	},
+	"deno": {
+		{"brew", "brew install deno"},
+		{"mise", "mise use -g deno"},
+		{"curl", "curl -fsSL https://deno.land/install.sh | sh"},
+		{"scoop", "scoop install deno"},
+		{"winget", "winget install DenoLand.Deno"},
+	},
+	"poetry": {
+		{"pipx", "pipx install poetry"},
+		{"brew", "brew install poetry"},
+		{"uv", "uv tool install poetry"},
+	},
+	"uv": {
+		{"brew", "brew install uv"},
+		{"curl", "curl -LsSf https://astral.sh/uv/install.sh | sh"},
+		{"pipx", "pipx install uv"},
+		{"winget", "winget install astral-sh.uv"},
+	},
+	"poe": {
+		{"pipx", "pipx install poethepoet"},
+		{"uv", "uv tool install poethepoet"},
+	},
+	"cargo": {
+		{"rustup", "rustup default stable"},
+		{"curl", "curl https://sh.rustup.rs -sSf | sh"},
+		{"brew", "brew install rustup"},
+		{"winget", "winget install Rustlang.Rustup"},
+	},
+	"cargo-make": {
+		{"cargo", "cargo install cargo-make"},
+		{"brew", "brew install cargo-make"},
+	},
+	"go": {
+		{"brew", "brew install go"},
+		{"mise", "mise use -g go"},
+		{"apt-get", "sudo apt install golang-go"},
+		{"winget", "winget install GoLang.Go"},
+	},
+	"gradle": {
+		{"brew", "brew install gradle"},
+		{"mise", "mise use -g gradle"},
+		{"scoop", "scoop install gradle"},
+	},
+	"mvn": {
+		{"brew", "brew install maven"},
+		{"mise", "mise use -g maven"},
+		{"apt-get", "sudo apt install maven"},
+		{"scoop", "scoop install maven"},
+	},
+}
+
+// managerPlatforms limits managers to the platforms they're found on, when
+// they aren't found everywhere
+var managerPlatforms = map[string][]string{
+	"xcode-select": {"darwin"},
+	"apt-get":      {"linux"},
+	"dnf":          {"linux"},
+	"scoop":        {"windows"},
+	"winget":       {"windows"},
+	"curl":         {"darwin", "linux", "freebsd", "openbsd", "netbsd"},
+}
+
+// maxInstallSuggestions is how many ways to install a tool are suggested
+const maxInstallSuggestions = 2
+
+// installSuggestions returns commands that would install tool with the
+// package managers on this machine, best first, or nil if cmdr doesn't
+// know the tool or none of its managers is installed
+func (x *Executor) installSuggestions(tool string) []string {
+	// A path such as ./gradlew names a wrapper the project should provide
+	if strings.ContainsAny(tool, `/\`) {
+		return nil
+	}
+	tool = strings.TrimSuffix(tool, ".exe")
+	var suggestions []string
+	for _, method := range toolInstalls[tool] {
+		if platforms, ok := managerPlatforms[method.manager]; ok && !slices.Contains(platforms, runtime.GOOS) {
+			continue
+		}
+		if _, err := x.lookPath(method.manager); err != nil {
+			continue
+		}
+		suggestions = append(suggestions, method.command)
+		if len(suggestions) == maxInstallSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
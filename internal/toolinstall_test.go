@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestInstallSuggestions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("suggests Unix package managers")
+	}
+	executorWith := func(programs ...string) *Executor {
+		return &Executor{LookPath: func(file string) (string, error) {
+			for _, program := range programs {
+				if file == program {
+					return "/usr/bin/" + file, nil
+				}
+			}
+			return "", errors.New("not found")
+		}}
+	}
+	for _, test := range []struct {
+		tool     string
+		programs []string
+		want     []string
+	}{
+		{"just", []string{"brew", "mise", "cargo"}, []string{"brew install just", "mise use -g just"}},
+		{"just", []string{"cargo"}, []string{"cargo install just"}},
+		{"pnpm", []string{"corepack", "npm"}, []string{"corepack enable pnpm", "npm install -g pnpm"}},
+		{"just", nil, nil},
+		{"./gradlew", []string{"brew"}, nil},
+		{"unknown-tool", []string{"brew"}, nil},
+	} {
+		if got := executorWith(test.programs...).installSuggestions(test.tool); !slicesEqual(got, test.want) {
+			t.Errorf("installSuggestions(%q) with %v = %v, want %v", test.tool, test.programs, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// projectToolDirs are the directories, relative to a project, where package
+// managers and builds put the project's own executables
+func projectToolDirs() []string {
+	venvBin := ".venv/bin"
+	if runtime.GOOS == "windows" {
+		venvBin = ".venv/Scripts"
+	}
+	return []string{"node_modules/.bin", venvBin, "vendor/bin", "target/debug"}
+}
+
+// augmentPath reports whether commands get the project's tool directories
+// on PATH; the nearest config that sets augment_path decides
+func (r *CommandRunner) augmentPath() bool {
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.AugmentPath != nil {
+			return *config.AugmentPath
+		}
+	}
+	return true
+}
+
+// toolPathLayer returns PATH with the tool directories of the current
+// directory and then the project root in front of inherited, or nil if
+// there are none. It comes before config layers, so a PATH set in config or
+// with -e replaces it.
+func (r *CommandRunner) toolPathLayer(inherited string) *envLayer {
+	if !r.augmentPath() {
+		return nil
+	}
+	dirs := []string{}
+	for _, dir := range r.searchDirs() {
+		for _, rel := range projectToolDirs() {
+			if path := filepath.Join(dir, filepath.FromSlash(rel)); isDir(path) {
+				dirs = append(dirs, path)
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	if inherited != "" {
+		dirs = append(dirs, inherited)
+	}
+	return &envLayer{source: "project tool directories", vars: map[string]string{"PATH": strings.Join(dirs, string(os.PathListSeparator))}}
+}
+
+// lookUpProgram points cmd at its program as found on the PATH it runs
+// with. exec.Command searched cmdr's own PATH, which lacks the project's
+// tool directories, so that tools such as .venv/bin/mypy wouldn't be found.
+func (r *CommandRunner) lookUpProgram(cmd *exec.Cmd) {
+	if len(cmd.Args) == 0 || strings.ContainsAny(cmd.Args[0], `/\`) {
+		return
+	}
+	env := cmd.Env
+	if env == nil {
+		env = r.commandEnv()
+	}
+	path, ok := envPath(env)
+	if !ok || path == os.Getenv("PATH") {
+		return
+	}
+	if program, err := r.executor.lookPathIn(cmd.Args[0], path); err == nil {
+		cmd.Path, cmd.Err = program, nil
+	}
+}
+
+// envPath returns the PATH a command with env gets: the last entry for it,
+// since later entries win
+func envPath(env []string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		name, value, _ := strings.Cut(env[i], "=")
+		if name == "PATH" || runtime.GOOS == "windows" && strings.EqualFold(name, "PATH") {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// toolVersionModes are the values of tool_versions: warn when a pinned
+// tool's version differs from the one on PATH, run the command through
+// mise exec instead, or don't compare them
+var toolVersionModes = []string{"warn", "mise", "off"}
+
+// toolProbes are the commands that report the active version of each tool
+// that can be pinned
+var toolProbes = map[string][]string{
+	"node":   {"node", "--version"},
+	"rust":   {"rustc", "--version"},
+	"go":     {"go", "version"},
+	"python": {"python3", "--version"},
+	"ruby":   {"ruby", "--version"},
+	"deno":   {"deno", "--version"},
+	"bun":    {"bun", "--version"},
+}
+
+// toolAliases are the asdf plugin names of tools toolProbes names otherwise
+var toolAliases = map[string]string{"nodejs": "node", "golang": "go"}
+
+// programTools maps the programs commands run to the tool whose version
+// they use
+var programTools = map[string]string{
+	"node": "node", "npm": "node", "npx": "node", "pnpm": "node", "yarn": "node", "corepack": "node",
+	"cargo": "rust", "rustc": "rust",
+	"go":     "go",
+	"python": "python", "python3": "python", "pip": "python", "pip3": "python",
+	"ruby": "ruby", "bundle": "ruby", "rake": "ruby",
+	"deno": "deno",
+	"bun":  "bun",
+}
+
+// miseReads are the pin files mise exec applies without extra settings
+var miseReads = []string{".mise.toml", "mise.toml", ".tool-versions"}
+
+// toolPin is a tool version a project file pins
+type toolPin struct {
+	tool    string
+	version string
+	file    string
+	dir     string
+}
+
+var (
+	pinVersionPattern    = regexp.MustCompile(`^\d+(\.\d+)*$`)
+	activeVersionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+)
+
+// toolPins returns the tool versions pinned in dir, from mise's config,
+// .tool-versions, rust-toolchain.toml or rust-toolchain, and .nvmrc, in
+// that order of precedence. Pins that aren't version numbers, such as
+// "lts" or "stable", are skipped, since they can't be compared.
+func toolPins(dir string) []toolPin {
+	var pins []toolPin
+	add := func(file, tool, version string) {
+		tool = strings.ToLower(tool)
+		if alias, ok := toolAliases[tool]; ok {
+			tool = alias
+		}
+		version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+		if pinVersionPattern.MatchString(version) {
+			pins = append(pins, toolPin{tool, version, file, dir})
+		}
+	}
+
+	for _, file := range []string{".mise.toml", "mise.toml"} {
+		var config struct {
+			Tools map[string]any `toml:"tools"`
+		}
+		if _, err := toml.DecodeFile(filepath.Join(dir, file), &config); err != nil {
+			continue
+		}
+		for _, tool := range sortCommands(config.Tools) {
+			// A tool is a version, a list whose first entry is the default,
+			// or a table with its options
+			switch value := config.Tools[tool].(type) {
+			case string:
+				add(file, tool, value)
+			case []any:
+				if len(value) > 0 {
+					if version, ok := value[0].(string); ok {
+						add(file, tool, version)
+					}
+				}
+			case map[string]any:
+				if version, ok := value["version"].(string); ok {
+					add(file, tool, version)
+				}
+			}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".tool-versions")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			if fields := strings.Fields(line); len(fields) >= 2 {
+				add(".tool-versions", fields[0], fields[1])
+			}
+		}
+	}
+	var toolchain struct {
+		Toolchain struct {
+			Channel string `toml:"channel"`
+		} `toml:"toolchain"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(dir, "rust-toolchain.toml"), &toolchain); err == nil {
+		add("rust-toolchain.toml", "rust", toolchain.Toolchain.Channel)
+	} else if data, err := os.ReadFile(filepath.Join(dir, "rust-toolchain")); err == nil {
+		line, _, _ := strings.Cut(string(data), "\n")
+		add("rust-toolchain", "rust", line)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".nvmrc")); err == nil {
+		line, _, _ := strings.Cut(string(data), "\n")
+		add(".nvmrc", "node", line)
+	}
+	return pins
+}
+
+// toolPin returns the pin for tool in the nearest of the current directory
+// and project root that pins it, or nil
+func (r *CommandRunner) toolPin(tool string) *toolPin {
+	for _, dir := range r.searchDirs() {
+		for _, pin := range toolPins(dir) {
+			if pin.tool == tool {
+				return &pin
+			}
+		}
+	}
+	return nil
+}
+
+// toolVersionsMode returns the tool_versions setting of the nearest config
+// that has one, or "warn"
+func (r *CommandRunner) toolVersionsMode() string {
+	for _, dir := range r.searchDirs() {
+		if config := loadProjectConfig(dir); config != nil && config.ToolVersions != "" {
+			return config.ToolVersions
+		}
+	}
+	return "warn"
+}
+
+// pinMatches reports whether the active version is one the pin allows: the
+// pin's numbers start the active version's, so that "20" allows 20.11.0
+func pinMatches(pin, active string) bool {
+	pinParts, activeParts := strings.Split(pin, "."), strings.Split(active, ".")
+	return len(pinParts) <= len(activeParts) && slices.Equal(pinParts, activeParts[:len(pinParts)])
+}
+
+// activeToolVersions holds the version on PATH of each pinned tool, by pin
+// file and tool, so that each is probed and reported once per run
+var activeToolVersions = struct {
+	sync.Mutex
+	data map[string]string
+}{data: make(map[string]string)}
+
+// checkToolVersion compares the version of the tool cmd's program uses with
+// the project's pin for it. On a mismatch it warns, or with tool_versions =
+// "mise" returns cmd wrapped in mise exec, which applies the pin.
+func (r *CommandRunner) checkToolVersion(cmd *exec.Cmd) *exec.Cmd {
+	if analyzeOnly || r.InContainer || len(cmd.Args) == 0 {
+		return cmd
+	}
+	tool := programTools[strings.TrimSuffix(filepath.Base(cmd.Args[0]), ".exe")]
+	if tool == "" {
+		return cmd
+	}
+	mode := r.toolVersionsMode()
+	if mode == "off" {
+		return cmd
+	}
+	pin := r.toolPin(tool)
+	if pin == nil {
+		return cmd
+	}
+
+	key := filepath.Join(pin.dir, pin.file) + ":" + tool
+	activeToolVersions.Lock()
+	active, probed := activeToolVersions.data[key]
+	if !probed {
+		active = r.probeToolVersion(cmd, tool)
+		activeToolVersions.data[key] = active
+	}
+	activeToolVersions.Unlock()
+	if active == "" || pinMatches(pin.version, active) {
+		return cmd
+	}
+
+	_, err := r.executor.lookPath("mise")
+	canWrap := err == nil && slices.Contains(miseReads, pin.file)
+	mismatch := fmt.Sprintf("%s pins %s %s, but %s on PATH is %s", pin.file, tool, pin.version, tool, active)
+	if mode == "mise" && canWrap {
+		if !probed {
+			fmt.Fprintf(r.statusWriter(), "%s; running through mise exec\n", mismatch)
+		}
+		wrapped := r.executor.command("mise", append([]string{"exec", "--"}, cmd.Args...)...)
+		wrapped.Dir = cmd.Dir
+		wrapped.Env = cmd.Env
+		return wrapped
+	}
+	if !probed {
+		hint := ""
+		switch {
+		case mode == "mise":
+			hint = "; mise can't apply it, since it isn't installed or doesn't read " + pin.file
+		case canWrap:
+			hint = " (set tool_versions = \"mise\" in " + ProjectConfigFile + " to run through mise exec)"
+		}
+		fmt.Fprintf(r.statusWriter(), "Warning: %s%s\n", mismatch, hint)
+	}
+	return cmd
+}
+
+// probeToolVersion returns the version of tool on the PATH cmd runs with,
+// or "" if it can't be told
+func (r *CommandRunner) probeToolVersion(cmd *exec.Cmd, tool string) string {
+	args := toolProbes[tool]
+	probe := r.executor.command(args[0], args[1:]...)
+	probe.Dir = cmd.Dir
+	probe.Env = cmd.Env
+	if probe.Env == nil {
+		probe.Env = r.commandEnv()
+	}
+	output, err := outputWithin(probe, 10*time.Second)
+	if err != nil {
+		return ""
+	}
+	return activeVersionPattern.FindString(string(output))
+}
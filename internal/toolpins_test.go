@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestToolPins(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		".mise.toml":          "[tools]\nnode = \"20\"\npython = [\"3.12\", \"3.11\"]\ngo = { version = \"1.22.1\" }\nusage = \"latest\"\n",
+		".tool-versions":      "nodejs 18.19.0  # shadowed by .mise.toml\nruby 3.3.0\n",
+		"rust-toolchain.toml": "[toolchain]\nchannel = \"1.80\"\n",
+		".nvmrc":              "v16\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, pin := range toolPins(dir) {
+		got = append(got, pin.file+" "+pin.tool+" "+pin.version)
+	}
+	want := []string{
+		".mise.toml go 1.22.1",
+		".mise.toml node 20",
+		".mise.toml python 3.12",
+		".tool-versions node 18.19.0",
+		".tool-versions ruby 3.3.0",
+		"rust-toolchain.toml rust 1.80",
+		".nvmrc node 16",
+	}
+	if !slicesEqual(got, want) {
+		t.Errorf("toolPins() = %q, want %q", got, want)
+	}
+}
+
+func TestPinMatches(t *testing.T) {
+	for _, tt := range []struct {
+		pin, active string
+		want        bool
+	}{
+		{"20", "20.11.0", true},
+		{"20.11", "20.11.0", true},
+		{"20.11.0", "20.11.0", true},
+		{"20", "18.19.0", false},
+		{"1.8", "1.80.0", false},
+		{"3.12.1", "3.12", false},
+	} {
+		if got := pinMatches(tt.pin, tt.active); got != tt.want {
+			t.Errorf("pinMatches(%q, %q) = %v, want %v", tt.pin, tt.active, got, tt.want)
+		}
+	}
+}
+
+func TestCheckToolVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses echo")
+	}
+	factory := func(name string, arg ...string) *exec.Cmd {
+		if name == "node" && len(arg) == 1 && arg[0] == "--version" {
+			return exec.Command("echo", "v18.19.0")
+		}
+		return exec.Command(name, arg...)
+	}
+	lookPath := func(file string) (string, error) {
+		if file == "mise" || file == "npm" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	for _, tt := range []struct {
+		name, config, pinFile, want string
+	}{
+		{"matching pin", "", "node 18\n", "npm test"},
+		{"warn", "", "node 20\n", "npm test"},
+		{"mise", "tool_versions = \"mise\"\n", "node 20\n", "mise exec -- npm test"},
+		{"off", "tool_versions = \"off\"\n", "node 20\n", "npm test"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ".tool-versions"), []byte(tt.pinFile), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.config != "" {
+				writeConfig(t, dir, tt.config)
+			}
+			runner := New("", nil, WithCommandFactory(factory), WithLookPath(lookPath))
+			runner.CurrentDir, runner.ProjectRoot = dir, dir
+			runner.Quiet = true
+
+			cmd := runner.checkToolVersion(exec.Command("npm", "test"))
+			if got := strings.Join(cmd.Args, " "); got != tt.want {
+				t.Errorf("checkToolVersion(npm test) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
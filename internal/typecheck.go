@@ -17,7 +17,7 @@ func HandleTypecheckCommand(r *CommandRunner) error {
 
 	// First try to find a native typecheck command
 	for _, dir := range dirs {
-		project := ResolveProject(dir)
+		project := r.resolveProject(dir)
 		for _, source := range project.CommandSources {
 			if cmd := source.FindCommand("typecheck", r.Args); cmd != nil {
 				return r.ExecuteCommand(cmd)
@@ -27,7 +27,7 @@ func HandleTypecheckCommand(r *CommandRunner) error {
 
 	// Check if this project type supports typechecking
 	if !r.hasTypecheckCapability() {
-		return fmt.Errorf("no typecheck command or type checking capability found for this project")
+		return missingCommandError(fmt.Errorf("no typecheck command or type checking capability found for this project"))
 	}
 
 	// Try to synthesize a typecheck command based on project type
@@ -46,7 +46,7 @@ func (r *CommandRunner) synthesizeTypecheckCommand() error {
 		if FileExists(filepath.Join(dir, "tsconfig.json")) {
 			packageManager := detectPackageManager(dir)
 			if packageManager != "" {
-				fmt.Fprintf(os.Stderr, "Running typecheck using tsc...\n")
+				r.progressf("Running typecheck using tsc...\n")
 				cmd := r.createTypescriptCheckCommand(dir, packageManager)
 				if cmd != nil {
 					return r.ExecuteCommand(cmd)
@@ -60,7 +60,7 @@ func (r *CommandRunner) synthesizeTypecheckCommand() error {
 			content := string(data)
 
 			// Detect if we have a Python package manager
-			project := ResolveProject(dir)
+			project := r.resolveProject(dir)
 			var packageManager string
 			for _, source := range project.CommandSources {
 				switch source.Name() {
@@ -74,29 +74,29 @@ func (r *CommandRunner) synthesizeTypecheckCommand() error {
 				switch packageManager {
 				case "uv":
 					cmdArgs := append([]string{"run", "pyright"}, r.Args...)
-					execCmd = exec.Command("uv", cmdArgs...)
+					execCmd = r.executor.command("uv", cmdArgs...)
 				case "Poetry":
 					cmdArgs := append([]string{"run", "pyright"}, r.Args...)
-					execCmd = exec.Command("poetry", cmdArgs...)
+					execCmd = r.executor.command("poetry", cmdArgs...)
 				default:
 					// Run pyright directly
-					execCmd = exec.Command("pyright", r.Args...)
+					execCmd = r.executor.command("pyright", r.Args...)
 				}
-				fmt.Fprintf(os.Stderr, "Running typecheck using pyright...\n")
+				r.progressf("Running typecheck using pyright...\n")
 			} else if strings.Contains(content, "mypy") {
 				switch packageManager {
 				case "uv":
 					cmdArgs := append([]string{"run", "mypy", "."}, r.Args...)
-					execCmd = exec.Command("uv", cmdArgs...)
+					execCmd = r.executor.command("uv", cmdArgs...)
 				case "Poetry":
 					cmdArgs := append([]string{"run", "mypy", "."}, r.Args...)
-					execCmd = exec.Command("poetry", cmdArgs...)
+					execCmd = r.executor.command("poetry", cmdArgs...)
 				default:
 					// Run mypy directly
 					cmdArgs := append([]string{"."}, r.Args...)
-					execCmd = exec.Command("mypy", cmdArgs...)
+					execCmd = r.executor.command("mypy", cmdArgs...)
 				}
-				fmt.Fprintf(os.Stderr, "Running typecheck using mypy...\n")
+				r.progressf("Running typecheck using mypy...\n")
 			}
 
 			if execCmd != nil {
@@ -107,8 +107,8 @@ func (r *CommandRunner) synthesizeTypecheckCommand() error {
 
 		// Rust projects - use cargo check
 		if FileExists(filepath.Join(dir, "Cargo.toml")) {
-			fmt.Fprintf(os.Stderr, "Running typecheck using cargo check...\n")
-			project := ResolveProject(dir)
+			r.progressf("Running typecheck using cargo check...\n")
+			project := r.resolveProject(dir)
 			if cargoSource := findSourceByName(project.CommandSources, "Cargo"); cargoSource != nil {
 				if cargoCmd := cargoSource.FindCommand("typecheck", r.Args); cargoCmd != nil {
 					return r.ExecuteCommand(cargoCmd)
@@ -118,8 +118,8 @@ func (r *CommandRunner) synthesizeTypecheckCommand() error {
 
 		// Go projects - use go build
 		if FileExists(filepath.Join(dir, "go.mod")) {
-			fmt.Fprintf(os.Stderr, "Running typecheck using go build...\n")
-			project := ResolveProject(dir)
+			r.progressf("Running typecheck using go build...\n")
+			project := r.resolveProject(dir)
 			if goSource := findSourceByName(project.CommandSources, "Go"); goSource != nil {
 				if goCmd := goSource.FindCommand("typecheck", r.Args); goCmd != nil {
 					return r.ExecuteCommand(goCmd)
@@ -128,7 +128,7 @@ func (r *CommandRunner) synthesizeTypecheckCommand() error {
 		}
 	}
 
-	return fmt.Errorf("could not synthesize typecheck command for this project")
+	return missingCommandError(fmt.Errorf("could not synthesize typecheck command for this project"))
 }
 
 // createTypescriptCheckCommand creates a TypeScript check command
@@ -163,7 +163,7 @@ func (r *CommandRunner) createTypescriptCheckCommand(dir string, packageManager
 		args = append([]string{"tsc", "--noEmit"}, r.Args...)
 	}
 
-	cmd := exec.Command(cmdName, args...)
+	cmd := r.executor.command(cmdName, args...)
 	cmd.Dir = dir
 	return cmd
 }
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// upgradeStep is a toolchain updater that upgrade-tools runs, and how to
+// see what it changed
+type upgradeStep struct {
+	name  string // the file that calls for it
+	dir   string
+	args  []string
+	probe func() string // lines of "tool version ...", read before and after
+}
+
+// upgradeSteps returns the toolchain updaters for the nearest of the current
+// directory and project root that calls for any. An updater whose tool
+// isn't installed is reported in skipped instead.
+func (r *CommandRunner) upgradeSteps() (steps []upgradeStep, skipped []string) {
+	for _, dir := range r.searchDirs() {
+		add := func(name, program string, args []string, probe func() string) {
+			if _, err := r.executor.lookPath(program); err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s needs %s, which isn't installed", name, program))
+				return
+			}
+			steps = append(steps, upgradeStep{name, dir, append([]string{program}, args...), probe})
+		}
+
+		if name := firstExisting(dir, ".mise.toml", "mise.toml", ".tool-versions"); name != "" {
+			add(name, "mise", []string{"upgrade"}, r.commandProbe(dir, "mise", "ls", "--current"))
+		}
+		if name := firstExisting(dir, "rust-toolchain.toml", "rust-toolchain", "Cargo.toml"); name != "" {
+			add(name, "rustup", []string{"update"}, r.commandProbe(dir, "rustc", "--version"))
+		}
+		if name, _ := pinnedPackageManager(dir); corepackManages(name) {
+			add("package.json packageManager", "corepack", []string{"up"}, func() string {
+				name, version := pinnedPackageManager(dir)
+				return name + " " + version
+			})
+		}
+		if FileExists(filepath.Join(dir, "uv.lock")) || (FileExists(filepath.Join(dir, "pyproject.toml")) && isUvProject(dir)) {
+			add("pyproject.toml", "uv", []string{"self", "update"}, r.commandProbe(dir, "uv", "--version"))
+		}
+		if len(steps) > 0 || len(skipped) > 0 {
+			return steps, skipped
+		}
+	}
+	return nil, nil
+}
+
+// isUvProject reports whether the Python project in dir uses uv
+func isUvProject(dir string) bool {
+	_, ok := detectPythonProject(dir).(*UvSource)
+	return ok
+}
+
+// commandProbe returns a probe that runs a program in dir for its output
+func (r *CommandRunner) commandProbe(dir, program string, args ...string) func() string {
+	return func() string {
+		cmd := r.executor.command(program, args...)
+		cmd.Dir = dir
+		output, err := outputWithin(cmd, 30*time.Second)
+		if err != nil {
+			return ""
+		}
+		return string(output)
+	}
+}
+
+// UpgradeTools runs the updaters of the toolchains the project uses (mise
+// upgrade, rustup update, corepack up, uv self update) and then writes what
+// each changed. With dryRun it only lists them. Every updater runs even if
+// one fails, and the error reports the failures.
+func (r *CommandRunner) UpgradeTools(w io.Writer, dryRun bool) error {
+	steps, skipped := r.upgradeSteps()
+	for _, message := range skipped {
+		fmt.Fprintf(r.statusWriter(), "Warning: %s; skipping\n", message)
+	}
+	if len(steps) == 0 {
+		return notFoundError(fmt.Errorf("no toolchains to upgrade in current directory or project root"))
+	}
+	if dryRun {
+		for _, step := range steps {
+			fmt.Fprintf(w, "Would run %s (for %s)\n", strings.Join(step.args, " "), step.name)
+		}
+		return nil
+	}
+
+	var summary []string
+	failed := 0
+	for _, step := range steps {
+		command := strings.Join(step.args, " ")
+		if err := refuseToRun(command); err != nil {
+			return err
+		}
+		before := step.probe()
+		cmd := r.executor.command(step.args[0], step.args[1:]...)
+		cmd.Dir = step.dir
+		if err := r.ExecuteCommand(cmd); err != nil {
+			failed++
+			summary = append(summary, fmt.Sprintf("%s %s: %v", passMark(w, false), command, err))
+			continue
+		}
+		changes := versionChanges(before, step.probe())
+		if len(changes) == 0 {
+			summary = append(summary, fmt.Sprintf("%s %s: no changes", passMark(w, true), command))
+			continue
+		}
+		summary = append(summary, fmt.Sprintf("%s %s: %s", passMark(w, true), command, strings.Join(changes, ", ")))
+	}
+
+	fmt.Fprintln(w, "\nSummary:")
+	for _, line := range summary {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d updaters failed", failed, len(steps))
+	}
+	return nil
+}
+
+// versionChanges compares two outputs of a probe, whose lines start with a
+// tool's name and version, and describes each tool whose version changed
+func versionChanges(before, after string) []string {
+	versions := func(output string) (names []string, byName map[string]string) {
+		byName = make(map[string]string)
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			if _, seen := byName[fields[0]]; !seen {
+				names = append(names, fields[0])
+			}
+			byName[fields[0]] = fields[1]
+		}
+		return names, byName
+	}
+	_, old := versions(before)
+	names, current := versions(after)
+	var changes []string
+	for _, name := range names {
+		switch was, ok := old[name]; {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s %s (new)", name, current[name]))
+		case was != current[name]:
+			changes = append(changes, fmt.Sprintf("%s %s → %s", name, was, current[name]))
+		}
+	}
+	return changes
+}
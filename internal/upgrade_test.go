@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"Cargo.toml":     "[package]\nname = \"app\"\n",
+		"pyproject.toml": "[project]\nname = \"app\"\n",
+		"uv.lock":        "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// rustup update changes what rustc --version prints, and uv fails
+	state := filepath.Join(t.TempDir(), "rustc")
+	if err := os.WriteFile(state, []byte("rustc 1.80.0 (051478957 2024-07-21)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	factory := func(name string, arg ...string) *exec.Cmd {
+		switch strings.Join(append([]string{name}, arg...), " ") {
+		case "rustup update":
+			return exec.Command("sh", "-c", `echo "rustc 1.81.0 (eeb90cda1 2024-09-04)" > "$1"`, "sh", state)
+		case "rustc --version":
+			return exec.Command("cat", state)
+		case "uv --version":
+			return exec.Command("echo", "uv 0.4.0")
+		}
+		return exec.Command("false")
+	}
+	lookPath := func(file string) (string, error) {
+		if file == "rustup" || file == "uv" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	runner := New("", nil, WithCommandFactory(factory), WithLookPath(lookPath))
+	runner.CurrentDir, runner.ProjectRoot = dir, dir
+	runner.Quiet = true
+
+	var out strings.Builder
+	if err := runner.UpgradeTools(&out, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Would run rustup update (for Cargo.toml)\nWould run uv self update (for pyproject.toml)\n"; out.String() != want {
+		t.Errorf("dry run = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := runner.UpgradeTools(&out, false); err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("UpgradeTools() error = %v, want one failure", err)
+	}
+	for _, want := range []string{
+		"✓ rustup update: rustc 1.80.0 → 1.81.0",
+		"✗ uv self update: ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("UpgradeTools() = %q, missing %q", out.String(), want)
+		}
+	}
+}
+
+func TestVersionChanges(t *testing.T) {
+	before := "node  20.1.0  ~/.mise.toml  20\npython  3.12.1  ~/.mise.toml  3.12\n"
+	after := "node  20.11.0  ~/.mise.toml  20\npython  3.12.1  ~/.mise.toml  3.12\ngo  1.23.0  ~/.mise.toml  1.23\n"
+	want := []string{"node 20.1.0 → 20.11.0", "go 1.23.0 (new)"}
+	if got := versionChanges(before, after); !slicesEqual(got, want) {
+		t.Errorf("versionChanges() = %q, want %q", got, want)
+	}
+	if got := versionChanges(after, after); len(got) != 0 {
+		t.Errorf("versionChanges() of the same output = %q", got)
+	}
+}
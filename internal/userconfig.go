@@ -0,0 +1,314 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// UserConfig is the global configuration in ~/.config/cmdr/config.toml
+type UserConfig struct {
+	Path string
+
+	// SourceOrder lists source names (e.g. "just", "npm") that should be
+	// preferred, in order, over the default priorities
+	SourceOrder []string
+
+	// DefaultFlags are cmdr options inserted before the command line arguments
+	DefaultFlags []string
+
+	// Color is "auto", "always", or "never"
+	Color string
+
+	// Aliases map a name to a command line, e.g. ut = "test --unit"
+	Aliases map[string]string
+
+	// Commands are available in every project
+	Commands map[string]CommandConfig
+
+	// Projects map names to project directories, for `cmdr -p <name>`
+	Projects map[string]string
+
+	// AuditLog is the file that records every command cmdr runs, or ""
+	AuditLog string
+
+	// Jobs is the default for --jobs; SourceJobs limits how many commands
+	// from a source run at once
+	Jobs       int
+	SourceJobs map[string]int
+
+	// Time prints how long each run took, as --time does
+	Time bool
+
+	// Banner is the line written before a command runs, with {command} and
+	// {dir} placeholders; "" turns it off and nil keeps the default
+	Banner *string
+
+	// LogDir is a directory to copy each run's output to a file in, as
+	// --log-file does
+	LogDir string
+
+	// History records each run in the history file; nil means true
+	History *bool
+
+	// Cache keeps source listings on disk between runs; nil means true
+	Cache *bool
+
+	// PrefixMatch lets a unique prefix of a command name run that command
+	PrefixMatch bool
+
+	// Pick makes cmdr with no arguments open the picker on a terminal
+	Pick bool
+
+	// Colors override the styles of parts of the output, by role
+	Colors map[string]string
+}
+
+// UserConfigPath returns the location of the user config file, honoring
+// XDG_CONFIG_HOME
+func UserConfigPath() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "cmdr", "config.toml")
+}
+
+// parseUserConfig reads a user config file
+func parseUserConfig(path string) (*UserConfig, error) {
+	var raw struct {
+		SourceOrder  []string                  `toml:"source_order"`
+		DefaultFlags []string                  `toml:"default_flags"`
+		Color        string                    `toml:"color"`
+		Aliases      map[string]string         `toml:"aliases"`
+		Commands     map[string]toml.Primitive `toml:"commands"`
+		Projects     map[string]string         `toml:"projects"`
+		AuditLog     string                    `toml:"audit_log"`
+		Jobs         int                       `toml:"jobs"`
+		SourceJobs   map[string]int            `toml:"source_jobs"`
+		Banner       *string                   `toml:"banner"`
+		Time         bool                      `toml:"time"`
+		History      *bool                     `toml:"history"`
+		Cache        *bool                     `toml:"cache"`
+		LogDir       string                    `toml:"log_dir"`
+		PrefixMatch  bool                      `toml:"prefix_match"`
+		Pick         bool                      `toml:"pick"`
+		Colors       map[string]string         `toml:"colors"`
+	}
+	md, err := toml.DecodeFile(path, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch raw.Color {
+	case "", "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("color must be auto, always, or never, got %q", raw.Color)
+	}
+
+	if raw.Jobs < 0 {
+		return nil, fmt.Errorf("jobs must not be negative, got %d", raw.Jobs)
+	}
+	if err := validateSourceJobs(raw.SourceJobs); err != nil {
+		return nil, err
+	}
+	if err := validateColors(raw.Colors); err != nil {
+		return nil, err
+	}
+
+	commands, err := decodeCommands(md, raw.Commands)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUndecoded(md); err != nil {
+		return nil, err
+	}
+
+	return &UserConfig{
+		Path:         path,
+		SourceOrder:  raw.SourceOrder,
+		DefaultFlags: raw.DefaultFlags,
+		Color:        raw.Color,
+		Aliases:      raw.Aliases,
+		Commands:     commands,
+		Projects:     raw.Projects,
+		AuditLog:     raw.AuditLog,
+		Jobs:         raw.Jobs,
+		SourceJobs:   raw.SourceJobs,
+		Banner:       raw.Banner,
+		Time:         raw.Time,
+		History:      raw.History,
+		Cache:        raw.Cache,
+		LogDir:       raw.LogDir,
+		PrefixMatch:  raw.PrefixMatch,
+		Pick:         raw.Pick,
+		Colors:       raw.Colors,
+	}, nil
+}
+
+// userConfigCache holds the parsed user config by path
+var userConfigCache = struct {
+	sync.Mutex
+	data map[string]*UserConfig
+}{data: make(map[string]*UserConfig)}
+
+// LoadUserConfig returns the user config, or nil if there is none or it
+// can't be parsed
+func LoadUserConfig() *UserConfig {
+	path := UserConfigPath()
+	if path == "" {
+		return nil
+	}
+
+	userConfigCache.Lock()
+	defer userConfigCache.Unlock()
+	if config, ok := userConfigCache.data[path]; ok {
+		return config
+	}
+
+	var config *UserConfig
+	if FileExists(path) {
+		var err error
+		config, err = parseUserConfig(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", path, err)
+		}
+	}
+	userConfigCache.data[path] = config
+	return config
+}
+
+// DefaultFlags returns the cmdr options configured in the user config
+func DefaultFlags() []string {
+	if config := LoadUserConfig(); config != nil {
+		return config.DefaultFlags
+	}
+	return nil
+}
+
+// RegisteredProjects returns the projects in the user config, with their
+// directories expanded
+func RegisteredProjects() map[string]string {
+	config := LoadUserConfig()
+	if config == nil {
+		return nil
+	}
+	projects := make(map[string]string)
+	for name, dir := range config.Projects {
+		projects[name] = expandProjectDir(config.Path, dir)
+	}
+	return projects
+}
+
+// expandProjectDir expands ~ and environment variables in a project
+// directory. Relative directories are relative to the config file.
+func expandProjectDir(configPath, dir string) string {
+	dir = os.ExpandEnv(dir)
+	if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = home + rest
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(configPath), dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// ProjectDir returns the directory of a project registered in the user
+// config. A name that isn't registered may also be a path to a directory.
+func ProjectDir(name string) (string, error) {
+	projects := RegisteredProjects()
+	if dir, ok := projects[name]; ok {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return "", configError(fmt.Errorf("project '%s' is registered as %s, which is not a directory", name, dir))
+		}
+		return dir, nil
+	}
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return filepath.Abs(name)
+	}
+	if len(projects) == 0 {
+		return "", notFoundError(fmt.Errorf("no project named '%s'; register projects under [projects] in %s", name, UserConfigPath()))
+	}
+	return "", notFoundError(fmt.Errorf("no project named '%s' (registered: %s)", name, strings.Join(sortCommands(projects), ", ")))
+}
+
+// ShowProjects lists the registered projects and their directories
+func ShowProjects(w io.Writer) {
+	projects := RegisteredProjects()
+	if len(projects) == 0 {
+		fmt.Fprintf(w, "No projects registered; add them under [projects] in %s\n", UserConfigPath())
+		return
+	}
+	width := 0
+	for name := range projects {
+		width = max(width, len(name))
+	}
+	for _, name := range sortCommands(projects) {
+		dir := projects[name]
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			dir += " (missing)"
+		}
+		fmt.Fprintf(w, "%-*s  %s\n", width, name, dir)
+	}
+}
+
+// expandUserAlias returns the command and arguments for a user-defined
+// alias, or ok=false if command is not an alias
+func expandUserAlias(command string, args []string) (string, []string, bool) {
+	config := LoadUserConfig()
+	if config == nil {
+		return "", nil, false
+	}
+	expansion, ok := config.Aliases[command]
+	if !ok {
+		return "", nil, false
+	}
+	fields := strings.Fields(expansion)
+	if len(fields) == 0 || fields[0] == command {
+		return "", nil, false
+	}
+	return fields[0], append(fields[1:], args...), true
+}
+
+// applySourceOrder moves the sources named in the user's source_order to the
+// front, in that order. The project config source always stays first.
+func applySourceOrder(sources []CommandSource) {
+	config := LoadUserConfig()
+	if config == nil || len(config.SourceOrder) == 0 {
+		return
+	}
+
+	rank := func(source CommandSource) int {
+		if _, ok := source.(*ConfigSource); ok && source.Priority() == 0 {
+			return -1
+		}
+		for i, name := range config.SourceOrder {
+			if strings.EqualFold(name, source.Name()) {
+				return i
+			}
+		}
+		return len(config.SourceOrder)
+	}
+
+	// Stable insertion sort, preserving priority order among unranked sources
+	for i := 1; i < len(sources); i++ {
+		key := sources[i]
+		j := i - 1
+		for j >= 0 && rank(sources[j]) > rank(key) {
+			sources[j+1] = sources[j]
+			j--
+		}
+		sources[j+1] = key
+	}
+}
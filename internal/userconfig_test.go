@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setUserConfig points XDG_CONFIG_HOME at a temporary user config
+func setUserConfig(t *testing.T, content string) {
+	t.Helper()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if err := os.MkdirAll(filepath.Join(configHome, "cmdr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "cmdr", "config.toml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadUserConfig(t *testing.T) {
+	setUserConfig(t, `source_order = ["npm"]
+default_flags = ["--retry", "2"]
+color = "never"
+
+[aliases]
+ut = "test --unit"
+
+[commands]
+todo = "rg TODO"
+`)
+
+	config := LoadUserConfig()
+	if config == nil {
+		t.Fatal("LoadUserConfig() = nil")
+	}
+	if !slicesEqual(config.DefaultFlags, []string{"--retry", "2"}) || config.Color != "never" {
+		t.Errorf("LoadUserConfig() = %+v", config)
+	}
+
+	command, args, ok := expandUserAlias("ut", []string{"-v"})
+	if !ok || command != "test" || !slicesEqual(args, []string{"--unit", "-v"}) {
+		t.Errorf("expandUserAlias(ut) = %q, %v, %v", command, args, ok)
+	}
+	if _, _, ok := expandUserAlias("test", nil); ok {
+		t.Error("expandUserAlias(test) should not expand a non-alias")
+	}
+}
+
+func TestUserConfigSourceOrder(t *testing.T) {
+	setUserConfig(t, `source_order = ["npm"]
+
+[commands]
+todo = "rg TODO"
+`)
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte("build:\n\ttrue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "package.json"), []byte(`{"scripts": {"build": "tsc"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	project := ResolveProject(tempDir)
+	names := []string{}
+	for _, source := range project.CommandSources {
+		names = append(names, source.Name())
+	}
+	expected := []string{"npm", "make", "user config"}
+	if !slicesEqual(names, expected) {
+		t.Errorf("ResolveProject() sources = %v, want %v", names, expected)
+	}
+}
+
+func TestProjectDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, dir := range []string{"src/api", "work/web"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setUserConfig(t, `[projects]
+api = "~/src/api"
+web = "$HOME/work/web"
+gone = "~/src/gone"
+`)
+
+	tests := []struct {
+		name     string
+		expected string
+		message  string
+	}{
+		{name: "api", expected: filepath.Join(home, "src", "api")},
+		{name: "web", expected: filepath.Join(home, "work", "web")},
+		{name: home, expected: home},
+		{name: "gone", message: "not a directory"},
+		{name: "other", message: "registered: api, gone, web"},
+	}
+	for _, tt := range tests {
+		dir, err := ProjectDir(tt.name)
+		if tt.message != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("ProjectDir(%q) error = %v, want %q", tt.name, err, tt.message)
+			}
+			continue
+		}
+		if err != nil || dir != tt.expected {
+			t.Errorf("ProjectDir(%q) = %q, %v; want %q", tt.name, dir, err, tt.expected)
+		}
+	}
+}
+
+func TestBanner(t *testing.T) {
+	cmd := exec.Command("make", "test")
+	cmd.Dir = "/project"
+	runner := &CommandRunner{}
+
+	setUserConfig(t, "")
+	if got := runner.banner(cmd); got != "Running: make test" {
+		t.Errorf("default banner = %q", got)
+	}
+
+	setUserConfig(t, `banner = "→ {command} in {dir}"`)
+	if got := runner.banner(cmd); got != "→ make test in /project" {
+		t.Errorf("configured banner = %q", got)
+	}
+
+	runner.Quiet = true
+	if got := runner.banner(cmd); got != "" {
+		t.Errorf("quiet banner = %q, want none", got)
+	}
+
+	setUserConfig(t, `banner = ""`)
+	if got := (&CommandRunner{}).banner(cmd); got != "" {
+		t.Errorf("empty banner = %q, want none", got)
+	}
+}
@@ -0,0 +1,369 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// watchDebounce is how long to wait for more changes before starting a run
+const watchDebounce = 200 * time.Millisecond
+
+// WatchRule runs Commands when a file matching Pattern changes. An empty
+// pattern matches every file.
+type WatchRule struct {
+	Pattern  string
+	Commands []string
+}
+
+// matches reports whether the rule applies to a path relative to the watch root
+func (rule WatchRule) matches(path string) bool {
+	return rule.Pattern == "" || matchGlob(rule.Pattern, filepath.ToSlash(path))
+}
+
+// validateGlob checks that each segment of a glob is well-formed
+func validateGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := pathpkg.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchGlob matches a slash-separated path against a glob in which "**"
+// matches any number of directories. A pattern without a slash matches the
+// file name at any depth, as in .gitignore.
+func matchGlob(pattern, path string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := pathpkg.Match(pattern, pathpkg.Base(path))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := pathpkg.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// ProjectWatchRules returns the [watch] rules from the nearest .cmdr.toml
+// that has them, and the directory their globs are relative to
+func (r *CommandRunner) ProjectWatchRules() ([]WatchRule, string) {
+	for _, dir := range r.searchDirs() {
+		config := loadProjectConfig(dir)
+		if config == nil || len(config.Watch) == 0 {
+			continue
+		}
+		rules := []WatchRule{}
+		for _, pattern := range sortCommands(config.Watch) {
+			rules = append(rules, WatchRule{Pattern: pattern, Commands: config.Watch[pattern]})
+		}
+		return rules, dir
+	}
+	return nil, ""
+}
+
+// watchResult is the latest outcome of one watched command
+type watchResult struct {
+	name     string
+	status   string // "ok", "failed", "cancelled", or "error"
+	duration time.Duration
+	output   []byte
+}
+
+// watchSession tracks the commands under watch and their latest results
+type watchSession struct {
+	runner   *CommandRunner
+	commands []string // every watched command, in display order
+	latest   map[string]watchResult
+	slots    *jobSlots
+}
+
+// Watch runs the commands, then re-runs them whenever files under the
+// project root change. Changes that arrive while a run is in progress cancel
+// it and start a new one. Watch returns when interrupted, or with ctx.Err()
+// once the runner's context is done.
+func (r *CommandRunner) Watch(commands []string) error {
+	root := r.ProjectRoot
+	if root == "" {
+		root = r.CurrentDir
+	}
+	return r.WatchRules([]WatchRule{{Commands: commands}}, root)
+}
+
+// WatchRules watches root and, for each batch of changes, runs the commands
+// of every rule that matches a changed file
+func (r *CommandRunner) WatchRules(rules []WatchRule, root string) error {
+	if err := refuseToRun("watched commands"); err != nil {
+		return err
+	}
+	session := &watchSession{runner: r, latest: make(map[string]watchResult), slots: r.newJobSlots()}
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		for _, name := range rule.Commands {
+			if !seen[name] {
+				seen[name] = true
+				session.commands = append(session.commands, name)
+			}
+		}
+	}
+	if len(session.commands) == 0 {
+		return fmt.Errorf("no commands to watch")
+	}
+
+	watcher := newWatcher(root)
+	defer watcher.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	fmt.Fprintf(os.Stderr, "%sWatching %s for changes (Ctrl+C to stop)\n", r.badge(), root)
+	cancel := session.start(session.commands, nil)
+	for {
+		select {
+		case <-interrupt:
+			cancel()
+			return nil
+		case <-r.context().Done():
+			cancel()
+			return r.context().Err()
+		case changed := <-watcher.Changes():
+			changed = coalesceChanges(watcher, changed)
+			// A changed justfile or .cmdr.toml changes what the commands run
+			invalidatePaths(changed)
+			changed = relativePaths(root, changed)
+			triggered := triggeredCommands(rules, changed)
+			if len(triggered) == 0 {
+				continue
+			}
+			// Commands cut short by this change run again along with the
+			// newly triggered ones
+			unfinished := cancel()
+			cancel = session.start(session.ordered(append(unfinished, triggered...)), changed)
+		}
+	}
+}
+
+// triggeredCommands returns the commands of the rules that match any of the
+// changed paths
+func triggeredCommands(rules []WatchRule, changed []string) []string {
+	commands := []string{}
+	for _, rule := range rules {
+		for _, path := range changed {
+			if rule.matches(path) {
+				commands = append(commands, rule.Commands...)
+				break
+			}
+		}
+	}
+	return commands
+}
+
+// ordered returns the distinct names in display order
+func (s *watchSession) ordered(names []string) []string {
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+	result := []string{}
+	for _, name := range s.commands {
+		if wanted[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// coalesceChanges collects further changes until the tree is quiet for the
+// debounce interval
+func coalesceChanges(watcher fileWatcher, changed []string) []string {
+	seen := make(map[string]bool)
+	for _, path := range changed {
+		seen[path] = true
+	}
+	timer := time.NewTimer(watchDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case more := <-watcher.Changes():
+			for _, path := range more {
+				seen[path] = true
+			}
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			return sortCommands(seen)
+		}
+	}
+}
+
+// relativePaths returns paths relative to root where possible
+func relativePaths(root string, paths []string) []string {
+	relative := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = rel
+		}
+		relative = append(relative, path)
+	}
+	return relative
+}
+
+// start runs the commands concurrently in the background. The returned
+// function cancels any that are still running, waits for the batch to finish,
+// and returns the names of the commands that did not complete.
+func (s *watchSession) start(commands []string, changed []string) func() []string {
+	ctx, cancel := context.WithCancel(s.runner.context())
+	results := make([]watchResult, len(commands))
+	done := make(chan struct{})
+
+	if len(changed) > 0 {
+		summary := changed[0]
+		if len(changed) > 1 {
+			summary += fmt.Sprintf(" (+%d more)", len(changed)-1)
+		}
+		fmt.Fprintf(os.Stderr, "\n%s[%s] Changed: %s → %s\n", s.runner.badge(), time.Now().Format("15:04:05"), summary, strings.Join(commands, ", "))
+	}
+
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i, name := range commands {
+			wg.Add(1)
+			go func(i int, name string) {
+				defer wg.Done()
+				results[i] = s.runner.runWatched(ctx, name, s.slots)
+			}(i, name)
+		}
+		wg.Wait()
+		if ctx.Err() == nil {
+			s.report(results)
+		}
+	}()
+
+	return func() []string {
+		cancel()
+		<-done
+		unfinished := []string{}
+		for _, result := range results {
+			if result.status == "cancelled" {
+				unfinished = append(unfinished, result.name)
+			}
+		}
+		return unfinished
+	}
+}
+
+// watchedCommand returns the process to run for a watched command. Commands
+// that resolve to a single invocation run directly; synthesized commands
+// run through a nested cmdr.
+func (r *CommandRunner) watchedCommand(name string) (*exec.Cmd, error) {
+	sub := r.subRunner(name, r.Args)
+	sub.applyCommandConfig()
+	if cmd, err := sub.Resolve(); err == nil {
+		if cmd.Env == nil {
+			cmd.Env = sub.commandEnv()
+		}
+		return cmd, nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := r.executor.command(self, append([]string{name}, r.Args...)...)
+	cmd.Dir = r.CurrentDir
+	cmd.Env = r.commandEnv()
+	return cmd, nil
+}
+
+// runWatched runs one command, capturing its output, once slots has room
+// for it and until it exits or ctx is cancelled
+func (r *CommandRunner) runWatched(ctx context.Context, name string, slots *jobSlots) watchResult {
+	result := watchResult{name: name}
+
+	cmd, err := r.watchedCommand(name)
+	if err != nil {
+		result.status = "error"
+		result.output = []byte(err.Error() + "\n")
+		return result
+	}
+	release, err := slots.acquire(ctx, r.commandSource(name))
+	if err != nil {
+		result.status = "cancelled"
+		return result
+	}
+	defer release()
+	start := time.Now()
+	output := &tailBuffer{max: 1024 * 1024}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err = runContext(ctx, cmd)
+	r.audit("command", cmd, start, err)
+	if ctx.Err() != nil {
+		result.status = "cancelled"
+		return result
+	}
+	result.duration = time.Since(start)
+	result.output = output.Bytes()
+	if err != nil {
+		result.status = "failed"
+		if len(result.output) == 0 {
+			result.output = []byte(err.Error() + "\n")
+		}
+	} else {
+		result.status = "ok"
+	}
+	return result
+}
+
+// report records a finished batch and prints the output of its failed
+// commands, followed by the latest status of every watched command
+func (s *watchSession) report(results []watchResult) {
+	for _, result := range results {
+		s.latest[result.name] = result
+		if result.status == "failed" || result.status == "error" {
+			fmt.Fprintf(os.Stderr, "\n── %s ──\n%s", result.name, strings.TrimRight(string(result.output), "\n"))
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr)
+	for _, name := range s.commands {
+		result, ok := s.latest[name]
+		switch {
+		case !ok:
+			fmt.Fprintf(os.Stderr, "  · %s\n", name)
+		case result.status == "ok":
+			fmt.Fprintf(os.Stderr, "  %s %-12s %s\n", passMark(os.Stderr, true), name, result.duration.Round(100*time.Millisecond))
+		default:
+			fmt.Fprintf(os.Stderr, "  %s %-12s %s\n", passMark(os.Stderr, false), name, result.duration.Round(100*time.Millisecond))
+		}
+	}
+}
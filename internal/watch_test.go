@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "internal/watch.go", true},
+		{"**/*.go", "internal/watch.go.orig", false},
+		{"*.css", "web/styles/site.css", true},
+		{"src/**/*.ts", "src/a/b/c.ts", true},
+		{"src/**/*.ts", "lib/a.ts", false},
+		{"docs/*.md", "docs/a/b.md", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTriggeredCommands(t *testing.T) {
+	rules := []WatchRule{
+		{Pattern: "**/*.css", Commands: []string{"format"}},
+		{Pattern: "**/*.go", Commands: []string{"test", "lint"}},
+	}
+	got := triggeredCommands(rules, []string{"cmd/main.go"})
+	want := []string{"test", "lint"}
+	if !slicesEqual(got, want) {
+		t.Errorf("triggeredCommands() = %v, want %v", got, want)
+	}
+}
+
+func TestRunWatchedAudits(t *testing.T) {
+	setUserConfig(t, "")
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("CMDR_AUDIT_LOG", path)
+	dir := t.TempDir()
+	writeConfig(t, dir, "[commands]\ngreet = \"echo watched\"\n")
+	runner := &CommandRunner{CurrentDir: dir, ProjectRoot: dir, Quiet: true}
+
+	result := runner.runWatched(context.Background(), "greet", runner.newJobSlots())
+	if result.status != "ok" {
+		t.Fatalf("runWatched() status = %q, output %q", result.status, result.output)
+	}
+	lines, err := readJSONLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("audit log has %d lines, want 1", len(lines))
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(lines[0], &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Kind != "command" || entry.Cwd != dir || !strings.Contains(strings.Join(entry.Argv, " "), "echo watched") {
+		t.Errorf("audit entry = %+v, want the watched command", entry)
+	}
+}
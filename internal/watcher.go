@@ -0,0 +1,372 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileWatcher reports batches of changed paths
+type fileWatcher interface {
+	Changes() <-chan []string
+	Close()
+}
+
+// pollInterval is how often the polling watcher rescans the tree
+const pollInterval = 500 * time.Millisecond
+
+// eventBatchWindow groups native events that arrive close together
+const eventBatchWindow = 50 * time.Millisecond
+
+// defaultIgnoredDirs are version control, dependency, and cache directories
+// that are never watched
+var defaultIgnoredDirs = map[string]bool{
+	".git": true, ".hg": true, ".jj": true, ".svn": true,
+	"node_modules": true, "vendor": true, "target": true,
+	".venv": true, "venv": true, "__pycache__": true, ".tox": true,
+	".mypy_cache": true, ".pytest_cache": true, ".ruff_cache": true,
+	".gradle": true,
+}
+
+// watchIgnore decides which paths under a watch root are excluded
+type watchIgnore struct {
+	root     string
+	patterns []string // from the root .gitignore
+}
+
+func newWatchIgnore(root string) *watchIgnore {
+	ignore := &watchIgnore{root: root}
+	file, err := os.Open(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return ignore
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Negations can't be honored without full gitignore semantics, so
+		// they are skipped rather than misapplied
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		if validateGlob(strings.Trim(line, "/")) == nil {
+			ignore.patterns = append(ignore.patterns, line)
+		}
+	}
+	return ignore
+}
+
+// ignored reports whether path, which is under the root, is excluded
+func (w *watchIgnore) ignored(path string, isDir bool) bool {
+	if path == w.root {
+		return false
+	}
+	if isDir && defaultIgnoredDirs[filepath.Base(path)] {
+		return true
+	}
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range w.patterns {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+		// A leading slash anchors the pattern to the root
+		if anchored, ok := strings.CutPrefix(pattern, "/"); ok {
+			if matchSegments(strings.Split(anchored, "/"), strings.Split(rel, "/")) {
+				return true
+			}
+			continue
+		}
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkDirs calls fn for every directory under the root that isn't ignored
+func (w *watchIgnore) walkDirs(fn func(dir string) error) error {
+	return filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if w.ignored(path, true) {
+			return filepath.SkipDir
+		}
+		return fn(path)
+	})
+}
+
+// newWatcher returns a native watcher for root, falling back to polling with
+// a warning when the platform's watch limits would be exceeded
+func newWatcher(root string) fileWatcher {
+	ignore := newWatchIgnore(root)
+	watcher, err := newNativeWatcher(ignore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; polling for changes instead\n", err)
+		return newPollingWatcher(ignore, pollInterval)
+	}
+	return watcher
+}
+
+// nativeWatcher uses the platform's file notification API (inotify, kqueue,
+// or ReadDirectoryChangesW) with one watch per directory
+type nativeWatcher struct {
+	ignore  *watchIgnore
+	watcher *fsnotify.Watcher
+	changes chan []string
+	done    chan struct{}
+}
+
+func newNativeWatcher(ignore *watchIgnore) (*nativeWatcher, error) {
+	dirs := []string{}
+	_ = ignore.walkDirs(func(dir string) error {
+		dirs = append(dirs, dir)
+		return nil
+	})
+	if limit := watchLimit(); limit > 0 && len(dirs) > limit {
+		return nil, fmt.Errorf("%d directories exceed the limit of %d file watches", len(dirs), limit)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &nativeWatcher{
+		ignore:  ignore,
+		watcher: watcher,
+		changes: make(chan []string),
+		done:    make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		if err := w.add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+	go w.loop()
+	return w, nil
+}
+
+// add watches dir, reporting watch exhaustion as an error. Directories that
+// vanish before they can be watched are skipped.
+func (w *nativeWatcher) add(dir string) error {
+	err := w.watcher.Add(dir)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if isWatchLimitError(err) {
+		return fmt.Errorf("ran out of file watches at %s", dir)
+	}
+	return err
+}
+
+func (w *nativeWatcher) Changes() <-chan []string {
+	return w.changes
+}
+
+func (w *nativeWatcher) Close() {
+	close(w.done)
+	_ = w.watcher.Close()
+}
+
+// loop collects events into batches, emitting a batch once no event has
+// arrived for eventBatchWindow
+func (w *nativeWatcher) loop() {
+	pending := make(map[string]bool)
+	timer := time.NewTimer(eventBatchWindow)
+	timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			info, err := os.Stat(event.Name)
+			isDir := err == nil && info.IsDir()
+			if w.ignore.ignored(event.Name, isDir) {
+				continue
+			}
+			if isDir && event.Has(fsnotify.Create) {
+				// New directories are watched recursively; files created in
+				// them before the watch was added are picked up by the walk
+				_ = filepath.WalkDir(event.Name, func(path string, d fs.DirEntry, err error) error {
+					if err != nil {
+						return nil
+					}
+					if d.IsDir() {
+						if w.ignore.ignored(path, true) {
+							return filepath.SkipDir
+						}
+						if err := w.add(path); err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: %v; changes under %s will be missed\n", err, path)
+							return filepath.SkipDir
+						}
+						return nil
+					}
+					pending[path] = true
+					return nil
+				})
+				timer.Reset(eventBatchWindow)
+				continue
+			}
+			pending[event.Name] = true
+			timer.Reset(eventBatchWindow)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Warning: file watch error: %v\n", err)
+		case <-timer.C:
+			if len(pending) == 0 {
+				continue
+			}
+			batch := sortCommands(pending)
+			pending = make(map[string]bool)
+			select {
+			case w.changes <- batch:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// watchLimit returns the maximum number of directory watches, or 0 if the
+// platform has no fixed limit that can be read
+func watchLimit() int {
+	if runtime.GOOS != "linux" {
+		return 0
+	}
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return 0
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return limit
+}
+
+// isWatchLimitError reports whether err means the process or user has run
+// out of watches or file descriptors
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
+
+// pollingWatcher detects changes by periodically scanning the tree
+type pollingWatcher struct {
+	ignore   *watchIgnore
+	interval time.Duration
+	changes  chan []string
+	done     chan struct{}
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newPollingWatcher(ignore *watchIgnore, interval time.Duration) *pollingWatcher {
+	w := &pollingWatcher{
+		ignore:   ignore,
+		interval: interval,
+		changes:  make(chan []string),
+		done:     make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *pollingWatcher) Changes() <-chan []string {
+	return w.changes
+}
+
+func (w *pollingWatcher) Close() {
+	close(w.done)
+}
+
+func (w *pollingWatcher) loop() {
+	previous := w.scan()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			current := w.scan()
+			if changed := diffStamps(previous, current); len(changed) > 0 {
+				select {
+				case w.changes <- changed:
+				case <-w.done:
+					return
+				}
+			}
+			previous = current
+		}
+	}
+}
+
+// scan records the stamp of every file under the root
+func (w *pollingWatcher) scan() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	_ = w.ignore.walkDirs(func(dir string) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || w.ignore.ignored(path, false) {
+				continue
+			}
+			if info, err := entry.Info(); err == nil {
+				stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			}
+		}
+		return nil
+	})
+	return stamps
+}
+
+// diffStamps returns the sorted paths that were added, removed, or modified
+func diffStamps(previous, current map[string]fileStamp) []string {
+	changed := []string{}
+	for path, stamp := range current {
+		if old, ok := previous[path]; !ok || old != stamp {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffStamps(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileStamp{
+		"a.go": {modTime: now, size: 1},
+		"b.go": {modTime: now, size: 2},
+		"c.go": {modTime: now, size: 3},
+	}
+	current := map[string]fileStamp{
+		"a.go": {modTime: now, size: 1},
+		"b.go": {modTime: now.Add(time.Second), size: 2},
+		"d.go": {modTime: now, size: 4},
+	}
+
+	got := diffStamps(previous, current)
+	want := []string{"b.go", "c.go", "d.go"}
+	if !slicesEqual(got, want) {
+		t.Errorf("diffStamps() = %v, want %v", got, want)
+	}
+}
+
+func TestPollingWatcherSkipsIgnoredDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"main.go", ".git/HEAD", "pkg/lib.go"} {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &pollingWatcher{ignore: newWatchIgnore(root)}
+	stamps := w.scan()
+	got := relativePaths(root, sortCommands(stamps))
+	want := []string{"main.go", filepath.Join("pkg", "lib.go")}
+	if !slicesEqual(got, want) {
+		t.Errorf("scan() = %v, want %v", got, want)
+	}
+}
+
+func TestWatchIgnore(t *testing.T) {
+	root := t.TempDir()
+	gitignore := "# build output\n/dist\n*.log\ntmp/\n!keep.log\n"
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte(gitignore), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ignore := newWatchIgnore(root)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"dist", true, true},
+		{"web/dist", true, false},
+		{"debug.log", false, true},
+		{"logs/server.log", false, true},
+		{"tmp", true, true},
+		{"tmp", false, false},
+		{"src", true, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := ignore.ignored(filepath.Join(root, tt.path), tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestNativeWatcherReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "node_modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	w, err := newNativeWatcher(newWatchIgnore(root))
+	if err != nil {
+		t.Skipf("native watcher unavailable: %v", err)
+	}
+	defer w.Close()
+
+	if err := os.WriteFile(filepath.Join(root, "node_modules", "dep.js"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "lib.go"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changed := <-w.Changes():
+		got := relativePaths(root, changed)
+		want := []string{filepath.Join("pkg", "lib.go")}
+		if !slicesEqual(got, want) {
+			t.Errorf("Changes() = %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes reported")
+	}
+}
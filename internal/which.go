@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// WhichFormats lists the output formats supported by ShowWhich
+var WhichFormats = []string{"text", "shell", "json", "argv"}
+
+// Invocation is the machine-readable form of a resolved command
+type Invocation struct {
+	Argv   []string          `json:"argv"`
+	Path   string            `json:"path,omitempty"`
+	Cwd    string            `json:"cwd"`
+	Env    map[string]string `json:"env"`
+	Source string            `json:"source,omitempty"`
+}
+
+// invocation describes cmd together with the environment cmd-runner adds
+func (r *CommandRunner) invocation(cmd *exec.Cmd) Invocation {
+	env := make(map[string]string)
+	for _, v := range r.ResolveEnv(false) {
+		env[v.Name] = v.Value
+	}
+	return Invocation{
+		Argv: cmd.Args,
+		Path: cmd.Path,
+		Cwd:  cmd.Dir,
+		Env:  env,
+	}
+}
+
+// ShowWhich resolves the runner's command and writes the invocation in the
+// given format
+func (r *CommandRunner) ShowWhich(w io.Writer, format string) error {
+	plan, err := r.Resolution()
+	if err != nil {
+		return err
+	}
+	inv := r.invocation(plan.Cmd)
+	inv.Source = plan.Source
+
+	switch format {
+	case "", "text":
+		fmt.Fprintln(w, strings.Join(inv.Argv, " "))
+		if inv.Cwd != "" && inv.Cwd != r.CurrentDir {
+			fmt.Fprintf(w, "  (in %s)\n", inv.Cwd)
+		}
+	case "shell":
+		fmt.Fprintln(w, shellCommandLine(inv))
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(inv)
+	case "argv":
+		// NUL-separated, for consumption by xargs -0 and similar tools
+		for _, arg := range inv.Argv {
+			fmt.Fprintf(w, "%s\x00", arg)
+		}
+	default:
+		return fmt.Errorf("unknown format %q (expected one of: %s)", format, strings.Join(WhichFormats, ", "))
+	}
+	return nil
+}
+
+// shellCommandLine renders an invocation as a single POSIX shell command line
+func shellCommandLine(inv Invocation) string {
+	parts := []string{}
+	if inv.Cwd != "" {
+		parts = append(parts, "cd", shellQuote(inv.Cwd), "&&")
+	}
+	for _, name := range sortCommands(inv.Env) {
+		parts = append(parts, name+"="+shellQuote(inv.Env[name]))
+	}
+	for _, arg := range inv.Argv {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellSafePattern matches strings that need no quoting in a POSIX shell
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,36 @@
+package internal
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"npm", "npm"},
+		{"./node_modules/.bin/tsc", "./node_modules/.bin/tsc"},
+		{"--filter=web", "--filter=web"},
+		{"hello world", "'hello world'"},
+		{"it's", `'it'\''s'`},
+		{"", "''"},
+		{"$HOME", "'$HOME'"},
+	}
+
+	for _, tt := range tests {
+		if result := shellQuote(tt.input); result != tt.expected {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestShellCommandLine(t *testing.T) {
+	inv := Invocation{
+		Argv: []string{"npm", "run", "test", "--", "--grep", "a b"},
+		Cwd:  "/work/my app",
+		Env:  map[string]string{"NODE_ENV": "test", "B": "x"},
+	}
+	expected := `cd '/work/my app' && B=x NODE_ENV=test npm run test -- --grep 'a b'`
+	if result := shellCommandLine(inv); result != expected {
+		t.Errorf("shellCommandLine() = %s, want %s", result, expected)
+	}
+}